@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+// runExport function runs the export subcommand, which exports the social graph with the same rules as GET /api/admin/graph
+// usage: golang_project export [-format graphml|gexf|dot|csv] [-ego email [-hops n]] [-domain domain] [-o file]
+// pass the arguments of the subcommand and the standard output as parameters
+// return the exit code: 0 on success, 2 when the export failed
+func runExport(args []string, stdout io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", models.GraphFormatGraphML, "format of the document: graphml, gexf, dot or csv")
+	ego := flags.String("ego", "", "email of the user at the center of an ego network")
+	hops := flags.Int("hops", 0, "depth of the ego network, from 1 (default) to 5")
+	domain := flags.String("domain", "", "keep the users of an email domain")
+	path := flags.String("o", "-", "file receiving the document, - for stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: golang_project export [options]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	out := stdout
+	if *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			slog.Error("cannot create the export file", slog.String("path", *path), slog.Any("error", err))
+			return 2
+		}
+		defer file.Close()
+		out = file
+	}
+
+	service := services.NewGraphExportService(repositories.NewGraphExportRepository(config.GetDBInstance()))
+	defer config.CloseDB()
+	if err := service.Export(context.Background(), out, *format, models.GraphExportFilter{Ego: *ego, Hops: *hops, Domain: *domain}); err != nil {
+		slog.Error("export failed", slog.Any("error", err))
+		return 2
+	}
+	return 0
+}
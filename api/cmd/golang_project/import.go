@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+// runImport function runs the import subcommand, which imports a CSV or JSONL file with the same rules as POST /api/admin/imports/{kind}
+// usage: golang_project import -kind users|friendships|subscriptions|blocks [-format csv|jsonl] [-dry-run] [-all-or-nothing] [-auto-create-users] <file|->
+// the report is written to stdout as JSON
+// pass the arguments of the subcommand, the standard input and the standard output as parameters
+// return the exit code: 0 when every row was imported, 1 when some rows failed, 2 when the import could not run
+func runImport(args []string, stdin io.Reader, stdout io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	kind := flags.String("kind", "", "kind of the records: users, friendships, subscriptions or blocks")
+	format := flags.String("format", "", "format of the file: csv or jsonl, guessed from the file extension when omitted")
+	dryRun := flags.Bool("dry-run", false, "validate the file without writing anything")
+	allOrNothing := flags.Bool("all-or-nothing", false, "write nothing when a row fails")
+	autoCreateUsers := flags.Bool("auto-create-users", false, "register the unknown emails of relationships instead of rejecting their rows")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: golang_project import -kind <kind> [options] <file|->")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	source := stdin
+	path := flags.Arg(0)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			slog.Error("cannot open the import file", slog.String("path", path), slog.Any("error", err))
+			return 2
+		}
+		defer file.Close()
+		source = file
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+			if *format == "ndjson" {
+				*format = models.ImportFormatJSONL
+			}
+		}
+	}
+
+	var options []repositories.BulkImportOption
+	if config.GetOutboxEnabled() {
+		options = append(options, repositories.WithBulkImportOutbox())
+	}
+	repo := repositories.NewBulkImportRepository(config.GetDBInstance(), options...)
+	// the memory cache belongs to the server process, only a redis cache can be invalidated from here
+	if config.GetCacheStore() == "redis" {
+		if _, err := config.GetRedisClient(); err != nil {
+			slog.Error("invalid REDIS_URL", slog.Any("error", err))
+			return 1
+		}
+		repo = repositories.NewCachedBulkImportRepository(repo, config.GetRelationshipCache())
+	}
+	service := services.NewBulkImportService(repo)
+	defer config.CloseDB()
+	report, err := service.Import(context.Background(), source, models.BulkImportOptions{
+		Kind:            *kind,
+		Format:          *format,
+		DryRun:          *dryRun,
+		AllOrNothing:    *allOrNothing,
+		AutoCreateUsers: *autoCreateUsers,
+	})
+	if err != nil {
+		slog.Error("import failed", slog.Any("error", err))
+		return 2
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		slog.Error("cannot write the import report", slog.Any("error", err))
+		return 2
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,16 +1,97 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log/slog"
+	"net"
 	"os"
 
 	"golang_project/api/internal/api/router"
 	"golang_project/api/internal/config"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/outbox"
 )
 
+// outboxPublisher is the message broker client the broker sink of the outbox sends the domain events with (OUTBOX_SINKS=broker),
+// e.g. a NATS or Kafka client implementing outbox.Publisher; none is bundled, plug one here, until then the broker sink is skipped with a warning
+var outboxPublisher outbox.Publisher
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT or API key, sent as "Bearer <credential>"
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key of a service account
 func main() {
+	slog.SetDefault(logger.New())
+
+	if len(os.Args) > 1 {
+		// the subcommands write their result to stdout, their logs go to stderr
+		switch os.Args[1] {
+		case "import":
+			slog.SetDefault(logger.NewWithWriter(os.Stderr))
+			os.Exit(runImport(os.Args[2:], os.Stdin, os.Stdout))
+		case "export":
+			slog.SetDefault(logger.NewWithWriter(os.Stderr))
+			os.Exit(runExport(os.Args[2:], os.Stdout))
+		}
+	}
+
+	flags := flag.NewFlagSet("golang_project", flag.ExitOnError)
+	storage := flags.String("storage", config.GetStorage(), "where the users and relationships are kept: postgres, sqlite, or memory for local demos")
+	_ = flags.Parse(os.Args[1:])
+	if err := config.SetStorage(*storage); err != nil {
+		slog.Error("invalid storage", slog.Any("error", err))
+		os.Exit(2)
+	}
+
+	if config.GetCacheStore() == "redis" {
+		if _, err := config.GetRedisClient(); err != nil {
+			slog.Error("invalid REDIS_URL", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	if config.GetStorage() == config.StorageSQLite {
+		if err := router.OpenSQLiteStorage(context.Background()); err != nil {
+			slog.Error("cannot open the sqlite database", slog.String("path", config.GetSQLitePath()), slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	if config.GetStorage() == config.StoragePostgres {
+		if err := config.WaitForDB(context.Background()); err != nil {
+			slog.Error("database is unreachable", slog.Duration("waited", config.GetDBConnectTimeout()), slog.Any("error", err))
+			os.Exit(1)
+		}
+		if config.GetOutboxEnabled() {
+			go router.NewOutboxRelay(outboxPublisher).Run(context.Background())
+		}
+	}
+
+	grpcServer := router.SetupGRPCServer()
+	grpcAddress := ":" + config.GetGRPCPort()
+	listener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		slog.Error("cannot listen on grpc address", slog.String("address", grpcAddress), slog.Any("error", err))
+		os.Exit(1)
+	}
+	go func() {
+		slog.Info("starting grpc server", slog.String("address", grpcAddress))
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("grpc server stopped", slog.Any("error", err))
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
 	server := router.SetupRouter()
 	port := ":" + os.Getenv("APP_PORT")
-	server.Run(port)
+	slog.Info("starting http server", slog.String("address", port))
+	if err := server.Run(port); err != nil {
+		slog.Error("http server stopped", slog.Any("error", err))
+	}
 
 	defer config.CloseDB()
 }
@@ -0,0 +1 @@
+drop table API_KEY;
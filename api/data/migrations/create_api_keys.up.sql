@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS API_KEY(key_id varchar primary key, name varchar not null, key_hash varchar not null,
+roles varchar[] not null default '{}', created_at timestamptz not null default now(), revoked_at timestamptz);
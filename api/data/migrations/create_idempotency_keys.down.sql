@@ -0,0 +1 @@
+drop table IDEMPOTENCY_KEY;
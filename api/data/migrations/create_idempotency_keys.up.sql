@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS IDEMPOTENCY_KEY(idempotency_key varchar(400) primary key, fingerprint char(64) not null,
+status_code integer, headers jsonb, body bytea, expires_at timestamptz not null);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at ON IDEMPOTENCY_KEY(expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS OUTBOX(outbox_id bigserial primary key, event_id uuid not null default gen_random_uuid(),
+event_type varchar not null, aggregate_id varchar not null, payload jsonb not null, request_id varchar,
+occurred_at timestamptz not null default now(), available_at timestamptz not null default now(),
+attempts integer not null default 0, last_error varchar, published_at timestamptz,
+CONSTRAINT outbox_event_id UNIQUE(event_id));
+
+-- the relay reads the unpublished events in order, and the unpublished events of an aggregate
+CREATE INDEX IF NOT EXISTS outbox_pending ON public.outbox(outbox_id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_pending_aggregate ON public.outbox(aggregate_id, outbox_id) WHERE published_at IS NULL;
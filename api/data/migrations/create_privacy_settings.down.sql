@@ -0,0 +1 @@
+drop table PRIVACY_SETTING;
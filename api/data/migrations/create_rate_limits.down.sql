@@ -0,0 +1 @@
+drop table RATE_LIMIT_BUCKET;
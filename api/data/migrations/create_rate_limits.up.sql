@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS RATE_LIMIT_BUCKET(bucket_key varchar primary key, tokens double precision not null,
+allowed boolean not null default true, updated_at timestamptz not null default now(), expires_at timestamptz not null);
+
+CREATE INDEX IF NOT EXISTS rate_limit_bucket_expires_at ON RATE_LIMIT_BUCKET(expires_at);
@@ -0,0 +1,2 @@
+drop table RELATIONSHIP_AUDIT;
+drop function relationship_audit_append_only;
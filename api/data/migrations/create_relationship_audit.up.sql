@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS RELATIONSHIP_AUDIT(audit_id bigserial primary key, requestor varchar not null, target varchar not null,
+action varchar not null, actor varchar, request_id varchar,
+before_is_friend boolean, before_friend_blocked boolean, before_subscribed boolean, before_subscribe_blocked boolean,
+after_is_friend boolean not null, after_friend_blocked boolean not null, after_subscribed boolean not null, after_subscribe_blocked boolean not null,
+changed_at timestamptz not null default now(),
+CONSTRAINT relationship_audit_action CHECK (action IN ('friend', 'unfriend', 'subscribe', 'unsubscribe', 'block', 'unblock')));
+
+CREATE INDEX IF NOT EXISTS relationship_audit_requestor ON public.relationship_audit(requestor, audit_id);
+CREATE INDEX IF NOT EXISTS relationship_audit_target ON public.relationship_audit(target, audit_id);
+CREATE INDEX IF NOT EXISTS relationship_audit_changed_at ON public.relationship_audit(changed_at);
+
+-- the audit is append-only: its rows are never updated nor deleted
+CREATE OR REPLACE FUNCTION relationship_audit_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'relationship_audit is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS relationship_audit_append_only ON public.relationship_audit;
+CREATE TRIGGER relationship_audit_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON public.relationship_audit
+FOR EACH STATEMENT EXECUTE FUNCTION relationship_audit_append_only();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.relationship_friends;
+DROP INDEX IF EXISTS public.relationship_subscribers;
@@ -1,12 +1,18 @@
 package router
 
 import (
+	"expvar"
+	"log/slog"
+
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang_project/api/internal/auth"
 	"golang_project/api/internal/config"
 	"golang_project/api/internal/controllers"
 	"golang_project/api/internal/docs"
+	"golang_project/api/internal/graphqlapi"
+	"golang_project/api/internal/middlewares"
 	"golang_project/api/internal/repositories"
 	"golang_project/api/internal/services"
 )
@@ -15,32 +21,124 @@ import (
 // no parameter
 // return a pointer of gin.Engine
 func SetupRouter() *gin.Engine {
-	friendConnectionRepo := repositories.New(config.GetDBInstance())
-	friendConnectionSrv := services.New(friendConnectionRepo)
+	// every API route requires credentials, except when AUTH_ENABLED is false
+	authenticator := newAuthenticator()
+	var authenticated []gin.HandlerFunc
+	if authenticator != nil {
+		authenticated = append(authenticated, middlewares.Authenticate(authenticator))
+	}
+
+	friendConnectionSrv := authorize(withQuotas(newFriendConnectionService()), authenticator)
 	friendConnectionCtrl := controllers.New(friendConnectionSrv)
+	userResourceCtrl := controllers.NewUserResourceController(friendConnectionSrv)
 
-	router := gin.Default()
-	docs.SwaggerInfo.BasePath = "/api/v1"
-	api := router.Group("/api")
+	router := gin.New()
+	router.Use(middlewares.RequestID(slog.Default()), middlewares.AccessLog(), middlewares.Recovery(), middlewares.CacheControl())
+	docs.SwaggerInfo.BasePath = "/api"
+	api := router.Group("/api", authenticated...)
 	{
-		v1 := api.Group("/v1")
+		v1 := api.Group("/v1", middlewares.Deprecated("/api/v2", config.GetAPIV1Sunset()))
 		{
-			v1.POST("/users/createUser", friendConnectionCtrl.CreateUser)
+			v1.POST("/users/createUser", middlewares.Timeout(config.GetRouteTimeout("createUser")), rateLimit("createUser"), idempotent(), friendConnectionCtrl.CreateUser)
 
-			v1.POST("/friends/createConnection", friendConnectionCtrl.CreateFriendConnection)
+			v1.POST("/friends/createConnection", middlewares.Timeout(config.GetRouteTimeout("createConnection")), rateLimit("createConnection"), idempotent(), friendConnectionCtrl.CreateFriendConnection)
 
-			v1.POST("/friends/showFriendsByEmail", friendConnectionCtrl.GetFriendListByEmail)
+			v1.POST("/friends/showFriendsByEmail", middlewares.Timeout(config.GetRouteTimeout("showFriendsByEmail")), rateLimit("showFriendsByEmail"), friendConnectionCtrl.GetFriendListByEmail)
 
-			v1.POST("/friends/showCommonFriendList", friendConnectionCtrl.ShowCommonFriendList)
+			v1.POST("/friends/showCommonFriendList", middlewares.Timeout(config.GetRouteTimeout("showCommonFriendList")), rateLimit("showCommonFriendList"), friendConnectionCtrl.ShowCommonFriendList)
 
-			v1.POST("/friends/subscribeFromEmail", friendConnectionCtrl.SubscribeFromEmail)
+			v1.POST("/friends/subscribeFromEmail", middlewares.Timeout(config.GetRouteTimeout("subscribeFromEmail")), rateLimit("subscribeFromEmail"), idempotent(), friendConnectionCtrl.SubscribeFromEmail)
 
-			v1.POST("/friends/blockSubscribeByEmail", friendConnectionCtrl.BlockSubscribeByEmail)
+			v1.POST("/friends/blockSubscribeByEmail", middlewares.Timeout(config.GetRouteTimeout("blockSubscribeByEmail")), rateLimit("blockSubscribeByEmail"), idempotent(), friendConnectionCtrl.BlockSubscribeByEmail)
 
-			v1.POST("/friends/showSubscribingEmailListByEmail", friendConnectionCtrl.GetSubscribingEmailListByEmail)
+			v1.POST("/friends/showSubscribingEmailListByEmail", middlewares.Timeout(config.GetRouteTimeout("showSubscribingEmailListByEmail")), rateLimit("showSubscribingEmailListByEmail"), idempotent(), friendConnectionCtrl.GetSubscribingEmailListByEmail)
+		}
+
+		v2 := api.Group("/v2")
+		{
+			v2.POST("/users", middlewares.Timeout(config.GetRouteTimeout("createUser")), rateLimit("createUser"), idempotent(), userResourceCtrl.CreateUser)
+
+			v2.GET("/users/:email/friends", middlewares.Timeout(config.GetRouteTimeout("showFriendsByEmail")), rateLimit("showFriendsByEmail"), userResourceCtrl.GetFriends)
+
+			v2.PUT("/users/:email/friends/:other", middlewares.Timeout(config.GetRouteTimeout("createConnection")), rateLimit("createConnection"), idempotent(), userResourceCtrl.PutFriend)
+
+			v2.DELETE("/users/:email/friends/:other", middlewares.Timeout(config.GetRouteTimeout("removeConnection")), rateLimit("removeConnection"), idempotent(), userResourceCtrl.DeleteFriend)
+
+			v2.GET("/users/:email/common-friends", middlewares.Timeout(config.GetRouteTimeout("showCommonFriendList")), rateLimit("showCommonFriendList"), userResourceCtrl.GetCommonFriends)
+
+			v2.PUT("/users/:email/subscriptions/:target", middlewares.Timeout(config.GetRouteTimeout("subscribeFromEmail")), rateLimit("subscribeFromEmail"), idempotent(), userResourceCtrl.PutSubscription)
+
+			v2.DELETE("/users/:email/subscriptions/:target", middlewares.Timeout(config.GetRouteTimeout("unsubscribeFromEmail")), rateLimit("unsubscribeFromEmail"), idempotent(), userResourceCtrl.DeleteSubscription)
+
+			v2.PUT("/users/:email/blocks/:target", middlewares.Timeout(config.GetRouteTimeout("blockSubscribeByEmail")), rateLimit("blockSubscribeByEmail"), idempotent(), userResourceCtrl.PutBlock)
+
+			v2.DELETE("/users/:email/blocks/:target", middlewares.Timeout(config.GetRouteTimeout("unblockSubscribeByEmail")), rateLimit("unblockSubscribeByEmail"), idempotent(), userResourceCtrl.DeleteBlock)
+
+			// the streamed recipients have their own timeout and rate limit, a stream to a large audience lasts longer than a list
+			v2.GET("/users/:email/recipients",
+				middlewares.ByAccept(controllers.NDJSONContentType, middlewares.Timeout(config.GetRouteTimeout("streamRecipients")), middlewares.Timeout(config.GetRouteTimeout("showSubscribingEmailListByEmail"))),
+				middlewares.ByAccept(controllers.NDJSONContentType, rateLimit("streamRecipients"), rateLimit("showSubscribingEmailListByEmail")),
+				userResourceCtrl.GetRecipients)
+
+			v2.POST("/users/:email/updates", middlewares.Timeout(config.GetRouteTimeout("postUpdate")), rateLimit("postUpdate"), idempotent(), userResourceCtrl.PostUpdate)
+
+			v2.GET("/users/:email/privacy", middlewares.Timeout(config.GetRouteTimeout("showPrivacySettings")), rateLimit("showPrivacySettings"), userResourceCtrl.GetPrivacySettings)
+
+			v2.PUT("/users/:email/privacy", middlewares.Timeout(config.GetRouteTimeout("updatePrivacySettings")), rateLimit("updatePrivacySettings"), idempotent(), userResourceCtrl.PutPrivacySettings)
+
+			v2.POST("/batch/friends", middlewares.Timeout(config.GetRouteTimeout("batchFriends")), rateLimit("batchFriends"), userResourceCtrl.BatchGetFriends)
+
+			v2.POST("/batch/subscribers", middlewares.Timeout(config.GetRouteTimeout("batchSubscribers")), rateLimit("batchSubscribers"), userResourceCtrl.BatchGetSubscribers)
+		}
+
+		// the API keys, imports, graph export and relationship audit are kept in Postgres only,
+		// their routes are not registered with the memory and SQLite storages rather than reading another database
+		if config.GetStorage() == config.StoragePostgres {
+			setupAdminRoutes(api.Group("/admin", middlewares.RequireRole(auth.RoleAdmin)))
+		} else {
+			slog.Warn("the admin routes need the postgres storage, they are disabled", slog.String("storage", config.GetStorage()))
 		}
 	}
 
+	graphql := router.Group("/graphql", authenticated...)
+	graphql.POST("", middlewares.Timeout(config.GetRouteTimeout("graphql")), rateLimit("graphql"), idempotent(), graphqlapi.NewHandler(friendConnectionSrv, graphqlapi.Limits{
+		MaxDepth:      config.GetGraphQLMaxDepth(),
+		MaxComplexity: config.GetGraphQLMaxComplexity(),
+	}))
+
+	// the cache counters and the other expvars, for the admins
+	router.GET("/debug/vars", append(authenticated, middlewares.RequireRole(auth.RoleAdmin), gin.WrapH(expvar.Handler()))...)
+
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	return router
 }
+
+func newFriendConnectionService() services.FriendConnectionService {
+	options := []services.Option{services.WithAutoCreateUsers(config.GetAutoCreateUsers())}
+	// the updates are not written by the repository, the service appends their events itself
+	if config.GetOutboxEnabled() && config.GetStorage() == config.StoragePostgres {
+		options = append(options, services.WithOutbox(repositories.NewOutboxRepository(config.GetDBInstance())))
+	}
+	return services.New(friendConnectionRepository(), options...)
+}
+
+// setupAdminRoutes function used to register the admin routes backed by Postgres
+// pass the gin.RouterGroup of the admin routes as parameter
+func setupAdminRoutes(admin *gin.RouterGroup) {
+	apiKeyCtrl := controllers.NewAPIKeyController(services.NewAPIKeyService(repositories.NewBreakerAPIKeyRepository(repositories.NewAPIKeyRepository(config.GetDBInstance()), config.GetDBBreaker())))
+	bulkImportCtrl := controllers.NewBulkImportController(services.NewBulkImportService(bulkImportRepository()))
+	graphExportCtrl := controllers.NewGraphExportController(services.NewGraphExportService(repositories.NewGraphExportRepository(config.GetDBInstance())))
+	relationshipAuditCtrl := controllers.NewRelationshipAuditController(services.NewRelationshipAuditService(repositories.NewRelationshipAuditRepository(config.GetDBInstance())))
+
+	admin.POST("/api-keys", middlewares.Timeout(config.GetRouteTimeout("createAPIKey")), rateLimit("createAPIKey"), apiKeyCtrl.CreateAPIKey)
+
+	admin.GET("/api-keys", middlewares.Timeout(config.GetRouteTimeout("listAPIKeys")), rateLimit("listAPIKeys"), apiKeyCtrl.ListAPIKeys)
+
+	admin.DELETE("/api-keys/:id", middlewares.Timeout(config.GetRouteTimeout("revokeAPIKey")), rateLimit("revokeAPIKey"), apiKeyCtrl.RevokeAPIKey)
+
+	admin.POST("/imports/:kind", middlewares.Timeout(config.GetRouteTimeout("bulkImport")), rateLimit("bulkImport"), bulkImportCtrl.Import)
+
+	admin.GET("/graph", middlewares.Timeout(config.GetRouteTimeout("exportGraph")), rateLimit("exportGraph"), graphExportCtrl.ExportGraph)
+
+	admin.GET("/relationship-audit", middlewares.Timeout(config.GetRouteTimeout("relationshipAudit")), rateLimit("relationshipAudit"), relationshipAuditCtrl.ListRelationshipAudit)
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"log/slog"
+	"os"
+
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/policies"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+// newAuthenticator function used to build the Authenticator shared by the HTTP, GraphQL and gRPC APIs
+// return nil when authentication is disabled, the process exits when the JWKS file cannot be loaded
+func newAuthenticator() *auth.Authenticator {
+	if !config.GetAuthEnabled() {
+		slog.Warn("AUTH_ENABLED is false, the APIs accept unauthenticated requests")
+		return nil
+	}
+
+	jwtConfig := auth.JWTConfig{
+		HS256Secret: config.GetJWTHS256Secret(),
+		Issuer:      config.GetJWTIssuer(),
+		Audience:    config.GetJWTAudience(),
+	}
+	// anyone who knows the secret can sign tokens with any role, a guessable secret would open the admin routes
+	if err := auth.CheckHS256Secret(jwtConfig.HS256Secret); err != nil {
+		slog.Error("invalid JWT_HS256_SECRET", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if path := config.GetJWTJWKSFile(); path != "" {
+		keys, err := auth.LoadJWKSFile(path)
+		if err != nil {
+			slog.Error("cannot load the JWKS file", slog.String("path", path), slog.Any("error", err))
+			os.Exit(1)
+		}
+		jwtConfig.RS256Keys = keys
+	}
+
+	var jwtVerifier *auth.JWTVerifier
+	if len(jwtConfig.HS256Secret) > 0 || len(jwtConfig.RS256Keys) > 0 {
+		jwtVerifier = auth.NewJWTVerifier(jwtConfig)
+	} else {
+		slog.Warn("neither JWT_HS256_SECRET nor JWT_JWKS_FILE is set, only API keys are accepted")
+	}
+
+	// the API keys are kept in Postgres, like their admin routes
+	var apiKeyVerifier *auth.APIKeyVerifier
+	if config.GetStorage() == config.StoragePostgres {
+		apiKeyVerifier = auth.NewAPIKeyVerifier(repositories.NewBreakerAPIKeyRepository(repositories.NewAPIKeyRepository(config.GetDBInstance()), config.GetDBBreaker()))
+	} else {
+		slog.Warn("API keys need the postgres storage, only JWTs are accepted", slog.String("storage", config.GetStorage()))
+	}
+	return auth.NewAuthenticator(jwtVerifier, apiKeyVerifier)
+}
+
+// authorize function used to guard a FriendConnectionService with the authorization policy,
+// the service is returned as it is when authentication is disabled since there is no principal to authorize
+// the process exits when the policy file cannot be loaded
+func authorize(service services.FriendConnectionService, authenticator *auth.Authenticator) services.FriendConnectionService {
+	if authenticator == nil {
+		return service
+	}
+
+	rules := policies.DefaultRules
+	if path := config.GetAuthzPolicyFile(); path != "" {
+		var err error
+		if rules, err = policies.LoadRules(path); err != nil {
+			slog.Error("cannot load the authorization policy file", slog.String("path", path), slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	policy, err := policies.New(rules,
+		policies.WithVisibility(services.NewPrivacyChecker(friendConnectionRepository())),
+		policies.WithAuditLog(policies.NewLogAuditLog(slog.Default())))
+	if err != nil {
+		slog.Error("invalid authorization policy", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return policies.NewFriendConnectionService(service, policy)
+}
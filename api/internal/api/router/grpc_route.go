@@ -0,0 +1,22 @@
+package router
+
+import (
+	"log/slog"
+
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/grpcapi"
+	"google.golang.org/grpc"
+)
+
+// SetupGRPCServer function used to initialize the gRPC server, backed by the same service layer, authentication and authorization as the HTTP routes
+// no parameter
+// return a pointer of grpc.Server
+func SetupGRPCServer() *grpc.Server {
+	authenticator := newAuthenticator()
+	server, _ := grpcapi.NewServer(authorize(withQuotas(newFriendConnectionService()), authenticator), grpcapi.Options{
+		Logger:         slog.Default(),
+		Authenticator:  authenticator,
+		RequestTimeout: config.GetRequestTimeout(),
+	})
+	return server
+}
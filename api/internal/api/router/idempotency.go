@@ -0,0 +1,35 @@
+package router
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/idempotency"
+	"golang_project/api/internal/middlewares"
+)
+
+var (
+	idempotencyStoreOnce sync.Once
+	idempotencyStore     idempotency.Store
+)
+
+// idempotent function used to build the middleware honoring the Idempotency-Key header of the write routes,
+// every route shares the same store so that a key cannot be reused across routes
+// the middleware does nothing when idempotency keys are disabled
+func idempotent() gin.HandlerFunc {
+	idempotencyStoreOnce.Do(func() {
+		if !config.GetIdempotencyEnabled() {
+			return
+		}
+		if config.GetIdempotencyStore() == "postgres" {
+			idempotencyStore = idempotency.NewBreakerStore(idempotency.NewPostgresStore(config.GetDBInstance()), config.GetDBBreaker())
+			return
+		}
+		idempotencyStore = idempotency.NewMemoryStore()
+	})
+	if idempotencyStore == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middlewares.Idempotency(idempotencyStore, config.GetIdempotencyTTL())
+}
@@ -0,0 +1,61 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/fanout"
+	"golang_project/api/internal/outbox"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+// outboxWebhookTimeout is how long the webhook sink of the outbox waits for the answer to an event
+const outboxWebhookTimeout = 10 * time.Second
+
+// NewOutboxRelay function used to build the relay publishing the domain events of outbox table to the sinks of OUTBOX_SINKS,
+// the fanout sink reads the recipients through the repository of the routes, with its replicas, retries, breaker and cache
+// pass the message broker client of the broker sink, e.g. a NATS or Kafka client, as parameter, nil when none is plugged
+// return a pointer of outbox.Relay
+func NewOutboxRelay(publisher outbox.Publisher) *outbox.Relay {
+	sinks := outboxSinks(config.GetOutboxSinks(), slog.Default(), publisher, services.New(friendConnectionRepository()))
+	return outbox.NewRelay(repositories.NewOutboxRepository(config.GetDBInstance()), outbox.NewMultiSink(sinks...),
+		config.GetOutboxBatchSize(), config.GetOutboxPollInterval(), config.GetOutboxLease(), config.GetOutboxRetries())
+}
+
+// outboxSinks function used to build the sinks named by OUTBOX_SINKS, the unknown sinks and the sinks missing their settings
+// are skipped with a warning, and the log sink is used when none is left
+// pass the names of the sinks, the logger of the log sinks, the broker client of the broker sink, nil when none is plugged,
+// and the service resolving the recipients of the fanout sink as parameters
+// return an array of outbox.Sink
+func outboxSinks(names []string, logger *slog.Logger, publisher outbox.Publisher, recipients services.FriendConnectionService) []outbox.Sink {
+	var sinks []outbox.Sink
+	for _, name := range names {
+		switch name {
+		case "log":
+			sinks = append(sinks, outbox.NewLogSink(logger))
+		case "webhook":
+			if config.GetOutboxWebhookURL() == "" {
+				slog.Warn("the webhook sink of the outbox is skipped, OUTBOX_WEBHOOK_URL is empty")
+				continue
+			}
+			sinks = append(sinks, outbox.NewWebhookSink(config.GetOutboxWebhookURL(), config.GetOutboxWebhookSecret(), &http.Client{Timeout: outboxWebhookTimeout}))
+		case "broker":
+			if publisher == nil {
+				slog.Warn("the broker sink of the outbox is skipped, no broker client is plugged")
+				continue
+			}
+			sinks = append(sinks, outbox.NewPublisherSink(publisher, config.GetOutboxBrokerPrefix()))
+		case "fanout":
+			sinks = append(sinks, fanout.NewSink(recipients, config.GetFanoutWorkers(), fanout.NewLogDeliverer(logger)))
+		default:
+			slog.Warn("unknown sink of the outbox is skipped", slog.String("sink", name))
+		}
+	}
+	if len(sinks) == 0 {
+		sinks = append(sinks, outbox.NewLogSink(logger))
+	}
+	return sinks
+}
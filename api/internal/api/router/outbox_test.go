@@ -0,0 +1,66 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/outbox"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+func TestOutboxSinksFollowTheirNames(t *testing.T) {
+	webhooks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhooks++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	t.Setenv("OUTBOX_WEBHOOK_URL", server.URL)
+	t.Setenv("OUTBOX_BROKER_PREFIX", "events.")
+
+	repo := repositories.NewMemoryRepository()
+	ctx := context.Background()
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{"sender@example.com", "fan@example.com"}))
+	_, err := repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "fan@example.com", Target: "sender@example.com"})
+	assert.Nil(t, err)
+	var logs bytes.Buffer
+	publisher := &publisherFake{}
+
+	sinks := outboxSinks([]string{"log", "webhook", "broker", "fanout", "unknown"}, slog.New(slog.NewJSONHandler(&logs, nil)), publisher, services.New(repo))
+	assert.Len(t, sinks, 4)
+	err = outbox.NewMultiSink(sinks...).Publish(ctx, models.Event{ID: "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", Type: models.EventUpdatePosted,
+		AggregateID: "sender@example.com", Payload: []byte(`{"sender":"sender@example.com","text":"hi"}`)})
+	assert.Nil(t, err)
+	assert.Contains(t, logs.String(), `"msg":"domain event"`)
+	assert.Equal(t, 1, webhooks)
+	assert.Equal(t, []string{"events.UpdatePosted"}, publisher.subjects)
+	assert.Contains(t, logs.String(), `"msg":"update delivered","event_id":"0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10","sender":"sender@example.com","recipient":"fan@example.com"`)
+}
+
+func TestOutboxSinksFallBackToTheLogSink(t *testing.T) {
+	t.Setenv("OUTBOX_WEBHOOK_URL", "")
+	var logs bytes.Buffer
+
+	// the webhook sink needs OUTBOX_WEBHOOK_URL and the broker sink a plugged client
+	sinks := outboxSinks([]string{"webhook", "broker"}, slog.New(slog.NewJSONHandler(&logs, nil)), nil, nil)
+	assert.Len(t, sinks, 1)
+	assert.Nil(t, sinks[0].Publish(context.Background(), models.Event{Type: models.EventUserCreated, AggregateID: "abc@def.com"}))
+	assert.Contains(t, logs.String(), `"msg":"domain event"`)
+}
+
+// publisherFake records the subjects of the published messages
+type publisherFake struct {
+	subjects []string
+}
+
+func (p *publisherFake) Publish(ctx context.Context, subject, key string, data []byte) error {
+	p.subjects = append(p.subjects, subject)
+	return nil
+}
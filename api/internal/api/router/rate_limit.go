@@ -0,0 +1,54 @@
+package router
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/middlewares"
+	"golang_project/api/internal/ratelimit"
+	"golang_project/api/internal/services"
+)
+
+var (
+	rateLimitStoreOnce sync.Once
+	rateLimitStore     ratelimit.Store
+)
+
+// getRateLimitStore function used to get the Store shared by the HTTP rate limits and the quotas of every API,
+// so that a user cannot double its quota by switching from HTTP to gRPC
+// return nil when rate limiting is disabled
+func getRateLimitStore() ratelimit.Store {
+	rateLimitStoreOnce.Do(func() {
+		if !config.GetRateLimitEnabled() {
+			return
+		}
+		if config.GetRateLimitStore() == "postgres" {
+			rateLimitStore = ratelimit.NewBreakerStore(ratelimit.NewPostgresStore(config.GetDBInstance()), config.GetDBBreaker())
+			return
+		}
+		rateLimitStore = ratelimit.NewMemoryStore()
+	})
+	return rateLimitStore
+}
+
+// rateLimit function used to build the rate limiting middleware of a route, configured with config.GetRouteRateLimits
+// the middleware does nothing when rate limiting is disabled
+func rateLimit(route string) gin.HandlerFunc {
+	store := getRateLimitStore()
+	if store == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	perPrincipal, perIP := config.GetRouteRateLimits(route)
+	return middlewares.RateLimit(store, route, perPrincipal, perIP)
+}
+
+// withQuotas function used to enforce the business quotas on a FriendConnectionService
+// the service is returned as it is when rate limiting is disabled
+func withQuotas(service services.FriendConnectionService) services.FriendConnectionService {
+	store := getRateLimitStore()
+	if store == nil {
+		return service
+	}
+	return ratelimit.NewFriendConnectionService(service, store, config.GetQuotas())
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"golang_project/api/internal/config"
+	"golang_project/api/internal/repositories"
+)
+
+var (
+	friendConnectionRepoOnce sync.Once
+	friendConnectionRepo     repositories.FriendConnectionRepository
+)
+
+// friendConnectionRepository function used to get the FriendConnectionRepository of the configured storage,
+// the HTTP routes, the gRPC server and the authorization policy share it so that they see the same data in memory
+// the friend lists and the recipients are cached when CACHE_STORE is set
+func friendConnectionRepository() repositories.FriendConnectionRepository {
+	friendConnectionRepoOnce.Do(func() {
+		friendConnectionRepo = newStorageRepository()
+		if c := config.GetRelationshipCache(); c != nil {
+			friendConnectionRepo = repositories.NewCachedRepository(friendConnectionRepo, c)
+		}
+	})
+	return friendConnectionRepo
+}
+
+// bulkImportRepository function used to get the BulkImportRepository of the imports,
+// it invalidates the cached friend lists and recipients of the users it relates
+func bulkImportRepository() repositories.BulkImportRepository {
+	var options []repositories.BulkImportOption
+	if config.GetOutboxEnabled() {
+		options = append(options, repositories.WithBulkImportOutbox())
+	}
+	repo := repositories.NewBulkImportRepository(config.GetDBInstance(), options...)
+	if c := config.GetRelationshipCache(); c != nil {
+		return repositories.NewCachedBulkImportRepository(repo, c)
+	}
+	return repo
+}
+
+// OpenSQLiteStorage function used at startup by the sqlite storage to open the database file and to create or upgrade its schema
+// pass a context as parameter
+// return an error type
+func OpenSQLiteStorage(ctx context.Context) error {
+	if err := config.OpenSQLite(ctx); err != nil {
+		return err
+	}
+	return repositories.MigrateSQLite(ctx, config.GetSQLiteDBInstance())
+}
+
+func newStorageRepository() repositories.FriendConnectionRepository {
+	switch config.GetStorage() {
+	case config.StorageMemory:
+		slog.Warn("users and relationships are kept in memory, they are lost when the server stops")
+		return repositories.NewMemoryRepository()
+	case config.StorageSQLite:
+		return repositories.NewSQLiteRepository(config.GetSQLiteDBInstance())
+	}
+	options := []repositories.Option{repositories.WithReplicas(config.GetReplicaSet()), repositories.WithReadRetries(config.GetDBReadRetries())}
+	if config.GetOutboxEnabled() {
+		options = append(options, repositories.WithOutbox())
+	}
+	repo := repositories.New(config.GetDBInstance(), options...)
+	return repositories.NewBreakerRepository(repo, config.GetDBBreaker())
+}
@@ -0,0 +1,194 @@
+package apperrors
+
+import (
+	"context"
+	"errors"
+)
+
+// Kind classifies a domain error, every transport (HTTP, gRPC...) maps a Kind to its own status codes
+type Kind string
+
+const (
+	// KindInvalidRequest means the request itself is malformed (missing fields, bad JSON...)
+	KindInvalidRequest Kind = "invalid_request"
+	// KindValidation means the request is well-formed but violates a domain rule
+	KindValidation Kind = "validation_failed"
+	// KindNotFound means a referenced resource, usually a user, does not exist
+	KindNotFound Kind = "not_found"
+	// KindAlreadyExists means the resource being created exists already
+	KindAlreadyExists Kind = "already_exists"
+	// KindConflict means the operation conflicts with a concurrent change and can be retried
+	KindConflict Kind = "conflict"
+	// KindUnauthenticated means the caller did not provide valid credentials
+	KindUnauthenticated Kind = "unauthenticated"
+	// KindForbidden means the caller is authenticated but not allowed to perform the operation
+	KindForbidden Kind = "forbidden"
+	// KindBlocked means the operation is not allowed because of a block between the users
+	KindBlocked Kind = "blocked"
+	// KindRateLimited means the caller exceeded a rate limit or a quota and must retry later
+	KindRateLimited Kind = "rate_limited"
+	// KindTimeout means the request's deadline expired before the operation completed
+	KindTimeout Kind = "timeout"
+	// KindCanceled means the client went away before the operation completed
+	KindCanceled Kind = "canceled"
+	// KindUnavailable means a dependency such as the database is temporarily unavailable
+	KindUnavailable Kind = "unavailable"
+	// KindInternal means an unexpected failure, its details must never be exposed to clients
+	KindInternal Kind = "internal"
+)
+
+// Error struct is the domain error returned by the Service and Repository layers
+// Code is a stable machine-readable identifier, Message is safe to show to clients,
+// Details carries optional structured data (e.g. the list of unknown emails) and Err keeps the underlying cause for logging
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details map[string]interface{}
+	Err     error
+}
+
+// Error function returns the client-safe message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap function returns the underlying cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is function makes errors.Is match any Error of the same Kind, so the Err* sentinels below can be used as targets
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Kind == t.Kind && (t.Code == "" || t.Code == e.Code)
+}
+
+// WithDetail function returns a copy of the error with an extra structured detail
+// pass a key string and a value as parameters
+// return a pointer of Error
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	cp := *e
+	cp.Details = make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		cp.Details[k] = v
+	}
+	cp.Details[key] = value
+	return &cp
+}
+
+// sentinels used as errors.Is targets, they match every Error of the same Kind
+var (
+	ErrInvalidRequest  = &Error{Kind: KindInvalidRequest}
+	ErrValidation      = &Error{Kind: KindValidation}
+	ErrNotFound        = &Error{Kind: KindNotFound}
+	ErrAlreadyExists   = &Error{Kind: KindAlreadyExists}
+	ErrConflict        = &Error{Kind: KindConflict}
+	ErrUnauthenticated = &Error{Kind: KindUnauthenticated}
+	ErrForbidden       = &Error{Kind: KindForbidden}
+	ErrBlocked         = &Error{Kind: KindBlocked}
+	ErrRateLimited     = &Error{Kind: KindRateLimited}
+	ErrTimeout         = &Error{Kind: KindTimeout}
+	ErrCanceled        = &Error{Kind: KindCanceled}
+	ErrUnavailable     = &Error{Kind: KindUnavailable}
+	ErrInternal        = &Error{Kind: KindInternal}
+)
+
+// New function used to create a domain error
+// pass a Kind, a machine-readable code and a client-safe message as parameters
+// return a pointer of Error
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap function used to create a domain error which keeps the underlying cause
+// pass a cause error, a Kind, a machine-readable code and a client-safe message as parameters
+// return a pointer of Error
+func Wrap(err error, kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+// InvalidRequest function used to create an error of KindInvalidRequest
+func InvalidRequest(code, message string) *Error {
+	return New(KindInvalidRequest, code, message)
+}
+
+// Validation function used to create an error of KindValidation
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+// NotFound function used to create an error of KindNotFound
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// AlreadyExists function used to create an error of KindAlreadyExists
+func AlreadyExists(code, message string) *Error {
+	return New(KindAlreadyExists, code, message)
+}
+
+// Conflict function used to create an error of KindConflict
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// Unauthenticated function used to create an error of KindUnauthenticated
+func Unauthenticated(code, message string) *Error {
+	return New(KindUnauthenticated, code, message)
+}
+
+// Forbidden function used to create an error of KindForbidden
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+// Blocked function used to create an error of KindBlocked
+func Blocked(code, message string) *Error {
+	return New(KindBlocked, code, message)
+}
+
+// RateLimited function used to create an error of KindRateLimited
+func RateLimited(code, message string) *Error {
+	return New(KindRateLimited, code, message)
+}
+
+// Internal function used to wrap an unexpected error, the cause is kept for logging but hidden from clients
+func Internal(err error) *Error {
+	return Wrap(err, KindInternal, "internal_error", "internal server error")
+}
+
+// From function used to convert any error into a domain error
+// domain errors are returned as they are, context errors become Timeout/Canceled and anything else becomes Internal
+// pass an error as parameter
+// return a pointer of Error, nil when err is nil
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, KindTimeout, "timeout", "the request took too long to complete")
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, KindCanceled, "canceled", "the request was canceled")
+	default:
+		return Internal(err)
+	}
+}
+
+// KindOf function used to get the Kind of any error
+// pass an error as parameter
+// return a Kind, empty when err is nil
+func KindOf(err error) Kind {
+	if appErr := From(err); appErr != nil {
+		return appErr.Kind
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package apperrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	notFound := NotFound("user_not_found", "the user does not exist")
+	cause := errors.New("connection refused")
+
+	tests := map[string]struct {
+		err      error
+		expected *Error
+	}{
+		"nil":                {nil, nil},
+		"domain error":       {notFound, notFound},
+		"wrapped domain":     {fmt.Errorf("find user: %w", notFound), notFound},
+		"deadline":           {context.DeadlineExceeded, Wrap(context.DeadlineExceeded, KindTimeout, "timeout", "the request took too long to complete")},
+		"wrapped deadline":   {fmt.Errorf("query: %w", context.DeadlineExceeded), Wrap(fmt.Errorf("query: %w", context.DeadlineExceeded), KindTimeout, "timeout", "the request took too long to complete")},
+		"canceled":           {context.Canceled, Wrap(context.Canceled, KindCanceled, "canceled", "the request was canceled")},
+		"foreign error":      {cause, Internal(cause)},
+		"wrapped by a cause": {Wrap(cause, KindUnavailable, "database_unavailable", "retry later"), Wrap(cause, KindUnavailable, "database_unavailable", "retry later")},
+	}
+	for name, test := range tests {
+		assert.Equal(t, test.expected, From(test.err), name)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, Kind(""), KindOf(nil))
+	assert.Equal(t, KindBlocked, KindOf(fmt.Errorf("subscribe: %w", Blocked("blocked", "the target blocked the requestor"))))
+	assert.Equal(t, KindTimeout, KindOf(context.DeadlineExceeded))
+	assert.Equal(t, KindInternal, KindOf(errors.New("boom")))
+}
+
+func TestWrapKeepsTheCause(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(cause, KindUnavailable, "database_unavailable", "the database is unavailable, retry later")
+
+	assert.Equal(t, "the database is unavailable, retry later", err.Error())
+	assert.ErrorIs(t, err, cause)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.NotErrorIs(t, err, ErrInternal)
+	assert.ErrorIs(t, err, New(KindUnavailable, "database_unavailable", ""))
+	assert.NotErrorIs(t, err, New(KindUnavailable, "cache_unavailable", ""))
+}
+
+func TestWithDetailCopiesTheError(t *testing.T) {
+	err := NotFound("user_not_found", "the user does not exist").WithDetail("emails", []string{"kate@example.com"})
+	detailed := err.WithDetail("retry_after", 7)
+
+	assert.Equal(t, map[string]interface{}{"emails": []string{"kate@example.com"}}, err.Details)
+	assert.Equal(t, map[string]interface{}{"emails": []string{"kate@example.com"}, "retry_after": 7}, detailed.Details)
+	assert.Equal(t, err.Code, detailed.Code)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+// APIKeyPrefix is the prefix of every API key, it tells API keys and JWTs apart and makes leaked keys easy to scan for
+const APIKeyPrefix = "fck_"
+
+// ErrInvalidAPIKey is returned when an API key is unknown, revoked or does not match its hash
+var ErrInvalidAPIKey = apperrors.Unauthenticated("invalid_api_key", "invalid api key")
+
+// APIKeyStore interface declares the lookup used to verify API keys, it is implemented by repositories.APIKeyRepository
+type APIKeyStore interface {
+	FindAPIKeyByID(ctx context.Context, id string) (models.APIKey, error)
+}
+
+// GenerateAPIKey function used to create a new random API key, formatted as "fck_<id>_<secret>"
+// no parameter
+// return the id, the hash of the secret to store, the plain key to hand out once and an error type
+func GenerateAPIKey() (id, hash, key string, err error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+	id = hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return id, HashAPIKeySecret(secret), APIKeyPrefix + id + "_" + secret, nil
+}
+
+// HashAPIKeySecret function used to hash the secret part of an API key
+// the secret carries 256 bits of entropy so a plain SHA-256 is enough, no slow password hash is needed
+// pass the secret as parameter
+// return the hex encoded hash
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAPIKey function splits an API key into its id and secret
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// APIKeyVerifier struct verifies API keys against their stored hash
+type APIKeyVerifier struct {
+	store APIKeyStore
+}
+
+// NewAPIKeyVerifier function used for initializing an APIKeyVerifier
+// pass an APIKeyStore as parameter
+// return a pointer of APIKeyVerifier
+func NewAPIKeyVerifier(store APIKeyStore) *APIKeyVerifier {
+	return &APIKeyVerifier{store: store}
+}
+
+// Verify function used to check an API key
+// pass a context and the plain key as parameters
+// return the Principal of the service account and an error type
+func (v *APIKeyVerifier) Verify(ctx context.Context, key string) (Principal, error) {
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return Principal{}, ErrInvalidAPIKey
+	}
+
+	stored, err := v.store.FindAPIKeyByID(ctx, id)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return Principal{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if stored.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(HashAPIKeySecret(secret))) != 1 {
+		return Principal{}, ErrInvalidAPIKey
+	}
+
+	return Principal{Subject: stored.ID, Roles: stored.Roles, Method: MethodAPIKey}, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestGenerateAndVerifyAPIKey(t *testing.T) {
+	id, hash, key, err := GenerateAPIKey()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix+id+"_"))
+	assert.NotContains(t, key, hash)
+
+	store := apiKeyStoreFake{id: {ID: id, KeyHash: hash, Roles: []string{RoleService}}}
+	authenticator := NewAuthenticator(nil, NewAPIKeyVerifier(store))
+
+	principal, err := authenticator.Authenticate(context.Background(), key)
+	assert.Nil(t, err)
+	assert.Equal(t, Principal{Subject: id, Roles: []string{RoleService}, Method: MethodAPIKey}, principal)
+
+	_, err = authenticator.Authenticate(context.Background(), key+"x")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = authenticator.Authenticate(context.Background(), APIKeyPrefix+"unknown_secret")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = authenticator.Authenticate(context.Background(), APIKeyPrefix+"malformed")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	revokedAt := time.Now()
+	store[id] = models.APIKey{ID: id, KeyHash: hash, Roles: []string{RoleService}, RevokedAt: &revokedAt}
+	_, err = authenticator.Authenticate(context.Background(), key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAuthenticateWithoutCredential(t *testing.T) {
+	authenticator := NewAuthenticator(nil, nil)
+
+	_, err := authenticator.Authenticate(context.Background(), " ")
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+	_, err = authenticator.Authenticate(context.Background(), "a.jwt.token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAPIKeyStoreFailureIsNotAnAuthenticationError(t *testing.T) {
+	authenticator := NewAuthenticator(nil, NewAPIKeyVerifier(apiKeyStoreFake{}))
+
+	_, err := authenticator.Authenticate(context.Background(), APIKeyPrefix+"outage_secret")
+	assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+}
+
+type apiKeyStoreFake map[string]models.APIKey
+
+func (s apiKeyStoreFake) FindAPIKeyByID(ctx context.Context, id string) (models.APIKey, error) {
+	if id == "outage" {
+		return models.APIKey{}, apperrors.New(apperrors.KindUnavailable, "database_unavailable", "database unavailable")
+	}
+	key, ok := s[id]
+	if !ok {
+		return models.APIKey{}, apperrors.NotFound("api_key_not_found", "api key not found")
+	}
+	return key, nil
+}
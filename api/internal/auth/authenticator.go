@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+)
+
+// ErrMissingCredentials is returned when a request carries neither a bearer token nor an API key
+var ErrMissingCredentials = apperrors.Unauthenticated("missing_credentials", "missing credentials, expected an Authorization bearer token or an X-API-Key header")
+
+// Authenticator struct authenticates the credentials of a request, shared by the HTTP, GraphQL and gRPC APIs
+// credentials starting with APIKeyPrefix are API keys, anything else is a JWT
+type Authenticator struct {
+	jwt     *JWTVerifier
+	apiKeys *APIKeyVerifier
+}
+
+// NewAuthenticator function used for initializing an Authenticator, a nil verifier disables the matching credential type
+// pass a pointer of JWTVerifier and a pointer of APIKeyVerifier as parameters
+// return a pointer of Authenticator
+func NewAuthenticator(jwt *JWTVerifier, apiKeys *APIKeyVerifier) *Authenticator {
+	return &Authenticator{jwt: jwt, apiKeys: apiKeys}
+}
+
+// Authenticate function used to verify a credential
+// pass a context and the credential (a JWT or an API key) as parameters
+// return the authenticated Principal and an error type
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (Principal, error) {
+	credential = strings.TrimSpace(credential)
+	switch {
+	case credential == "":
+		return Principal{}, ErrMissingCredentials
+	case strings.HasPrefix(credential, APIKeyPrefix):
+		if a.apiKeys == nil {
+			return Principal{}, ErrInvalidAPIKey
+		}
+		return a.apiKeys.Verify(ctx, credential)
+	default:
+		if a.jwt == nil {
+			return Principal{}, ErrInvalidToken
+		}
+		return a.jwt.Verify(credential)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/pkg"
+)
+
+// clockSkew is the leeway tolerated on the exp, nbf and iat claims
+const clockSkew = 30 * time.Second
+
+// MinHS256SecretLength is the minimum length in bytes of an HS256 secret, the 256 bits of the HMAC-SHA256 key
+const MinHS256SecretLength = 32
+
+// knownHS256Secrets are secrets published with the project, anyone could sign tokens with them
+var knownHS256Secrets = []string{"local-development-secret"}
+
+// ErrInvalidToken is returned when a JWT cannot be verified, the reason is only logged to avoid helping attackers
+var ErrInvalidToken = apperrors.Unauthenticated("invalid_token", "invalid or expired token")
+
+// JWTConfig struct used to configure the verification of JWT bearer tokens
+// at least one of HS256Secret and RS256Keys must be set, Issuer and Audience are checked only when set
+type JWTConfig struct {
+	HS256Secret []byte
+	RS256Keys   map[string]*rsa.PublicKey
+	Issuer      string
+	Audience    string
+}
+
+// jwtClaims struct holds the claims read from a token, the subject is the email of the user
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// JWTVerifier struct verifies HS256 and RS256 tokens
+type JWTVerifier struct {
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+// CheckHS256Secret function used to refuse an HS256 secret which is easy to guess: shorter than MinHS256SecretLength or published with the project
+// pass the secret as parameter
+// return an error type, nil when the secret is empty (HS256 disabled) or strong enough
+func CheckHS256Secret(secret []byte) error {
+	if len(secret) == 0 {
+		return nil
+	}
+	for _, known := range knownHS256Secrets {
+		if string(secret) == known {
+			return fmt.Errorf("the HS256 secret is a sample value published with the project")
+		}
+	}
+	if len(secret) < MinHS256SecretLength {
+		return fmt.Errorf("the HS256 secret has %d bytes, at least %d are required", len(secret), MinHS256SecretLength)
+	}
+	return nil
+}
+
+// NewJWTVerifier function used for initializing a JWTVerifier
+// pass a JWTConfig as parameter
+// return a pointer of JWTVerifier
+func NewJWTVerifier(config JWTConfig) *JWTVerifier {
+	var methods []string
+	if len(config.HS256Secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(config.RS256Keys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithLeeway(clockSkew)}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	return &JWTVerifier{config: config, parser: jwt.NewParser(options...)}
+}
+
+// Verify function used to check the signature and the claims of a token
+// the subject must be a valid email address, the roles claim defaults to the user role
+// pass the raw token as parameter
+// return the Principal of the token and an error type
+func (v *JWTVerifier) Verify(raw string) (Principal, error) {
+	var claims jwtClaims
+	if _, err := v.parser.ParseWithClaims(raw, &claims, v.key); err != nil {
+		return Principal{}, apperrors.Wrap(err, ErrInvalidToken.Kind, ErrInvalidToken.Code, ErrInvalidToken.Message)
+	}
+	if err := pkg.CheckValidEmail(claims.Subject); err != nil {
+		return Principal{}, apperrors.Wrap(fmt.Errorf("subject %q is not an email address", claims.Subject), ErrInvalidToken.Kind, ErrInvalidToken.Code, ErrInvalidToken.Message)
+	}
+
+	roles := []string{}
+	for _, role := range claims.Roles {
+		if IsValidRole(role) {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = []string{RoleUser}
+	}
+	return Principal{Subject: claims.Subject, Email: claims.Subject, Roles: roles, Method: MethodJWT}, nil
+}
+
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.config.HS256Secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.config.RS256Keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+}
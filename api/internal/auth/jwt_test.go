@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+)
+
+var hs256Secret = []byte("test-secret")
+
+func TestVerifyHS256Token(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{HS256Secret: hs256Secret})
+
+	principal, err := verifier.Verify(signHS256(t, jwt.MapClaims{"sub": "thehaohcm@yahoo.com.vn", "roles": []string{"admin", "unknown"}}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Principal{Subject: "thehaohcm@yahoo.com.vn", Email: "thehaohcm@yahoo.com.vn", Roles: []string{RoleAdmin}, Method: MethodJWT}, principal)
+}
+
+func TestVerifyTokenDefaultsToUserRole(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{HS256Secret: hs256Secret})
+
+	principal, err := verifier.Verify(signHS256(t, jwt.MapClaims{"sub": "thehaohcm@yahoo.com.vn"}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{RoleUser}, principal.Roles)
+}
+
+func TestVerifyRejectedTokens(t *testing.T) {
+	verifier := NewJWTVerifier(JWTConfig{HS256Secret: hs256Secret, Issuer: "https://issuer.example.com"})
+	valid := jwt.MapClaims{"sub": "thehaohcm@yahoo.com.vn", "iss": "https://issuer.example.com"}
+
+	cases := map[string]string{
+		"expired":      signHS256(t, merge(valid, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"wrong issuer": signHS256(t, merge(valid, jwt.MapClaims{"iss": "https://other.example.com"})),
+		"not an email": signHS256(t, merge(valid, jwt.MapClaims{"sub": "thehaohcm"})),
+		"wrong secret": mustSign(t, jwt.NewWithClaims(jwt.SigningMethodHS256, withTimes(valid)), []byte("other")),
+		"alg none":     mustSign(t, jwt.NewWithClaims(jwt.SigningMethodNone, withTimes(valid)), jwt.UnsafeAllowNoneSignatureType),
+		"not a token":  "abc.def.ghi",
+		"missing exp":  mustSign(t, jwt.NewWithClaims(jwt.SigningMethodHS256, valid), hs256Secret),
+	}
+	for name, token := range cases {
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+		assert.Equal(t, apperrors.KindUnauthenticated, apperrors.KindOf(err), name)
+	}
+}
+
+func TestCheckHS256Secret(t *testing.T) {
+	assert.Nil(t, CheckHS256Secret(nil))
+	assert.Nil(t, CheckHS256Secret([]byte("0123456789abcdef0123456789abcdef")))
+	assert.NotNil(t, CheckHS256Secret(hs256Secret))
+	assert.NotNil(t, CheckHS256Secret([]byte("local-development-secret")))
+}
+
+func TestVerifyRS256TokenWithJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	path := writeJWKS(t, "key-1", &key.PublicKey)
+
+	keys, err := LoadJWKSFile(path)
+	assert.Nil(t, err)
+	verifier := NewJWTVerifier(JWTConfig{RS256Keys: keys})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, withTimes(jwt.MapClaims{"sub": "thehaohcm@yahoo.com.vn"}))
+	token.Header["kid"] = "key-1"
+	principal, err := verifier.Verify(mustSign(t, token, key))
+	assert.Nil(t, err)
+	assert.Equal(t, "thehaohcm@yahoo.com.vn", principal.Email)
+
+	token.Header["kid"] = "key-2"
+	_, err = verifier.Verify(mustSign(t, token, key))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// HS256 tokens are rejected when no secret is configured
+	_, err = verifier.Verify(signHS256(t, jwt.MapClaims{"sub": "thehaohcm@yahoo.com.vn"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	return mustSign(t, jwt.NewWithClaims(jwt.SigningMethodHS256, withTimes(claims)), hs256Secret)
+}
+
+func mustSign(t *testing.T, token *jwt.Token, key interface{}) string {
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func withTimes(claims jwt.MapClaims) jwt.MapClaims {
+	return merge(jwt.MapClaims{"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}, claims)
+}
+
+func merge(base, extra jwt.MapClaims) jwt.MapClaims {
+	merged := jwt.MapClaims{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	content, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
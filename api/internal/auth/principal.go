@@ -0,0 +1,68 @@
+package auth
+
+import "context"
+
+// roles granted to authenticated callers
+const (
+	// RoleUser is the role of an end user, who acts as the email of its token
+	RoleUser = "user"
+	// RoleAdmin is the role of operators, who may manage API keys and act on behalf of anyone
+	RoleAdmin = "admin"
+	// RoleService is the role of service accounts authenticated with an API key
+	RoleService = "service"
+)
+
+// authentication methods of a Principal
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal struct describes the authenticated caller of a request
+// Subject is the email of a user (JWT) or the id of an API key, Email is empty for service accounts
+type Principal struct {
+	Subject string
+	Email   string
+	Roles   []string
+	Method  string
+}
+
+// HasRole function used to know whether the principal was granted one of the given roles
+// pass roles as parameters
+// return a boolean
+func (p Principal) HasRole(roles ...string) bool {
+	for _, granted := range p.Roles {
+		for _, role := range roles {
+			if granted == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal function used to attach the authenticated principal to a context
+// pass a context and a Principal as parameters
+// return a context
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext function used to get the authenticated principal of a context
+// pass a context as parameter
+// return a Principal and whether the context carries one
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// IsValidRole function used to know whether a role name is one of the known roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin, RoleService:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package bulkimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+// maxLineSize bounds the size of a JSONL line
+const maxLineSize = 1024 * 1024
+
+// Record struct holds one row of an import file
+// Email is the user, or the requestor of a relationship, and Target is empty for users;
+// Err is set when the row cannot be read, the next rows can still be read
+type Record struct {
+	Line   int
+	Email  string
+	Target string
+	Err    error
+}
+
+// Decoder struct reads the rows of an import file one at a time, so files of any size can be imported
+type Decoder struct {
+	kind  string
+	csv   *csv.Reader
+	lines *bufio.Scanner
+	line  int
+	first bool
+}
+
+// NewDecoder function used for initializing a Decoder
+// CSV files have one column (email) for users and two columns (requestor, target) for relationships, with an optional header line;
+// JSONL files have one object per line: {"email": ...} for users, {"requestor": ..., "target": ...} for relationships,
+// friendships also accept {"friends": [..., ...]}
+// pass a reader, the format and the kind of the records as parameters
+// return a pointer of Decoder and an error type
+func NewDecoder(r io.Reader, format, kind string) (*Decoder, error) {
+	if !IsValidKind(kind) {
+		return nil, apperrors.Validation("invalid_import_kind", "invalid import kind, expected users, friendships, subscriptions or blocks").WithDetail("kind", kind)
+	}
+	decoder := &Decoder{kind: kind, first: true}
+	switch format {
+	case models.ImportFormatCSV:
+		decoder.csv = csv.NewReader(r)
+		decoder.csv.FieldsPerRecord = -1
+		decoder.csv.TrimLeadingSpace = true
+		decoder.csv.ReuseRecord = true
+	case models.ImportFormatJSONL:
+		decoder.lines = bufio.NewScanner(r)
+		decoder.lines.Buffer(make([]byte, 64*1024), maxLineSize)
+	default:
+		return nil, apperrors.Validation("invalid_import_format", "invalid import format, expected csv or jsonl").WithDetail("format", format)
+	}
+	return decoder, nil
+}
+
+// IsValidKind function used to check the kind of an import
+// pass a kind as parameter
+// return a boolean
+func IsValidKind(kind string) bool {
+	switch kind {
+	case models.ImportUsers, models.ImportFriendships, models.ImportSubscriptions, models.ImportBlocks:
+		return true
+	}
+	return false
+}
+
+// Next function used to read the next row
+// return a Record and an error type, io.EOF after the last row, other errors mean the file cannot be read any further
+func (d *Decoder) Next() (Record, error) {
+	if d.csv != nil {
+		return d.nextCSV()
+	}
+	return d.nextJSONL()
+}
+
+func (d *Decoder) nextCSV() (Record, error) {
+	for {
+		fields, err := d.csv.Read()
+		if err == io.EOF {
+			return Record{}, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			d.first = false
+			return Record{Line: parseErr.StartLine, Err: rowError("malformed_row", parseErr.Err.Error())}, nil
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		line, _ := d.csv.FieldPos(0)
+
+		// the first line is a header when it holds no email address
+		if d.first {
+			d.first = false
+			if !strings.Contains(strings.Join(fields, ""), "@") {
+				continue
+			}
+		}
+		if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+		if len(fields) != d.columns() {
+			return Record{Line: line, Err: rowError("malformed_row", fmt.Sprintf("expected %d columns, got %d", d.columns(), len(fields)))}, nil
+		}
+		record := Record{Line: line, Email: strings.TrimSpace(fields[0])}
+		if d.columns() == 2 {
+			record.Target = strings.TrimSpace(fields[1])
+		}
+		return record, nil
+	}
+}
+
+type jsonRecord struct {
+	Email     string   `json:"email"`
+	Requestor string   `json:"requestor"`
+	Target    string   `json:"target"`
+	Friends   []string `json:"friends"`
+}
+
+func (d *Decoder) nextJSONL() (Record, error) {
+	for d.lines.Scan() {
+		d.line++
+		text := strings.TrimSpace(d.lines.Text())
+		if text == "" {
+			continue
+		}
+		var row jsonRecord
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return Record{Line: d.line, Err: rowError("malformed_row", "invalid JSON object")}, nil
+		}
+		record := Record{Line: d.line}
+		switch {
+		case d.kind == models.ImportUsers:
+			record.Email = strings.TrimSpace(row.Email)
+		case d.kind == models.ImportFriendships && row.Friends != nil:
+			if len(row.Friends) != 2 {
+				record.Err = rowError("malformed_row", "friends must hold 2 emails")
+				break
+			}
+			record.Email, record.Target = strings.TrimSpace(row.Friends[0]), strings.TrimSpace(row.Friends[1])
+		default:
+			record.Email, record.Target = strings.TrimSpace(row.Requestor), strings.TrimSpace(row.Target)
+		}
+		return record, nil
+	}
+	if err := d.lines.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func (d *Decoder) columns() int {
+	if d.kind == models.ImportUsers {
+		return 1
+	}
+	return 2
+}
+
+func rowError(code, message string) error {
+	return apperrors.Validation(code, message)
+}
@@ -0,0 +1,77 @@
+package bulkimport
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	decoder, err := NewDecoder(strings.NewReader("requestor,target\n"+
+		"thehaohcm@yahoo.com.vn, hao.nguyen@s3corp.com.vn\n"+
+		"\n"+
+		"only-one-column@example.com\n"+
+		"\"broken,chinh.nguyen@s3corp.com.vn\n"), models.ImportFormatCSV, models.ImportSubscriptions)
+	assert.Nil(t, err)
+
+	records := readAll(t, decoder)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, Record{Line: 2, Email: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"}, records[0])
+		assert.Equal(t, 4, records[1].Line)
+		assert.Equal(t, "malformed_row", apperrors.From(records[1].Err).Code)
+		assert.Equal(t, 5, records[2].Line)
+		assert.NotNil(t, records[2].Err)
+	}
+}
+
+func TestDecodeCSVWithoutHeader(t *testing.T) {
+	decoder, _ := NewDecoder(strings.NewReader("thehaohcm@yahoo.com.vn\nhao.nguyen@s3corp.com.vn\n"), models.ImportFormatCSV, models.ImportUsers)
+
+	records := readAll(t, decoder)
+	assert.Equal(t, []Record{{Line: 1, Email: "thehaohcm@yahoo.com.vn"}, {Line: 2, Email: "hao.nguyen@s3corp.com.vn"}}, records)
+}
+
+func TestDecodeJSONL(t *testing.T) {
+	decoder, err := NewDecoder(strings.NewReader(`{"friends": ["thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"]}`+"\n"+
+		"\n"+
+		`{"requestor": "chinh.nguyen@s3corp.com.vn", "target": "son.le@s3corp.com.vn"}`+"\n"+
+		`{"friends": ["thehaohcm@yahoo.com.vn"]}`+"\n"+
+		`not json`), models.ImportFormatJSONL, models.ImportFriendships)
+	assert.Nil(t, err)
+
+	records := readAll(t, decoder)
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, Record{Line: 1, Email: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"}, records[0])
+		assert.Equal(t, Record{Line: 3, Email: "chinh.nguyen@s3corp.com.vn", Target: "son.le@s3corp.com.vn"}, records[1])
+		assert.Equal(t, 4, records[2].Line)
+		assert.NotNil(t, records[2].Err)
+		assert.Equal(t, 5, records[3].Line)
+		assert.Equal(t, "invalid JSON object", apperrors.From(records[3].Err).Message)
+	}
+}
+
+func TestNewDecoderWithInvalidOptions(t *testing.T) {
+	_, err := NewDecoder(strings.NewReader(""), "xml", models.ImportUsers)
+	assert.Equal(t, "invalid_import_format", apperrors.From(err).Code)
+
+	_, err = NewDecoder(strings.NewReader(""), models.ImportFormatCSV, "groups")
+	assert.Equal(t, "invalid_import_kind", apperrors.From(err).Code)
+}
+
+func readAll(t *testing.T, decoder *Decoder) []Record {
+	var records []Record
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			return records
+		}
+		if !assert.Nil(t, err) {
+			return records
+		}
+		records = append(records, record)
+	}
+}
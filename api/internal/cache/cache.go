@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+)
+
+// Cache interface keeps read results under a key, a key holding one value per field,
+// e.g. the recipients of a sender for each list of mentioned users
+//
+// a read racing with a write must not store the result it read before the write: Get returns the version of the key,
+// Invalidate changes it, and Set stores nothing when the version given by the read is not the current one anymore
+type Cache interface {
+	// Get returns the value of the field of a key, whether it was found, and the version of the key to pass to Set
+	Get(ctx context.Context, key, field string) ([]byte, bool, int64, error)
+	// Set stores the value of the field of a key, unless the key was invalidated since its version was read
+	Set(ctx context.Context, key, field string, value []byte, version int64) error
+	// Invalidate removes every field of the keys
+	Invalidate(ctx context.Context, keys ...string) error
+}
+
+// Stats holds the hit, miss, bypass and error counters of the caches, published as the "cache" expvar on /debug/vars
+var Stats = expvar.NewMap("cache")
+
+type contextKey int
+
+const bypassKey contextKey = iota
+
+// WithBypass function used to mark a context so that the reads made with it skip the cached values and refresh them,
+// e.g. for a request with the Cache-Control: no-cache header
+// pass a context as parameter
+// return a new context carrying the mark
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey, true)
+}
+
+// Bypassed function used to know whether the reads made with a context must skip the cached values
+// pass a context as parameter
+// return a boolean
+func Bypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(bypassKey).(bool)
+	return bypass
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	fields    map[string][]byte
+	expiresAt time.Time
+	// invalidated is the version of the last invalidation of the key
+	invalidated int64
+}
+
+// LRU struct keeps the values in the memory of the process, up to a number of keys, the least recently used keys being evicted first,
+// the invalidations are not seen by the other replicas, so an LRU only fits a single replica, see Redis otherwise
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	// order has the most recently used entries first
+	order *list.List
+	// version is incremented by every invalidation, the reads return it as the version of every key
+	version int64
+	// floor is the version of the last invalidation of a key which is not kept anymore,
+	// the values read before it are not stored for the keys which are not kept
+	floor int64
+	now   func() time.Time
+}
+
+// NewLRU function used for initializing an LRU
+// pass the maximum number of keys and how long the values are kept as parameters
+// return a pointer of LRU
+func NewLRU(size int, ttl time.Duration) *LRU {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get function used to read the value of the field of a key, see Cache
+// pass a context, the key and the field as parameters
+// return the value, whether it was found, the version of the key and an error type, always nil
+func (c *LRU) Get(ctx context.Context, key, field string) ([]byte, bool, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, c.version, nil
+	}
+	e := element.Value.(*lruEntry)
+	if !c.now().Before(e.expiresAt) {
+		e.fields = map[string][]byte{}
+		return nil, false, c.version, nil
+	}
+	value, found := e.fields[field]
+	if found {
+		c.order.MoveToFront(element)
+	}
+	return value, found, c.version, nil
+}
+
+// Set function used to store the value of the field of a key, unless the key was invalidated since the version was read
+// the key expires after the TTL of the LRU, counted from the last field stored
+// pass a context, the key, the field, the value and the version returned by Get as parameters
+// return an error type, always nil
+func (c *LRU) Set(ctx context.Context, key, field string, value []byte, version int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	element, ok := c.entries[key]
+	if !ok {
+		if version < c.floor {
+			return nil
+		}
+		element = c.order.PushFront(&lruEntry{key: key, fields: map[string][]byte{}})
+		c.entries[key] = element
+		c.evict()
+	}
+	e := element.Value.(*lruEntry)
+	if version < e.invalidated {
+		return nil
+	}
+	if !now.Before(e.expiresAt) {
+		e.fields = map[string][]byte{}
+	}
+	e.fields[field] = value
+	e.expiresAt = now.Add(c.ttl)
+	c.order.MoveToFront(element)
+	return nil
+}
+
+// Invalidate function used to remove every field of the keys
+// pass a context and the keys as parameters
+// return an error type, always nil
+func (c *LRU) Invalidate(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			e := element.Value.(*lruEntry)
+			e.fields = map[string][]byte{}
+			e.invalidated = c.version
+			continue
+		}
+		c.floor = c.version
+	}
+	return nil
+}
+
+// Len function used to get the number of keys kept by the LRU
+// return an integer
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) evict() {
+	for c.order.Len() > c.size {
+		element := c.order.Back()
+		e := element.Value.(*lruEntry)
+		c.order.Remove(element)
+		delete(c.entries, e.key)
+		if e.invalidated > c.floor {
+			c.floor = e.invalidated
+		}
+	}
+}
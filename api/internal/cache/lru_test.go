@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUGetSetAndInvalidate(t *testing.T) {
+	c := NewLRU(10, time.Minute)
+	ctx := context.Background()
+
+	_, found, version, err := c.Get(ctx, "friends:a@example.com", "")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, c.Set(ctx, "friends:a@example.com", "", []byte("[]"), version))
+	assert.Nil(t, c.Set(ctx, "friends:a@example.com", "other", []byte("[1]"), version))
+
+	value, found, _, _ := c.Get(ctx, "friends:a@example.com", "")
+	assert.True(t, found)
+	assert.Equal(t, []byte("[]"), value)
+
+	assert.Nil(t, c.Invalidate(ctx, "friends:a@example.com"))
+	_, found, _, _ = c.Get(ctx, "friends:a@example.com", "")
+	assert.False(t, found)
+	_, found, _, _ = c.Get(ctx, "friends:a@example.com", "other")
+	assert.False(t, found)
+}
+
+func TestLRUDoesNotStoreValuesReadBeforeAnInvalidation(t *testing.T) {
+	c := NewLRU(10, time.Minute)
+	ctx := context.Background()
+
+	// a kept key
+	_, _, version, _ := c.Get(ctx, "kept", "")
+	assert.Nil(t, c.Set(ctx, "kept", "", []byte("old"), version))
+	_, _, version, _ = c.Get(ctx, "kept", "field")
+	assert.Nil(t, c.Invalidate(ctx, "kept"))
+	assert.Nil(t, c.Set(ctx, "kept", "field", []byte("stale"), version))
+	_, found, _, _ := c.Get(ctx, "kept", "field")
+	assert.False(t, found)
+
+	// a key which is not kept yet
+	_, _, version, _ = c.Get(ctx, "new", "")
+	assert.Nil(t, c.Invalidate(ctx, "new"))
+	assert.Nil(t, c.Set(ctx, "new", "", []byte("stale"), version))
+	_, found, version, _ = c.Get(ctx, "new", "")
+	assert.False(t, found)
+
+	// the reads made after the invalidation are stored
+	assert.Nil(t, c.Set(ctx, "new", "", []byte("fresh"), version))
+	value, found, _, _ := c.Get(ctx, "new", "")
+	assert.True(t, found)
+	assert.Equal(t, []byte("fresh"), value)
+}
+
+func TestLRUEvictsTheLeastRecentlyUsedKeys(t *testing.T) {
+	c := NewLRU(2, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key%d", i)
+		_, _, version, _ := c.Get(ctx, key, "")
+		assert.Nil(t, c.Set(ctx, key, "", []byte(key), version))
+		if i == 1 {
+			// key0 is used again, key1 becomes the least recently used
+			_, found, _, _ := c.Get(ctx, "key0", "")
+			assert.True(t, found)
+		}
+	}
+
+	assert.Equal(t, 2, c.Len())
+	_, found, _, _ := c.Get(ctx, "key0", "")
+	assert.True(t, found)
+	_, found, _, _ = c.Get(ctx, "key1", "")
+	assert.False(t, found)
+	_, found, _, _ = c.Get(ctx, "key2", "")
+	assert.True(t, found)
+}
+
+func TestLRUExpiresTheValues(t *testing.T) {
+	c := NewLRU(10, time.Minute)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	_, _, version, _ := c.Get(ctx, "key", "")
+	assert.Nil(t, c.Set(ctx, "key", "", []byte("value"), version))
+
+	clock = clock.Add(59 * time.Second)
+	_, found, _, _ := c.Get(ctx, "key", "")
+	assert.True(t, found)
+
+	clock = clock.Add(time.Second)
+	_, found, _, _ = c.Get(ctx, "key", "")
+	assert.False(t, found)
+}
+
+func TestBypass(t *testing.T) {
+	assert.False(t, Bypassed(context.Background()))
+	assert.True(t, Bypassed(WithBypass(context.Background())))
+}
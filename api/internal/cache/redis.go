@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setIfCurrent stores a field of KEYS[1] only when the version kept in KEYS[2] is still ARGV[1], a missing version being 0
+var setIfCurrent = redis.NewScript(`
+local version = redis.call('GET', KEYS[2]) or '0'
+if version ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[2], ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// Redis struct keeps the values in a Redis (or Redis compatible) server shared by every replica,
+// a key is a hash of its fields, its version is kept next to it, in the same cluster slot
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedis function used for initializing a Redis cache
+// pass a Redis client, the prefix of the keys and how long the values are kept as parameters
+// return a pointer of Redis
+func NewRedis(client redis.UniversalClient, prefix string, ttl time.Duration) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Get function used to read the value of the field of a key, see Cache
+// pass a context, the key and the field as parameters
+// return the value, whether it was found, the version of the key and an error type
+func (c *Redis) Get(ctx context.Context, key, field string) ([]byte, bool, int64, error) {
+	pipe := c.client.Pipeline()
+	value := pipe.HGet(ctx, c.dataKey(key), field)
+	version := pipe.Get(ctx, c.versionKey(key))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, false, 0, err
+	}
+
+	current, err := version.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, false, 0, err
+	}
+	data, err := value.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, current, nil
+	}
+	if err != nil {
+		return nil, false, 0, err
+	}
+	return data, true, current, nil
+}
+
+// Set function used to store the value of the field of a key, unless the key was invalidated since the version was read
+// the key expires after the TTL of the cache, counted from the last field stored
+// pass a context, the key, the field, the value and the version returned by Get as parameters
+// return an error type
+func (c *Redis) Set(ctx context.Context, key, field string, value []byte, version int64) error {
+	keys := []string{c.dataKey(key), c.versionKey(key)}
+	return setIfCurrent.Run(ctx, c.client, keys, version, field, value, c.ttl.Milliseconds()).Err()
+}
+
+// Invalidate function used to remove every field of the keys and to change their version
+// the version is kept for the TTL of the cache, longer than any read which could still store a value read before the invalidation
+// pass a context and the keys as parameters
+// return an error type
+func (c *Redis) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, c.dataKey(key))
+		pipe.Incr(ctx, c.versionKey(key))
+		pipe.PExpire(ctx, c.versionKey(key), c.ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *Redis) dataKey(key string) string {
+	return c.prefix + "{" + key + "}"
+}
+
+func (c *Redis) versionKey(key string) string {
+	return c.prefix + "{" + key + "}:version"
+}
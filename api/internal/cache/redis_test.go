@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisForTesting(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client, "test:", time.Minute), server
+}
+
+func TestRedisGetSetAndInvalidate(t *testing.T) {
+	c, server := newRedisForTesting(t)
+	ctx := context.Background()
+
+	_, found, version, err := c.Get(ctx, "recipients:a@example.com", "")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int64(0), version)
+	assert.Nil(t, c.Set(ctx, "recipients:a@example.com", "", []byte("[]"), version))
+	assert.Nil(t, c.Set(ctx, "recipients:a@example.com", "b@example.com", []byte("[1]"), version))
+
+	value, found, _, err := c.Get(ctx, "recipients:a@example.com", "b@example.com")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("[1]"), value)
+	assert.Equal(t, time.Minute, server.TTL("test:{recipients:a@example.com}"))
+
+	assert.Nil(t, c.Invalidate(ctx, "recipients:a@example.com", "friends:a@example.com"))
+	assert.False(t, server.Exists("test:{recipients:a@example.com}"))
+	_, found, version, err = c.Get(ctx, "recipients:a@example.com", "")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestRedisDoesNotStoreValuesReadBeforeAnInvalidation(t *testing.T) {
+	c, server := newRedisForTesting(t)
+	ctx := context.Background()
+
+	_, _, version, _ := c.Get(ctx, "friends:a@example.com", "")
+	assert.Nil(t, c.Invalidate(ctx, "friends:a@example.com"))
+	assert.Nil(t, c.Set(ctx, "friends:a@example.com", "", []byte("stale"), version))
+	assert.False(t, server.Exists("test:{friends:a@example.com}"))
+
+	_, _, version, _ = c.Get(ctx, "friends:a@example.com", "")
+	assert.Nil(t, c.Set(ctx, "friends:a@example.com", "", []byte("fresh"), version))
+	value, found, _, _ := c.Get(ctx, "friends:a@example.com", "")
+	assert.True(t, found)
+	assert.Equal(t, []byte("fresh"), value)
+}
+
+func TestRedisReturnsTheErrorsOfTheServer(t *testing.T) {
+	c, server := newRedisForTesting(t)
+	server.Close()
+
+	_, _, _, err := c.Get(context.Background(), "friends:a@example.com", "")
+	assert.NotNil(t, err)
+	assert.NotNil(t, c.Invalidate(context.Background(), "friends:a@example.com"))
+}
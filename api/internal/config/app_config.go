@@ -0,0 +1,49 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GetAutoCreateUsers function used to know whether the autoCreateUsers mode is enabled
+// read from the AUTO_CREATE_USERS environment variable, default is false
+// when enabled, relationships targeting unregistered emails register these users on the fly (used by bulk onboarding)
+// return a boolean
+func GetAutoCreateUsers() bool {
+	return getEnvBool("AUTO_CREATE_USERS", false)
+}
+
+func getEnvBool(name string, fallback bool) bool {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid boolean configuration, using default", slog.String("variable", name), slog.String("value", value), slog.Bool("default", fallback))
+		return fallback
+	}
+	return enabled
+}
+
+func getEnvInt(name string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		slog.Warn("invalid integer configuration, using default", slog.String("variable", name), slog.String("value", value), slog.Int("default", fallback))
+		return fallback
+	}
+	return number
+}
+
+// GetAPIV1Sunset function used to get the date after which the deprecated /api/v1 routes may be removed
+// read from the API_V1_SUNSET environment variable as an HTTP-date (e.g. "Wed, 31 Dec 2025 23:59:59 GMT"), default is empty
+// return a string
+func GetAPIV1Sunset() string {
+	return strings.TrimSpace(os.Getenv("API_V1_SUNSET"))
+}
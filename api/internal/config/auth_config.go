@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// GetAuthEnabled function used to know whether the APIs require authentication
+// read from the AUTH_ENABLED environment variable, default is true
+// return a boolean
+func GetAuthEnabled() bool {
+	return getEnvBool("AUTH_ENABLED", true)
+}
+
+// GetJWTHS256Secret function used to get the shared secret verifying HS256 tokens
+// read from the JWT_HS256_SECRET environment variable, HS256 tokens are rejected when it is empty
+// return an array of byte
+func GetJWTHS256Secret() []byte {
+	return []byte(os.Getenv("JWT_HS256_SECRET"))
+}
+
+// GetJWTJWKSFile function used to get the path of the JWKS file holding the public keys verifying RS256 tokens
+// read from the JWT_JWKS_FILE environment variable, RS256 tokens are rejected when it is empty
+// return a string
+func GetJWTJWKSFile() string {
+	return strings.TrimSpace(os.Getenv("JWT_JWKS_FILE"))
+}
+
+// GetJWTIssuer function used to get the expected "iss" claim of tokens
+// read from the JWT_ISSUER environment variable, the claim is not checked when it is empty
+// return a string
+func GetJWTIssuer() string {
+	return strings.TrimSpace(os.Getenv("JWT_ISSUER"))
+}
+
+// GetJWTAudience function used to get the expected "aud" claim of tokens
+// read from the JWT_AUDIENCE environment variable, the claim is not checked when it is empty
+// return a string
+func GetJWTAudience() string {
+	return strings.TrimSpace(os.Getenv("JWT_AUDIENCE"))
+}
+
+// GetAuthzPolicyFile function used to get the path of the JSON file overriding the default authorization rules
+// read from the AUTHZ_POLICY_FILE environment variable, the default rules are applied when it is empty
+// return a string
+func GetAuthzPolicyFile() string {
+	return strings.TrimSpace(os.Getenv("AUTHZ_POLICY_FILE"))
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang_project/api/internal/cache"
+)
+
+const (
+	defaultCacheSize = 10000
+	defaultCacheTTL  = 5 * time.Minute
+	defaultRedisURL  = "redis://localhost:6379/0"
+	// redisCachePrefix is the prefix of the keys of the redis cache
+	redisCachePrefix = "golang_project:cache:"
+)
+
+var (
+	relationshipCacheOnce sync.Once
+	relationshipCache     cache.Cache
+)
+
+// GetCacheStore function used to get where the friend lists and the recipients are cached:
+// "none", "memory" (an LRU per replica, for a single replica) or "redis" (shared by every replica)
+// read from the CACHE_STORE environment variable, default is none
+// return a string
+func GetCacheStore() string {
+	if store := strings.ToLower(strings.TrimSpace(os.Getenv("CACHE_STORE"))); store == "memory" || store == "redis" {
+		return store
+	}
+	return "none"
+}
+
+// GetCacheSize function used to get how many users the memory cache keeps the friend lists and the recipients of
+// read from the CACHE_SIZE environment variable, default is 10000
+// return an integer
+func GetCacheSize() int {
+	if size := getEnvInt("CACHE_SIZE", defaultCacheSize); size > 0 {
+		return size
+	}
+	return defaultCacheSize
+}
+
+// GetCacheTTL function used to get how long the friend lists and the recipients are cached,
+// the writes invalidate them, the TTL only bounds the staleness when an invalidation fails
+// read from the CACHE_TTL environment variable (Go duration format, e.g. "5m"), default is 5 minutes
+// return a time.Duration
+func GetCacheTTL() time.Duration {
+	return parseTimeout("CACHE_TTL", os.Getenv("CACHE_TTL"), defaultCacheTTL)
+}
+
+// GetRedisURL function used to get the address of the Redis server of the redis cache, e.g. "redis://:password@redis:6379/0"
+// read from the REDIS_URL environment variable, default is redis://localhost:6379/0
+// return a string
+func GetRedisURL() string {
+	if url := strings.TrimSpace(os.Getenv("REDIS_URL")); url != "" {
+		return url
+	}
+	return defaultRedisURL
+}
+
+// GetRelationshipCache function used to get the cache of the friend lists and the recipients configured by CACHE_STORE,
+// the HTTP routes and the imports share it so that the imports invalidate what the routes cached
+// no parameter
+// return a Cache, nil when CACHE_STORE is none
+// singleton pattern
+func GetRelationshipCache() cache.Cache {
+	relationshipCacheOnce.Do(func() {
+		switch GetCacheStore() {
+		case "memory":
+			relationshipCache = cache.NewLRU(GetCacheSize(), GetCacheTTL())
+		case "redis":
+			client, err := GetRedisClient()
+			if err != nil {
+				slog.Error("the redis cache is disabled, REDIS_URL is invalid", slog.Any("error", err))
+				return
+			}
+			relationshipCache = cache.NewRedis(client, redisCachePrefix, GetCacheTTL())
+		}
+	})
+	return relationshipCache
+}
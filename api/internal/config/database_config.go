@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/resilience"
+)
+
+// default pool limits, small enough for several replicas of the server to share the max_connections (100) of a default Postgres
+const (
+	defaultDBMaxOpenConns    = 20
+	defaultDBMaxIdleConns    = 10
+	defaultDBConnMaxLifetime = 30 * time.Minute
+	defaultDBConnMaxIdleTime = 5 * time.Minute
+	defaultDBConnectTimeout  = 30 * time.Second
+	defaultDBReadRetries     = 2
+	defaultDBBreakerFailures = 5
+	defaultDBBreakerCooldown = 10 * time.Second
+)
+
+var (
+	dbBreakerOnce sync.Once
+	dbBreaker     *resilience.Breaker
+)
+
+// GetDBMaxOpenConns function used to get the maximum number of connections of a pool, 0 means unlimited
+// read from the POSTGRES_MAX_OPEN_CONNS environment variable, default is 20
+// return an integer
+func GetDBMaxOpenConns() int {
+	return getEnvInt("POSTGRES_MAX_OPEN_CONNS", defaultDBMaxOpenConns)
+}
+
+// GetDBMaxIdleConns function used to get the maximum number of idle connections kept by a pool
+// read from the POSTGRES_MAX_IDLE_CONNS environment variable, default is 10
+// return an integer
+func GetDBMaxIdleConns() int {
+	return getEnvInt("POSTGRES_MAX_IDLE_CONNS", defaultDBMaxIdleConns)
+}
+
+// GetDBConnMaxLifetime function used to get how long a connection is reused, so the connections follow a failover or a DNS change
+// read from the POSTGRES_CONN_MAX_LIFETIME environment variable (Go duration format, e.g. "30m"), default is 30 minutes
+// return a time.Duration
+func GetDBConnMaxLifetime() time.Duration {
+	return parseTimeout("POSTGRES_CONN_MAX_LIFETIME", os.Getenv("POSTGRES_CONN_MAX_LIFETIME"), defaultDBConnMaxLifetime)
+}
+
+// GetDBConnMaxIdleTime function used to get how long an idle connection is kept
+// read from the POSTGRES_CONN_MAX_IDLE_TIME environment variable (Go duration format, e.g. "5m"), default is 5 minutes
+// return a time.Duration
+func GetDBConnMaxIdleTime() time.Duration {
+	return parseTimeout("POSTGRES_CONN_MAX_IDLE_TIME", os.Getenv("POSTGRES_CONN_MAX_IDLE_TIME"), defaultDBConnMaxIdleTime)
+}
+
+// GetDBConnectTimeout function used to get how long the server waits for the database at startup, see WaitForDB
+// read from the POSTGRES_CONNECT_TIMEOUT environment variable (Go duration format, e.g. "30s"), default is 30 seconds
+// return a time.Duration
+func GetDBConnectTimeout() time.Duration {
+	return parseTimeout("POSTGRES_CONNECT_TIMEOUT", os.Getenv("POSTGRES_CONNECT_TIMEOUT"), defaultDBConnectTimeout)
+}
+
+// GetDBReadRetries function used to get how the reads failing with a transient error are retried:
+// POSTGRES_READ_RETRIES times (default 2, 0 disables the retries) after a random delay of up to 50ms, doubled at each retry
+// return a resilience.Backoff
+func GetDBReadRetries() resilience.Backoff {
+	return resilience.Backoff{
+		Attempts: 1 + getEnvInt("POSTGRES_READ_RETRIES", defaultDBReadRetries),
+		Base:     50 * time.Millisecond,
+		Max:      time.Second,
+	}
+}
+
+// GetDBBreaker function used to get the circuit breaker of the primary database:
+// it opens after DB_BREAKER_FAILURES consecutive outage errors (default 5) and lets a trial request through
+// after DB_BREAKER_COOLDOWN (Go duration format, default "10s"), see repositories.OutageError
+// no parameter
+// return a pointer of resilience.Breaker
+// singleton pattern
+func GetDBBreaker() *resilience.Breaker {
+	dbBreakerOnce.Do(func() {
+		failures := getEnvInt("DB_BREAKER_FAILURES", defaultDBBreakerFailures)
+		cooldown := parseTimeout("DB_BREAKER_COOLDOWN", os.Getenv("DB_BREAKER_COOLDOWN"), defaultDBBreakerCooldown)
+		dbBreaker = resilience.NewBreaker("database", failures, cooldown, repositories.OutageError)
+	})
+	return dbBreaker
+}
@@ -0,0 +1,21 @@
+package config
+
+const (
+	defaultGraphQLMaxDepth      = 8
+	defaultGraphQLMaxComplexity = 2000
+)
+
+// GetGraphQLMaxDepth function used to get the maximum nesting level of fields accepted in a GraphQL query
+// read from the GRAPHQL_MAX_DEPTH environment variable, default is 8, 0 disables the limit
+// return an int
+func GetGraphQLMaxDepth() int {
+	return getEnvInt("GRAPHQL_MAX_DEPTH", defaultGraphQLMaxDepth)
+}
+
+// GetGraphQLMaxComplexity function used to get the maximum complexity accepted in a GraphQL query
+// every field costs 1 and the fields below a list cost 10 times more
+// read from the GRAPHQL_MAX_COMPLEXITY environment variable, default is 2000, 0 disables the limit
+// return an int
+func GetGraphQLMaxComplexity() int {
+	return getEnvInt("GRAPHQL_MAX_COMPLEXITY", defaultGraphQLMaxComplexity)
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+const defaultGRPCPort = "9090"
+
+// GetGRPCPort function used to get the port of the gRPC server running next to the HTTP server
+// read from the GRPC_PORT environment variable, default is 9090
+// return a string
+func GetGRPCPort() string {
+	port := strings.TrimSpace(os.Getenv("GRPC_PORT"))
+	if port == "" {
+		return defaultGRPCPort
+	}
+	return port
+}
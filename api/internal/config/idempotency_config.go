@@ -0,0 +1,43 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// GetIdempotencyEnabled function used to know whether the Idempotency-Key header is honored by the write routes
+// read from the IDEMPOTENCY_ENABLED environment variable, default is true
+// return a boolean
+func GetIdempotencyEnabled() bool {
+	return getEnvBool("IDEMPOTENCY_ENABLED", true)
+}
+
+// GetIdempotencyStore function used to get where the idempotency keys are kept: "memory" (per replica) or "postgres" (shared by every replica)
+// read from the IDEMPOTENCY_STORE environment variable, default is memory
+// return a string
+func GetIdempotencyStore() string {
+	if store := strings.ToLower(strings.TrimSpace(os.Getenv("IDEMPOTENCY_STORE"))); store == "postgres" {
+		return store
+	}
+	return "memory"
+}
+
+// GetIdempotencyTTL function used to get how long the responses are replayed for the retries of a request
+// read from the IDEMPOTENCY_TTL environment variable (Go duration format, e.g. "24h"), default is 24 hours
+// return a time.Duration
+func GetIdempotencyTTL() time.Duration {
+	value := strings.TrimSpace(os.Getenv("IDEMPOTENCY_TTL"))
+	if value == "" {
+		return defaultIdempotencyTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		slog.Warn("invalid duration configuration, using default", slog.String("variable", "IDEMPOTENCY_TTL"), slog.String("value", value), slog.Duration("default", defaultIdempotencyTTL))
+		return defaultIdempotencyTTL
+	}
+	return ttl
+}
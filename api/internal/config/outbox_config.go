@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"golang_project/api/internal/resilience"
+)
+
+const (
+	defaultOutboxSinks        = "log"
+	defaultOutboxBatchSize    = 100
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxLease        = time.Minute
+	defaultOutboxBrokerPrefix = "friend_connections."
+	defaultFanoutWorkers      = 8
+)
+
+// GetOutboxEnabled function used to get whether the writes append their domain events to outbox table and a relay publishes them,
+// the create_outbox migration must be applied first; only the postgres storage has an outbox
+// read from the OUTBOX_ENABLED environment variable, default is false
+// return a boolean
+func GetOutboxEnabled() bool {
+	return getEnvBool("OUTBOX_ENABLED", false)
+}
+
+// GetOutboxSinks function used to get where the relay publishes the domain events, a comma separated list of "log", "webhook", "broker" and "fanout"
+// read from the OUTBOX_SINKS environment variable, default is log
+// return an array of string
+func GetOutboxSinks() []string {
+	value := os.Getenv("OUTBOX_SINKS")
+	if strings.TrimSpace(value) == "" {
+		value = defaultOutboxSinks
+	}
+	var sinks []string
+	for _, sink := range strings.Split(value, ",") {
+		if sink = strings.ToLower(strings.TrimSpace(sink)); sink != "" {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// GetOutboxWebhookURL function used to get the URL the webhook sink posts the domain events to
+// read from the OUTBOX_WEBHOOK_URL environment variable
+// return a string
+func GetOutboxWebhookURL() string {
+	return strings.TrimSpace(os.Getenv("OUTBOX_WEBHOOK_URL"))
+}
+
+// GetOutboxWebhookSecret function used to get the secret the webhook sink signs the domain events with, empty to skip the signature
+// read from the OUTBOX_WEBHOOK_SECRET environment variable
+// return a string
+func GetOutboxWebhookSecret() string {
+	return os.Getenv("OUTBOX_WEBHOOK_SECRET")
+}
+
+// GetOutboxBrokerPrefix function used to get the prefix of the subjects the broker sink sends the domain events to, followed by the type of the event
+// read from the OUTBOX_BROKER_PREFIX environment variable, default is friend_connections.
+// return a string
+func GetOutboxBrokerPrefix() string {
+	if prefix := strings.TrimSpace(os.Getenv("OUTBOX_BROKER_PREFIX")); prefix != "" {
+		return prefix
+	}
+	return defaultOutboxBrokerPrefix
+}
+
+// GetFanoutWorkers function used to get how many recipients the fanout sink delivers an update to at once
+// read from the FANOUT_WORKERS environment variable, default is 8
+// return an integer
+func GetFanoutWorkers() int {
+	if workers := getEnvInt("FANOUT_WORKERS", defaultFanoutWorkers); workers > 0 {
+		return workers
+	}
+	return defaultFanoutWorkers
+}
+
+// GetOutboxBatchSize function used to get how many domain events the relay claims per batch
+// read from the OUTBOX_BATCH_SIZE environment variable, default is 100
+// return an integer
+func GetOutboxBatchSize() int {
+	if size := getEnvInt("OUTBOX_BATCH_SIZE", defaultOutboxBatchSize); size > 0 {
+		return size
+	}
+	return defaultOutboxBatchSize
+}
+
+// GetOutboxPollInterval function used to get how long the relay waits before polling an empty outbox again
+// read from the OUTBOX_POLL_INTERVAL environment variable (Go duration format, e.g. "1s"), default is 1 second
+// return a time.Duration
+func GetOutboxPollInterval() time.Duration {
+	return parseTimeout("OUTBOX_POLL_INTERVAL", os.Getenv("OUTBOX_POLL_INTERVAL"), defaultOutboxPollInterval)
+}
+
+// GetOutboxLease function used to get how long a batch claimed by a relay is not claimed again, by this relay or another one;
+// the events not published before its end are claimed again, it must be longer than the publication of an event by the sinks
+// read from the OUTBOX_LEASE environment variable (Go duration format, e.g. "1m"), default is 1 minute
+// return a time.Duration
+func GetOutboxLease() time.Duration {
+	return parseTimeout("OUTBOX_LEASE", os.Getenv("OUTBOX_LEASE"), defaultOutboxLease)
+}
+
+// GetOutboxRetries function used to get the delays before a domain event is published again after a failure:
+// a random delay of up to 1 second, doubled at each failure, up to 5 minutes; the event is retried until it is published
+// return a resilience.Backoff
+func GetOutboxRetries() resilience.Backoff {
+	return resilience.Backoff{
+		Base: time.Second,
+		Max:  5 * time.Minute,
+	}
+}
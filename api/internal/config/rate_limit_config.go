@@ -0,0 +1,79 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang_project/api/internal/ratelimit"
+)
+
+// default limits, generous enough for interactive use while stopping scripts
+var (
+	defaultPrincipalRateLimit = ratelimit.Limit{Requests: 60, Per: time.Minute}
+	defaultIPRateLimit        = ratelimit.Limit{Requests: 300, Per: time.Minute}
+)
+
+// GetRateLimitEnabled function used to know whether the rate limits and the quotas are enforced
+// read from the RATE_LIMIT_ENABLED environment variable, default is true
+// return a boolean
+func GetRateLimitEnabled() bool {
+	return getEnvBool("RATE_LIMIT_ENABLED", true)
+}
+
+// GetRateLimitStore function used to get where the token buckets are kept: "memory" (per replica) or "postgres" (shared by every replica)
+// read from the RATE_LIMIT_STORE environment variable, default is memory
+// return a string
+func GetRateLimitStore() string {
+	if store := strings.ToLower(strings.TrimSpace(os.Getenv("RATE_LIMIT_STORE"))); store == "postgres" {
+		return store
+	}
+	return "memory"
+}
+
+// GetRouteRateLimits function used to get the per principal and per IP limits of a route
+// the defaults are read from RATE_LIMIT_PER_PRINCIPAL (default "60/1m") and RATE_LIMIT_PER_IP (default "300/1m"),
+// the ROUTE_RATE_LIMITS environment variable overrides them as a comma separated list of route=principal_limit[|ip_limit] pairs,
+// e.g. "createConnection=10/1m|30/1m,subscribeFromEmail=20/1m"
+// pass a route name as parameter
+// return the per principal Limit and the per IP Limit
+func GetRouteRateLimits(route string) (ratelimit.Limit, ratelimit.Limit) {
+	perPrincipal := parseLimit("RATE_LIMIT_PER_PRINCIPAL", os.Getenv("RATE_LIMIT_PER_PRINCIPAL"), defaultPrincipalRateLimit)
+	perIP := parseLimit("RATE_LIMIT_PER_IP", os.Getenv("RATE_LIMIT_PER_IP"), defaultIPRateLimit)
+	for _, pair := range strings.Split(os.Getenv("ROUTE_RATE_LIMITS"), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || strings.TrimSpace(name) != route {
+			continue
+		}
+		principalValue, ipValue, hasIP := strings.Cut(value, "|")
+		perPrincipal = parseLimit("ROUTE_RATE_LIMITS", principalValue, perPrincipal)
+		if hasIP {
+			perIP = parseLimit("ROUTE_RATE_LIMITS", ipValue, perIP)
+		}
+	}
+	return perPrincipal, perIP
+}
+
+// GetQuotas function used to get the business quotas of the users
+// read from the QUOTA_FRIEND_REQUESTS_PER_DAY (default 100) and QUOTA_SUBSCRIPTIONS_PER_HOUR (default 30) environment variables,
+// 0 disables a quota
+// return a ratelimit.Quotas
+func GetQuotas() ratelimit.Quotas {
+	return ratelimit.Quotas{
+		FriendRequests: ratelimit.Limit{Requests: getEnvInt("QUOTA_FRIEND_REQUESTS_PER_DAY", 100), Per: 24 * time.Hour},
+		Subscriptions:  ratelimit.Limit{Requests: getEnvInt("QUOTA_SUBSCRIPTIONS_PER_HOUR", 30), Per: time.Hour},
+	}
+}
+
+func parseLimit(name, value string, fallback ratelimit.Limit) ratelimit.Limit {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		slog.Warn("invalid rate limit configuration, using default", slog.String("variable", name), slog.String("value", value), slog.String("default", fallback.String()))
+		return fallback
+	}
+	return limit
+}
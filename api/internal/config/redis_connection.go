@@ -0,0 +1,32 @@
+package config
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	redisOnce   sync.Once
+	redisClient *redis.Client
+	redisErr    error
+)
+
+// GetRedisClient function used to get or initialize the client of the Redis server of GetRedisURL,
+// the connections are opened on first use; it is called at startup so an invalid REDIS_URL stops the process
+// no parameter
+// return a pointer of redis.Client type and an error type, when REDIS_URL is invalid
+// singleton pattern
+func GetRedisClient() (*redis.Client, error) {
+	redisOnce.Do(func() {
+		options, err := redis.ParseURL(GetRedisURL())
+		if err != nil {
+			redisErr = err
+			return
+		}
+		redisClient = redis.NewClient(options)
+		slog.Info("created redis client", slog.String("address", options.Addr), slog.Int("database", options.DB))
+	})
+	return redisClient, redisErr
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	sqliteOnce sync.Once
+	sqliteDB   *sql.DB
+	sqliteErr  error
+)
+
+// GetSQLitePath function used to get the path of the SQLite database file used by the sqlite storage
+// read from the SQLITE_PATH environment variable, default is golang_project.db in the working directory
+// return a string
+func GetSQLitePath() string {
+	if path := strings.TrimSpace(os.Getenv("SQLITE_PATH")); path != "" {
+		return path
+	}
+	return "golang_project.db"
+}
+
+// OpenSQLite function used at startup to open the SQLite database file used by the sqlite storage and to check it can be read
+// pass a context as parameter
+// return an error type, when the file cannot be opened
+func OpenSQLite(ctx context.Context) error {
+	sqliteOnce.Do(func() {
+		slog.Debug("creating sqlite database connection instance")
+		sqliteDB, sqliteErr = connectSQLite(ctx, GetSQLitePath())
+	})
+	return sqliteErr
+}
+
+// GetSQLiteDBInstance function used to get the connection to the SQLite database file, opened by OpenSQLite at startup
+// no parameter
+// return a pointer of sql.DB type, its queries fail when the file could not be opened
+// singleton pattern
+func GetSQLiteDBInstance() *sql.DB {
+	if err := OpenSQLite(context.Background()); err != nil {
+		slog.Error("the sqlite database is not available", slog.Any("error", err))
+	}
+	return sqliteDB
+}
+
+// SQLiteDSN function used to build the data source name of a SQLite database file
+// foreign keys are enforced and writers wait for each other instead of failing with SQLITE_BUSY
+// pass the path of the database file as parameter
+// return a string
+func SQLiteDSN(path string) string {
+	return "file:" + path + "?" + url.Values{"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"}}.Encode()
+}
+
+// connectSQLite function used to open a SQLite database file and to ping it, so a missing directory or an unreadable file is reported at once
+// pass a context and the path of the database file as parameters
+// return a pointer of sql.DB type, which is returned even when the ping fails, and an error type
+func connectSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", SQLiteDSN(path))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer, one connection avoids "database is locked" errors between our own connections
+	db.SetMaxOpenConns(1)
+	if err := db.PingContext(ctx); err != nil {
+		return db, err
+	}
+	slog.Info("connected to sqlite database", slog.String("path", path))
+	return db, nil
+}
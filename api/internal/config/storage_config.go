@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// storages of the users and relationships
+const (
+	// StoragePostgres keeps the users and relationships in the Postgres database
+	StoragePostgres = "postgres"
+	// StorageMemory keeps the users and relationships in memory, they are lost when the server stops
+	StorageMemory = "memory"
+	// StorageSQLite keeps the users and relationships in a SQLite file, see GetSQLitePath
+	StorageSQLite = "sqlite"
+)
+
+var (
+	storageLock     sync.RWMutex
+	storageOverride string
+)
+
+// GetStorage function used to get where the users and relationships are kept: "postgres", "sqlite" or "memory" (for local demos)
+// read from the --storage flag, or else from the STORAGE environment variable, default is postgres
+// return a string
+func GetStorage() string {
+	storageLock.RLock()
+	defer storageLock.RUnlock()
+	if storageOverride != "" {
+		return storageOverride
+	}
+	if storage := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE"))); storage == StorageMemory || storage == StorageSQLite {
+		return storage
+	}
+	return StoragePostgres
+}
+
+// SetStorage function used to override the STORAGE environment variable, e.g. with the --storage flag
+// pass the name of the storage as parameter
+// return an error type when the storage is unknown
+func SetStorage(storage string) error {
+	storage = strings.ToLower(strings.TrimSpace(storage))
+	if storage != StoragePostgres && storage != StorageMemory && storage != StorageSQLite {
+		return fmt.Errorf("unknown storage %q, expected %s, %s or %s", storage, StoragePostgres, StorageSQLite, StorageMemory)
+	}
+	storageLock.Lock()
+	defer storageLock.Unlock()
+	storageOverride = storage
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultRequestTimeout = 5 * time.Second
+
+// GetRequestTimeout function used to get the default deadline applied to every API request
+// read from the REQUEST_TIMEOUT environment variable (Go duration format, e.g. "5s"), default is 5 seconds
+// return a time.Duration
+func GetRequestTimeout() time.Duration {
+	return parseTimeout("REQUEST_TIMEOUT", os.Getenv("REQUEST_TIMEOUT"), defaultRequestTimeout)
+}
+
+// GetRouteTimeout function used to get the deadline of a specific route
+// read from the ROUTE_TIMEOUTS environment variable as a comma separated list of route=duration pairs,
+// e.g. "showSubscribingEmailListByEmail=10s,createUser=2s", routes without an entry fall back to GetRequestTimeout
+// pass a route name as parameter
+// return a time.Duration
+func GetRouteTimeout(route string) time.Duration {
+	fallback := GetRequestTimeout()
+	for _, pair := range strings.Split(os.Getenv("ROUTE_TIMEOUTS"), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && strings.TrimSpace(name) == route {
+			return parseTimeout("ROUTE_TIMEOUTS", value, fallback)
+		}
+	}
+	return fallback
+}
+
+func parseTimeout(name, value string, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		slog.Warn("invalid timeout configuration, using default", slog.String("variable", name), slog.String("value", value), slog.Duration("default", fallback))
+		return fallback
+	}
+	return timeout
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+// APIKeyController interface declares all functions used by the admin routes managing API keys
+type APIKeyController interface {
+	CreateAPIKey(c *gin.Context)
+	ListAPIKeys(c *gin.Context)
+	RevokeAPIKey(c *gin.Context)
+}
+
+type apiKeyController struct {
+	service services.APIKeyService
+}
+
+// NewAPIKeyController function used for initializing an APIKeyController
+// pass an APIKeyService as parameter
+func NewAPIKeyController(service services.APIKeyService) APIKeyController {
+	return &apiKeyController{
+		service: service,
+	}
+}
+
+// PingExample godoc
+// @Summary Create an API key
+// @Schemes
+// @Description Create an API key for a service account, the plain key is only returned in this response. Requires the admin role
+// @Tags Admin API
+// @Accept json
+// @Produce json
+// @Param   Request body models.CreatingAPIKeyRequest true "Name and roles of the key, roles default to service"
+// @Success 201 {object} models.CreatingAPIKeyResponse
+// @Failure 400,401,403,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys [post]
+// CreateAPIKey function works as a controller for creating an API key
+// pass a gin's context as parameter
+func (ctl *apiKeyController) CreateAPIKey(c *gin.Context) {
+	var request models.CreatingAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.CreateAPIKey(c.Request.Context(), request)
+	if err != nil {
+		respondError(c, "CreateAPIKey", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// PingExample godoc
+// @Summary List the API keys
+// @Schemes
+// @Description List the API keys, revoked ones included, without their secret. Requires the admin role
+// @Tags Admin API
+// @Produce json
+// @Success 200 {object} models.APIKeyListResponse
+// @Failure 401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys [get]
+// ListAPIKeys function works as a controller for listing the API keys
+// pass a gin's context as parameter
+func (ctl *apiKeyController) ListAPIKeys(c *gin.Context) {
+	response, err := ctl.service.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondError(c, "ListAPIKeys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Revoke an API key
+// @Schemes
+// @Description Revoke an API key, requests using it are rejected from now on. Requires the admin role
+// @Tags Admin API
+// @Produce json
+// @Param   id path string true "API key id"
+// @Success 204
+// @Failure 401,403,404,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys/{id} [delete]
+// RevokeAPIKey function works as a controller for revoking an API key
+// pass a gin's context as parameter
+func (ctl *apiKeyController) RevokeAPIKey(c *gin.Context) {
+	if err := ctl.service.RevokeAPIKey(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, "RevokeAPIKey", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
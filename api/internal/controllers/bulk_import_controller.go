@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+// import modes accepted by the mode query parameter
+const (
+	importModeBestEffort   = "best_effort"
+	importModeAllOrNothing = "all_or_nothing"
+)
+
+// BulkImportController interface declares the functions used by the admin routes importing data in bulk
+type BulkImportController interface {
+	Import(c *gin.Context)
+}
+
+type bulkImportController struct {
+	service services.BulkImportService
+}
+
+// NewBulkImportController function used for initializing a BulkImportController
+// pass a BulkImportService as parameter
+func NewBulkImportController(service services.BulkImportService) BulkImportController {
+	return &bulkImportController{
+		service: service,
+	}
+}
+
+// PingExample godoc
+// @Summary Import users or relationships in bulk
+// @Schemes
+// @Description Import a CSV or JSONL file of users, friendships, subscriptions or blocks, streamed in the request body. CSV files have one column (email) for users and two columns (requestor, target) for relationships, JSONL files have one object per line such as {"email": ...} or {"requestor": ..., "target": ...}. Invalid rows are listed in the report. Requires the admin role
+// @Tags Admin API
+// @Accept plain
+// @Produce json
+// @Param   kind path string true "users, friendships, subscriptions or blocks"
+// @Param   format query string false "csv or jsonl, guessed from the Content-Type header (text/csv or application/x-ndjson) when omitted"
+// @Param   mode query string false "best_effort (default) writes the valid rows, all_or_nothing writes nothing when a row fails"
+// @Param   dry_run query bool false "validate the file without writing anything"
+// @Param   auto_create_users query bool false "register the unknown emails of relationships instead of rejecting their rows"
+// @Param   Request body string true "CSV or JSONL rows"
+// @Success 200 {object} models.BulkImportReport
+// @Failure 400,401,403,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/imports/{kind} [post]
+// Import function works as a controller for importing users or relationships in bulk
+// pass a gin's context as parameter
+func (ctl *bulkImportController) Import(c *gin.Context) {
+	options := models.BulkImportOptions{Kind: c.Param("kind"), Format: c.Query("format")}
+	if options.Format == "" {
+		options.Format = importFormatOf(c.GetHeader("Content-Type"))
+	}
+	switch c.DefaultQuery("mode", importModeBestEffort) {
+	case importModeBestEffort:
+	case importModeAllOrNothing:
+		options.AllOrNothing = true
+	default:
+		respondError(c, "Import", apperrors.Validation("invalid_import_mode", "invalid import mode, expected best_effort or all_or_nothing"))
+		return
+	}
+	for name, target := range map[string]*bool{"dry_run": &options.DryRun, "auto_create_users": &options.AutoCreateUsers} {
+		value, ok := c.GetQuery(name)
+		if !ok {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid "+name+" parameter, expected a boolean"))
+			return
+		}
+		*target = enabled
+	}
+
+	report, err := ctl.service.Import(c.Request.Context(), c.Request.Body, options)
+	if err != nil {
+		respondError(c, "Import", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importFormatOf function used to guess the format of an import from its media type
+func importFormatOf(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return models.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return models.ImportFormatJSONL
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+)
+
+func TestImportUsesContentTypeAndQueryOptions(t *testing.T) {
+	service := &bulkImportServiceMock{}
+	router := setupBulkImportRouterForTesting(service)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/admin/imports/users?dry_run=true&mode=all_or_nothing", strings.NewReader("a@example.com\n"))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.BulkImportOptions{Kind: models.ImportUsers, Format: models.ImportFormatCSV, DryRun: true, AllOrNothing: true}, service.options)
+	assert.Equal(t, "a@example.com\n", service.body)
+	assert.JSONEq(t, `{"kind":"users","format":"csv","dry_run":true,"all_or_nothing":true,"committed":false,"total":1,"imported":1,"failed":0,"errors":[]}`, w.Body.String())
+}
+
+func TestImportWithInvalidOptions(t *testing.T) {
+	service := &bulkImportServiceMock{}
+	router := setupBulkImportRouterForTesting(service)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/admin/imports/users?format=jsonl&mode=partial", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/admin/imports/users?format=jsonl&dry_run=maybe", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, service.options.Kind)
+}
+
+func setupBulkImportRouterForTesting(service *bulkImportServiceMock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/admin/imports/:kind", NewBulkImportController(service).Import)
+	return router
+}
+
+// bulkImportServiceMock records the options and the body of the last import
+type bulkImportServiceMock struct {
+	options models.BulkImportOptions
+	body    string
+}
+
+func (s *bulkImportServiceMock) Import(ctx context.Context, source io.Reader, options models.BulkImportOptions) (models.BulkImportReport, error) {
+	body, _ := io.ReadAll(source)
+	s.options, s.body = options, string(body)
+	return models.BulkImportReport{Kind: options.Kind, Format: options.Format, DryRun: options.DryRun, AllOrNothing: options.AllOrNothing,
+		Total: 1, Imported: 1, Errors: []models.BulkImportRowError{}}, nil
+}
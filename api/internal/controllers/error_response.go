@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+// statusClientClosedRequest is the non-standard status code used when the client went away before the response was ready
+const statusClientClosedRequest = 499
+
+// HTTPStatus function used to map a domain error Kind to an HTTP status code
+// pass an apperrors.Kind as parameter
+// return an HTTP status code
+func HTTPStatus(kind apperrors.Kind) int {
+	switch kind {
+	case apperrors.KindInvalidRequest:
+		return http.StatusBadRequest
+	case apperrors.KindValidation:
+		return http.StatusUnprocessableEntity
+	case apperrors.KindNotFound:
+		return http.StatusNotFound
+	case apperrors.KindAlreadyExists, apperrors.KindConflict:
+		return http.StatusConflict
+	case apperrors.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case apperrors.KindForbidden, apperrors.KindBlocked:
+		return http.StatusForbidden
+	case apperrors.KindRateLimited:
+		return http.StatusTooManyRequests
+	case apperrors.KindTimeout:
+		return http.StatusGatewayTimeout
+	case apperrors.KindCanceled:
+		return statusClientClosedRequest
+	case apperrors.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError function used to write an error returned from the Service layer as a JSON error envelope
+// the status code is derived from the error's Kind, and the underlying cause is logged but never sent to the client
+// pass a gin's context, an operation name and an error as parameters
+func respondError(c *gin.Context, operation string, err error) {
+	appErr := apperrors.From(err)
+	status := HTTPStatus(appErr.Kind)
+
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request failed",
+		slog.String("operation", operation), slog.String("code", appErr.Code), slog.Int("status", status), slog.Any("error", err))
+
+	if retryAfter, ok := appErr.Details["retry_after"].(int); ok && (appErr.Kind == apperrors.KindRateLimited || appErr.Kind == apperrors.KindUnavailable) {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	c.JSON(status, newErrorResponse(appErr))
+}
+
+// respondBadRequest function used to reject a request that failed the controller's own checks with a 400 status
+// pass a gin's context and an error as parameters
+func respondBadRequest(c *gin.Context, err error) {
+	appErr := apperrors.From(err)
+	if appErr.Kind == apperrors.KindInternal {
+		// binding errors from gin are not domain errors, but they are still safe to report
+		appErr = apperrors.Wrap(err, apperrors.KindInvalidRequest, "invalid_request", err.Error())
+	}
+	c.JSON(http.StatusBadRequest, newErrorResponse(appErr))
+}
+
+func newErrorResponse(err *apperrors.Error) models.ErrorResponse {
+	return models.ErrorResponse{Error: err.Message, Code: err.Code, Details: err.Details}
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[apperrors.Kind]int{
+		apperrors.KindInvalidRequest:  http.StatusBadRequest,
+		apperrors.KindValidation:      http.StatusUnprocessableEntity,
+		apperrors.KindNotFound:        http.StatusNotFound,
+		apperrors.KindAlreadyExists:   http.StatusConflict,
+		apperrors.KindConflict:        http.StatusConflict,
+		apperrors.KindUnauthenticated: http.StatusUnauthorized,
+		apperrors.KindForbidden:       http.StatusForbidden,
+		apperrors.KindBlocked:         http.StatusForbidden,
+		apperrors.KindRateLimited:     http.StatusTooManyRequests,
+		apperrors.KindTimeout:         http.StatusGatewayTimeout,
+		apperrors.KindCanceled:        statusClientClosedRequest,
+		apperrors.KindUnavailable:     http.StatusServiceUnavailable,
+		apperrors.KindInternal:        http.StatusInternalServerError,
+		apperrors.Kind("unknown"):     http.StatusInternalServerError,
+	}
+	for kind, expected := range tests {
+		assert.Equal(t, expected, HTTPStatus(kind), string(kind))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
 	"golang_project/api/internal/models"
 	"golang_project/api/internal/pkg"
 	"golang_project/api/internal/services"
@@ -32,7 +33,7 @@ func New(service services.FriendConnectionService) FriendConnectionController {
 	}
 }
 
-// @BasePath /api/v1
+// @BasePath /api
 
 // PingExample godoc
 // @Summary Create an User
@@ -42,24 +43,29 @@ func New(service services.FriendConnectionService) FriendConnectionController {
 // @Accept json
 // @Produce json
 // @Param   Request body models.CreatingUserRequest true "Create an User"
-// @Router /users/createUser [post]
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/users/createUser [post]
 // CreateUser function works as a controller for creating an new user
 // pass a gin's context as parameter
 func (ctl *controller) CreateUser(c *gin.Context) {
 	var request models.CreatingUserRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.CreateUser(request)
+	response, err := ctl.service.CreateUser(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "CreateUser", err)
 		return
 	}
 
@@ -74,29 +80,34 @@ func (ctl *controller) CreateUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param   Request body models.FriendConnectionRequest true "Create a friend connection between 2 user emails"
-// @Router /friends/createConnection [post]
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/createConnection [post]
 // CreateFriendConnection function works as a controller for creating friend connection between 2 user emails
 // pass a gin's context as parameter
 func (ctl *controller) CreateFriendConnection(c *gin.Context) {
 	var request models.FriendConnectionRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if len(request.Friends) != 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Request, the model must not be empty"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid Request, the model must not be empty"))
 		return
 	}
 
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.CreateConnection(request)
+	response, err := ctl.service.CreateConnection(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "CreateConnection", err)
 		return
 	}
 
@@ -111,29 +122,33 @@ func (ctl *controller) CreateFriendConnection(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param   Request body models.FriendListRequest true "Get a list of friend by user email"
-// @Router /friends/showFriendsByEmail [post]
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/showFriendsByEmail [post]
 // GetFriendListByEmail function works as a controller for getting a friend list by an email address
 // pass a gin's context as parameter
 func (ctl *controller) GetFriendListByEmail(c *gin.Context) {
 	var request models.FriendListRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if request == (models.FriendListRequest{}) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Request, the model must not be empty"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid Request, the model must not be empty"))
 		return
 	}
 
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.GetFriendConnection(request)
+	response, err := ctl.service.GetFriendConnection(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "GetFriendConnection", err)
 		return
 	}
 
@@ -148,29 +163,33 @@ func (ctl *controller) GetFriendListByEmail(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param   Request body models.CommonFriendListRequest true "Retrieve the common friends list between two email addresses"
-// @Router /friends/showCommonFriendList [post]
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/showCommonFriendList [post]
 // ShowCommonFriendList function works as a controller for getting a list of common friends between two email addresses
 // pass a gin's context as parameter
 func (ctl *controller) ShowCommonFriendList(c *gin.Context) {
 	var request models.CommonFriendListRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if request.Friends == nil || len(request.Friends) < 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Request, the friends list must have over 1 item"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid Request, the friends list must have over 1 item"))
 		return
 	}
 
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.ShowCommonFriendList(request)
+	response, err := ctl.service.ShowCommonFriendList(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "ShowCommonFriendList", err)
 		return
 	}
 
@@ -185,29 +204,34 @@ func (ctl *controller) ShowCommonFriendList(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param   Request body models.SubscribeRequest true "Subscribe to updates from an email address"
-// @Router /friends/subscribeFromEmail [post]
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/subscribeFromEmail [post]
 // SubscribeFromEmail function works as a controller for creating a subscribe from an email address to another one
 // pass a gin's context as parameter
 func (ctl *controller) SubscribeFromEmail(c *gin.Context) {
 	var request models.SubscribeRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if request.Requestor == "" || request.Target == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, both requestor and target must not be null"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, both requestor and target must not be null"))
 		return
 	}
 
 	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.SubscribeFromEmail(request)
+	response, err := ctl.service.SubscribeFromEmail(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "SubscribeFromEmail", err)
 		return
 	}
 
@@ -222,29 +246,34 @@ func (ctl *controller) SubscribeFromEmail(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param   Request body models.BlockSubscribeRequest true "Block updates from an email address"
-// @Router /friends/blockSubscribeByEmail [post]
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/blockSubscribeByEmail [post]
 // BlockSubscribeByEmail function works as a controller for creating a block subscribe update from an email address to another one
 // pass a gin's context as parameter
 func (ctl *controller) BlockSubscribeByEmail(c *gin.Context) {
 	var request models.BlockSubscribeRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if request.Requestor == "" || request.Target == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, both requestor and target must not be null"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, both requestor and target must not be null"))
 		return
 	}
 
 	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.BlockSubscribeByEmail(request)
+	response, err := ctl.service.BlockSubscribeByEmail(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "BlockSubscribeByEmail", err)
 		return
 	}
 
@@ -254,34 +283,39 @@ func (ctl *controller) BlockSubscribeByEmail(c *gin.Context) {
 // PingExample godoc
 // @Summary Get Subscribing email list by email
 // @Schemes
-// @Description Requirement 6: As a user, I need an API to retrieve all email addresses that can receive updates from an email address.
+// @Description Requirement 6: As a user, I need an API to retrieve all email addresses that can receive updates from an email address. The update is posted: with the outbox enabled, an UpdatePosted event is written.
 // @Tags Friend API
 // @Accept json
 // @Produce json
 // @Param   Request body models.GetSubscribingEmailListRequest true "retrieve all email addresses that can receive update from an email address"
-// @Router /friends/showSubscribingEmailListByEmail [post]
-// GetSubscribingEmailListByEmail function works as a controller for getting a list of subscribe email by an email address
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/friends/showSubscribingEmailListByEmail [post]
+// GetSubscribingEmailListByEmail function works as a controller for posting an update and getting a list of subscribe email by an email address
 // pass a gin's context as parameter
 func (ctl *controller) GetSubscribingEmailListByEmail(c *gin.Context) {
 	var request models.GetSubscribingEmailListRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
 	if request.Sender == "" || request.Text == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, both Sender and Text must not be null"})
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, both Sender and Text must not be null"))
 		return
 	}
 
 	if err := pkg.CheckValidEmail(request.Sender); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err)
 		return
 	}
 
-	response, err := ctl.service.GetSubscribingEmailListByEmail(request)
+	response, err := ctl.service.PostUpdate(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, "GetSubscribingEmailListByEmail", err)
 		return
 	}
 
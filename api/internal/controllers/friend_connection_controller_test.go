@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang_project/api/internal/apperrors"
 	"golang_project/api/internal/docs"
 	"golang_project/api/internal/models"
 	"golang_project/api/internal/pkg"
@@ -89,6 +91,40 @@ func TestCreateUserFailCaseWithNilBody(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestCreateUserFailCaseWithExistingUser(t *testing.T) {
+	router := SetupRouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/users/createUser", strings.NewReader("{\"email\":\"existing@example.com\"}"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "{\"error\":\"user already exists\",\"code\":\"user_already_exists\"}", w.Body.String())
+}
+
+func TestCreateUserFailCaseWithDatabaseOutage(t *testing.T) {
+	router := SetupRouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/users/createUser", strings.NewReader("{\"email\":\"outage@example.com\"}"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"error\":\"internal server error\",\"code\":\"internal_error\"}", w.Body.String())
+}
+
 func TestCreateFriendConnectionSuccessfulCase(t *testing.T) {
 	router := SetupRouterForTesting()
 
@@ -222,7 +258,7 @@ func TestShowFriendsByEmailEmptyBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid request\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid request\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowFriendsByEmailWrongBody(t *testing.T) {
@@ -239,7 +275,7 @@ func TestShowFriendsByEmailWrongBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid Request, the model must not be empty\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid Request, the model must not be empty\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowFriendsByEmailWithInvalidEmail(t *testing.T) {
@@ -256,7 +292,7 @@ func TestShowFriendsByEmailWithInvalidEmail(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid email address\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid email address\",\"code\":\"invalid_email\"}", w.Body.String())
 }
 
 func TestShowCommonFriendListSuccessfulCode(t *testing.T) {
@@ -305,7 +341,7 @@ func TestShowCommonFriendListEmptyBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid request\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid request\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowCommonFriendListWrongBody(t *testing.T) {
@@ -322,7 +358,7 @@ func TestShowCommonFriendListWrongBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid Request, the friends list must have over 1 item\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid Request, the friends list must have over 1 item\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowCommonFriendListWithInvalid(t *testing.T) {
@@ -343,7 +379,7 @@ func TestShowCommonFriendListWithInvalid(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid character 'c' after array element\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid character 'c' after array element\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestSubscribeFromEmailSuccessfulCase(t *testing.T) {
@@ -625,7 +661,7 @@ func TestShowSubscribingEmailListByEmailEmptyBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid request\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid request\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowSubscribingEmailListByEmailWrongBody(t *testing.T) {
@@ -642,7 +678,7 @@ func TestShowSubscribingEmailListByEmailWrongBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid request, both Sender and Text must not be null\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid request, both Sender and Text must not be null\",\"code\":\"invalid_request\"}", w.Body.String())
 }
 
 func TestShowSubscribingEmailListByEmailWithInvalidEmail(t *testing.T) {
@@ -659,21 +695,27 @@ func TestShowSubscribingEmailListByEmailWithInvalidEmail(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"invalid email address\"}", w.Body.String())
+	assert.Equal(t, "{\"error\":\"invalid email address\",\"code\":\"invalid_email\"}", w.Body.String())
 }
 
 type ServiceMock struct {
 	mock.Mock
 }
 
-func (s *ServiceMock) CreateUser(req models.CreatingUserRequest) (models.CreatingUserResponse, error) {
+func (s *ServiceMock) CreateUser(ctx context.Context, req models.CreatingUserRequest) (models.CreatingUserResponse, error) {
 	if err := pkg.CheckValidEmail(req.Email); err != nil {
 		return models.CreatingUserResponse{}, err
 	}
+	switch req.Email {
+	case "existing@example.com":
+		return models.CreatingUserResponse{}, apperrors.AlreadyExists("user_already_exists", "user already exists")
+	case "outage@example.com":
+		return models.CreatingUserResponse{}, apperrors.Internal(errors.New("pq: connection refused"))
+	}
 	return models.CreatingUserResponse{Success: true}, nil
 }
 
-func (s *ServiceMock) CreateConnection(request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+func (s *ServiceMock) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return models.FriendConnectionResponse{Success: true}, errors.New("invalid email address")
 	}
@@ -682,13 +724,19 @@ func (s *ServiceMock) CreateConnection(request models.FriendConnectionRequest) (
 	}
 	return models.FriendConnectionResponse{Success: false}, nil
 }
-func (s *ServiceMock) GetFriendConnection(request models.FriendListRequest) (models.FriendListResponse, error) {
+func (s *ServiceMock) RemoveConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	return models.FriendConnectionResponse{Success: true}, nil
+}
+func (s *ServiceMock) GetFriendConnection(ctx context.Context, request models.FriendListRequest) (models.FriendListResponse, error) {
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
 		return models.FriendListResponse{Success: false}, err
 	}
 	return models.FriendListResponse{Success: true, Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}, Count: 2}, nil
 }
-func (s *ServiceMock) ShowCommonFriendList(request models.CommonFriendListRequest) (models.CommonFriendListResponse, error) {
+func (s *ServiceMock) ShowCommonFriendList(ctx context.Context, request models.CommonFriendListRequest) (models.CommonFriendListResponse, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return models.CommonFriendListResponse{}, err
 	}
@@ -697,16 +745,28 @@ func (s *ServiceMock) ShowCommonFriendList(request models.CommonFriendListReques
 	}
 	return models.CommonFriendListResponse{}, nil
 }
-func (s *ServiceMock) SubscribeFromEmail(request models.SubscribeRequest) (models.SubscribeResponse, error) {
+func (s *ServiceMock) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
 	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
 		return models.SubscribeResponse{}, err
 	}
+	if request.Requestor == "busy@example.com" {
+		return models.SubscribeResponse{}, apperrors.RateLimited("quota_exceeded", "the subscriptions quota is exceeded, retry later").WithDetail("retry_after", 120)
+	}
+	if request.Requestor == "outage@example.com" {
+		return models.SubscribeResponse{}, apperrors.New(apperrors.KindUnavailable, "database_unavailable", "the database is unavailable, retry later").WithDetail("retry_after", 7)
+	}
 	if request.Requestor != "" && request.Target != "" {
 		return models.SubscribeResponse{Success: true}, nil
 	}
 	return models.SubscribeResponse{}, nil
 }
-func (s *ServiceMock) BlockSubscribeByEmail(request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+func (s *ServiceMock) UnsubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+	return models.SubscribeResponse{Success: true}, nil
+}
+func (s *ServiceMock) BlockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
 	if err := pkg.CheckValidEmail(request.Requestor); err != nil {
 		return models.BlockSubscribeResponse{}, err
 	}
@@ -715,7 +775,13 @@ func (s *ServiceMock) BlockSubscribeByEmail(request models.BlockSubscribeRequest
 	}
 	return models.BlockSubscribeResponse{}, nil
 }
-func (s *ServiceMock) GetSubscribingEmailListByEmail(request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+func (s *ServiceMock) UnblockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+	return models.BlockSubscribeResponse{Success: true}, nil
+}
+func (s *ServiceMock) GetSubscribingEmailListByEmail(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
 	if err := pkg.CheckValidEmail(request.Sender); err != nil {
 		return models.GetSubscribingEmailListResponse{}, err
 	}
@@ -728,6 +794,29 @@ func (s *ServiceMock) GetSubscribingEmailListByEmail(request models.GetSubscribi
 	return models.GetSubscribingEmailListResponse{}, nil
 }
 
+func (s *ServiceMock) PostUpdate(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+	return s.GetSubscribingEmailListByEmail(ctx, request)
+}
+
+func (s *ServiceMock) StreamRecipients(ctx context.Context, request models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	if request.Text == "interrupted" {
+		if err := fn("hao.nguyen@s3corp.com.vn"); err != nil {
+			return err
+		}
+		return apperrors.New(apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+	response, err := s.GetSubscribingEmailListByEmail(ctx, request)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range response.Recipients {
+		if err := fn(recipient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func SetupRouterForTesting() *gin.Engine {
 	serv := &ServiceMock{}
 	controller := New(serv)
@@ -756,3 +845,59 @@ func SetupRouterForTesting() *gin.Engine {
 	}
 	return router
 }
+
+func (s *ServiceMock) GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error) {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return nil, err
+	}
+	result := map[string]models.UserRelationships{}
+	for _, email := range emails {
+		result[email] = models.UserRelationships{Email: email, Friends: []string{}, Subscribers: []string{}, Subscriptions: []string{}, Blocked: []string{}}
+	}
+	return result, nil
+}
+
+func (s *ServiceMock) GetFriendListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchFriendListResponse, error) {
+	response := models.BatchFriendListResponse{Success: true, Results: map[string]models.BatchFriendList{}, Errors: map[string]models.BatchItemError{}}
+	for _, email := range request.Emails {
+		if err := pkg.CheckValidEmail(email); err != nil {
+			response.Errors[email] = models.BatchItemError{Code: "invalid_email", Error: err.Error()}
+			continue
+		}
+		response.Results[email] = models.BatchFriendList{Friends: []string{"hao.nguyen@s3corp.com.vn"}, Count: 1}
+	}
+	return response, nil
+}
+
+func (s *ServiceMock) GetSubscriberListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchSubscriberListResponse, error) {
+	response := models.BatchSubscriberListResponse{Success: true, Results: map[string]models.BatchSubscriberList{}, Errors: map[string]models.BatchItemError{}}
+	for _, email := range request.Emails {
+		if strings.HasPrefix(email, "unregistered") {
+			response.Errors[email] = models.BatchItemError{Code: "user_not_found", Error: "unregistered email address: " + email}
+			continue
+		}
+		response.Results[email] = models.BatchSubscriberList{Subscribers: []string{"kate@example.com"}, Count: 1}
+	}
+	return response, nil
+}
+
+func (s *ServiceMock) GetPrivacySettings(ctx context.Context, email string) (models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmail(email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return models.DefaultPrivacySettings(email), nil
+}
+
+func (s *ServiceMock) UpdatePrivacySettings(ctx context.Context, email string, request models.UpdatingPrivacySettingsRequest) (models.PrivacySettings, error) {
+	settings := models.DefaultPrivacySettings(email)
+	if request.FriendListVisibility != "" {
+		if request.FriendListVisibility != models.FriendListPublic && request.FriendListVisibility != models.FriendListFriends && request.FriendListVisibility != models.FriendListPrivate {
+			return models.PrivacySettings{}, apperrors.Validation("invalid_friend_list_visibility", "friend_list_visibility must be public, friends or private")
+		}
+		settings.FriendListVisibility = request.FriendListVisibility
+	}
+	if request.SubscriptionPolicy != "" {
+		settings.SubscriptionPolicy = request.SubscriptionPolicy
+	}
+	return settings, nil
+}
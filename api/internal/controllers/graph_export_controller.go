@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/graphexport"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+// GraphExportController interface declares the functions used by the admin routes exporting the social graph
+type GraphExportController interface {
+	ExportGraph(c *gin.Context)
+}
+
+type graphExportController struct {
+	service services.GraphExportService
+}
+
+// NewGraphExportController function used for initializing a GraphExportController
+// pass a GraphExportService as parameter
+func NewGraphExportController(service services.GraphExportService) GraphExportController {
+	return &graphExportController{
+		service: service,
+	}
+}
+
+// PingExample godoc
+// @Summary Export the social graph
+// @Schemes
+// @Description Stream the users and their relationships as GraphML, GEXF (Gephi), DOT (Graphviz) or an edge list CSV. Edges carry the is_friend, friend_blocked, subscribed and subscribe_blocked attributes. Requires the admin role
+// @Tags Admin API
+// @Produce xml
+// @Produce plain
+// @Param   format query string false "graphml (default), gexf, dot or csv"
+// @Param   ego query string false "email of the user at the center of an ego network"
+// @Param   hops query int false "depth of the ego network, from 1 (default) to 5"
+// @Param   domain query string false "keep the users of an email domain, e.g. s3corp.com.vn"
+// @Success 200 {string} string "the graph document"
+// @Failure 400,401,403,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/graph [get]
+// ExportGraph function works as a controller for exporting the social graph
+// pass a gin's context as parameter
+func (ctl *graphExportController) ExportGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", models.GraphFormatGraphML)
+	filter := models.GraphExportFilter{Ego: c.Query("ego"), Domain: c.Query("domain")}
+	if value, ok := c.GetQuery("hops"); ok {
+		hops, err := strconv.Atoi(value)
+		if err != nil {
+			respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid hops parameter, expected a number"))
+			return
+		}
+		filter.Hops = hops
+	}
+
+	w := &exportResponseWriter{c: c, contentType: graphexport.ContentType(format), filename: "friend_connections." + format}
+	if err := ctl.service.Export(c.Request.Context(), w, format, filter); err != nil {
+		if !w.started {
+			respondError(c, "ExportGraph", err)
+			return
+		}
+		// the status is already sent, the client gets a truncated document
+		logger.FromContext(c.Request.Context()).Error("graph export interrupted", slog.Any("error", err))
+		c.Abort()
+	}
+}
+
+// exportResponseWriter sends the headers of a download with its first bytes, so that errors raised before can still get an error response
+type exportResponseWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+// Write function sends the headers before the first bytes of the document
+func (w *exportResponseWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(data)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestExportGraphStreamsDocument(t *testing.T) {
+	service := &graphExportServiceMock{}
+	router := setupGraphExportRouterForTesting(service)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin/graph?format=dot&ego=thehaohcm@yahoo.com.vn&hops=2&domain=s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/vnd.graphviz; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="friend_connections.dot"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "digraph friend_connections {\n}\n", w.Body.String())
+	assert.Equal(t, models.GraphExportFilter{Ego: "thehaohcm@yahoo.com.vn", Hops: 2, Domain: "s3corp.com.vn"}, service.filter)
+}
+
+func TestExportGraphWithInvalidOptions(t *testing.T) {
+	router := setupGraphExportRouterForTesting(&graphExportServiceMock{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin/graph?format=png", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/admin/graph?hops=two", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func setupGraphExportRouterForTesting(service *graphExportServiceMock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/admin/graph", NewGraphExportController(service).ExportGraph)
+	return router
+}
+
+// graphExportServiceMock writes an empty DOT graph, or rejects formats other than dot
+type graphExportServiceMock struct {
+	filter models.GraphExportFilter
+}
+
+func (s *graphExportServiceMock) Export(ctx context.Context, w io.Writer, format string, filter models.GraphExportFilter) error {
+	if format != models.GraphFormatDOT {
+		return apperrors.Validation("invalid_graph_format", "invalid graph format, expected graphml, gexf, dot or csv")
+	}
+	s.filter = filter
+	_, err := io.WriteString(w, "digraph friend_connections {\n}\n")
+	return err
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+// RelationshipAuditController interface declares the functions used by the admin routes querying the audit of the relationship writes
+type RelationshipAuditController interface {
+	ListRelationshipAudit(c *gin.Context)
+}
+
+type relationshipAuditController struct {
+	service services.RelationshipAuditService
+}
+
+// NewRelationshipAuditController function used for initializing a RelationshipAuditController
+// pass a RelationshipAuditService as parameter
+func NewRelationshipAuditController(service services.RelationshipAuditService) RelationshipAuditController {
+	return &relationshipAuditController{
+		service: service,
+	}
+}
+
+// PingExample godoc
+// @Summary List the relationship changes
+// @Schemes
+// @Description List the audit of the relationship writes (friend, unfriend, subscribe, unsubscribe, block, unblock), newest first, with the flags of the relationship before and after each write, its actor and its request id. before is null when the write created the relationship. Requires the admin role
+// @Tags Admin API
+// @Produce json
+// @Param   email query string false "keep the writes where the user is the requestor or the target"
+// @Param   action query string false "friend, unfriend, subscribe, unsubscribe, block or unblock"
+// @Param   from query string false "keep the writes made at or after this time, RFC 3339"
+// @Param   to query string false "keep the writes made before this time, RFC 3339"
+// @Param   before query int false "keep the writes older than this entry id, the next_before of the previous page"
+// @Param   limit query int false "size of the page, from 1 to 1000, default 100"
+// @Success 200 {object} models.RelationshipAuditResponse
+// @Failure 400,401,403,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/relationship-audit [get]
+// ListRelationshipAudit function works as a controller for listing the relationship changes
+// pass a gin's context as parameter
+func (ctl *relationshipAuditController) ListRelationshipAudit(c *gin.Context) {
+	filter := models.RelationshipAuditFilter{Email: c.Query("email"), Action: c.Query("action")}
+	var err error
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	if value, ok := c.GetQuery("before"); ok {
+		if filter.Before, err = strconv.ParseInt(value, 10, 64); err != nil {
+			respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid before parameter, expected a number"))
+			return
+		}
+	}
+	if value, ok := c.GetQuery("limit"); ok {
+		if filter.Limit, err = strconv.Atoi(value); err != nil {
+			respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid limit parameter, expected a number"))
+			return
+		}
+	}
+
+	response, err := ctl.service.ListRelationshipAudit(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, "ListRelationshipAudit", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// timeQuery function parses an optional RFC 3339 query parameter
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, apperrors.InvalidRequest("invalid_request", "invalid "+name+" parameter, expected an RFC 3339 time")
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+)
+
+func TestListRelationshipAuditWithFilters(t *testing.T) {
+	service := &relationshipAuditServiceMock{}
+	router := setupRelationshipAuditRouterForTesting(service)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin/relationship-audit?email=thehaohcm@yahoo.com.vn&action=block&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00%2B07:00&before=42&limit=10", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":[{"id":41,"requestor":"thehaohcm@yahoo.com.vn","target":"hao.nguyen@s3corp.com.vn","action":"block","actor":"thehaohcm@yahoo.com.vn",
+	"before":null,"after":{"is_friend":false,"friend_blocked":false,"subscribed":false,"subscribe_blocked":true},"changed_at":"2024-01-02T00:00:00Z"}],"count":1}`, w.Body.String())
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.FixedZone("", 7*60*60))
+	assert.Equal(t, "thehaohcm@yahoo.com.vn", service.filter.Email)
+	assert.Equal(t, models.AuditActionBlock, service.filter.Action)
+	assert.True(t, from.Equal(*service.filter.From))
+	assert.True(t, to.Equal(*service.filter.To))
+	assert.Equal(t, int64(42), service.filter.Before)
+	assert.Equal(t, 10, service.filter.Limit)
+}
+
+func TestListRelationshipAuditWithInvalidParameters(t *testing.T) {
+	router := setupRelationshipAuditRouterForTesting(&relationshipAuditServiceMock{})
+
+	for _, query := range []string{"from=yesterday", "to=2024-01-01", "before=last", "limit=ten"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/relationship-audit?"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func setupRelationshipAuditRouterForTesting(service *relationshipAuditServiceMock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/admin/relationship-audit", NewRelationshipAuditController(service).ListRelationshipAudit)
+	return router
+}
+
+// relationshipAuditServiceMock records the filter and returns a single block
+type relationshipAuditServiceMock struct {
+	filter models.RelationshipAuditFilter
+}
+
+func (s *relationshipAuditServiceMock) ListRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) (models.RelationshipAuditResponse, error) {
+	s.filter = filter
+	entries := []models.RelationshipAuditEntry{{
+		ID: 41, Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", Action: models.AuditActionBlock, Actor: "thehaohcm@yahoo.com.vn",
+		After: models.RelationshipFlags{SubscribeBlocked: true}, ChangedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}}
+	return models.RelationshipAuditResponse{Entries: entries, Count: len(entries)}, nil
+}
@@ -0,0 +1,660 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+)
+
+// UserResourceController interface declares all functions used by the resource-oriented /api/v2 routes
+type UserResourceController interface {
+	CreateUser(c *gin.Context)
+	GetFriends(c *gin.Context)
+	PutFriend(c *gin.Context)
+	DeleteFriend(c *gin.Context)
+	GetCommonFriends(c *gin.Context)
+	PutSubscription(c *gin.Context)
+	DeleteSubscription(c *gin.Context)
+	PutBlock(c *gin.Context)
+	DeleteBlock(c *gin.Context)
+	GetRecipients(c *gin.Context)
+	PostUpdate(c *gin.Context)
+	GetPrivacySettings(c *gin.Context)
+	PutPrivacySettings(c *gin.Context)
+	BatchGetFriends(c *gin.Context)
+	BatchGetSubscribers(c *gin.Context)
+}
+
+type userResourceController struct {
+	service services.FriendConnectionService
+}
+
+// NewUserResourceController function used for initializing a UserResourceController
+// the v2 routes share the same FriendConnectionService as the v1 routes
+// pass a FriendConnectionService as parameter
+func NewUserResourceController(service services.FriendConnectionService) UserResourceController {
+	return &userResourceController{
+		service: service,
+	}
+}
+
+// PingExample godoc
+// @Summary Create an User
+// @Schemes
+// @Description Create a new user, the response's Location header points to the user's friends resource
+// @Tags User API v2
+// @Accept json
+// @Produce json
+// @Param   Request body models.CreatingUserRequest true "Create an User"
+// @Success 201 {object} models.CreatingUserResponse
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,409,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users [post]
+// CreateUser function works as a controller for creating an new user
+// pass a gin's context as parameter
+func (ctl *userResourceController) CreateUser(c *gin.Context) {
+	var request models.CreatingUserRequest
+	if err := c.BindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.CreateUser(c.Request.Context(), request)
+	if err != nil {
+		respondError(c, "CreateUser", err)
+		return
+	}
+
+	c.Header("Location", "/api/v2/users/"+url.PathEscape(request.Email)+"/friends")
+	c.JSON(http.StatusCreated, response)
+}
+
+// PingExample godoc
+// @Summary Get Friend list of a user
+// @Schemes
+// @Description Retrieve the friends list for an email address
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Success 200 {object} models.FriendListResponse
+// @Failure 400,401,403,404,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/friends [get]
+// GetFriends function works as a controller for getting the friend list of the user in the path
+// pass a gin's context as parameter
+func (ctl *userResourceController) GetFriends(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	response, err := ctl.service.GetFriendConnection(c.Request.Context(), models.FriendListRequest{Email: email})
+	if err != nil {
+		respondError(c, "GetFriendConnection", err)
+		return
+	}
+
+	respondCacheable(c, response)
+}
+
+// PingExample godoc
+// @Summary Create a friend connection
+// @Schemes
+// @Description Create a friend connection between the user in the path and another user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   other path string true "friend email"
+// @Success 200 {object} models.FriendConnectionResponse
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/friends/{other} [put]
+// PutFriend function works as a controller for creating a friend connection between 2 user emails
+// pass a gin's context as parameter
+func (ctl *userResourceController) PutFriend(c *gin.Context) {
+	email, other, ok := emailPairParams(c, "other")
+	if !ok {
+		return
+	}
+
+	response, err := ctl.service.CreateConnection(c.Request.Context(), models.FriendConnectionRequest{Friends: []string{email, other}})
+	if err != nil {
+		respondError(c, "CreateConnection", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Remove a friend connection
+// @Schemes
+// @Description Remove the friend connection between the user in the path and another user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   other path string true "friend email"
+// @Success 204
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/friends/{other} [delete]
+// DeleteFriend function works as a controller for removing a friend connection between 2 user emails
+// pass a gin's context as parameter
+func (ctl *userResourceController) DeleteFriend(c *gin.Context) {
+	email, other, ok := emailPairParams(c, "other")
+	if !ok {
+		return
+	}
+
+	if _, err := ctl.service.RemoveConnection(c.Request.Context(), models.FriendConnectionRequest{Friends: []string{email, other}}); err != nil {
+		respondError(c, "RemoveConnection", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PingExample godoc
+// @Summary Show common Friend list
+// @Schemes
+// @Description Retrieve the common friends list between the user in the path and one or more other users
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   with query []string true "other user emails, repeated or comma separated" collectionFormat(multi)
+// @Success 200 {object} models.CommonFriendListResponse
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/common-friends [get]
+// GetCommonFriends function works as a controller for getting a list of common friends between the user in the path and other users
+// pass a gin's context as parameter
+func (ctl *userResourceController) GetCommonFriends(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	friends := []string{email}
+	for _, value := range c.QueryArray("with") {
+		for _, other := range strings.Split(value, ",") {
+			if other = strings.TrimSpace(other); other != "" {
+				friends = append(friends, other)
+			}
+		}
+	}
+	if len(friends) < 2 {
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, the with query parameter is required"))
+		return
+	}
+	if err := pkg.CheckValidEmails(friends); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.ShowCommonFriendList(c.Request.Context(), models.CommonFriendListRequest{Friends: friends})
+	if err != nil {
+		respondError(c, "ShowCommonFriendList", err)
+		return
+	}
+
+	respondCacheable(c, response)
+}
+
+// PingExample godoc
+// @Summary Subscribe to updates from a user
+// @Schemes
+// @Description The user in the path subscribes to updates from the target user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "subscriber email"
+// @Param   target path string true "target email"
+// @Success 200 {object} models.SubscribeResponse
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/subscriptions/{target} [put]
+// PutSubscription function works as a controller for creating a subscribe from an email address to another one
+// pass a gin's context as parameter
+func (ctl *userResourceController) PutSubscription(c *gin.Context) {
+	email, target, ok := emailPairParams(c, "target")
+	if !ok {
+		return
+	}
+
+	response, err := ctl.service.SubscribeFromEmail(c.Request.Context(), models.SubscribeRequest{Requestor: email, Target: target})
+	if err != nil {
+		respondError(c, "SubscribeFromEmail", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Unsubscribe from updates of a user
+// @Schemes
+// @Description The user in the path stops receiving updates from the target user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "subscriber email"
+// @Param   target path string true "target email"
+// @Success 204
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/subscriptions/{target} [delete]
+// DeleteSubscription function works as a controller for removing a subscribe from an email address to another one
+// pass a gin's context as parameter
+func (ctl *userResourceController) DeleteSubscription(c *gin.Context) {
+	email, target, ok := emailPairParams(c, "target")
+	if !ok {
+		return
+	}
+
+	if _, err := ctl.service.UnsubscribeFromEmail(c.Request.Context(), models.SubscribeRequest{Requestor: email, Target: target}); err != nil {
+		respondError(c, "UnsubscribeFromEmail", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PingExample godoc
+// @Summary Block updates from a user
+// @Schemes
+// @Description The user in the path blocks updates from the target user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   target path string true "blocked email"
+// @Success 200 {object} models.BlockSubscribeResponse
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/blocks/{target} [put]
+// PutBlock function works as a controller for creating a block subscribe update from an email address to another one
+// pass a gin's context as parameter
+func (ctl *userResourceController) PutBlock(c *gin.Context) {
+	email, target, ok := emailPairParams(c, "target")
+	if !ok {
+		return
+	}
+
+	response, err := ctl.service.BlockSubscribeByEmail(c.Request.Context(), models.BlockSubscribeRequest{Requestor: email, Target: target})
+	if err != nil {
+		respondError(c, "BlockSubscribeByEmail", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Unblock updates from a user
+// @Schemes
+// @Description The user in the path lifts the block on updates from the target user, calling it again has no effect
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   target path string true "blocked email"
+// @Success 204
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/blocks/{target} [delete]
+// DeleteBlock function works as a controller for lifting a block subscribe update from an email address to another one
+// pass a gin's context as parameter
+func (ctl *userResourceController) DeleteBlock(c *gin.Context) {
+	email, target, ok := emailPairParams(c, "target")
+	if !ok {
+		return
+	}
+
+	if _, err := ctl.service.UnblockSubscribeByEmail(c.Request.Context(), models.BlockSubscribeRequest{Requestor: email, Target: target}); err != nil {
+		respondError(c, "UnblockSubscribeByEmail", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PingExample godoc
+// @Summary Get recipients of an update
+// @Schemes
+// @Description Retrieve all email addresses that can receive an update posted by the user in the path. With "Accept: application/x-ndjson" the recipients are streamed, one models.Recipient object per line, and a failure after the first line ends the stream with a models.ErrorResponse line
+// @Tags User API v2
+// @Produce json
+// @Produce application/x-ndjson
+// @Param   email path string true "sender email"
+// @Param   text query string true "text of the update, mentioned emails are included in the recipients"
+// @Param   Accept header string false "application/x-ndjson to stream the recipients"
+// @Success 200 {object} models.GetSubscribingEmailListResponse
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/recipients [get]
+// GetRecipients function works as a controller for getting a list of subscribe email by an email address
+// pass a gin's context as parameter
+func (ctl *userResourceController) GetRecipients(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	text := c.Query("text")
+	if text == "" {
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, the text query parameter is required"))
+		return
+	}
+
+	request := models.GetSubscribingEmailListRequest{Sender: email, Text: text}
+	if strings.Contains(c.GetHeader("Accept"), NDJSONContentType) {
+		ctl.streamRecipients(c, request)
+		return
+	}
+
+	response, err := ctl.service.GetSubscribingEmailListByEmail(c.Request.Context(), request)
+	if err != nil {
+		respondError(c, "GetSubscribingEmailListByEmail", err)
+		return
+	}
+
+	respondCacheable(c, response)
+}
+
+// NDJSONContentType is the media type of the streamed responses, one JSON object per line
+const NDJSONContentType = "application/x-ndjson"
+
+// streamRecipients function used to write the recipients of an update as NDJSON while they are read from the database
+// the status is sent with the first line, so errors raised before it still get an error response,
+// later errors end the stream with an ErrorResponse line since the status can no longer change
+func (ctl *userResourceController) streamRecipients(c *gin.Context, request models.GetSubscribingEmailListRequest) {
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", NDJSONContentType)
+		c.Status(http.StatusOK)
+	}
+	encoder := json.NewEncoder(c.Writer)
+	err := ctl.service.StreamRecipients(c.Request.Context(), request, func(email string) error {
+		if !started {
+			start()
+		}
+		return encoder.Encode(models.Recipient{Email: email})
+	})
+	if err != nil && !started {
+		respondError(c, "StreamRecipients", err)
+		return
+	}
+	if err != nil {
+		appErr := apperrors.From(err)
+		logger.FromContext(c.Request.Context()).Error("recipients stream interrupted", slog.String("code", appErr.Code), slog.Any("error", err))
+		_ = encoder.Encode(newErrorResponse(appErr))
+		c.Abort()
+		return
+	}
+	if !started {
+		start()
+	}
+}
+
+// PingExample godoc
+// @Summary Post an update
+// @Schemes
+// @Description Post an update as the user in the path and retrieve the email addresses that receive it; with the outbox enabled, an UpdatePosted event is written
+// @Tags User API v2
+// @Accept json
+// @Produce json
+// @Param   email path string true "sender email"
+// @Param   Request body models.PostingUpdateRequest true "text of the update, mentioned emails are included in the recipients"
+// @Success 200 {object} models.GetSubscribingEmailListResponse
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/updates [post]
+// PostUpdate function works as a controller for posting an update as the user in the path
+// pass a gin's context as parameter
+func (ctl *userResourceController) PostUpdate(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	var request models.PostingUpdateRequest
+	if err := c.BindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+	if request.Text == "" {
+		respondBadRequest(c, apperrors.InvalidRequest("invalid_request", "invalid request, the text must not be empty"))
+		return
+	}
+
+	response, err := ctl.service.PostUpdate(c.Request.Context(), models.GetSubscribingEmailListRequest{Sender: email, Text: request.Text})
+	if err != nil {
+		respondError(c, "PostUpdate", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Get the privacy settings of a user
+// @Schemes
+// @Description Retrieve who can see the friend list of the user in the path and who can subscribe to its updates
+// @Tags User API v2
+// @Produce json
+// @Param   email path string true "user email"
+// @Success 200 {object} models.PrivacySettings
+// @Failure 400,401,403,404,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/privacy [get]
+// GetPrivacySettings function works as a controller for getting the privacy settings of the user in the path
+// pass a gin's context as parameter
+func (ctl *userResourceController) GetPrivacySettings(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	response, err := ctl.service.GetPrivacySettings(c.Request.Context(), email)
+	if err != nil {
+		respondError(c, "GetPrivacySettings", err)
+		return
+	}
+
+	respondCacheable(c, response)
+}
+
+// PingExample godoc
+// @Summary Change the privacy settings of a user
+// @Schemes
+// @Description Change the friend list visibility (public, friends or private) and the subscription policy (everyone, friends_of_friends or nobody) of the user in the path, the omitted settings are left unchanged
+// @Tags User API v2
+// @Accept json
+// @Produce json
+// @Param   email path string true "user email"
+// @Param   Request body models.UpdatingPrivacySettingsRequest true "New privacy settings"
+// @Success 200 {object} models.PrivacySettings
+// @Param   Idempotency-Key header string false "Key making the request safe to retry, the first response is replayed"
+// @Failure 400,401,403,404,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{email}/privacy [put]
+// PutPrivacySettings function works as a controller for changing the privacy settings of the user in the path
+// pass a gin's context as parameter
+func (ctl *userResourceController) PutPrivacySettings(c *gin.Context) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return
+	}
+
+	var request models.UpdatingPrivacySettingsRequest
+	if err := c.BindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.UpdatePrivacySettings(c.Request.Context(), email, request)
+	if err != nil {
+		respondError(c, "UpdatePrivacySettings", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Get the friend lists of many users
+// @Schemes
+// @Description Retrieve the friend lists of up to 500 email addresses with a single query, the emails which cannot be read (invalid_email, user_not_found, friend_list_hidden, forbidden) are reported in errors
+// @Tags User API v2
+// @Accept json
+// @Produce json
+// @Param   Request body models.BatchEmailsRequest true "Emails of the users"
+// @Success 200 {object} models.BatchFriendListResponse
+// @Failure 400,401,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/batch/friends [post]
+// BatchGetFriends function works as a controller for getting the friend lists of many email addresses at once
+// pass a gin's context as parameter
+func (ctl *userResourceController) BatchGetFriends(c *gin.Context) {
+	var request models.BatchEmailsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.GetFriendListsByEmails(c.Request.Context(), request)
+	if err != nil {
+		respondError(c, "GetFriendListsByEmails", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PingExample godoc
+// @Summary Get the subscriber lists of many users
+// @Schemes
+// @Description Retrieve the subscriber lists of up to 500 email addresses with a single query, the blocked subscribers are left out and the emails which cannot be read (invalid_email, user_not_found, forbidden) are reported in errors
+// @Tags User API v2
+// @Accept json
+// @Produce json
+// @Param   Request body models.BatchEmailsRequest true "Emails of the users"
+// @Success 200 {object} models.BatchSubscriberListResponse
+// @Failure 400,401,422,429 {object} models.ErrorResponse
+// @Failure 500,503,504 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/batch/subscribers [post]
+// BatchGetSubscribers function works as a controller for getting the subscriber lists of many email addresses at once
+// pass a gin's context as parameter
+func (ctl *userResourceController) BatchGetSubscribers(c *gin.Context) {
+	var request models.BatchEmailsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBadRequest(c, err)
+		return
+	}
+
+	response, err := ctl.service.GetSubscriberListsByEmails(c.Request.Context(), request)
+	if err != nil {
+		respondError(c, "GetSubscriberListsByEmails", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// emailParam function used to read and validate an email path parameter, a 400 response is written when it is invalid
+func emailParam(c *gin.Context, name string) (string, bool) {
+	email := strings.TrimSpace(c.Param(name))
+	if err := pkg.CheckValidEmail(email); err != nil {
+		respondBadRequest(c, err)
+		return "", false
+	}
+	return email, true
+}
+
+// emailPairParams function used to read and validate the :email path parameter together with another email path parameter
+func emailPairParams(c *gin.Context, other string) (string, string, bool) {
+	email, ok := emailParam(c, "email")
+	if !ok {
+		return "", "", false
+	}
+	otherEmail, ok := emailParam(c, other)
+	if !ok {
+		return "", "", false
+	}
+	return email, otherEmail, true
+}
+
+// respondCacheable function used to write a read response with an ETag, so clients and caches can revalidate it cheaply
+// a 304 Not Modified response is written when the If-None-Match header matches the current representation
+func respondCacheable(c *gin.Context, response interface{}) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		respondError(c, "respondCacheable", err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if candidate = strings.TrimSpace(candidate); candidate == etag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
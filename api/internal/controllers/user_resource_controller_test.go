@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+)
+
+func TestV2CreateUserSuccessfulCase(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/users", strings.NewReader("{\"email\":\"fda@yahoo.com.vn\"}"))
+	if err != nil {
+		log.Panic(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/users/fda@yahoo.com.vn/friends", w.Header().Get("Location"))
+}
+
+func TestV2GetFriendsSuccessfulCase(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/friends", nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	var modelRes models.FriendListResponse
+	err = json.Unmarshal(w.Body.Bytes(), &modelRes)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, models.FriendListResponse{Success: true, Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}, Count: 2}, modelRes)
+}
+
+func TestV2GetFriendsNotModified(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/friends", nil)
+	router.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/friends", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestV2GetFriendsWithInvalidEmail(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm/friends", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"error\":\"invalid email address\",\"code\":\"invalid_email\"}", w.Body.String())
+}
+
+func TestV2PutAndDeleteFriend(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v2/users/fda@yahoo.com.vn/friends/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"success\":true}", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/api/v2/users/fda@yahoo.com.vn/friends/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestV2GetCommonFriendsSuccessfulCase(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/common-friends?with=chinh.nguyen@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var modelRes models.CommonFriendListResponse
+	err := json.Unmarshal(w.Body.Bytes(), &modelRes)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, models.CommonFriendListResponse{Success: true, Friends: []string{"hao.nguyen@s3corp.com.vn"}, Count: 1}, modelRes)
+}
+
+func TestV2GetCommonFriendsWithoutOtherUser(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/common-friends", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2PutAndDeleteSubscription(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v2/users/fda@yahoo.com.vn/subscriptions/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/api/v2/users/fda@yahoo.com.vn/subscriptions/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestV2PutSubscriptionOverQuota(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v2/users/busy@example.com/subscriptions/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"the subscriptions quota is exceeded, retry later","code":"quota_exceeded","details":{"retry_after":120}}`, w.Body.String())
+}
+
+func TestV2PutSubscriptionDuringADatabaseOutage(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v2/users/outage@example.com/subscriptions/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "7", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"the database is unavailable, retry later","code":"database_unavailable","details":{"retry_after":7}}`, w.Body.String())
+}
+
+func TestV2PutAndDeleteBlock(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/api/v2/users/fda@yahoo.com.vn/blocks/hsa@s3corp.com.vn", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/api/v2/users/fda@yahoo.com.vn/blocks/hsa", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2GetRecipientsSuccessfulCase(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/recipients?text=Hello+World!+kate@example.com", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var modelRes models.GetSubscribingEmailListResponse
+	err := json.Unmarshal(w.Body.Bytes(), &modelRes)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "kate@example.com"}, modelRes.Recipients)
+}
+
+func TestV2PostUpdate(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v2/users/thehaohcm@yahoo.com.vn/updates", strings.NewReader(`{"text": "Hello World! kate@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var modelRes models.GetSubscribingEmailListResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &modelRes))
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "kate@example.com"}, modelRes.Recipients)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v2/users/thehaohcm@yahoo.com.vn/updates", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v2/users/invalid/updates", strings.NewReader(`{"text": "hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2StreamRecipients(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/recipients?text=Hello+World!+kate@example.com", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Equal(t, "{\"email\":\"hao.nguyen@s3corp.com.vn\"}\n{\"email\":\"kate@example.com\"}\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/recipients?text=interrupted", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"email\":\"hao.nguyen@s3corp.com.vn\"}\n{\"error\":\"the database is temporarily unavailable\",\"code\":\"database_unavailable\"}\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/recipients", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2GetAndPutPrivacySettings(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/users/thehaohcm@yahoo.com.vn/privacy", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"email":"thehaohcm@yahoo.com.vn","friend_list_visibility":"public","subscription_policy":"everyone"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPut, "/api/v2/users/thehaohcm@yahoo.com.vn/privacy", strings.NewReader(`{"friend_list_visibility":"private"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"email":"thehaohcm@yahoo.com.vn","friend_list_visibility":"private","subscription_policy":"everyone"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPut, "/api/v2/users/thehaohcm@yahoo.com.vn/privacy", strings.NewReader(`{"friend_list_visibility":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_friend_list_visibility"`)
+}
+
+func TestV2BatchGetFriends(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v2/batch/friends", strings.NewReader(`{"emails":["thehaohcm@yahoo.com.vn","thehaohcm"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":true,
+		"results":{"thehaohcm@yahoo.com.vn":{"friends":["hao.nguyen@s3corp.com.vn"],"count":1}},
+		"errors":{"thehaohcm":{"code":"invalid_email","error":"invalid email address"}}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/v2/batch/friends", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2BatchGetSubscribers(t *testing.T) {
+	router := SetupV2RouterForTesting()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v2/batch/subscribers", strings.NewReader(`{"emails":["thehaohcm@yahoo.com.vn","unregistered@example.com"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var modelRes models.BatchSubscriberListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &modelRes); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, models.BatchSubscriberList{Subscribers: []string{"kate@example.com"}, Count: 1}, modelRes.Results["thehaohcm@yahoo.com.vn"])
+	assert.Equal(t, "user_not_found", modelRes.Errors["unregistered@example.com"].Code)
+}
+
+func SetupV2RouterForTesting() *gin.Engine {
+	serv := &ServiceMock{}
+	controller := NewUserResourceController(serv)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v2 := router.Group("/api/v2")
+	{
+		v2.POST("/users", controller.CreateUser)
+		v2.GET("/users/:email/friends", controller.GetFriends)
+		v2.PUT("/users/:email/friends/:other", controller.PutFriend)
+		v2.DELETE("/users/:email/friends/:other", controller.DeleteFriend)
+		v2.GET("/users/:email/common-friends", controller.GetCommonFriends)
+		v2.PUT("/users/:email/subscriptions/:target", controller.PutSubscription)
+		v2.DELETE("/users/:email/subscriptions/:target", controller.DeleteSubscription)
+		v2.PUT("/users/:email/blocks/:target", controller.PutBlock)
+		v2.DELETE("/users/:email/blocks/:target", controller.DeleteBlock)
+		v2.GET("/users/:email/recipients", controller.GetRecipients)
+		v2.POST("/users/:email/updates", controller.PostUpdate)
+		v2.GET("/users/:email/privacy", controller.GetPrivacySettings)
+		v2.PUT("/users/:email/privacy", controller.PutPrivacySettings)
+		v2.POST("/batch/friends", controller.BatchGetFriends)
+		v2.POST("/batch/subscribers", controller.BatchGetSubscribers)
+	}
+	return router
+}
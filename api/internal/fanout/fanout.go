@@ -0,0 +1,79 @@
+package fanout
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+// Source type passes the recipients of an update to a callback one at a time and stops when the callback returns an error,
+// like FriendConnectionService.StreamRecipients
+type Source func(ctx context.Context, fn func(email string) error) error
+
+// Deliver type sends an update to one recipient
+type Deliver func(ctx context.Context, email string) error
+
+// Result struct counts the deliveries of a fan-out
+type Result struct {
+	Delivered int64
+	Failed    int64
+}
+
+// Recipients function used to get the Source of the recipients of an update, read with FriendConnectionService.StreamRecipients
+// pass a FriendConnectionService and a GetSubscribingEmailListRequest model as parameters
+// return a Source
+func Recipients(service services.FriendConnectionService, request models.GetSubscribingEmailListRequest) Source {
+	return func(ctx context.Context, fn func(email string) error) error {
+		return service.StreamRecipients(ctx, request, fn)
+	}
+}
+
+// Run function used to deliver an update to every recipient of a source with a pool of workers
+// the recipients are handed to the workers through a channel as large as the pool, so a slow delivery slows the source down
+// instead of piling the audience up in memory; a failed delivery is logged and counted, it does not stop the others
+// pass a context, the number of workers, a Source and a Deliver function as parameters
+// return the Result and the error of the source, the error of the context when it is canceled
+func Run(ctx context.Context, workers int, source Source, deliver Deliver) (Result, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var result Result
+	recipients := make(chan string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range recipients {
+				if err := deliver(ctx, email); err != nil {
+					atomic.AddInt64(&result.Failed, 1)
+					logger.FromContext(ctx).Warn("delivery failed", slog.String("recipient", email), slog.Any("error", err))
+					continue
+				}
+				atomic.AddInt64(&result.Delivered, 1)
+			}
+		}()
+	}
+
+	err := source(ctx, func(email string) error {
+		// once canceled, the workers drain the channel quickly, the select alone could keep on picking the send
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		select {
+		case recipients <- email:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(recipients)
+	wg.Wait()
+	return result, err
+}
@@ -0,0 +1,127 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+func TestRunDeliversEveryRecipientOnce(t *testing.T) {
+	var mu sync.Mutex
+	delivered := map[string]int{}
+	result, err := Run(context.Background(), 4, numbered(1000), func(ctx context.Context, email string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[email]++
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Delivered: 1000}, result)
+	assert.Len(t, delivered, 1000)
+	for _, count := range delivered {
+		assert.Equal(t, 1, count)
+	}
+}
+
+func TestRunCountsFailedDeliveries(t *testing.T) {
+	result, err := Run(context.Background(), 3, numbered(10), func(ctx context.Context, email string) error {
+		if email == "user3@example.com" || email == "user7@example.com" {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Delivered: 8, Failed: 2}, result)
+}
+
+func TestRunKeepsTheSourceAsFastAsTheWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the source announces each recipient before passing it on, and stops when the test stops listening
+	reads := make(chan int)
+	source := func(ctx context.Context, fn func(email string) error) error {
+		for i := 0; ; i++ {
+			select {
+			case reads <- i:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if err := fn(fmt.Sprintf("user%d@example.com", i)); err != nil {
+				return err
+			}
+		}
+	}
+	started := make(chan string, 2)
+	done := make(chan error)
+	var result Result
+	go func() {
+		var err error
+		result, err = Run(ctx, 2, source, func(ctx context.Context, email string) error {
+			started <- email
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		done <- err
+	}()
+
+	// 2 recipients held by the workers, 2 in the channel and 1 waiting to be sent
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, i, <-reads)
+	}
+	<-started
+	<-started
+	// both workers are blocked, so the source is blocked on the fifth recipient and cannot read a sixth one
+	select {
+	case i := <-reads:
+		t.Fatalf("recipient %d read while the workers are busy", i)
+	default:
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, int64(0), result.Delivered)
+}
+
+func TestRunReadsTheRecipientsOfTheService(t *testing.T) {
+	repo := repositories.NewMemoryRepository()
+	ctx := context.Background()
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{"sender@example.com", "friend@example.com", "fan@example.com"}))
+	_, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"sender@example.com", "friend@example.com"}})
+	assert.Nil(t, err)
+	_, err = repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "fan@example.com", Target: "sender@example.com"})
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	var delivered []string
+	source := Recipients(services.New(repo), models.GetSubscribingEmailListRequest{Sender: "sender@example.com", Text: "hi kate@example.com"})
+	result, err := Run(ctx, 2, source, func(ctx context.Context, email string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, email)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Delivered: 3}, result)
+	assert.ElementsMatch(t, []string{"kate@example.com", "fan@example.com", "friend@example.com"}, delivered)
+}
+
+func numbered(n int) Source {
+	return func(ctx context.Context, fn func(email string) error) error {
+		for i := 0; i < n; i++ {
+			if err := fn(fmt.Sprintf("user%d@example.com", i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/outbox"
+	"golang_project/api/internal/services"
+)
+
+// DeliverUpdate type sends a posted update to one recipient, the id of the event lets the recipient drop an update it already received
+type DeliverUpdate func(ctx context.Context, event models.Event, update models.UpdatePostedPayload, email string) error
+
+type sink struct {
+	service services.FriendConnectionService
+	workers int
+	deliver DeliverUpdate
+}
+
+// NewSink function used for initializing an outbox.Sink delivering each UpdatePosted event to the recipients of the update,
+// resolved when the event is published, with Run; the other events are ignored
+// the event is published again when a delivery failed, so every recipient is delivered at least once
+// pass a FriendConnectionService, the number of workers per update and a DeliverUpdate function as parameters
+// return an outbox.Sink
+func NewSink(service services.FriendConnectionService, workers int, deliver DeliverUpdate) outbox.Sink {
+	return &sink{
+		service: service,
+		workers: workers,
+		deliver: deliver,
+	}
+}
+
+// Publish function used to deliver an UpdatePosted event to the recipients of its update
+// an update which cannot be delivered at all, e.g. its sender was removed, is dropped with a warning instead of holding the next events of its sender
+// pass a context and an Event model as parameters
+// return an error type, when the recipients cannot be read or a delivery failed
+func (s *sink) Publish(ctx context.Context, event models.Event) error {
+	if event.Type != models.EventUpdatePosted {
+		return nil
+	}
+	var update models.UpdatePostedPayload
+	if err := json.Unmarshal(event.Payload, &update); err != nil {
+		logger.FromContext(ctx).Warn("update dropped, its payload is malformed", slog.String("event_id", event.ID), slog.Any("error", err))
+		return nil
+	}
+
+	source := Recipients(s.service, models.GetSubscribingEmailListRequest{Sender: update.Sender, Text: update.Text})
+	result, err := Run(ctx, s.workers, source, func(ctx context.Context, email string) error {
+		return s.deliver(ctx, event, update, email)
+	})
+	if err != nil {
+		switch apperrors.KindOf(err) {
+		case apperrors.KindInvalidRequest, apperrors.KindValidation, apperrors.KindNotFound:
+			logger.FromContext(ctx).Warn("update dropped, its recipients cannot be resolved", slog.String("event_id", event.ID), slog.Any("error", err))
+			return nil
+		}
+		return err
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("%d of %d deliveries of update %s failed", result.Failed, result.Delivered+result.Failed, event.ID)
+	}
+	return nil
+}
+
+// NewLogDeliverer function used for initializing a DeliverUpdate writing one structured log line per recipient
+// pass a pointer of slog.Logger as parameter
+// return a DeliverUpdate
+func NewLogDeliverer(logger *slog.Logger) DeliverUpdate {
+	return func(ctx context.Context, event models.Event, update models.UpdatePostedPayload, email string) error {
+		logger.LogAttrs(ctx, slog.LevelInfo, "update delivered",
+			slog.String("event_id", event.ID),
+			slog.String("sender", update.Sender),
+			slog.String("recipient", email))
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+func TestSinkDeliversAnUpdateToItsRecipients(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	sink := NewSink(newFanoutService(t), 2, func(ctx context.Context, event models.Event, update models.UpdatePostedPayload, email string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", event.ID)
+		assert.Equal(t, "hi kate@example.com", update.Text)
+		delivered = append(delivered, email)
+		return nil
+	})
+
+	err := sink.Publish(context.Background(), updatePosted(`{"sender":"sender@example.com","text":"hi kate@example.com"}`))
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"kate@example.com", "fan@example.com", "friend@example.com"}, delivered)
+
+	// the other events and the malformed updates are not delivered
+	assert.Nil(t, sink.Publish(context.Background(), models.Event{Type: models.EventSubscribed, Payload: []byte(`{}`)}))
+	assert.Nil(t, sink.Publish(context.Background(), updatePosted(`{"sender":`)))
+	assert.Len(t, delivered, 3)
+}
+
+func TestSinkFailsWhenADeliveryFailed(t *testing.T) {
+	sink := NewSink(newFanoutService(t), 2, func(ctx context.Context, event models.Event, update models.UpdatePostedPayload, email string) error {
+		if email == "fan@example.com" {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+
+	err := sink.Publish(context.Background(), updatePosted(`{"sender":"sender@example.com","text":"hi"}`))
+	assert.EqualError(t, err, "1 of 2 deliveries of update 0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10 failed")
+}
+
+func newFanoutService(t *testing.T) services.FriendConnectionService {
+	repo := repositories.NewMemoryRepository()
+	ctx := context.Background()
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{"sender@example.com", "friend@example.com", "fan@example.com"}))
+	_, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"sender@example.com", "friend@example.com"}})
+	assert.Nil(t, err)
+	_, err = repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "fan@example.com", Target: "sender@example.com"})
+	assert.Nil(t, err)
+	return services.New(repo)
+}
+
+func updatePosted(payload string) models.Event {
+	return models.Event{ID: "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", Type: models.EventUpdatePosted, AggregateID: "sender@example.com", Payload: []byte(payload)}
+}
@@ -0,0 +1,262 @@
+package graphexport
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+// edgeAttributes are the attributes of every edge, in the order of the relationship table columns
+var edgeAttributes = []string{"is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}
+
+// Writer interface writes a graph as a stream: every node first, then every edge, then Close
+type Writer interface {
+	WriteNode(email string) error
+	WriteEdge(relationship models.Relationship) error
+	// Close writes the end of the document and flushes it, it must be called even for an empty graph
+	Close() error
+}
+
+// NewWriter function used for initializing the Writer of a format: GraphML, GEXF, DOT or an edge list CSV,
+// nothing is written before the first node, edge or Close
+// pass an io.Writer and a format as parameters
+// return a Writer and an error type
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	out := &output{w: bufio.NewWriter(w)}
+	switch format {
+	case models.GraphFormatGraphML:
+		return &graphMLWriter{output: out}, nil
+	case models.GraphFormatGEXF:
+		return &gexfWriter{output: out}, nil
+	case models.GraphFormatDOT:
+		return &dotWriter{output: out}, nil
+	case models.GraphFormatCSV:
+		return &csvWriter{output: out}, nil
+	}
+	return nil, apperrors.Validation("invalid_graph_format", "invalid graph format, expected graphml, gexf, dot or csv").WithDetail("format", format)
+}
+
+// ContentType function used to get the media type of a format
+// pass a format as parameter
+// return a media type
+func ContentType(format string) string {
+	switch format {
+	case models.GraphFormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case models.GraphFormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "application/xml; charset=utf-8"
+}
+
+// output buffers the document and keeps the first write error, so the writers only check it once per call
+type output struct {
+	w       *bufio.Writer
+	err     error
+	started bool
+	edges   int
+}
+
+func (o *output) write(parts ...string) {
+	for _, part := range parts {
+		if o.err != nil {
+			return
+		}
+		_, o.err = o.w.WriteString(part)
+	}
+}
+
+func (o *output) flush() error {
+	if o.err != nil {
+		return o.err
+	}
+	return o.w.Flush()
+}
+
+func xmlEscape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+func flags(relationship models.Relationship) []bool {
+	return []bool{relationship.IsFriend, relationship.FriendBlocked, relationship.Subscribed, relationship.SubscribeBlock}
+}
+
+type graphMLWriter struct {
+	*output
+}
+
+func (g *graphMLWriter) begin() {
+	if g.started {
+		return
+	}
+	g.started = true
+	g.write(xml.Header, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" `,
+		`xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">`, "\n")
+	for _, attribute := range edgeAttributes {
+		g.write(`  <key id="`, attribute, `" for="edge" attr.name="`, attribute, `" attr.type="boolean"/>`, "\n")
+	}
+	g.write(`  <graph id="friend_connections" edgedefault="directed">`, "\n")
+}
+
+func (g *graphMLWriter) WriteNode(email string) error {
+	g.begin()
+	g.write(`    <node id="`, xmlEscape(email), `"/>`, "\n")
+	return g.err
+}
+
+func (g *graphMLWriter) WriteEdge(relationship models.Relationship) error {
+	g.begin()
+	g.write(`    <edge id="e`, strconv.Itoa(g.edges), `" source="`, xmlEscape(relationship.Requestor), `" target="`, xmlEscape(relationship.Target), `">`, "\n")
+	for i, value := range flags(relationship) {
+		g.write(`      <data key="`, edgeAttributes[i], `">`, strconv.FormatBool(value), `</data>`, "\n")
+	}
+	g.write(`    </edge>`, "\n")
+	g.edges++
+	return g.err
+}
+
+func (g *graphMLWriter) Close() error {
+	g.begin()
+	g.write(`  </graph>`, "\n", `</graphml>`, "\n")
+	return g.flush()
+}
+
+type gexfWriter struct {
+	*output
+	inEdges bool
+}
+
+func (g *gexfWriter) begin() {
+	if g.started {
+		return
+	}
+	g.started = true
+	g.write(xml.Header, `<gexf xmlns="http://gexf.net/1.3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" `,
+		`xsi:schemaLocation="http://gexf.net/1.3 http://gexf.net/1.3/gexf.xsd" version="1.3">`, "\n",
+		`  <graph defaultedgetype="directed" mode="static">`, "\n",
+		`    <attributes class="edge">`, "\n")
+	for i, attribute := range edgeAttributes {
+		g.write(`      <attribute id="`, strconv.Itoa(i), `" title="`, attribute, `" type="boolean"/>`, "\n")
+	}
+	g.write(`    </attributes>`, "\n", `    <nodes>`, "\n")
+}
+
+// edgesSection function closes the nodes and opens the edges the first time it is called
+func (g *gexfWriter) edgesSection() {
+	g.begin()
+	if !g.inEdges {
+		g.inEdges = true
+		g.write(`    </nodes>`, "\n", `    <edges>`, "\n")
+	}
+}
+
+func (g *gexfWriter) WriteNode(email string) error {
+	g.begin()
+	escaped := xmlEscape(email)
+	g.write(`      <node id="`, escaped, `" label="`, escaped, `"/>`, "\n")
+	return g.err
+}
+
+func (g *gexfWriter) WriteEdge(relationship models.Relationship) error {
+	g.edgesSection()
+	g.write(`      <edge id="`, strconv.Itoa(g.edges), `" source="`, xmlEscape(relationship.Requestor), `" target="`, xmlEscape(relationship.Target), `">`, "\n",
+		`        <attvalues>`, "\n")
+	for i, value := range flags(relationship) {
+		g.write(`          <attvalue for="`, strconv.Itoa(i), `" value="`, strconv.FormatBool(value), `"/>`, "\n")
+	}
+	g.write(`        </attvalues>`, "\n", `      </edge>`, "\n")
+	g.edges++
+	return g.err
+}
+
+func (g *gexfWriter) Close() error {
+	g.edgesSection()
+	g.write(`    </edges>`, "\n", `  </graph>`, "\n", `</gexf>`, "\n")
+	return g.flush()
+}
+
+type dotWriter struct {
+	*output
+}
+
+func (d *dotWriter) begin() {
+	if !d.started {
+		d.started = true
+		d.write("digraph friend_connections {\n")
+	}
+}
+
+func dotID(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func (d *dotWriter) WriteNode(email string) error {
+	d.begin()
+	d.write("  ", dotID(email), ";\n")
+	return d.err
+}
+
+func (d *dotWriter) WriteEdge(relationship models.Relationship) error {
+	d.begin()
+	d.write("  ", dotID(relationship.Requestor), " -> ", dotID(relationship.Target), " [")
+	for i, value := range flags(relationship) {
+		if i > 0 {
+			d.write(", ")
+		}
+		d.write(edgeAttributes[i], "=", strconv.FormatBool(value))
+	}
+	d.write("];\n")
+	return d.err
+}
+
+func (d *dotWriter) Close() error {
+	d.begin()
+	d.write("}\n")
+	return d.flush()
+}
+
+// csvWriter writes an edge list, the nodes are only known through their edges
+type csvWriter struct {
+	*output
+}
+
+func (c *csvWriter) begin() {
+	if !c.started {
+		c.started = true
+		c.write("source,target,", strings.Join(edgeAttributes, ","), "\n")
+	}
+}
+
+func csvField(value string) string {
+	if strings.ContainsAny(value, ",\"\r\n") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
+func (c *csvWriter) WriteNode(email string) error {
+	c.begin()
+	return c.err
+}
+
+func (c *csvWriter) WriteEdge(relationship models.Relationship) error {
+	c.begin()
+	c.write(csvField(relationship.Requestor), ",", csvField(relationship.Target))
+	for _, value := range flags(relationship) {
+		c.write(",", strconv.FormatBool(value))
+	}
+	c.write("\n")
+	return c.err
+}
+
+func (c *csvWriter) Close() error {
+	c.begin()
+	return c.flush()
+}
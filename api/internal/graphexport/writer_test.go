@@ -0,0 +1,79 @@
+package graphexport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+var testRelationship = models.Relationship{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true, Subscribed: true}
+
+func TestWriteDOT(t *testing.T) {
+	document := writeGraph(t, models.GraphFormatDOT)
+
+	assert.Equal(t, `digraph friend_connections {
+  "thehaohcm@yahoo.com.vn";
+  "hao.nguyen@s3corp.com.vn";
+  "thehaohcm@yahoo.com.vn" -> "hao.nguyen@s3corp.com.vn" [is_friend=true, friend_blocked=false, subscribed=true, subscribe_blocked=false];
+}
+`, document)
+}
+
+func TestWriteCSV(t *testing.T) {
+	document := writeGraph(t, models.GraphFormatCSV)
+
+	assert.Equal(t, "source,target,is_friend,friend_blocked,subscribed,subscribe_blocked\n"+
+		"thehaohcm@yahoo.com.vn,hao.nguyen@s3corp.com.vn,true,false,true,false\n", document)
+}
+
+func TestWriteGraphML(t *testing.T) {
+	document := writeGraph(t, models.GraphFormatGraphML)
+
+	assert.True(t, strings.HasPrefix(document, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, document, `<key id="subscribe_blocked" for="edge" attr.name="subscribe_blocked" attr.type="boolean"/>`)
+	assert.Contains(t, document, `<node id="hao.nguyen@s3corp.com.vn"/>`)
+	assert.Contains(t, document, `<edge id="e0" source="thehaohcm@yahoo.com.vn" target="hao.nguyen@s3corp.com.vn">`)
+	assert.Contains(t, document, `<data key="subscribed">true</data>`)
+	assert.True(t, strings.HasSuffix(document, "</graph>\n</graphml>\n"))
+}
+
+func TestWriteGEXF(t *testing.T) {
+	document := writeGraph(t, models.GraphFormatGEXF)
+
+	assert.Contains(t, document, `<attribute id="0" title="is_friend" type="boolean"/>`)
+	assert.Contains(t, document, `<node id="thehaohcm@yahoo.com.vn" label="thehaohcm@yahoo.com.vn"/>`)
+	assert.Contains(t, document, "</nodes>\n    <edges>\n      <edge id=\"0\"")
+	assert.Contains(t, document, `<attvalue for="2" value="true"/>`)
+	assert.True(t, strings.HasSuffix(document, "</edges>\n  </graph>\n</gexf>\n"))
+}
+
+func TestWriteEmptyGraphAndEscaping(t *testing.T) {
+	var b strings.Builder
+	writer, _ := NewWriter(&b, models.GraphFormatGEXF)
+	assert.Nil(t, writer.Close())
+	assert.Contains(t, b.String(), "<nodes>\n    </nodes>\n    <edges>\n    </edges>")
+
+	b.Reset()
+	writer, _ = NewWriter(&b, models.GraphFormatGraphML)
+	writer.WriteNode(`o'brien&co@example.com`)
+	writer.Close()
+	assert.Contains(t, b.String(), `<node id="o&#39;brien&amp;co@example.com"/>`)
+
+	_, err := NewWriter(&b, "svg")
+	assert.Equal(t, "invalid_graph_format", apperrors.From(err).Code)
+}
+
+func writeGraph(t *testing.T, format string) string {
+	var b strings.Builder
+	writer, err := NewWriter(&b, format)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteNode(testRelationship.Requestor))
+	assert.Nil(t, writer.WriteNode(testRelationship.Target))
+	assert.Empty(t, b.String(), "the document is buffered until Close")
+	assert.Nil(t, writer.WriteEdge(testRelationship))
+	assert.Nil(t, writer.Close())
+	return b.String()
+}
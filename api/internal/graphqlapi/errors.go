@@ -0,0 +1,74 @@
+package graphqlapi
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
+)
+
+// resolverError struct wraps a domain error so that its code and kind are exposed in the "extensions" of the GraphQL error
+type resolverError struct {
+	err *apperrors.Error
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Message
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+// Extensions function returns the machine readable part of the error, mirroring the code of the HTTP error envelope
+func (e *resolverError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code": e.err.Code,
+		"kind": string(e.err.Kind),
+	}
+	for key, value := range e.err.Details {
+		extensions[key] = value
+	}
+	return extensions
+}
+
+// toGraphQLError function used to translate an error returned by the service layer into a GraphQL resolver error and log it
+// unexpected failures are masked behind a generic internal error, like in the HTTP and gRPC APIs
+// pass a context, the name of the failed operation and the error as parameters
+// return an error type
+func toGraphQLError(ctx context.Context, operation string, err error) error {
+	appErr := apperrors.From(err)
+
+	level := slog.LevelWarn
+	switch appErr.Kind {
+	case apperrors.KindInternal, apperrors.KindUnavailable:
+		level = slog.LevelError
+	}
+	logger.FromContext(ctx).Log(ctx, level, "graphql resolver failed",
+		slog.String("operation", operation), slog.String("code", appErr.Code), slog.Any("error", err))
+
+	return &resolverError{err: appErr}
+}
+
+// requestError struct used when a GraphQL request is rejected before execution (invalid body, query over the limits...)
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// Extensions function returns the machine readable code of the error
+func (e *requestError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// formatRequestError function used to format a requestError as the errors list of a GraphQL response, keeping its extensions
+func formatRequestError(err error) []gqlerrors.FormattedError {
+	return []gqlerrors.FormattedError{gqlerrors.FormatError(gqlerrors.NewError(err.Error(), nil, "", nil, nil, err))}
+}
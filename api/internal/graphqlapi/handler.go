@@ -0,0 +1,64 @@
+package graphqlapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"golang_project/api/internal/services"
+)
+
+// Request struct used when a client posts a GraphQL operation
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewHandler function used to initialize the gin handler serving the GraphQL endpoint
+// documents which cannot be parsed, are invalid or exceed the limits are rejected with 400 before any resolver runs,
+// executed operations always answer 200 with the data and the resolver errors
+// pass a FriendConnectionService and the Limits as parameters
+// return a gin.HandlerFunc
+func NewHandler(service services.FriendConnectionService, limits Limits) gin.HandlerFunc {
+	schema, err := NewSchema(service)
+	if err != nil {
+		// the schema is static, failing to build it is a programming error
+		panic(err)
+	}
+
+	return func(c *gin.Context) {
+		var request Request
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: formatRequestError(&requestError{code: "invalid_request", message: "invalid request, a JSON body with a query is expected"})})
+			return
+		}
+
+		doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"})})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+		if validation := graphql.ValidateDocument(&schema, doc, nil); !validation.IsValid {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+			return
+		}
+		if err := checkLimits(schema, doc, request.OperationName, limits); err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: formatRequestError(err)})
+			return
+		}
+
+		ctx := withLoader(c.Request.Context(), service)
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        schema,
+			AST:           doc,
+			OperationName: request.OperationName,
+			Args:          request.Variables,
+			Context:       ctx,
+		})
+		c.JSON(http.StatusOK, result)
+	}
+}
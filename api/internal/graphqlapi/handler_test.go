@@ -0,0 +1,180 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+)
+
+func TestQueryNestedFriendsIsBatched(t *testing.T) {
+	serv := newServiceFake()
+	router := setupRouterForTesting(serv, Limits{})
+
+	w := postQuery(router, `{"query":"{ user(email: \"a@example.com\") { email friends { email friends { email } } } }"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"user":{"email":"a@example.com","friends":[
+		{"email":"b@example.com","friends":[{"email":"a@example.com"},{"email":"d@example.com"}]},
+		{"email":"c@example.com","friends":[{"email":"a@example.com"},{"email":"d@example.com"}]}
+	]}}}`, w.Body.String())
+	// one batch for a@example.com, one for all of its friends
+	assert.Equal(t, [][]string{{"a@example.com"}, {"b@example.com", "c@example.com"}}, serv.batches)
+}
+
+func TestQuerySubscribersSubscriptionsAndBlocked(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{})
+
+	w := postQuery(router, `{"query":"{ user(email: \"a@example.com\") { subscribers { email } subscriptions { email } blocked { email } } }"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"user":{"subscribers":[{"email":"e@example.com"}],"subscriptions":[{"email":"d@example.com"}],"blocked":[{"email":"f@example.com"}]}}}`, w.Body.String())
+}
+
+func TestQueryCommonFriendsAndSuggestions(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{})
+
+	w := postQuery(router, `{"query":"{ user(email: \"b@example.com\") { commonFriends(with: [\"c@example.com\"]) { email } } other: user(email: \"a@example.com\") { suggestions { email } } }"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"user":{"commonFriends":[{"email":"a@example.com"},{"email":"d@example.com"}]},"other":{"suggestions":[{"email":"d@example.com"}]}}}`, w.Body.String())
+}
+
+func TestQueryWithInvalidEmail(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{})
+
+	w := postQuery(router, `{"query":"{ user(email: \"abc\") { email } }"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result struct {
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, "invalid email address", result.Errors[0].Message)
+		assert.Equal(t, "invalid_email", result.Errors[0].Extensions["code"])
+	}
+}
+
+func TestMutationAddFriend(t *testing.T) {
+	serv := newServiceFake()
+	router := setupRouterForTesting(serv, Limits{})
+
+	w := postQuery(router, `{"query":"mutation($e: String!) { addFriend(email: $e, friend: \"e@example.com\") { email friends { email } } }","variables":{"e":"a@example.com"}}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"addFriend":{"email":"a@example.com","friends":[{"email":"b@example.com"},{"email":"c@example.com"},{"email":"e@example.com"}]}}}`, w.Body.String())
+}
+
+func TestMutationWithDomainError(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{})
+
+	w := postQuery(router, `{"query":"mutation { subscribe(requestor: \"a@example.com\", target: \"unregistered@example.com\") { email } }"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"user_not_found"`)
+	assert.Contains(t, w.Body.String(), `"data":null`)
+}
+
+func TestQueryTooDeep(t *testing.T) {
+	serv := newServiceFake()
+	router := setupRouterForTesting(serv, Limits{MaxDepth: 3})
+
+	w := postQuery(router, `{"query":"{ user(email: \"a@example.com\") { friends { friends { email } } } }"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"query_too_deep"`)
+	assert.Empty(t, serv.batches)
+}
+
+func TestQueryTooComplexWithFragments(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{MaxComplexity: 100})
+
+	w := postQuery(router, `{"query":"query { user(email: \"a@example.com\") { ...f } } fragment f on User { friends { friends { email } } }"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"query_too_complex"`)
+}
+
+func TestQueryWithInvalidDocument(t *testing.T) {
+	router := setupRouterForTesting(newServiceFake(), Limits{})
+
+	w := postQuery(router, `{"query":"{ user(email: \"a@example.com\") { unknown } }"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postQuery(router, `{"variables":{}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_request"`)
+}
+
+func setupRouterForTesting(serv services.FriendConnectionService, limits Limits) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/graphql", NewHandler(serv, limits))
+	return router
+}
+
+func postQuery(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// serviceFake keeps a small in-memory graph: a-b, a-c, b-d, c-d are friends, e subscribes to a, a subscribes to d and blocks f
+type serviceFake struct {
+	services.FriendConnectionService
+	users   map[string]models.UserRelationships
+	batches [][]string
+}
+
+func newServiceFake() *serviceFake {
+	return &serviceFake{users: map[string]models.UserRelationships{
+		"a@example.com": {Email: "a@example.com", Friends: []string{"b@example.com", "c@example.com"}, Subscribers: []string{"e@example.com"}, Subscriptions: []string{"d@example.com"}, Blocked: []string{"f@example.com"}},
+		"b@example.com": {Email: "b@example.com", Friends: []string{"a@example.com", "d@example.com"}},
+		"c@example.com": {Email: "c@example.com", Friends: []string{"a@example.com", "d@example.com"}},
+		"d@example.com": {Email: "d@example.com", Friends: []string{"b@example.com", "c@example.com"}},
+	}}
+}
+
+func (s *serviceFake) GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error) {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return nil, err
+	}
+	s.batches = append(s.batches, emails)
+	result := map[string]models.UserRelationships{}
+	for _, email := range emails {
+		result[email] = s.users[email]
+	}
+	return result, nil
+}
+
+func (s *serviceFake) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	user := s.users[request.Friends[0]]
+	user.Friends = append(user.Friends, request.Friends[1])
+	s.users[request.Friends[0]] = user
+	return models.FriendConnectionResponse{Success: true}, nil
+}
+
+func (s *serviceFake) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if _, ok := s.users[request.Target]; !ok {
+		return models.SubscribeResponse{}, apperrors.NotFound("user_not_found", "unregistered email address: "+request.Target)
+	}
+	return models.SubscribeResponse{Success: true}, nil
+}
@@ -0,0 +1,124 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listCostMultiplier is the assumed size of a list field when computing the complexity of a query:
+// the cost of the selections below a list field is multiplied by this factor
+const listCostMultiplier = 10
+
+// Limits struct used to bound the cost of a GraphQL query before executing it, a zero value disables the limit
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// checkLimits function used to compute the depth and the complexity of the selected operation and compare them with the limits
+// every field costs 1 and the selections below a list field cost listCostMultiplier times more,
+// introspection fields (starting with "__") are not counted so that tooling keeps working
+// the document must already be validated against the schema
+// pass a schema, a parsed document, the operation name and the limits as parameters
+// return an error type
+func checkLimits(schema graphql.Schema, doc *ast.Document, operationName string, limits Limits) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+
+	var root graphql.Type = schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+
+	w := &limitWalker{schema: schema, fragments: fragments}
+	depth, complexity := w.selectionSet(operation.SelectionSet, root, 0)
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return &requestError{code: "query_too_deep", message: fmt.Sprintf("query depth %d exceeds the maximum allowed depth of %d", depth, limits.MaxDepth)}
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return &requestError{code: "query_too_complex", message: fmt.Sprintf("query complexity %d exceeds the maximum allowed complexity of %d", complexity, limits.MaxComplexity)}
+	}
+	return nil
+}
+
+type limitWalker struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// selectionSet function returns the deepest field level and the cost of a selection set
+func (w *limitWalker) selectionSet(set *ast.SelectionSet, parent graphql.Type, depth int) (int, int) {
+	if set == nil {
+		return depth, 0
+	}
+
+	maxDepth, cost := depth, 0
+	for _, selection := range set.Selections {
+		var selectionDepth, selectionCost int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selectionDepth, selectionCost = w.field(selection, parent, depth)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = w.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			selectionDepth, selectionCost = w.selectionSet(selection.SelectionSet, fragmentType, depth)
+		case *ast.FragmentSpread:
+			fragment, ok := w.fragments[selection.Name.Value]
+			if !ok {
+				continue
+			}
+			selectionDepth, selectionCost = w.selectionSet(fragment.SelectionSet, w.schema.Type(fragment.TypeCondition.Name.Value), depth)
+		}
+		if selectionDepth > maxDepth {
+			maxDepth = selectionDepth
+		}
+		cost += selectionCost
+	}
+	return maxDepth, cost
+}
+
+func (w *limitWalker) field(field *ast.Field, parent graphql.Type, depth int) (int, int) {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return depth, 0
+	}
+
+	var fieldType graphql.Type
+	if object, ok := parent.(*graphql.Object); ok {
+		if definition, ok := object.Fields()[field.Name.Value]; ok {
+			fieldType = definition.Type
+		}
+	}
+
+	childType, _ := graphql.GetNamed(fieldType).(graphql.Type)
+	childDepth, childCost := w.selectionSet(field.SelectionSet, childType, depth+1)
+	if isList(fieldType) {
+		childCost *= listCostMultiplier
+	}
+	return childDepth, 1 + childCost
+}
+
+func isList(fieldType graphql.Type) bool {
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	_, ok := fieldType.(*graphql.List)
+	return ok
+}
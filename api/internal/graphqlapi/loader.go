@@ -0,0 +1,117 @@
+package graphqlapi
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+type loaderKey struct{}
+
+// relationshipLoader batches and caches the relationship lookups of a single GraphQL request
+// resolvers register the emails they need with Load and get a thunk back, the first thunk evaluated fetches every pending email
+// with one GetUserRelationships call, so a list of N users costs one query per nesting level instead of N queries
+type relationshipLoader struct {
+	service services.FriendConnectionService
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+	cache   map[string]models.UserRelationships
+	errs    map[string]error
+	batches int
+}
+
+func newRelationshipLoader(service services.FriendConnectionService) *relationshipLoader {
+	return &relationshipLoader{
+		service: service,
+		pending: map[string]struct{}{},
+		cache:   map[string]models.UserRelationships{},
+		errs:    map[string]error{},
+	}
+}
+
+// withLoader function used to attach a new relationshipLoader to the request context
+func withLoader(ctx context.Context, service services.FriendConnectionService) context.Context {
+	return context.WithValue(ctx, loaderKey{}, newRelationshipLoader(service))
+}
+
+// loaderFromContext function used to get the relationshipLoader of the request, a fresh one is never created here
+// because a loader outside of a request would silently lose the batching
+func loaderFromContext(ctx context.Context) *relationshipLoader {
+	loader, _ := ctx.Value(loaderKey{}).(*relationshipLoader)
+	return loader
+}
+
+// Load function used to register emails for the next batch
+// return a thunk which resolves the relationships of the emails, in the same order
+func (l *relationshipLoader) Load(ctx context.Context, emails ...string) func() ([]models.UserRelationships, error) {
+	l.mu.Lock()
+	for _, email := range emails {
+		if _, cached := l.cache[email]; cached {
+			continue
+		}
+		if _, failed := l.errs[email]; failed {
+			continue
+		}
+		l.pending[email] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	return func() ([]models.UserRelationships, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, email := range emails {
+			_, cached := l.cache[email]
+			_, failed := l.errs[email]
+			if !cached && !failed {
+				l.pending[email] = struct{}{}
+			}
+		}
+		l.dispatch(ctx)
+
+		result := make([]models.UserRelationships, 0, len(emails))
+		for _, email := range emails {
+			if err, failed := l.errs[email]; failed {
+				return nil, err
+			}
+			result = append(result, l.cache[email])
+		}
+		return result, nil
+	}
+}
+
+// Clear function used to drop cached relationships, called after a mutation touching these emails
+func (l *relationshipLoader) Clear(emails ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, email := range emails {
+		delete(l.cache, email)
+		delete(l.errs, email)
+	}
+}
+
+// dispatch function fetches every pending email in one batch, l.mu must be held
+func (l *relationshipLoader) dispatch(ctx context.Context) {
+	if len(l.pending) == 0 {
+		return
+	}
+	emails := make([]string, 0, len(l.pending))
+	for email := range l.pending {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	l.pending = map[string]struct{}{}
+	l.batches++
+
+	relationships, err := l.service.GetUserRelationships(ctx, emails)
+	for _, email := range emails {
+		if err != nil {
+			l.errs[email] = err
+			continue
+		}
+		l.cache[email] = relationships[email]
+	}
+}
@@ -0,0 +1,287 @@
+package graphqlapi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+)
+
+const defaultSuggestionLimit = 10
+
+// NewSchema function used to build the GraphQL schema, every field is resolved through the FriendConnectionService
+// the source of a User object is its email address, the relationships are fetched lazily through the request's relationshipLoader
+// pass a FriendConnectionService as parameter
+// return a graphql.Schema and an error type
+func NewSchema(service services.FriendConnectionService) (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "A registered user, identified by its email address",
+		Fields:      graphql.Fields{},
+	})
+	userList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType)))
+	emailList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))
+
+	userType.AddFieldConfig("email", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(string), nil
+		},
+	})
+	userType.AddFieldConfig("friends", &graphql.Field{
+		Type:        userList,
+		Description: "Users having a friend connection with this user",
+		Resolve: relationshipResolver("friends", func(user models.UserRelationships) []string {
+			return user.Friends
+		}),
+	})
+	userType.AddFieldConfig("subscribers", &graphql.Field{
+		Type:        userList,
+		Description: "Users subscribed to the updates of this user",
+		Resolve: relationshipResolver("subscribers", func(user models.UserRelationships) []string {
+			return user.Subscribers
+		}),
+	})
+	userType.AddFieldConfig("subscriptions", &graphql.Field{
+		Type:        userList,
+		Description: "Users this user is subscribed to",
+		Resolve: relationshipResolver("subscriptions", func(user models.UserRelationships) []string {
+			return user.Subscriptions
+		}),
+	})
+	userType.AddFieldConfig("blocked", &graphql.Field{
+		Type:        userList,
+		Description: "Users whose updates are blocked by this user",
+		Resolve: relationshipResolver("blocked", func(user models.UserRelationships) []string {
+			return user.Blocked
+		}),
+	})
+	userType.AddFieldConfig("commonFriends", &graphql.Field{
+		Type:        userList,
+		Description: "Friends shared by this user and every user of the with argument",
+		Args: graphql.FieldConfigArgument{
+			"with": &graphql.ArgumentConfig{Type: emailList},
+		},
+		Resolve: resolveCommonFriends,
+	})
+	userType.AddFieldConfig("suggestions", &graphql.Field{
+		Type:        userList,
+		Description: "Friends of friends who are not friends yet, ordered by number of mutual friends",
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultSuggestionLimit},
+		},
+		Resolve: resolveSuggestions,
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					email := p.Args["email"].(string)
+					if err := pkg.CheckValidEmail(email); err != nil {
+						return nil, toGraphQLError(p.Context, "user", err)
+					}
+					return email, nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: userList,
+				Args: graphql.FieldConfigArgument{
+					"emails": &graphql.ArgumentConfig{Type: emailList},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					emails := stringArgs(p.Args["emails"])
+					if err := pkg.CheckValidEmails(emails); err != nil {
+						return nil, toGraphQLError(p.Context, "users", err)
+					}
+					return pkg.RemoveDuplicatedItems(emails), nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					email := p.Args["email"].(string)
+					if _, err := service.CreateUser(p.Context, models.CreatingUserRequest{Email: email}); err != nil {
+						return nil, toGraphQLError(p.Context, "createUser", err)
+					}
+					return email, nil
+				},
+			},
+			"addFriend": pairMutation(userType, "email", "friend", func(ctx context.Context, email, other string) error {
+				_, err := service.CreateConnection(ctx, models.FriendConnectionRequest{Friends: []string{email, other}})
+				return err
+			}),
+			"removeFriend": pairMutation(userType, "email", "friend", func(ctx context.Context, email, other string) error {
+				_, err := service.RemoveConnection(ctx, models.FriendConnectionRequest{Friends: []string{email, other}})
+				return err
+			}),
+			"subscribe": pairMutation(userType, "requestor", "target", func(ctx context.Context, requestor, target string) error {
+				_, err := service.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: requestor, Target: target})
+				return err
+			}),
+			"unsubscribe": pairMutation(userType, "requestor", "target", func(ctx context.Context, requestor, target string) error {
+				_, err := service.UnsubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: requestor, Target: target})
+				return err
+			}),
+			"block": pairMutation(userType, "requestor", "target", func(ctx context.Context, requestor, target string) error {
+				_, err := service.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: requestor, Target: target})
+				return err
+			}),
+			"unblock": pairMutation(userType, "requestor", "target", func(ctx context.Context, requestor, target string) error {
+				_, err := service.UnblockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: requestor, Target: target})
+				return err
+			}),
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// relationshipResolver function used to build the resolver of a User list field backed by the relationshipLoader
+func relationshipResolver(operation string, pick func(models.UserRelationships) []string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		thunk := loaderFromContext(p.Context).Load(p.Context, p.Source.(string))
+		return func() (interface{}, error) {
+			users, err := thunk()
+			if err != nil {
+				return nil, toGraphQLError(p.Context, operation, err)
+			}
+			return pick(users[0]), nil
+		}, nil
+	}
+}
+
+func resolveCommonFriends(p graphql.ResolveParams) (interface{}, error) {
+	email := p.Source.(string)
+	with := stringArgs(p.Args["with"])
+	if err := pkg.CheckValidEmails(with); err != nil {
+		return nil, toGraphQLError(p.Context, "commonFriends", err)
+	}
+
+	emails := pkg.RemoveDuplicatedItems(append([]string{email}, with...))
+	thunk := loaderFromContext(p.Context).Load(p.Context, emails...)
+	return func() (interface{}, error) {
+		users, err := thunk()
+		if err != nil {
+			return nil, toGraphQLError(p.Context, "commonFriends", err)
+		}
+
+		counts := map[string]int{}
+		for _, user := range users {
+			for _, friend := range user.Friends {
+				counts[friend]++
+			}
+		}
+		common := []string{}
+		for _, friend := range users[0].Friends {
+			if counts[friend] == len(users) && !contains(emails, friend) {
+				common = append(common, friend)
+			}
+		}
+		return common, nil
+	}, nil
+}
+
+func resolveSuggestions(p graphql.ResolveParams) (interface{}, error) {
+	email := p.Source.(string)
+	limit, _ := p.Args["limit"].(int)
+	loader := loaderFromContext(p.Context)
+
+	thunk := loader.Load(p.Context, email)
+	return func() (interface{}, error) {
+		users, err := thunk()
+		if err != nil {
+			return nil, toGraphQLError(p.Context, "suggestions", err)
+		}
+		user := users[0]
+		if limit <= 0 || len(user.Friends) == 0 {
+			return []string{}, nil
+		}
+
+		friends, err := loader.Load(p.Context, user.Friends...)()
+		if err != nil {
+			return nil, toGraphQLError(p.Context, "suggestions", err)
+		}
+
+		mutual := map[string]int{}
+		for _, friend := range friends {
+			for _, candidate := range friend.Friends {
+				if candidate == email || contains(user.Friends, candidate) || contains(user.Blocked, candidate) {
+					continue
+				}
+				mutual[candidate]++
+			}
+		}
+		suggestions := make([]string, 0, len(mutual))
+		for candidate := range mutual {
+			suggestions = append(suggestions, candidate)
+		}
+		sort.Slice(suggestions, func(i, j int) bool {
+			if mutual[suggestions[i]] != mutual[suggestions[j]] {
+				return mutual[suggestions[i]] > mutual[suggestions[j]]
+			}
+			return suggestions[i] < suggestions[j]
+		})
+		if len(suggestions) > limit {
+			suggestions = suggestions[:limit]
+		}
+		return suggestions, nil
+	}, nil
+}
+
+// pairMutation function used to build a mutation acting from one user on another, the first user is returned
+// so that clients can select its updated relationships in the same round trip
+func pairMutation(userType *graphql.Object, from, to string, action func(ctx context.Context, from, to string) error) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(userType),
+		Args: graphql.FieldConfigArgument{
+			from: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			to:   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, target := p.Args[from].(string), p.Args[to].(string)
+			if err := action(p.Context, source, target); err != nil {
+				return nil, toGraphQLError(p.Context, p.Info.FieldName, err)
+			}
+			loaderFromContext(p.Context).Clear(source, target)
+			return source, nil
+		},
+	}
+}
+
+func stringArgs(value interface{}) []string {
+	items, _ := value.([]interface{})
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if text, ok := item.(string); ok {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
+func contains(items []string, item string) bool {
+	for _, other := range items {
+		if other == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain reported in the ErrorInfo detail of every gRPC error
+const errorDomain = "golang_project"
+
+// StatusCode function used to map a domain error Kind to a gRPC status code
+// pass an apperrors.Kind as parameter
+// return a codes.Code
+func StatusCode(kind apperrors.Kind) codes.Code {
+	switch kind {
+	case apperrors.KindInvalidRequest, apperrors.KindValidation:
+		return codes.InvalidArgument
+	case apperrors.KindNotFound:
+		return codes.NotFound
+	case apperrors.KindAlreadyExists:
+		return codes.AlreadyExists
+	case apperrors.KindConflict:
+		return codes.Aborted
+	case apperrors.KindUnauthenticated:
+		return codes.Unauthenticated
+	case apperrors.KindForbidden, apperrors.KindBlocked:
+		return codes.PermissionDenied
+	case apperrors.KindRateLimited:
+		return codes.ResourceExhausted
+	case apperrors.KindTimeout:
+		return codes.DeadlineExceeded
+	case apperrors.KindCanceled:
+		return codes.Canceled
+	case apperrors.KindUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// toStatus function used to convert an error returned from the Service layer into a gRPC status error
+// the machine-readable code travels in an ErrorInfo detail, the underlying cause is logged but never sent to the client
+func toStatus(ctx context.Context, operation string, err error) error {
+	appErr := apperrors.From(err)
+	code := StatusCode(appErr.Kind)
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unavailable {
+		level = slog.LevelError
+	}
+	logger.FromContext(ctx).Log(ctx, level, "rpc failed",
+		slog.String("operation", operation), slog.String("code", appErr.Code), slog.String("grpc_code", code.String()), slog.Any("error", err))
+
+	st := status.New(code, appErr.Message)
+	info := &errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}
+	if emails, ok := appErr.Details["emails"].([]string); ok {
+		info.Metadata = map[string]string{"emails": strings.Join(emails, ",")}
+	}
+	if withDetails, detailErr := st.WithDetails(info); detailErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,147 @@
+package grpcapi
+
+import (
+	"context"
+
+	pb "golang_project/api/internal/grpcapi/friendconnectionpb"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+type friendConnectionServer struct {
+	pb.UnimplementedFriendConnectionServiceServer
+	service services.FriendConnectionService
+}
+
+// NewFriendConnectionServer function used for initializing the gRPC implementation of FriendConnectionService
+// every RPC is delegated to the same FriendConnectionService used by the HTTP controllers
+// pass a FriendConnectionService as parameter
+// return a FriendConnectionServiceServer
+func NewFriendConnectionServer(service services.FriendConnectionService) pb.FriendConnectionServiceServer {
+	return &friendConnectionServer{
+		service: service,
+	}
+}
+
+// CreateUser function works as a gRPC handler for creating an new user
+func (srv *friendConnectionServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	response, err := srv.service.CreateUser(ctx, models.CreatingUserRequest{Email: req.GetEmail()})
+	if err != nil {
+		return nil, toStatus(ctx, "CreateUser", err)
+	}
+	return &pb.CreateUserResponse{Success: response.Success}, nil
+}
+
+// CreateFriendConnection function works as a gRPC handler for creating friend connection between 2 user emails
+func (srv *friendConnectionServer) CreateFriendConnection(ctx context.Context, req *pb.FriendConnectionRequest) (*pb.FriendConnectionResponse, error) {
+	response, err := srv.service.CreateConnection(ctx, models.FriendConnectionRequest{Friends: req.GetFriends()})
+	if err != nil {
+		return nil, toStatus(ctx, "CreateConnection", err)
+	}
+	return &pb.FriendConnectionResponse{Success: response.Success}, nil
+}
+
+// RemoveFriendConnection function works as a gRPC handler for removing the friend connection between 2 user emails
+func (srv *friendConnectionServer) RemoveFriendConnection(ctx context.Context, req *pb.FriendConnectionRequest) (*pb.FriendConnectionResponse, error) {
+	response, err := srv.service.RemoveConnection(ctx, models.FriendConnectionRequest{Friends: req.GetFriends()})
+	if err != nil {
+		return nil, toStatus(ctx, "RemoveConnection", err)
+	}
+	return &pb.FriendConnectionResponse{Success: response.Success}, nil
+}
+
+// GetFriendList function works as a gRPC handler for getting a friend list by an email address
+func (srv *friendConnectionServer) GetFriendList(ctx context.Context, req *pb.FriendListRequest) (*pb.FriendListResponse, error) {
+	response, err := srv.service.GetFriendConnection(ctx, models.FriendListRequest{Email: req.GetEmail()})
+	if err != nil {
+		return nil, toStatus(ctx, "GetFriendConnection", err)
+	}
+	return &pb.FriendListResponse{Success: response.Success, Friends: response.Friends, Count: int32(response.Count)}, nil
+}
+
+// StreamFriendList function works as a gRPC handler for streaming a friend list by an email address, one friend per message
+func (srv *friendConnectionServer) StreamFriendList(req *pb.FriendListRequest, stream pb.FriendConnectionService_StreamFriendListServer) error {
+	ctx := stream.Context()
+	response, err := srv.service.GetFriendConnection(ctx, models.FriendListRequest{Email: req.GetEmail()})
+	if err != nil {
+		return toStatus(ctx, "GetFriendConnection", err)
+	}
+	for _, friend := range response.Friends {
+		if err := stream.Send(&pb.Friend{Email: friend}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommonFriendList function works as a gRPC handler for getting a list of common friends between email addresses
+func (srv *friendConnectionServer) GetCommonFriendList(ctx context.Context, req *pb.CommonFriendListRequest) (*pb.CommonFriendListResponse, error) {
+	response, err := srv.service.ShowCommonFriendList(ctx, models.CommonFriendListRequest{Friends: req.GetFriends()})
+	if err != nil {
+		return nil, toStatus(ctx, "ShowCommonFriendList", err)
+	}
+	return &pb.CommonFriendListResponse{Success: response.Success, Friends: response.Friends, Count: int32(response.Count)}, nil
+}
+
+// Subscribe function works as a gRPC handler for creating a subscribe from an email address to another one
+func (srv *friendConnectionServer) Subscribe(ctx context.Context, req *pb.SubscribeRequest) (*pb.SubscribeResponse, error) {
+	response, err := srv.service.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: req.GetRequestor(), Target: req.GetTarget()})
+	if err != nil {
+		return nil, toStatus(ctx, "SubscribeFromEmail", err)
+	}
+	return &pb.SubscribeResponse{Success: response.Success}, nil
+}
+
+// Unsubscribe function works as a gRPC handler for removing a subscribe from an email address to another one
+func (srv *friendConnectionServer) Unsubscribe(ctx context.Context, req *pb.SubscribeRequest) (*pb.SubscribeResponse, error) {
+	response, err := srv.service.UnsubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: req.GetRequestor(), Target: req.GetTarget()})
+	if err != nil {
+		return nil, toStatus(ctx, "UnsubscribeFromEmail", err)
+	}
+	return &pb.SubscribeResponse{Success: response.Success}, nil
+}
+
+// BlockSubscribe function works as a gRPC handler for creating a block subscribe update from an email address to another one
+func (srv *friendConnectionServer) BlockSubscribe(ctx context.Context, req *pb.BlockSubscribeRequest) (*pb.BlockSubscribeResponse, error) {
+	response, err := srv.service.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: req.GetRequestor(), Target: req.GetTarget()})
+	if err != nil {
+		return nil, toStatus(ctx, "BlockSubscribeByEmail", err)
+	}
+	return &pb.BlockSubscribeResponse{Success: response.Success}, nil
+}
+
+// UnblockSubscribe function works as a gRPC handler for lifting a block subscribe update from an email address to another one
+func (srv *friendConnectionServer) UnblockSubscribe(ctx context.Context, req *pb.BlockSubscribeRequest) (*pb.BlockSubscribeResponse, error) {
+	response, err := srv.service.UnblockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: req.GetRequestor(), Target: req.GetTarget()})
+	if err != nil {
+		return nil, toStatus(ctx, "UnblockSubscribeByEmail", err)
+	}
+	return &pb.BlockSubscribeResponse{Success: response.Success}, nil
+}
+
+// GetRecipients function works as a gRPC handler for getting a list of subscribe email by an email address
+func (srv *friendConnectionServer) GetRecipients(ctx context.Context, req *pb.RecipientsRequest) (*pb.RecipientsResponse, error) {
+	response, err := srv.service.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: req.GetSender(), Text: req.GetText()})
+	if err != nil {
+		return nil, toStatus(ctx, "GetSubscribingEmailListByEmail", err)
+	}
+	return &pb.RecipientsResponse{Success: response.Success, Recipients: response.Recipients}, nil
+}
+
+// StreamRecipients function works as a gRPC handler for streaming the feed recipients of an update, one recipient per message
+// the recipients are sent while they are read from the database, without loading them in memory
+func (srv *friendConnectionServer) StreamRecipients(req *pb.RecipientsRequest, stream pb.FriendConnectionService_StreamRecipientsServer) error {
+	ctx := stream.Context()
+	var sendErr error
+	err := srv.service.StreamRecipients(ctx, models.GetSubscribingEmailListRequest{Sender: req.GetSender(), Text: req.GetText()}, func(email string) error {
+		sendErr = stream.Send(&pb.Recipient{Email: email})
+		return sendErr
+	})
+	if err != nil && err == sendErr {
+		return err
+	}
+	if err != nil {
+		return toStatus(ctx, "StreamRecipients", err)
+	}
+	return nil
+}
@@ -0,0 +1,596 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: friendconnection/v1/friend_connection.proto
+
+package friendconnectionpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FriendConnectionService_CreateUser_FullMethodName             = "/friendconnection.v1.FriendConnectionService/CreateUser"
+	FriendConnectionService_CreateFriendConnection_FullMethodName = "/friendconnection.v1.FriendConnectionService/CreateFriendConnection"
+	FriendConnectionService_RemoveFriendConnection_FullMethodName = "/friendconnection.v1.FriendConnectionService/RemoveFriendConnection"
+	FriendConnectionService_GetFriendList_FullMethodName          = "/friendconnection.v1.FriendConnectionService/GetFriendList"
+	FriendConnectionService_StreamFriendList_FullMethodName       = "/friendconnection.v1.FriendConnectionService/StreamFriendList"
+	FriendConnectionService_GetCommonFriendList_FullMethodName    = "/friendconnection.v1.FriendConnectionService/GetCommonFriendList"
+	FriendConnectionService_Subscribe_FullMethodName              = "/friendconnection.v1.FriendConnectionService/Subscribe"
+	FriendConnectionService_Unsubscribe_FullMethodName            = "/friendconnection.v1.FriendConnectionService/Unsubscribe"
+	FriendConnectionService_BlockSubscribe_FullMethodName         = "/friendconnection.v1.FriendConnectionService/BlockSubscribe"
+	FriendConnectionService_UnblockSubscribe_FullMethodName       = "/friendconnection.v1.FriendConnectionService/UnblockSubscribe"
+	FriendConnectionService_GetRecipients_FullMethodName          = "/friendconnection.v1.FriendConnectionService/GetRecipients"
+	FriendConnectionService_StreamRecipients_FullMethodName       = "/friendconnection.v1.FriendConnectionService/StreamRecipients"
+)
+
+// FriendConnectionServiceClient is the client API for FriendConnectionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FriendConnectionServiceClient interface {
+	// CreateUser registers a new user
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
+	// CreateFriendConnection creates a friend connection between two email addresses
+	CreateFriendConnection(ctx context.Context, in *FriendConnectionRequest, opts ...grpc.CallOption) (*FriendConnectionResponse, error)
+	// RemoveFriendConnection removes the friend connection between two email addresses
+	RemoveFriendConnection(ctx context.Context, in *FriendConnectionRequest, opts ...grpc.CallOption) (*FriendConnectionResponse, error)
+	// GetFriendList retrieves the friends list for an email address
+	GetFriendList(ctx context.Context, in *FriendListRequest, opts ...grpc.CallOption) (*FriendListResponse, error)
+	// StreamFriendList streams the friends list for an email address, one friend per message, for very large lists
+	StreamFriendList(ctx context.Context, in *FriendListRequest, opts ...grpc.CallOption) (FriendConnectionService_StreamFriendListClient, error)
+	// GetCommonFriendList retrieves the common friends list between two or more email addresses
+	GetCommonFriendList(ctx context.Context, in *CommonFriendListRequest, opts ...grpc.CallOption) (*CommonFriendListResponse, error)
+	// Subscribe subscribes the requestor to updates from the target
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (*SubscribeResponse, error)
+	// Unsubscribe removes the requestor's subscription to updates from the target
+	Unsubscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (*SubscribeResponse, error)
+	// BlockSubscribe blocks updates from the target for the requestor
+	BlockSubscribe(ctx context.Context, in *BlockSubscribeRequest, opts ...grpc.CallOption) (*BlockSubscribeResponse, error)
+	// UnblockSubscribe lifts the block on updates from the target for the requestor
+	UnblockSubscribe(ctx context.Context, in *BlockSubscribeRequest, opts ...grpc.CallOption) (*BlockSubscribeResponse, error)
+	// GetRecipients retrieves all email addresses that can receive an update from the sender
+	GetRecipients(ctx context.Context, in *RecipientsRequest, opts ...grpc.CallOption) (*RecipientsResponse, error)
+	// StreamRecipients streams the feed recipients of an update, one recipient per message, for very large audiences
+	StreamRecipients(ctx context.Context, in *RecipientsRequest, opts ...grpc.CallOption) (FriendConnectionService_StreamRecipientsClient, error)
+}
+
+type friendConnectionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFriendConnectionServiceClient(cc grpc.ClientConnInterface) FriendConnectionServiceClient {
+	return &friendConnectionServiceClient{cc}
+}
+
+func (c *friendConnectionServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error) {
+	out := new(CreateUserResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_CreateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) CreateFriendConnection(ctx context.Context, in *FriendConnectionRequest, opts ...grpc.CallOption) (*FriendConnectionResponse, error) {
+	out := new(FriendConnectionResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_CreateFriendConnection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) RemoveFriendConnection(ctx context.Context, in *FriendConnectionRequest, opts ...grpc.CallOption) (*FriendConnectionResponse, error) {
+	out := new(FriendConnectionResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_RemoveFriendConnection_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) GetFriendList(ctx context.Context, in *FriendListRequest, opts ...grpc.CallOption) (*FriendListResponse, error) {
+	out := new(FriendListResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_GetFriendList_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) StreamFriendList(ctx context.Context, in *FriendListRequest, opts ...grpc.CallOption) (FriendConnectionService_StreamFriendListClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FriendConnectionService_ServiceDesc.Streams[0], FriendConnectionService_StreamFriendList_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &friendConnectionServiceStreamFriendListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FriendConnectionService_StreamFriendListClient interface {
+	Recv() (*Friend, error)
+	grpc.ClientStream
+}
+
+type friendConnectionServiceStreamFriendListClient struct {
+	grpc.ClientStream
+}
+
+func (x *friendConnectionServiceStreamFriendListClient) Recv() (*Friend, error) {
+	m := new(Friend)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *friendConnectionServiceClient) GetCommonFriendList(ctx context.Context, in *CommonFriendListRequest, opts ...grpc.CallOption) (*CommonFriendListResponse, error) {
+	out := new(CommonFriendListResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_GetCommonFriendList_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (*SubscribeResponse, error) {
+	out := new(SubscribeResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_Subscribe_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) Unsubscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (*SubscribeResponse, error) {
+	out := new(SubscribeResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_Unsubscribe_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) BlockSubscribe(ctx context.Context, in *BlockSubscribeRequest, opts ...grpc.CallOption) (*BlockSubscribeResponse, error) {
+	out := new(BlockSubscribeResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_BlockSubscribe_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) UnblockSubscribe(ctx context.Context, in *BlockSubscribeRequest, opts ...grpc.CallOption) (*BlockSubscribeResponse, error) {
+	out := new(BlockSubscribeResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_UnblockSubscribe_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) GetRecipients(ctx context.Context, in *RecipientsRequest, opts ...grpc.CallOption) (*RecipientsResponse, error) {
+	out := new(RecipientsResponse)
+	err := c.cc.Invoke(ctx, FriendConnectionService_GetRecipients_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *friendConnectionServiceClient) StreamRecipients(ctx context.Context, in *RecipientsRequest, opts ...grpc.CallOption) (FriendConnectionService_StreamRecipientsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FriendConnectionService_ServiceDesc.Streams[1], FriendConnectionService_StreamRecipients_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &friendConnectionServiceStreamRecipientsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FriendConnectionService_StreamRecipientsClient interface {
+	Recv() (*Recipient, error)
+	grpc.ClientStream
+}
+
+type friendConnectionServiceStreamRecipientsClient struct {
+	grpc.ClientStream
+}
+
+func (x *friendConnectionServiceStreamRecipientsClient) Recv() (*Recipient, error) {
+	m := new(Recipient)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FriendConnectionServiceServer is the server API for FriendConnectionService service.
+// All implementations must embed UnimplementedFriendConnectionServiceServer
+// for forward compatibility
+type FriendConnectionServiceServer interface {
+	// CreateUser registers a new user
+	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
+	// CreateFriendConnection creates a friend connection between two email addresses
+	CreateFriendConnection(context.Context, *FriendConnectionRequest) (*FriendConnectionResponse, error)
+	// RemoveFriendConnection removes the friend connection between two email addresses
+	RemoveFriendConnection(context.Context, *FriendConnectionRequest) (*FriendConnectionResponse, error)
+	// GetFriendList retrieves the friends list for an email address
+	GetFriendList(context.Context, *FriendListRequest) (*FriendListResponse, error)
+	// StreamFriendList streams the friends list for an email address, one friend per message, for very large lists
+	StreamFriendList(*FriendListRequest, FriendConnectionService_StreamFriendListServer) error
+	// GetCommonFriendList retrieves the common friends list between two or more email addresses
+	GetCommonFriendList(context.Context, *CommonFriendListRequest) (*CommonFriendListResponse, error)
+	// Subscribe subscribes the requestor to updates from the target
+	Subscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error)
+	// Unsubscribe removes the requestor's subscription to updates from the target
+	Unsubscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error)
+	// BlockSubscribe blocks updates from the target for the requestor
+	BlockSubscribe(context.Context, *BlockSubscribeRequest) (*BlockSubscribeResponse, error)
+	// UnblockSubscribe lifts the block on updates from the target for the requestor
+	UnblockSubscribe(context.Context, *BlockSubscribeRequest) (*BlockSubscribeResponse, error)
+	// GetRecipients retrieves all email addresses that can receive an update from the sender
+	GetRecipients(context.Context, *RecipientsRequest) (*RecipientsResponse, error)
+	// StreamRecipients streams the feed recipients of an update, one recipient per message, for very large audiences
+	StreamRecipients(*RecipientsRequest, FriendConnectionService_StreamRecipientsServer) error
+	mustEmbedUnimplementedFriendConnectionServiceServer()
+}
+
+// UnimplementedFriendConnectionServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFriendConnectionServiceServer struct {
+}
+
+func (UnimplementedFriendConnectionServiceServer) CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) CreateFriendConnection(context.Context, *FriendConnectionRequest) (*FriendConnectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateFriendConnection not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) RemoveFriendConnection(context.Context, *FriendConnectionRequest) (*FriendConnectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFriendConnection not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) GetFriendList(context.Context, *FriendListRequest) (*FriendListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFriendList not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) StreamFriendList(*FriendListRequest, FriendConnectionService_StreamFriendListServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFriendList not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) GetCommonFriendList(context.Context, *CommonFriendListRequest) (*CommonFriendListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommonFriendList not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) Subscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) Unsubscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unsubscribe not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) BlockSubscribe(context.Context, *BlockSubscribeRequest) (*BlockSubscribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockSubscribe not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) UnblockSubscribe(context.Context, *BlockSubscribeRequest) (*BlockSubscribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnblockSubscribe not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) GetRecipients(context.Context, *RecipientsRequest) (*RecipientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecipients not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) StreamRecipients(*RecipientsRequest, FriendConnectionService_StreamRecipientsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRecipients not implemented")
+}
+func (UnimplementedFriendConnectionServiceServer) mustEmbedUnimplementedFriendConnectionServiceServer() {
+}
+
+// UnsafeFriendConnectionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FriendConnectionServiceServer will
+// result in compilation errors.
+type UnsafeFriendConnectionServiceServer interface {
+	mustEmbedUnimplementedFriendConnectionServiceServer()
+}
+
+func RegisterFriendConnectionServiceServer(s grpc.ServiceRegistrar, srv FriendConnectionServiceServer) {
+	s.RegisterService(&FriendConnectionService_ServiceDesc, srv)
+}
+
+func _FriendConnectionService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_CreateFriendConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FriendConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).CreateFriendConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_CreateFriendConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).CreateFriendConnection(ctx, req.(*FriendConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_RemoveFriendConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FriendConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).RemoveFriendConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_RemoveFriendConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).RemoveFriendConnection(ctx, req.(*FriendConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_GetFriendList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FriendListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).GetFriendList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_GetFriendList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).GetFriendList(ctx, req.(*FriendListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_StreamFriendList_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FriendListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FriendConnectionServiceServer).StreamFriendList(m, &friendConnectionServiceStreamFriendListServer{stream})
+}
+
+type FriendConnectionService_StreamFriendListServer interface {
+	Send(*Friend) error
+	grpc.ServerStream
+}
+
+type friendConnectionServiceStreamFriendListServer struct {
+	grpc.ServerStream
+}
+
+func (x *friendConnectionServiceStreamFriendListServer) Send(m *Friend) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FriendConnectionService_GetCommonFriendList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommonFriendListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).GetCommonFriendList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_GetCommonFriendList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).GetCommonFriendList(ctx, req.(*CommonFriendListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_Subscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).Subscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_Subscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).Subscribe(ctx, req.(*SubscribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_Unsubscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).Unsubscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_Unsubscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).Unsubscribe(ctx, req.(*SubscribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_BlockSubscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockSubscribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).BlockSubscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_BlockSubscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).BlockSubscribe(ctx, req.(*BlockSubscribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_UnblockSubscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockSubscribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).UnblockSubscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_UnblockSubscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).UnblockSubscribe(ctx, req.(*BlockSubscribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_GetRecipients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecipientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FriendConnectionServiceServer).GetRecipients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FriendConnectionService_GetRecipients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FriendConnectionServiceServer).GetRecipients(ctx, req.(*RecipientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FriendConnectionService_StreamRecipients_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RecipientsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FriendConnectionServiceServer).StreamRecipients(m, &friendConnectionServiceStreamRecipientsServer{stream})
+}
+
+type FriendConnectionService_StreamRecipientsServer interface {
+	Send(*Recipient) error
+	grpc.ServerStream
+}
+
+type friendConnectionServiceStreamRecipientsServer struct {
+	grpc.ServerStream
+}
+
+func (x *friendConnectionServiceStreamRecipientsServer) Send(m *Recipient) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// FriendConnectionService_ServiceDesc is the grpc.ServiceDesc for FriendConnectionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FriendConnectionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "friendconnection.v1.FriendConnectionService",
+	HandlerType: (*FriendConnectionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUser",
+			Handler:    _FriendConnectionService_CreateUser_Handler,
+		},
+		{
+			MethodName: "CreateFriendConnection",
+			Handler:    _FriendConnectionService_CreateFriendConnection_Handler,
+		},
+		{
+			MethodName: "RemoveFriendConnection",
+			Handler:    _FriendConnectionService_RemoveFriendConnection_Handler,
+		},
+		{
+			MethodName: "GetFriendList",
+			Handler:    _FriendConnectionService_GetFriendList_Handler,
+		},
+		{
+			MethodName: "GetCommonFriendList",
+			Handler:    _FriendConnectionService_GetCommonFriendList_Handler,
+		},
+		{
+			MethodName: "Subscribe",
+			Handler:    _FriendConnectionService_Subscribe_Handler,
+		},
+		{
+			MethodName: "Unsubscribe",
+			Handler:    _FriendConnectionService_Unsubscribe_Handler,
+		},
+		{
+			MethodName: "BlockSubscribe",
+			Handler:    _FriendConnectionService_BlockSubscribe_Handler,
+		},
+		{
+			MethodName: "UnblockSubscribe",
+			Handler:    _FriendConnectionService_UnblockSubscribe_Handler,
+		},
+		{
+			MethodName: "GetRecipients",
+			Handler:    _FriendConnectionService_GetRecipients_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFriendList",
+			Handler:       _FriendConnectionService_StreamFriendList_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamRecipients",
+			Handler:       _FriendConnectionService_StreamRecipients_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "friendconnection/v1/friend_connection.proto",
+}
@@ -0,0 +1,53 @@
+package grpcapi
+
+import (
+	"log/slog"
+	"time"
+
+	"golang_project/api/internal/auth"
+	pb "golang_project/api/internal/grpcapi/friendconnectionpb"
+	"golang_project/api/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Options struct used to configure the gRPC server
+// Authenticator may be nil, in which case every call is accepted
+type Options struct {
+	Logger         *slog.Logger
+	Authenticator  *auth.Authenticator
+	RequestTimeout time.Duration
+}
+
+// NewServer function used to initialize a gRPC server exposing FriendConnectionService, the health service and reflection
+// pass a FriendConnectionService and Options as parameters
+// return a pointer of grpc.Server and the health server, so callers can flip the serving status on shutdown
+func NewServer(service services.FriendConnectionService, opts Options) (*grpc.Server, *health.Server) {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	unary := []grpc.UnaryServerInterceptor{LoggingUnaryInterceptor(opts.Logger)}
+	stream := []grpc.StreamServerInterceptor{LoggingStreamInterceptor(opts.Logger)}
+	if opts.RequestTimeout > 0 {
+		unary = append(unary, TimeoutUnaryInterceptor(opts.RequestTimeout))
+	}
+	if opts.Authenticator != nil {
+		unary = append(unary, AuthUnaryInterceptor(opts.Authenticator))
+		stream = append(stream, AuthStreamInterceptor(opts.Authenticator))
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	pb.RegisterFriendConnectionServiceServer(server, NewFriendConnectionServer(service))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pb.FriendConnectionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server, healthServer
+}
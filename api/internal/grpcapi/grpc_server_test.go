@@ -0,0 +1,204 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	pb "golang_project/api/internal/grpcapi/friendconnectionpb"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGetFriendList(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	response, err := client.GetFriendList(context.Background(), &pb.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+
+	assert.Nil(t, err)
+	assert.True(t, response.GetSuccess())
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"}, response.GetFriends())
+	assert.Equal(t, int32(2), response.GetCount())
+}
+
+func TestGetFriendListWithInvalidEmail(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	_, err := client.GetFriendList(context.Background(), &pb.FriendListRequest{Email: "thehaohcm"})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid email address", st.Message())
+	if assert.Len(t, st.Details(), 1) {
+		info := st.Details()[0].(*errdetails.ErrorInfo)
+		assert.Equal(t, "invalid_email", info.GetReason())
+	}
+}
+
+func TestCreateFriendConnectionWithUnregisteredEmail(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	_, err := client.CreateFriendConnection(context.Background(), &pb.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "unregistered@example.com"}})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestStreamFriendList(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	stream, err := client.StreamFriendList(context.Background(), &pb.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+
+	var friends []string
+	for {
+		friend, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		friends = append(friends, friend.GetEmail())
+	}
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"}, friends)
+}
+
+func TestStreamRecipients(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	stream, err := client.StreamRecipients(context.Background(), &pb.RecipientsRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "Hello"})
+	assert.Nil(t, err)
+
+	recipient, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, "hao.nguyen@s3corp.com.vn", recipient.GetEmail())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRequestIDIsEchoed(t *testing.T) {
+	client, _ := setupClientForTesting(t, Options{})
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDMetadataKey, "req-123")
+	_, err := client.GetFriendList(ctx, &pb.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"}, grpc.Header(&header))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"req-123"}, header.Get(requestIDMetadataKey))
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	authenticator := auth.NewAuthenticator(auth.NewJWTVerifier(auth.JWTConfig{HS256Secret: []byte("secret")}), nil)
+	client, conn := setupClientForTesting(t, Options{Authenticator: authenticator})
+	request := &pb.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"}
+
+	_, err := client.GetFriendList(context.Background(), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.GetFriendList(ctx, request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := client.StreamFriendList(ctx, request)
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "thehaohcm@yahoo.com.vn", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	assert.Nil(t, err)
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	_, err = client.GetFriendList(ctx, request)
+	assert.Nil(t, err)
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.FriendConnectionService_ServiceDesc.ServiceName})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, codes.InvalidArgument, StatusCode(apperrors.KindValidation))
+	assert.Equal(t, codes.NotFound, StatusCode(apperrors.KindNotFound))
+	assert.Equal(t, codes.AlreadyExists, StatusCode(apperrors.KindAlreadyExists))
+	assert.Equal(t, codes.PermissionDenied, StatusCode(apperrors.KindBlocked))
+	assert.Equal(t, codes.Unauthenticated, StatusCode(apperrors.KindUnauthenticated))
+	assert.Equal(t, codes.PermissionDenied, StatusCode(apperrors.KindForbidden))
+	assert.Equal(t, codes.ResourceExhausted, StatusCode(apperrors.KindRateLimited))
+	assert.Equal(t, codes.DeadlineExceeded, StatusCode(apperrors.KindTimeout))
+	assert.Equal(t, codes.Unavailable, StatusCode(apperrors.KindUnavailable))
+	assert.Equal(t, codes.Internal, StatusCode(apperrors.KindInternal))
+}
+
+func setupClientForTesting(t *testing.T, opts Options) (pb.FriendConnectionServiceClient, *grpc.ClientConn) {
+	listener := bufconn.Listen(1024 * 1024)
+	server, _ := NewServer(&serviceFake{}, opts)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewFriendConnectionServiceClient(conn), conn
+}
+
+// serviceFake implements the read paths used by these tests, the other methods are not expected to be called
+type serviceFake struct {
+	services.FriendConnectionService
+}
+
+func (s *serviceFake) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	if request.Friends[1] == "unregistered@example.com" {
+		return models.FriendConnectionResponse{}, apperrors.NotFound("user_not_found", "unregistered email address: unregistered@example.com")
+	}
+	return models.FriendConnectionResponse{Success: true}, nil
+}
+
+func (s *serviceFake) GetFriendConnection(ctx context.Context, request models.FriendListRequest) (models.FriendListResponse, error) {
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		return models.FriendListResponse{}, err
+	}
+	return models.FriendListResponse{Success: true, Friends: []string{"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"}, Count: 2}, nil
+}
+
+func (s *serviceFake) GetSubscribingEmailListByEmail(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+	if err := pkg.CheckValidEmail(request.Sender); err != nil {
+		return models.GetSubscribingEmailListResponse{}, err
+	}
+	return models.GetSubscribingEmailListResponse{Success: true, Recipients: []string{"hao.nguyen@s3corp.com.vn"}}, nil
+}
+
+func (s *serviceFake) StreamRecipients(ctx context.Context, request models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	response, err := s.GetSubscribingEmailListByEmail(ctx, request)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range response.Recipients {
+		if err := fn(recipient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDMetadataKey is the metadata key used to propagate the request correlation ID, same as the HTTP X-Request-ID header
+const requestIDMetadataKey = "x-request-id"
+
+// publicMethods are reachable without credentials so that load balancers and tooling keep working
+var publicMethods = map[string]bool{
+	"/grpc.health.v1.Health/Check":                                   true,
+	"/grpc.health.v1.Health/Watch":                                   true,
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo":      true,
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": true,
+}
+
+// LoggingUnaryInterceptor function used to initialize an interceptor which assigns or propagates the x-request-id metadata,
+// attaches a request scoped logger to the context and writes one structured log line per call
+// pass a pointer of slog.Logger as parameter
+// return a grpc.UnaryServerInterceptor
+func LoggingUnaryInterceptor(base *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestLogger(ctx, base)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor function is the streaming counterpart of LoggingUnaryInterceptor
+// pass a pointer of slog.Logger as parameter
+// return a grpc.StreamServerInterceptor
+func LoggingStreamInterceptor(base *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestLogger(ss.Context(), base)
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// TimeoutUnaryInterceptor function used to initialize an interceptor which applies a default deadline to calls without one
+// pass a time.Duration as parameter
+// return a grpc.UnaryServerInterceptor
+func TimeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthUnaryInterceptor function used to initialize an interceptor which rejects calls without valid credentials,
+// sent as "authorization: Bearer <credential>" or "x-api-key" metadata, like in the HTTP API
+// health and reflection methods are always allowed
+// pass a pointer of auth.Authenticator as parameter
+// return a grpc.UnaryServerInterceptor
+func AuthUnaryInterceptor(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, authenticator)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor function is the streaming counterpart of AuthUnaryInterceptor
+// pass a pointer of auth.Authenticator as parameter
+// return a grpc.StreamServerInterceptor
+func AuthStreamInterceptor(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), authenticator)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate function reads the credential from the "authorization: Bearer <credential>" or "x-api-key" metadata,
+// and stores the authenticated principal in the context
+func authenticate(ctx context.Context, authenticator *auth.Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	credential := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, token, found := strings.Cut(values[0], " ")
+		if !found || !strings.EqualFold(scheme, "bearer") {
+			return ctx, toStatus(ctx, "Authenticate", apperrors.Unauthenticated("invalid_authorization_header", "invalid authorization metadata, expected a bearer token"))
+		}
+		credential = token
+	} else if values := md.Get("x-api-key"); len(values) > 0 {
+		credential = values[0]
+	}
+
+	principal, err := authenticator.Authenticate(ctx, credential)
+	if err != nil {
+		return ctx, toStatus(ctx, "Authenticate", err)
+	}
+	ctx = auth.WithPrincipal(ctx, principal)
+	return logger.WithContext(ctx, logger.FromContext(ctx).With(slog.String("subject", principal.Subject))), nil
+}
+
+func withRequestLogger(ctx context.Context, base *slog.Logger) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 && len(values[0]) <= 128 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err == nil {
+			requestID = hex.EncodeToString(b)
+		}
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+	ctx = logger.WithRequestID(ctx, requestID)
+	return logger.WithContext(ctx, base.With(slog.String("request_id", requestID)))
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	logger.FromContext(ctx).LogAttrs(ctx, level, "grpc request",
+		slog.String("method", method), slog.String("grpc_code", code.String()), slog.Duration("latency", time.Since(start)))
+}
+
+// contextStream wraps a grpc.ServerStream to replace its context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context function returns the replaced context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,47 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"golang_project/api/internal/resilience"
+)
+
+type breakerStore struct {
+	next    Store
+	breaker *resilience.Breaker
+}
+
+// NewBreakerStore function used for initializing a Store whose calls go through the circuit breaker of its database,
+// while it is open they fail fast with resilience.ErrOpen, so the requests are processed without waiting for their timeout
+// pass the wrapped Store and a resilience.Breaker as parameters
+// return a Store
+func NewBreakerStore(store Store, breaker *resilience.Breaker) Store {
+	return &breakerStore{
+		next:    store,
+		breaker: breaker,
+	}
+}
+
+// Reserve function used to reserve a key through the circuit breaker
+func (s *breakerStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (record Record, reserved bool, err error) {
+	err = s.breaker.Do(func() error {
+		record, reserved, err = s.next.Reserve(ctx, key, fingerprint, lockTTL)
+		return err
+	})
+	return record, reserved, err
+}
+
+// Complete function used to store the response of a key through the circuit breaker
+func (s *breakerStore) Complete(ctx context.Context, key string, response Response, ttl time.Duration) error {
+	return s.breaker.Do(func() error {
+		return s.next.Complete(ctx, key, response, ttl)
+	})
+}
+
+// Release function used to free a key through the circuit breaker
+func (s *breakerStore) Release(ctx context.Context, key string) error {
+	return s.breaker.Do(func() error {
+		return s.next.Release(ctx, key)
+	})
+}
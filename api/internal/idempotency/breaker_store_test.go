@@ -0,0 +1,31 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/resilience"
+)
+
+func TestBreakerStoreFailsFastWhileTheBreakerIsOpen(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+	breaker := resilience.NewBreaker("test", 1, time.Minute, func(error) bool { return true })
+	store := NewBreakerStore(NewPostgresStore(mockDB), breaker)
+
+	breaker.Do(func() error { return errors.New("connection refused") })
+
+	// the breaker is open, the database is not called
+	_, _, err = store.Reserve(context.Background(), "user:key", "fingerprint", time.Minute)
+	assert.ErrorIs(t, err, resilience.ErrOpen)
+	assert.ErrorIs(t, store.Complete(context.Background(), "user:key", Response{StatusCode: 200}, time.Hour), resilience.ErrOpen)
+	assert.ErrorIs(t, store.Release(context.Background(), "user:key"), resilience.ErrOpen)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the expired keys are removed from a MemoryStore
+const sweepInterval = time.Minute
+
+type entry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+// MemoryStore struct keeps the idempotency keys in the memory of the process, so retries must reach the same replica
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore function used for initializing a MemoryStore
+// return a pointer of MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]*entry{},
+		now:     time.Now,
+	}
+}
+
+// Reserve function used to take a key for a request, see Store
+// pass a context, the key, the fingerprint of the request and the lifetime of the reservation as parameters
+// return the Record of the key, whether it was reserved and an error type, always nil
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.record, false, nil
+	}
+	record := Record{Fingerprint: fingerprint}
+	s.entries[key] = &entry{record: record, expiresAt: now.Add(lockTTL)}
+	return record, true, nil
+}
+
+// Complete function used to store the response of the request holding a key
+// pass a context, the key, the Response and its lifetime as parameters
+// return an error type, always nil
+func (s *MemoryStore) Complete(ctx context.Context, key string, response Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.record.Response = &response
+		e.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+// Release function used to free a key which has no stored response
+// pass a context and the key as parameters
+// return an error type, always nil
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.record.Response == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
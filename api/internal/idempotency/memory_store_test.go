@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreReserveCompleteAndReplay(t *testing.T) {
+	store, clock := newMemoryStoreForTesting()
+
+	record, reserved, err := store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, Record{Fingerprint: "abc"}, record)
+
+	record, reserved, _ = store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.False(t, reserved)
+	assert.Nil(t, record.Response)
+
+	response := Response{StatusCode: 200, Header: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"success":true}`)}
+	assert.Nil(t, store.Complete(context.Background(), "user:key", response, time.Hour))
+	// a completed key is not released
+	assert.Nil(t, store.Release(context.Background(), "user:key"))
+
+	*clock = clock.Add(59 * time.Minute)
+	record, reserved, _ = store.Reserve(context.Background(), "user:key", "def", time.Minute)
+	assert.False(t, reserved)
+	assert.Equal(t, Record{Fingerprint: "abc", Response: &response}, record)
+
+	*clock = clock.Add(time.Minute)
+	_, reserved, _ = store.Reserve(context.Background(), "user:key", "def", time.Minute)
+	assert.True(t, reserved)
+}
+
+func TestMemoryStoreReleaseAndLockExpiry(t *testing.T) {
+	store, clock := newMemoryStoreForTesting()
+
+	store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.Nil(t, store.Release(context.Background(), "user:key"))
+	_, reserved, _ := store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.True(t, reserved)
+
+	*clock = clock.Add(2 * time.Minute)
+	_, reserved, _ = store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.True(t, reserved)
+	assert.Len(t, store.entries, 1)
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := Fingerprint("POST", "/api/v1/users/createUser", []byte(`{"email":"a@b.c"}`))
+
+	assert.Len(t, fingerprint, 64)
+	assert.Equal(t, fingerprint, Fingerprint("POST", "/api/v1/users/createUser", []byte(`{"email":"a@b.c"}`)))
+	assert.NotEqual(t, fingerprint, Fingerprint("POST", "/api/v1/users/createUser", []byte(`{"email":"d@b.c"}`)))
+	assert.NotEqual(t, fingerprint, Fingerprint("PUT", "/api/v1/users/createUser", []byte(`{"email":"a@b.c"}`)))
+}
+
+func newMemoryStoreForTesting() (*MemoryStore, *time.Time) {
+	clock := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return clock }
+	return store, &clock
+}
@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"golang_project/api/internal/logger"
+)
+
+// PostgresStore struct keeps the idempotency keys in the idempotency_key table, so retries may reach any replica
+type PostgresStore struct {
+	db        *sql.DB
+	lastSweep atomic.Int64
+}
+
+// NewPostgresStore function used for initializing a PostgresStore
+// pass a pointer sql.DB as parameter
+// return a pointer of PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// Reserve function used to take a key for a request, see Store; an expired key is taken over in the same statement
+// pass a context, the key, the fingerprint of the request and the lifetime of the reservation as parameters
+// return the Record of the key, whether it was reserved and an error type
+func (s *PostgresStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (Record, bool, error) {
+	s.deleteExpiredPeriodically(ctx)
+
+	var reserved string
+	err := s.db.QueryRowContext(ctx, `INSERT INTO public.idempotency_key(idempotency_key, fingerprint, expires_at)
+	VALUES($1, $2, now() + make_interval(secs => $3::float8))
+	ON CONFLICT (idempotency_key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, headers = NULL, body = NULL, expires_at = EXCLUDED.expires_at
+	WHERE idempotency_key.expires_at <= now()
+	RETURNING idempotency_key`, key, fingerprint, lockTTL.Seconds()).Scan(&reserved)
+	if err == nil {
+		return Record{Fingerprint: fingerprint}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Record{}, false, err
+	}
+
+	var record Record
+	var statusCode sql.NullInt64
+	var headers, body []byte
+	err = s.db.QueryRowContext(ctx, `SELECT fingerprint, status_code, headers, body FROM public.idempotency_key WHERE idempotency_key = $1`, key).
+		Scan(&record.Fingerprint, &statusCode, &headers, &body)
+	if err != nil {
+		return Record{}, false, err
+	}
+	if statusCode.Valid {
+		record.Response = &Response{StatusCode: int(statusCode.Int64), Body: body}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &record.Response.Header); err != nil {
+				return Record{}, false, err
+			}
+		}
+	}
+	return record, false, nil
+}
+
+// Complete function used to store the response of the request holding a key
+// pass a context, the key, the Response and its lifetime as parameters
+// return an error type
+func (s *PostgresStore) Complete(ctx context.Context, key string, response Response, ttl time.Duration) error {
+	headers, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE public.idempotency_key SET status_code = $2, headers = $3, body = $4, expires_at = now() + make_interval(secs => $5::float8)
+	WHERE idempotency_key = $1`, key, response.StatusCode, headers, response.Body, ttl.Seconds())
+	return err
+}
+
+// Release function used to free a key which has no stored response
+// pass a context and the key as parameters
+// return an error type
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM public.idempotency_key WHERE idempotency_key = $1 AND status_code IS NULL`, key)
+	return err
+}
+
+// DeleteExpired function used to remove the expired keys, Reserve calls it about once a minute
+// pass a context as parameter
+// return the number of removed keys and an error type
+func (s *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM public.idempotency_key WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) deleteExpiredPeriodically(ctx context.Context) {
+	now := time.Now()
+	if now.Sub(time.Unix(0, s.lastSweep.Load())) < sweepInterval {
+		return
+	}
+	s.lastSweep.Store(now.UnixNano())
+	if _, err := s.DeleteExpired(ctx); err != nil {
+		logger.FromContext(ctx).Warn("cannot delete the expired idempotency keys", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,65 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStoreReserve(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectExec("DELETE FROM public.idempotency_key WHERE expires_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("INSERT INTO public.idempotency_key").
+		WithArgs("user:key", "abc", float64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"idempotency_key"}).AddRow("user:key"))
+	sqlMock.ExpectQuery("INSERT INTO public.idempotency_key").
+		WithArgs("user:key", "abc", float64(60)).
+		WillReturnError(sql.ErrNoRows)
+	sqlMock.ExpectQuery("SELECT fingerprint, status_code, headers, body FROM public.idempotency_key").
+		WithArgs("user:key").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "headers", "body"}).
+			AddRow("abc", 201, []byte(`{"Content-Type":"application/json"}`), []byte(`{"success":true}`)))
+
+	store := NewPostgresStore(mockDB)
+	record, reserved, err := store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, Record{Fingerprint: "abc"}, record)
+
+	record, reserved, err = store.Reserve(context.Background(), "user:key", "abc", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, Record{Fingerprint: "abc", Response: &Response{
+		StatusCode: 201, Header: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"success":true}`),
+	}}, record)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestPostgresStoreCompleteAndRelease(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectExec("UPDATE public.idempotency_key SET status_code").
+		WithArgs("user:key", 200, []byte(`{"Content-Type":"application/json"}`), []byte(`{}`), float64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("DELETE FROM public.idempotency_key WHERE idempotency_key").
+		WithArgs("user:other").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	store := NewPostgresStore(mockDB)
+	assert.Nil(t, store.Complete(context.Background(), "user:key", Response{StatusCode: 200, Header: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{}`)}, time.Hour))
+	assert.Nil(t, store.Release(context.Background(), "user:other"))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,45 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Response struct holds the response stored for an idempotency key, replayed as it is for the retries of the request
+type Response struct {
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+}
+
+// Record struct describes the request which first used an idempotency key
+type Record struct {
+	// Fingerprint identifies the request, see Fingerprint
+	Fingerprint string
+	// Response is nil while the first request is still in progress
+	Response *Response
+}
+
+// Store interface keeps the idempotency keys until they expire
+type Store interface {
+	// Reserve takes a key for a request: it returns true when the key was free, otherwise false and the Record of the key;
+	// the reservation expires after lockTTL unless it is completed, so a crashed request does not hold the key forever
+	Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (Record, bool, error)
+	// Complete stores the response of the request holding the key, for ttl
+	Complete(ctx context.Context, key string, response Response, ttl time.Duration) error
+	// Release frees a key whose request has no response worth replaying, so the next retry runs again
+	Release(ctx context.Context, key string) error
+}
+
+// Fingerprint function used to identify a request by its method, its target and its body,
+// retries must have the same fingerprint as the first request
+// pass the method, the target (path and query) and the body of the request as parameters
+// return a hexadecimal SHA-256 hash
+func Fingerprint(method, target string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + target + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
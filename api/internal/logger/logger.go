@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// New function used to initialize the application's structured JSON logger
+// the level is read from the LOG_LEVEL environment variable (debug, info, warn, error), default is info
+// return a pointer of slog.Logger
+func New() *slog.Logger {
+	return NewWithWriter(os.Stdout)
+}
+
+// NewWithWriter function works like New, but writes the logs to the given writer, e.g. os.Stderr for command line tools
+// pass an io.Writer as parameter
+// return a pointer of slog.Logger
+func NewWithWriter(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: parseLevel(os.Getenv("LOG_LEVEL"))}))
+}
+
+// WithContext function used to attach a logger to a context
+// pass a context and a pointer of slog.Logger as parameters
+// return a new context carrying the logger
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext function used to get the logger attached to a context
+// pass a context as parameter
+// return the request scoped logger, or slog.Default() when the context does not carry one
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// WithRequestID function used to attach a request correlation ID to a context
+// pass a context and a request ID string as parameters
+// return a new context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext function used to get the request correlation ID attached to a context
+// pass a context as parameter
+// return the request ID string, empty when the context does not carry one
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(requestIDKey).(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ByAccept function used to initialize a middleware which runs one of two middlewares depending on the Accept header of the request,
+// e.g. to give a streamed response its own timeout and rate limit
+// pass the media type, the middleware of the requests accepting it and the middleware of the other requests as parameters
+// return a gin.HandlerFunc
+func ByAccept(mediaType string, matching, other gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.Contains(c.GetHeader("Accept"), mediaType) {
+			matching(c)
+			return
+		}
+		other(c)
+	}
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+// APIKeyHeader is the header carrying an API key, as an alternative to "Authorization: Bearer <key>"
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate function used to initialize a middleware which rejects requests without valid credentials with 401
+// the credentials are a JWT or an API key sent as "Authorization: Bearer <credential>", or an API key sent in X-API-Key;
+// the authenticated principal is stored in the request context (see auth.PrincipalFromContext)
+// pass a pointer of auth.Authenticator as parameter
+// return a gin.HandlerFunc
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		credential, err := credentialFromRequest(c.Request)
+		if err == nil {
+			var principal auth.Principal
+			principal, err = authenticator.Authenticate(ctx, credential)
+			if err == nil {
+				ctx = auth.WithPrincipal(ctx, principal)
+				ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(slog.String("subject", principal.Subject)))
+				c.Request = c.Request.WithContext(ctx)
+				c.Next()
+				return
+			}
+		}
+
+		appErr := apperrors.From(err)
+		status := http.StatusUnauthorized
+		if appErr.Kind != apperrors.KindUnauthenticated {
+			// the credential could not be checked, e.g. the database is down
+			status = http.StatusServiceUnavailable
+			if appErr.Kind != apperrors.KindUnavailable {
+				status = http.StatusInternalServerError
+			}
+		} else {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		}
+		logger.FromContext(ctx).Warn("authentication failed", slog.String("code", appErr.Code), slog.Any("error", err))
+		c.AbortWithStatusJSON(status, models.ErrorResponse{Error: appErr.Message, Code: appErr.Code})
+	}
+}
+
+// RequireRole function used to initialize a middleware which rejects with 403 the principals holding none of the roles
+// must be registered after Authenticate
+// pass the accepted roles as parameters
+// return a gin.HandlerFunc
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		if !ok || !principal.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: "insufficient permissions", Code: "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func credentialFromRequest(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, credential, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(credential) == "" {
+			return "", apperrors.Unauthenticated("invalid_authorization_header", "invalid Authorization header, expected a bearer token")
+		}
+		return strings.TrimSpace(credential), nil
+	}
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key, nil
+	}
+	return "", auth.ErrMissingCredentials
+}
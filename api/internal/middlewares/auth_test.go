@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/resilience"
+)
+
+var authSecret = []byte("secret")
+
+func TestAuthenticateRejectsMissingAndInvalidCredentials(t *testing.T) {
+	router := setupAuthRouter()
+
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz", "Bearer wrong"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+		assert.Equal(t, `Bearer realm="api"`, w.Header().Get("WWW-Authenticate"), header)
+	}
+}
+
+func TestAuthenticateAcceptsValidToken(t *testing.T) {
+	router := setupAuthRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "thehaohcm@yahoo.com.vn", nil))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "thehaohcm@yahoo.com.vn", w.Body.String())
+}
+
+func TestRequireRole(t *testing.T) {
+	router := setupAuthRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "thehaohcm@yahoo.com.vn", nil))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"insufficient permissions","code":"forbidden"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "thehaohcm@yahoo.com.vn", []string{auth.RoleAdmin}))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticateFailsFastWhileTheDatabaseBreakerIsOpen(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	breaker := resilience.NewBreaker("test", 1, time.Minute, repositories.OutageError)
+	breaker.Do(func() error { return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")} })
+	store := repositories.NewBreakerAPIKeyRepository(repositories.NewAPIKeyRepository(mockDB), breaker)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/ping", Authenticate(auth.NewAuthenticator(nil, auth.NewAPIKeyVerifier(store))), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	_, _, key, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set(APIKeyHeader, key)
+	router.ServeHTTP(w, req)
+
+	// the api_key table is not queried
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"the database is unavailable, retry later","code":"database_unavailable"}`, w.Body.String())
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func setupAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticator := auth.NewAuthenticator(auth.NewJWTVerifier(auth.JWTConfig{HS256Secret: authSecret}), nil)
+	api := router.Group("/api", Authenticate(authenticator))
+	api.GET("/ping", func(c *gin.Context) {
+		principal, _ := auth.PrincipalFromContext(c.Request.Context())
+		c.String(http.StatusOK, principal.Email)
+	})
+	api.GET("/admin", RequireRole(auth.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func signToken(t *testing.T, email string, roles []string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": email, "roles": roles, "iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(authSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/cache"
+)
+
+// CacheControl function used to initialize a middleware which honors the Cache-Control: no-cache (or no-store) header of a request,
+// the cached friend lists and recipients are skipped and refreshed from the database, e.g. to debug a stale read
+// return a gin.HandlerFunc
+func CacheControl() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, directive := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "no-cache" || directive == "no-store" {
+				c.Request = c.Request.WithContext(cache.WithBypass(c.Request.Context()))
+				break
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/cache"
+)
+
+func TestCacheControlBypassesTheCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CacheControl())
+	router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.FormatBool(cache.Bypassed(c.Request.Context())))
+	})
+
+	tests := map[string]string{
+		"":                    "false",
+		"max-age=60":          "false",
+		"no-cache":            "true",
+		"max-age=0, No-Store": "true",
+	}
+	for header, expected := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		if header != "" {
+			req.Header.Set("Cache-Control", header)
+		}
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Body.String(), header)
+	}
+}
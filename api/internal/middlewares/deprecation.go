@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated function used to initialize a middleware which flags every response of a route group as deprecated
+// it sets the Deprecation header, a Link header pointing to the successor version and, when sunset is not empty,
+// the Sunset header (an HTTP-date after which the routes may be removed)
+// pass the successor's URL and a sunset date string as parameters
+// return a gin.HandlerFunc
+func Deprecated(successor, sunset string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		c.Next()
+	}
+}
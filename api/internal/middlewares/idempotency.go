@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/idempotency"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the idempotency key chosen by the client
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL bounds the reservation of a key by a request which never completes
+	idempotencyLockTTL = time.Minute
+)
+
+// replayedHeaders are the response headers stored with the body, the others are computed again for each retry
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Idempotency function used to initialize a middleware which makes a write route safe to retry:
+// the first response to a request carrying an Idempotency-Key header is stored for ttl and replayed for the retries,
+// with the Idempotent-Replayed header; reusing a key for a different request gets 422, and a retry arriving while
+// the first request is still running gets 409. Keys are scoped by the authenticated principal.
+// Server errors, canceled requests and rate limited requests are not stored, so they can be retried with the same key;
+// requests without the header and requests arriving while the store fails are processed as usual
+// must be registered after Authenticate
+// pass an idempotency.Store and the lifetime of the stored responses as parameters
+// return a gin.HandlerFunc
+func Idempotency(store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(IdempotencyKeyHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+		if len(value) > maxIdempotencyKeyLength || strings.ContainsFunc(value, func(r rune) bool { return r < 0x20 || r > 0x7e }) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "the Idempotency-Key header must contain 1 to 255 printable ASCII characters",
+				Code:  "invalid_idempotency_key",
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot read the request body", Code: "invalid_request"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		key := "anonymous:" + value
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			key = principal.Subject + ":" + value
+		}
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		record, reserved, err := store.Reserve(ctx, key, fingerprint, idempotencyLockTTL)
+		if err != nil {
+			logger.FromContext(ctx).Warn("cannot check the idempotency key, the request is processed", slog.Any("error", err))
+			c.Next()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+					Error: "the Idempotency-Key was already used for a different request",
+					Code:  "idempotency_key_reused",
+				})
+			case record.Response == nil:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, models.ErrorResponse{
+					Error: "a request with the same Idempotency-Key is still in progress, retry later",
+					Code:  "idempotency_key_in_progress",
+				})
+			default:
+				for name, value := range record.Response.Header {
+					c.Header(name, value)
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(record.Response.StatusCode, record.Response.Header["Content-Type"], record.Response.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		// the outcome is saved even when the request context expired, the client may still retry
+		storeCtx := context.WithoutCancel(ctx)
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(storeCtx, key); err != nil {
+				logger.FromContext(ctx).Warn("cannot release the idempotency key", slog.Any("error", err))
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if !isReplayable(status) {
+			return
+		}
+		response := idempotency.Response{StatusCode: status, Header: map[string]string{}, Body: recorder.body.Bytes()}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				response.Header[name] = value
+			}
+		}
+		if err := store.Complete(storeCtx, key, response, ttl); err != nil {
+			logger.FromContext(ctx).Warn("cannot store the idempotent response", slog.Any("error", err))
+			return
+		}
+		completed = true
+	}
+}
+
+// isReplayable function tells whether a response is final, the other ones are worth retrying
+func isReplayable(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusTooManyRequests && status != http.StatusRequestTimeout && status != 499
+}
+
+// bodyRecorder wraps a gin.ResponseWriter to keep a copy of the response body
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write function writes the data to the client and keeps a copy
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString function writes the string to the client and keeps a copy
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
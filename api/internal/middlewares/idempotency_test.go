@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/idempotency"
+)
+
+func TestIdempotencyReplaysTheFirstResponse(t *testing.T) {
+	router, calls := setupIdempotencyRouter(idempotency.NewMemoryStore())
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+
+	w := performIdempotentRequest(router, token, "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+
+	w = performIdempotentRequest(router, token, "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "/api/users/1", w.Header().Get("Location"))
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"call":1}`, w.Body.String())
+	assert.Equal(t, 1, *calls)
+
+	// requests without a key and keys of other users are not replayed
+	performIdempotentRequest(router, token, "", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	w = performIdempotentRequest(router, signToken(t, "hao.nguyen@s3corp.com.vn", nil), "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 3, *calls)
+}
+
+func TestIdempotencyRejectsKeyReuseWithDifferentPayload(t *testing.T) {
+	router, calls := setupIdempotencyRouter(idempotency.NewMemoryStore())
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+
+	performIdempotentRequest(router, token, "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	w := performIdempotentRequest(router, token, "key-1", `{"email":"hao.nguyen@s3corp.com.vn"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error":"the Idempotency-Key was already used for a different request","code":"idempotency_key_reused"}`, w.Body.String())
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotencyRejectsConcurrentRetryAndInvalidKey(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	router, calls := setupIdempotencyRouter(store)
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+	body := `{"email":"thehaohcm@yahoo.com.vn"}`
+	store.Reserve(context.Background(), "thehaohcm@yahoo.com.vn:key-1", idempotency.Fingerprint(http.MethodPost, "/api/users", []byte(body)), time.Minute)
+
+	w := performIdempotentRequest(router, token, "key-1", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = performIdempotentRequest(router, token, strings.Repeat("k", 256), body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	router, calls := setupIdempotencyRouter(idempotency.NewMemoryStore())
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+
+	w := performIdempotentRequest(router, token, "key-1", `{"fail":true}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = performIdempotentRequest(router, token, "key-1", `{"fail":true}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyFailsOpen(t *testing.T) {
+	router, calls := setupIdempotencyRouter(failingIdempotencyStore{})
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+
+	performIdempotentRequest(router, token, "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	w := performIdempotentRequest(router, token, "key-1", `{"email":"thehaohcm@yahoo.com.vn"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, *calls)
+}
+
+func setupIdempotencyRouter(store idempotency.Store) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticator := auth.NewAuthenticator(auth.NewJWTVerifier(auth.JWTConfig{HS256Secret: authSecret}), nil)
+	calls := 0
+	router.POST("/api/users", Authenticate(authenticator), Idempotency(store, time.Hour), func(c *gin.Context) {
+		calls++
+		var request map[string]interface{}
+		_ = c.BindJSON(&request)
+		if request["fail"] == true {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+			return
+		}
+		c.Header("Location", "/api/users/1")
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func performIdempotentRequest(router *gin.Engine, token, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/users", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (idempotency.Record, bool, error) {
+	return idempotency.Record{}, false, errors.New("connection refused")
+}
+
+func (failingIdempotencyStore) Complete(ctx context.Context, key string, response idempotency.Response, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingIdempotencyStore) Release(ctx context.Context, key string) error {
+	return errors.New("connection refused")
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/ratelimit"
+)
+
+// RateLimit function used to initialize a middleware which applies two token buckets to a route:
+// one per authenticated principal (user or API key) and one per client IP;
+// the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers describe the most constrained bucket,
+// rejected requests get 429 with a Retry-After header; a failing store never rejects a request
+// must be registered after Authenticate
+// pass a ratelimit.Store, the route name, the per principal Limit and the per IP Limit as parameters, a zero Limit is not applied
+// return a gin.HandlerFunc
+func RateLimit(store ratelimit.Store, route string, perPrincipal, perIP ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var results []ratelimit.Result
+		take := func(key string, limit ratelimit.Limit) {
+			if limit.IsZero() {
+				return
+			}
+			result, err := store.Take(ctx, key, limit)
+			if err != nil {
+				logger.FromContext(ctx).Warn("cannot check the rate limit, the request is accepted", slog.String("route", route), slog.Any("error", err))
+				return
+			}
+			results = append(results, result)
+		}
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			take("http:"+route+":principal:"+principal.Subject, perPrincipal)
+		}
+		take("http:"+route+":ip:"+c.ClientIP(), perIP)
+		if len(results) == 0 {
+			c.Next()
+			return
+		}
+
+		binding := results[0]
+		policies := make([]string, 0, len(results))
+		for _, result := range results {
+			policies = append(policies, strconv.Itoa(result.Limit.Requests)+";w="+strconv.Itoa(int(result.Limit.Per.Seconds())))
+			if moreConstrained(result, binding) {
+				binding = result
+			}
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(binding.Limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(binding.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(binding.Reset.Seconds()))))
+		c.Header("RateLimit-Policy", strings.Join(policies, ", "))
+
+		if !binding.Allowed {
+			retryAfter := ratelimit.RetryAfterSeconds(binding)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			logger.FromContext(ctx).Warn("rate limit exceeded", slog.String("route", route), slog.Int("retry_after", retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "too many requests, retry later",
+				Code:    "rate_limited",
+				Details: map[string]interface{}{"retry_after": retryAfter},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// moreConstrained function tells whether a bucket constrains the request more than another one:
+// a rejecting bucket wins over an allowing one, then the longest wait or the fewest remaining tokens wins
+func moreConstrained(result, other ratelimit.Result) bool {
+	if result.Allowed != other.Allowed {
+		return !result.Allowed
+	}
+	if !result.Allowed {
+		return result.RetryAfter > other.RetryAfter
+	}
+	return result.Remaining < other.Remaining
+}
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/ratelimit"
+)
+
+func TestRateLimitSetsHeadersAndRejects(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.Limit{Requests: 2, Per: time.Minute}, ratelimit.Limit{Requests: 10, Per: time.Minute})
+	token := signToken(t, "thehaohcm@yahoo.com.vn", nil)
+
+	w := performRateLimitedRequest(router, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60, 10;w=60", w.Header().Get("RateLimit-Policy"))
+
+	performRateLimitedRequest(router, token)
+	w = performRateLimitedRequest(router, token)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.JSONEq(t, `{"error":"too many requests, retry later","code":"rate_limited","details":{"retry_after":30}}`, w.Body.String())
+
+	// another user behind the same IP still has its own bucket
+	w = performRateLimitedRequest(router, signToken(t, "hao.nguyen@s3corp.com.vn", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimitPerIP(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.Limit{Requests: 10, Per: time.Minute}, ratelimit.Limit{Requests: 1, Per: time.Minute})
+
+	w := performRateLimitedRequest(router, signToken(t, "thehaohcm@yahoo.com.vn", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRateLimitedRequest(router, signToken(t, "hao.nguyen@s3corp.com.vn", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+}
+
+func setupRateLimitRouter(perPrincipal, perIP ratelimit.Limit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticator := auth.NewAuthenticator(auth.NewJWTVerifier(auth.JWTConfig{HS256Secret: authSecret}), nil)
+	router.GET("/api/ping", Authenticate(authenticator), RateLimit(ratelimit.NewMemoryStore(), "ping", perPrincipal, perIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func performRateLimitedRequest(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/logger"
+)
+
+// Recovery function used to initialize a middleware which recovers from panics and logs them as structured errors
+// must be registered after RequestID so the panic can be tied to the request
+// return a gin.HandlerFunc
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		logger.FromContext(c.Request.Context()).Error("panic recovered", slog.Any("panic", recovered))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"golang_project/api/internal/logger"
+)
+
+// RequestIDHeader is the HTTP header used to propagate the request correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._:-]{1,128}$`)
+
+// RequestID function used to initialize a middleware which assigns or propagates the X-Request-ID header
+// the request ID and a request scoped logger are attached to the request's context,
+// so every layer below the controllers can log with the same correlation ID
+// pass a pointer of slog.Logger as parameter
+// return a gin.HandlerFunc
+func RequestID(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := logger.WithRequestID(c.Request.Context(), requestID)
+		ctx = logger.WithContext(ctx, base.With(slog.String("request_id", requestID)))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
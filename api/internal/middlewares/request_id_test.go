@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/logger"
+)
+
+func TestRequestIDPropagatesIncomingHeader(t *testing.T) {
+	var buf bytes.Buffer
+	router := setupRequestIDRouter(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "abc-123", w.Body.String())
+
+	var line map[string]interface{}
+	err := json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &line)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc-123", line["request_id"])
+	assert.Equal(t, "handler", line["msg"])
+}
+
+func TestRequestIDGeneratesMissingHeader(t *testing.T) {
+	router := setupRequestIDRouter(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+	assert.Equal(t, w.Header().Get(RequestIDHeader), w.Body.String())
+}
+
+func TestRequestIDReplacesInvalidHeader(t *testing.T) {
+	router := setupRequestIDRouter(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	router.ServeHTTP(w, req)
+
+	assert.NotEqual(t, "bad id\nwith newline", w.Header().Get(RequestIDHeader))
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+}
+
+func setupRequestIDRouter(base *slog.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(base), AccessLog())
+	router.GET("/ping", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Info("handler")
+		c.String(http.StatusOK, logger.RequestIDFromContext(c.Request.Context()))
+	})
+	return router
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout function used to initialize a middleware which attaches a deadline to the request's context
+// the deadline travels with the context into the service and repository layers, so database work is cancelled
+// when it expires or when the client disconnects
+// pass a time.Duration as parameter
+// return a gin.HandlerFunc
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// APIKey struct used when mapping to get an APIKey model after querying data from api_key table in database
+// only the hash of the secret is stored, the plain key is shown once when the key is created
+type APIKey struct {
+	ID        string
+	Name      string
+	KeyHash   string
+	Roles     []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// CreatingAPIKeyRequest struct used when an admin requests a new API key for a service account
+type CreatingAPIKeyRequest struct {
+	Name  string   `json:"name" binding:"required"`
+	Roles []string `json:"roles"`
+}
+
+// CreatingAPIKeyResponse struct used when the service return a new API key, Key is never returned again
+type CreatingAPIKeyResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKeyResponse struct used when the service return the metadata of an API key
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Roles     []string   `json:"roles"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyListResponse struct used when the service return the list of API keys
+type APIKeyListResponse struct {
+	Keys  []APIKeyResponse `json:"keys"`
+	Count int              `json:"count"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// BatchEmailsRequest struct used when user request the service to read the lists of many email addresses at once
+type BatchEmailsRequest struct {
+	Emails []string `json:"emails" binding:"required"`
+}
+
+// BatchItemError struct used to report why the list of one email of a batch could not be read
+type BatchItemError struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// BatchFriendList struct used for the friend list of one email of a batch
+type BatchFriendList struct {
+	Friends []string `json:"friends"`
+	Count   int      `json:"count"`
+}
+
+// BatchFriendListResponse struct used when the service return the friend lists of many email addresses,
+// each requested email is either in Results or in Errors
+type BatchFriendListResponse struct {
+	Success bool                       `json:"success"`
+	Results map[string]BatchFriendList `json:"results"`
+	Errors  map[string]BatchItemError  `json:"errors"`
+}
+
+// BatchSubscriberList struct used for the subscriber list of one email of a batch
+type BatchSubscriberList struct {
+	Subscribers []string `json:"subscribers"`
+	Count       int      `json:"count"`
+}
+
+// BatchSubscriberListResponse struct used when the service return the subscriber lists of many email addresses,
+// each requested email is either in Results or in Errors
+type BatchSubscriberListResponse struct {
+	Success bool                           `json:"success"`
+	Results map[string]BatchSubscriberList `json:"results"`
+	Errors  map[string]BatchItemError      `json:"errors"`
+}
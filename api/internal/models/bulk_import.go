@@ -0,0 +1,48 @@
+package models
+
+// kinds of records accepted by a bulk import
+const (
+	ImportUsers         = "users"
+	ImportFriendships   = "friendships"
+	ImportSubscriptions = "subscriptions"
+	ImportBlocks        = "blocks"
+)
+
+// formats accepted by a bulk import
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// BulkImportOptions struct used to describe a bulk import
+// in dry-run mode nothing is written, in all-or-nothing mode nothing is written when one row fails,
+// otherwise the valid rows are written and the failing ones are reported
+type BulkImportOptions struct {
+	Kind            string
+	Format          string
+	DryRun          bool
+	AllOrNothing    bool
+	AutoCreateUsers bool
+}
+
+// BulkImportRowError struct used to report a row which was not imported
+type BulkImportRowError struct {
+	Line  int    `json:"line"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// BulkImportReport struct used when the service return the outcome of a bulk import
+// Imported counts the rows written, or the rows which would be written in dry-run mode
+type BulkImportReport struct {
+	Kind            string               `json:"kind"`
+	Format          string               `json:"format"`
+	DryRun          bool                 `json:"dry_run"`
+	AllOrNothing    bool                 `json:"all_or_nothing"`
+	Committed       bool                 `json:"committed"`
+	Total           int                  `json:"total"`
+	Imported        int                  `json:"imported"`
+	Failed          int                  `json:"failed"`
+	Errors          []BulkImportRowError `json:"errors"`
+	ErrorsTruncated bool                 `json:"errors_truncated,omitempty"`
+}
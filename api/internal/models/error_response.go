@@ -0,0 +1,10 @@
+package models
+
+// ErrorResponse struct used when the service returns an error
+// Error is a human readable message, Code is a stable machine-readable identifier
+// and Details carries optional structured data about the error
+type ErrorResponse struct {
+	Error   string                 `json:"error"`
+	Code    string                 `json:"code"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
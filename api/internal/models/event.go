@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// types of the domain events, see Event
+const (
+	EventUserCreated         = "UserCreated"
+	EventFriendConnected     = "FriendConnected"
+	EventSubscribed          = "Subscribed"
+	EventSubscriptionBlocked = "SubscriptionBlocked"
+	EventUpdatePosted        = "UpdatePosted"
+)
+
+// Event struct used when mapping to get a domain event from outbox table in database, as published to the sinks
+// AggregateID is the email of the user the event belongs to, the events of a user are published in the order of Sequence
+// ID is unique per event, consumers use it to drop the events delivered twice
+type Event struct {
+	ID          string          `json:"id"`
+	Sequence    int64           `json:"sequence"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	RequestID   string          `json:"request_id,omitempty"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Attempts    int             `json:"-"`
+}
+
+// UserCreatedPayload struct used as the payload of a UserCreated event
+type UserCreatedPayload struct {
+	Email string `json:"email"`
+}
+
+// FriendConnectedPayload struct used as the payload of a FriendConnected event, the aggregate is the first friend
+type FriendConnectedPayload struct {
+	Friends []string `json:"friends"`
+}
+
+// SubscriptionPayload struct used as the payload of the Subscribed and SubscriptionBlocked events, the aggregate is the requestor
+type SubscriptionPayload struct {
+	Requestor string `json:"requestor"`
+	Target    string `json:"target"`
+}
+
+// UpdatePostedPayload struct used as the payload of an UpdatePosted event, the aggregate is the sender
+// the recipients are not part of it, they can be resolved from the relationships
+type UpdatePostedPayload struct {
+	Sender string `json:"sender"`
+	Text   string `json:"text"`
+}
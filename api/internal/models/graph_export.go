@@ -0,0 +1,18 @@
+package models
+
+// formats of a graph export
+const (
+	GraphFormatGraphML = "graphml"
+	GraphFormatGEXF    = "gexf"
+	GraphFormatDOT     = "dot"
+	GraphFormatCSV     = "csv"
+)
+
+// GraphExportFilter struct used to restrict a graph export, the zero value exports the whole graph
+// Ego keeps the users reachable from Ego in at most Hops relationships, whatever their direction;
+// Domain keeps the users whose email belongs to the domain; edges are kept when both of their users are
+type GraphExportFilter struct {
+	Ego    string
+	Hops   int
+	Domain string
+}
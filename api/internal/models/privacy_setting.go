@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// values of PrivacySettings.FriendListVisibility
+const (
+	// FriendListPublic lets every user read the friend list
+	FriendListPublic = "public"
+	// FriendListFriends lets only the friends of the owner read the friend list
+	FriendListFriends = "friends"
+	// FriendListPrivate lets only the owner read the friend list
+	FriendListPrivate = "private"
+)
+
+// values of PrivacySettings.SubscriptionPolicy
+const (
+	// SubscriptionEveryone lets every user subscribe to the owner
+	SubscriptionEveryone = "everyone"
+	// SubscriptionFriendsOfFriends lets the friends of the owner and their friends subscribe to the owner
+	SubscriptionFriendsOfFriends = "friends_of_friends"
+	// SubscriptionNobody rejects every new subscription to the owner
+	SubscriptionNobody = "nobody"
+)
+
+// PrivacySettings struct used when mapping to get the privacy settings of a user from privacy_setting table in database
+// users without a row get DefaultPrivacySettings
+type PrivacySettings struct {
+	Email                string     `json:"email"`
+	FriendListVisibility string     `json:"friend_list_visibility"`
+	SubscriptionPolicy   string     `json:"subscription_policy"`
+	UpdatedAt            *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdatingPrivacySettingsRequest struct used when a user changes its privacy settings, empty fields are left unchanged
+type UpdatingPrivacySettingsRequest struct {
+	FriendListVisibility string `json:"friend_list_visibility" enums:"public,friends,private"`
+	SubscriptionPolicy   string `json:"subscription_policy" enums:"everyone,friends_of_friends,nobody"`
+}
+
+// DefaultPrivacySettings function used to get the settings of a user who never changed them: a public friend list open to every subscriber
+// pass an email as parameter
+// return a PrivacySettings model
+func DefaultPrivacySettings(email string) PrivacySettings {
+	return PrivacySettings{Email: email, FriendListVisibility: FriendListPublic, SubscriptionPolicy: SubscriptionEveryone}
+}
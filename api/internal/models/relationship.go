@@ -9,3 +9,12 @@ type Relationship struct {
 	Subscribed     bool
 	SubscribeBlock bool
 }
+
+// UserRelationships struct used when the service return every relationship of a user, grouped by kind
+type UserRelationships struct {
+	Email         string   `json:"email"`
+	Friends       []string `json:"friends"`
+	Subscribers   []string `json:"subscribers"`
+	Subscriptions []string `json:"subscriptions"`
+	Blocked       []string `json:"blocked"`
+}
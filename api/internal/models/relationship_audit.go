@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// values of RelationshipAuditEntry.Action, one per relationship write
+const (
+	AuditActionFriend      = "friend"
+	AuditActionUnfriend    = "unfriend"
+	AuditActionSubscribe   = "subscribe"
+	AuditActionUnsubscribe = "unsubscribe"
+	AuditActionBlock       = "block"
+	AuditActionUnblock     = "unblock"
+)
+
+// RelationshipFlags struct used to describe the flags of a row of relationship table at a point in time
+type RelationshipFlags struct {
+	IsFriend         bool `json:"is_friend"`
+	FriendBlocked    bool `json:"friend_blocked"`
+	Subscribed       bool `json:"subscribed"`
+	SubscribeBlocked bool `json:"subscribe_blocked"`
+}
+
+// RelationshipAuditEntry struct used when mapping to get a row of relationship_audit table in database,
+// Before is nil when the write created the relationship; Actor is the subject of the caller and is empty when the write was not authenticated
+type RelationshipAuditEntry struct {
+	ID        int64              `json:"id"`
+	Requestor string             `json:"requestor"`
+	Target    string             `json:"target"`
+	Action    string             `json:"action"`
+	Actor     string             `json:"actor,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
+	Before    *RelationshipFlags `json:"before"`
+	After     RelationshipFlags  `json:"after"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+// RelationshipAuditFilter struct used to query the audit of the relationship writes, the zero value lists the latest writes
+// Email keeps the writes where the user is the requestor or the target, From is inclusive and To exclusive,
+// Before keeps the entries older than the entry of this id, to get the next page
+type RelationshipAuditFilter struct {
+	Email  string
+	Action string
+	From   *time.Time
+	To     *time.Time
+	Before int64
+	Limit  int
+}
+
+// RelationshipAuditResponse struct used when the service return a page of the audit, newest first,
+// NextBefore is the value of the before parameter of the next page, and is omitted on the last page
+type RelationshipAuditResponse struct {
+	Entries    []RelationshipAuditEntry `json:"entries"`
+	Count      int                      `json:"count"`
+	NextBefore int64                    `json:"next_before,omitempty"`
+}
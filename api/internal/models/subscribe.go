@@ -28,8 +28,18 @@ type GetSubscribingEmailListRequest struct {
 	Text   string `json:"text"`
 }
 
+// PostingUpdateRequest struct used when a user posts an update, the sender is the user in the path
+type PostingUpdateRequest struct {
+	Text string `json:"text"`
+}
+
 // GetSubscribingEmailListResponse struct used when the service response a list of emails
 type GetSubscribingEmailListResponse struct {
 	Success    bool     `json:"success"`
 	Recipients []string `json:"recipients"`
 }
+
+// Recipient struct used for each line of a recipients stream (application/x-ndjson)
+type Recipient struct {
+	Email string `json:"email"`
+}
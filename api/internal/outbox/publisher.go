@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"golang_project/api/internal/models"
+)
+
+// Publisher interface declares a message broker client, e.g. NATS (JetStream) or Kafka:
+// subject is the NATS subject or the Kafka topic, key is the Kafka message key, which keeps the messages of a key in one partition
+type Publisher interface {
+	Publish(ctx context.Context, subject, key string, data []byte) error
+}
+
+type publisherSink struct {
+	publisher Publisher
+	prefix    string
+}
+
+// NewPublisherSink function used for initializing a Sink sending each event as a JSON message to a broker,
+// on the subject made of the prefix and the type of the event (e.g. "friend_connections.Subscribed"),
+// with the email of its user as key, so the events of a user stay in order
+// pass a Publisher and the prefix of the subjects as parameters
+// return a Sink
+func NewPublisherSink(publisher Publisher, prefix string) Sink {
+	return &publisherSink{
+		publisher: publisher,
+		prefix:    prefix,
+	}
+}
+
+// Publish function used to send an event to the broker
+// pass a context and an Event model as parameters
+// return an error type
+func (s *publisherSink) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, s.prefix+event.Type, event.AggregateID, data)
+}
@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/resilience"
+)
+
+// Relay struct publishes the events of outbox table to a Sink, at least once and in order per user:
+// an event is marked as published only once the sink accepted it, and when the sink fails, the event and the next events
+// of its user wait for a retry while the events of the other users go on
+// a relay claims a batch for a lease in a short transaction and publishes it outside of any transaction,
+// so a slow sink neither holds a connection nor stops the relays of the other replicas, which claim the events of the other users
+type Relay struct {
+	repo      repositories.OutboxRepository
+	sink      Sink
+	batchSize int
+	interval  time.Duration
+	lease     time.Duration
+	retries   resilience.Backoff
+}
+
+// NewRelay function used for initializing a Relay
+// pass an OutboxRepository, a Sink, the maximum number of events published per batch, the interval between two polls of an empty outbox,
+// how long a batch is leased to the relay, longer than the publication of an event, and the delays between the retries of an event as parameters
+// return a pointer of Relay
+func NewRelay(repo repositories.OutboxRepository, sink Sink, batchSize int, interval, lease time.Duration, retries resilience.Backoff) *Relay {
+	return &Relay{
+		repo:      repo,
+		sink:      sink,
+		batchSize: batchSize,
+		interval:  interval,
+		lease:     lease,
+		retries:   retries,
+	}
+}
+
+// Run function used to publish the events until the context is done, the next batch is published at once when a batch was full
+// pass a context as parameter
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		published, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("cannot relay the outbox", slog.Any("error", err))
+		}
+		if published < r.batchSize || err != nil {
+			timer := time.NewTimer(r.interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// RelayOnce function used to claim a batch of pending events and to publish it, nothing is claimed while another relay is claiming
+// the events not published before the end of the lease are released to the next claim, with the next events of their users
+// pass a context as parameter
+// return the number of events published and an error type
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.repo.Claim(ctx, r.batchSize, r.lease)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, r.lease)
+	defer cancel()
+	var published []int64
+	var failures []repositories.OutboxFailure
+	var released []int64
+	waiting := map[string]bool{}
+	for _, event := range events {
+		if publishCtx.Err() != nil || waiting[event.AggregateID] {
+			released = append(released, event.Sequence)
+			continue
+		}
+		if err := r.sink.Publish(publishCtx, event); err != nil {
+			if publishCtx.Err() != nil {
+				released = append(released, event.Sequence)
+				continue
+			}
+			waiting[event.AggregateID] = true
+			retryAfter := r.retries.Delay(event.Attempts + 1)
+			Stats.Add("failures", 1)
+			slog.Warn("cannot publish an event, it is retried later with the next events of its user",
+				slog.String("event_id", event.ID), slog.String("type", event.Type), slog.String("aggregate_id", event.AggregateID),
+				slog.Int("attempts", event.Attempts+1), slog.Duration("retry_after", retryAfter), slog.Any("error", err))
+			failures = append(failures, repositories.OutboxFailure{Sequence: event.Sequence, Reason: err.Error(), RetryAfter: retryAfter})
+			continue
+		}
+		published = append(published, event.Sequence)
+	}
+
+	// the outcome is recorded even when the relay stops, so the published events are not published again
+	completeCtx, cancelComplete := context.WithTimeout(context.WithoutCancel(ctx), r.lease)
+	defer cancelComplete()
+	if err := r.repo.Complete(completeCtx, published, failures, released); err != nil {
+		return 0, err
+	}
+	Stats.Add("published", int64(len(published)))
+	return len(published), nil
+}
@@ -0,0 +1,253 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/resilience"
+)
+
+func TestRelayPublishesInOrderPerUserAndRetriesFailures(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{
+		{Sequence: 1, AggregateID: "a@example.com", Type: models.EventSubscribed},
+		{Sequence: 2, AggregateID: "b@example.com", Type: models.EventSubscribed},
+		{Sequence: 3, AggregateID: "a@example.com", Type: models.EventSubscriptionBlocked},
+		{Sequence: 4, AggregateID: "b@example.com", Type: models.EventSubscriptionBlocked},
+	}}
+	sink := &sinkFake{failures: map[int64]int{1: 1}}
+	relay := NewRelay(repo, sink, 10, time.Millisecond, time.Minute, resilience.Backoff{Base: time.Millisecond})
+
+	// the events of a@example.com wait for the retry of the first one, the events of b@example.com go on
+	published, err := relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{1, 2, 4}, sink.attempts)
+	assert.Equal(t, 1, repo.events[0].Attempts)
+
+	published, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{1, 2, 4, 1, 3}, sink.attempts)
+	assert.Equal(t, []int64{2, 4, 1, 3}, sink.published)
+
+	published, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestRelayPublishesAgainTheEventsOfAnUnrecordedBatch(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{{Sequence: 1, AggregateID: "a@example.com"}}, completeErr: errors.New("connection lost")}
+	sink := &sinkFake{}
+	relay := NewRelay(repo, sink, 10, time.Millisecond, time.Minute, resilience.Backoff{})
+
+	_, err := relay.RelayOnce(context.Background())
+	assert.NotNil(t, err)
+	// nothing is claimed until the lease ends
+	published, err := relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+
+	repo.endLeases()
+	repo.completeErr = nil
+	published, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []int64{1, 1}, sink.published)
+}
+
+func TestRelayLeavesTheEventsNotPublishedBeforeTheEndOfTheLease(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{{Sequence: 1, AggregateID: "a@example.com"}, {Sequence: 2, AggregateID: "b@example.com"}}}
+	sink := &sinkFake{block: map[int64]chan struct{}{1: make(chan struct{})}}
+
+	published, err := NewRelay(repo, sink, 10, time.Millisecond, time.Millisecond, resilience.Backoff{}).RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+	assert.Equal(t, []int64{1}, sink.attempts)
+	// the publication cut by the lease is not a failure, both events are released to the next claim
+	assert.Equal(t, 0, repo.events[0].Attempts)
+	events, err := repo.Claim(context.Background(), 10, time.Minute)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestRelaysShareTheOutboxWhileASinkIsSlow(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{
+		{Sequence: 1, AggregateID: "a@example.com"},
+		{Sequence: 2, AggregateID: "a@example.com"},
+	}}
+	release := make(chan struct{})
+	started := make(chan int64, 2)
+	slow := &sinkFake{block: map[int64]chan struct{}{1: release}, started: started}
+	done := make(chan int)
+	go func() {
+		published, _ := NewRelay(repo, slow, 10, time.Millisecond, time.Minute, resilience.Backoff{}).RelayOnce(context.Background())
+		done <- published
+	}()
+	assert.Equal(t, int64(1), <-started)
+
+	// while the first relay publishes, a second one claims the events of the other users, never the next events of a@example.com
+	repo.add(models.Event{Sequence: 3, AggregateID: "b@example.com"})
+	fast := &sinkFake{}
+	published, err := NewRelay(repo, fast, 10, time.Millisecond, time.Minute, resilience.Backoff{}).RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []int64{3}, fast.published)
+
+	close(release)
+	assert.Equal(t, 2, <-done)
+	assert.Equal(t, []int64{1, 2}, slow.published)
+}
+
+func TestRelayWaitsWhileAnotherRelayClaims(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{{Sequence: 1, AggregateID: "a@example.com"}}, busy: true}
+	sink := &sinkFake{}
+
+	published, err := NewRelay(repo, sink, 10, time.Millisecond, time.Minute, resilience.Backoff{}).RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+	assert.Empty(t, sink.attempts)
+}
+
+func TestRelayRunStopsWithItsContext(t *testing.T) {
+	repo := &outboxRepoFake{events: []models.Event{{Sequence: 1, AggregateID: "a@example.com"}}}
+	sink := &sinkFake{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRelay(repo, sink, 10, time.Millisecond, time.Minute, resilience.Backoff{}).Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return repo.publishedCount() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the relay did not stop")
+	}
+}
+
+// outboxRepoFake keeps the events in memory, the claimed events are leased until their outcome is recorded
+// and the events marked as failed are pending again at once
+type outboxRepoFake struct {
+	events      []models.Event
+	published   map[int64]bool
+	leased      map[int64]bool
+	busy        bool
+	completeErr error
+	mu          sync.Mutex
+}
+
+func (r *outboxRepoFake) Append(ctx context.Context, eventType, aggregateID string, payload interface{}) error {
+	return nil
+}
+
+func (r *outboxRepoFake) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.busy {
+		return nil, nil
+	}
+	if r.leased == nil {
+		r.leased = map[int64]bool{}
+	}
+	var events []models.Event
+	held := map[string]bool{}
+	for _, event := range r.events {
+		if r.published[event.Sequence] {
+			continue
+		}
+		if r.leased[event.Sequence] {
+			held[event.AggregateID] = true
+		}
+		if held[event.AggregateID] || len(events) == limit {
+			continue
+		}
+		events = append(events, event)
+	}
+	for _, event := range events {
+		r.leased[event.Sequence] = true
+	}
+	return events, nil
+}
+
+func (r *outboxRepoFake) Complete(ctx context.Context, published []int64, failures []repositories.OutboxFailure, released []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.completeErr != nil {
+		return r.completeErr
+	}
+	if r.published == nil {
+		r.published = map[int64]bool{}
+	}
+	for _, sequence := range published {
+		r.published[sequence] = true
+		delete(r.leased, sequence)
+	}
+	for _, failure := range failures {
+		for i := range r.events {
+			if r.events[i].Sequence == failure.Sequence {
+				r.events[i].Attempts++
+			}
+		}
+		delete(r.leased, failure.Sequence)
+	}
+	for _, sequence := range released {
+		delete(r.leased, sequence)
+	}
+	return nil
+}
+
+func (r *outboxRepoFake) add(event models.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *outboxRepoFake) endLeases() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leased = nil
+}
+
+func (r *outboxRepoFake) publishedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.published)
+}
+
+// sinkFake records the publications, fails the events of failures as many times as requested,
+// and holds the events of block until their channel is closed or the context is done
+type sinkFake struct {
+	failures  map[int64]int
+	block     map[int64]chan struct{}
+	started   chan int64
+	attempts  []int64
+	published []int64
+}
+
+func (s *sinkFake) Publish(ctx context.Context, event models.Event) error {
+	s.attempts = append(s.attempts, event.Sequence)
+	if s.started != nil {
+		s.started <- event.Sequence
+	}
+	if release, ok := s.block[event.Sequence]; ok {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if s.failures[event.Sequence] > 0 {
+		s.failures[event.Sequence]--
+		return errors.New("unavailable")
+	}
+	s.published = append(s.published, event.Sequence)
+	return nil
+}
@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+
+	"golang_project/api/internal/models"
+)
+
+// Stats counts the events published and the failed publications, it is exported as the outbox variable of /debug/vars
+var Stats = expvar.NewMap("outbox")
+
+// Sink interface declares where the Relay publishes the domain events, an event is published again until Publish succeeds,
+// so a sink may receive an event more than once and should be idempotent
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+type logSink struct {
+	logger *slog.Logger
+}
+
+// NewLogSink function used for initializing a Sink writing one structured log line per event,
+// the lines carry event=true so they can be routed to a dedicated sink
+// pass a pointer of slog.Logger as parameter
+// return a Sink
+func NewLogSink(logger *slog.Logger) Sink {
+	return &logSink{
+		logger: logger,
+	}
+}
+
+// Publish function used to write an event to the log
+// pass a context and an Event model as parameters
+// return an error type, always nil
+func (s *logSink) Publish(ctx context.Context, event models.Event) error {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "domain event",
+		slog.Bool("event", true),
+		slog.String("event_id", event.ID),
+		slog.Int64("sequence", event.Sequence),
+		slog.String("type", event.Type),
+		slog.String("aggregate_id", event.AggregateID),
+		slog.String("payload", string(event.Payload)),
+		slog.String("request_id", event.RequestID),
+		slog.Time("occurred_at", event.OccurredAt))
+	return nil
+}
+
+type multiSink []Sink
+
+// NewMultiSink function used for initializing a Sink publishing every event to each of the given sinks, in order
+// an event is published again to every sink when one of them fails
+// pass the sinks as parameters
+// return a Sink
+func NewMultiSink(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return multiSink(sinks)
+}
+
+// Publish function used to publish an event to each sink
+// pass a context and an Event model as parameters
+// return an error type, joining the errors of the sinks which failed
+func (s multiSink) Publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+)
+
+var testEvent = models.Event{
+	ID: "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", Sequence: 7, Type: models.EventSubscribed, AggregateID: "a@example.com",
+	Payload: json.RawMessage(`{"requestor":"a@example.com","target":"b@example.com"}`), OccurredAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestWebhookSinkPostsSignedEvents(t *testing.T) {
+	var body []byte
+	var header http.Header
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	sink := NewWebhookSink(server.URL, "secret", server.Client())
+
+	assert.Nil(t, sink.Publish(context.Background(), testEvent))
+	assert.JSONEq(t, `{"id":"0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10","sequence":7,"type":"Subscribed","aggregate_id":"a@example.com",
+	"payload":{"requestor":"a@example.com","target":"b@example.com"},"occurred_at":"2024-01-01T00:00:00Z"}`, string(body))
+	assert.Equal(t, testEvent.ID, header.Get(EventIDHeader))
+	assert.Equal(t, "Subscribed", header.Get(EventTypeHeader))
+	assert.Equal(t, Sign([]byte("secret"), body), header.Get(SignatureHeader))
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, sink.Publish(context.Background(), testEvent), "webhook answered 503")
+}
+
+func TestPublisherSinkKeysTheMessagesByUser(t *testing.T) {
+	publisher := &publisherFake{}
+	assert.Nil(t, NewPublisherSink(publisher, "friend_connections.").Publish(context.Background(), testEvent))
+	assert.Equal(t, "friend_connections.Subscribed", publisher.subject)
+	assert.Equal(t, "a@example.com", publisher.key)
+
+	var event models.Event
+	assert.Nil(t, json.Unmarshal(publisher.data, &event))
+	assert.Equal(t, testEvent, event)
+}
+
+func TestMultiSinkPublishesToEverySink(t *testing.T) {
+	first, second := &sinkFake{failures: map[int64]int{7: 1}}, &sinkFake{}
+	sink := NewMultiSink(first, second)
+
+	assert.NotNil(t, sink.Publish(context.Background(), testEvent))
+	assert.Equal(t, []int64{7}, second.published)
+	assert.Nil(t, sink.Publish(context.Background(), testEvent))
+	assert.Equal(t, []int64{7}, first.published)
+}
+
+type publisherFake struct {
+	subject, key string
+	data         []byte
+}
+
+func (p *publisherFake) Publish(ctx context.Context, subject, key string, data []byte) error {
+	if p.subject != "" {
+		return errors.New("published twice")
+	}
+	p.subject, p.key, p.data = subject, key, data
+	return nil
+}
@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang_project/api/internal/models"
+)
+
+// headers of a webhook request
+const (
+	EventIDHeader   = "X-Event-ID"
+	EventTypeHeader = "X-Event-Type"
+	SignatureHeader = "X-Signature"
+)
+
+type webhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink function used for initializing a Sink posting each event as JSON to a URL,
+// the event is published when the endpoint answers with a 2xx status; with a secret,
+// the X-Signature header holds "sha256=" and the hex HMAC-SHA256 of the body, for the endpoint to check it
+// pass the URL, the secret (empty to skip the signature) and the HTTP client as parameters
+// return a Sink
+func NewWebhookSink(url, secret string, client *http.Client) Sink {
+	return &webhookSink{
+		url:    url,
+		secret: []byte(secret),
+		client: client,
+	}
+}
+
+// Publish function used to post an event to the webhook
+// pass a context and an Event model as parameters
+// return an error type
+func (s *webhookSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, event.ID)
+	req.Header.Set(EventTypeHeader, event.Type)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign function used to compute the X-Signature header of a webhook body
+// pass the secret and the body as parameters
+// return the signature, "sha256=" and the hex HMAC-SHA256 of the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
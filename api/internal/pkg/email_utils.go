@@ -1,9 +1,17 @@
 package pkg
 
 import (
-	"errors"
 	"regexp"
 	"strings"
+
+	"golang_project/api/internal/apperrors"
+)
+
+var (
+	// ErrEmptyEmail is returned when no email address is provided
+	ErrEmptyEmail = apperrors.Validation("empty_email", "email address is empty")
+	// ErrInvalidEmail is returned when an email address has an invalid format
+	ErrInvalidEmail = apperrors.Validation("invalid_email", "invalid email address")
 )
 
 // CheckValidEmails used for checking an array of email address parameter are valid or not
@@ -11,11 +19,11 @@ import (
 // return an error type
 func CheckValidEmails(emails []string) error {
 	if emails == nil || len(emails) == 0 {
-		return errors.New("email address is empty")
+		return ErrEmptyEmail
 	}
 	for _, email := range emails {
 		if CheckValidEmail(email) != nil {
-			return errors.New("invalid email address")
+			return ErrInvalidEmail
 		}
 	}
 
@@ -28,7 +36,7 @@ func CheckValidEmails(emails []string) error {
 func CheckValidEmail(email string) error {
 	emailRegex := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 	if strings.TrimSpace(email) == "" || !emailRegex.MatchString(email) {
-		return errors.New("invalid email address")
+		return ErrInvalidEmail
 	}
 
 	return nil
@@ -0,0 +1,33 @@
+package policies
+
+import (
+	"context"
+	"log/slog"
+)
+
+type logAuditLog struct {
+	logger *slog.Logger
+}
+
+// NewLogAuditLog function used for initializing an AuditLog writing one structured log line per denial,
+// the lines carry audit=true so they can be routed to a dedicated sink
+// pass a pointer of slog.Logger as parameter
+// return an AuditLog
+func NewLogAuditLog(logger *slog.Logger) AuditLog {
+	return &logAuditLog{
+		logger: logger,
+	}
+}
+
+// RecordDenial function used to write a denial to the log
+// pass a context and a Denial as parameters
+func (a *logAuditLog) RecordDenial(ctx context.Context, denial Denial) {
+	a.logger.LogAttrs(ctx, slog.LevelWarn, "authorization denied",
+		slog.Bool("audit", true),
+		slog.Time("denied_at", denial.Time),
+		slog.String("request_id", denial.RequestID),
+		slog.String("subject", denial.Subject),
+		slog.Any("roles", denial.Roles),
+		slog.String("action", string(denial.Action)),
+		slog.Any("emails", denial.Emails))
+}
@@ -0,0 +1,237 @@
+package policies
+
+import (
+	"context"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+)
+
+type friendConnectionService struct {
+	next   services.FriendConnectionService
+	policy *Policy
+}
+
+// NewFriendConnectionService function used for initializing a FriendConnectionService which authorizes every call
+// with the policy before delegating it, so that the controllers, GraphQL and gRPC share the same rules
+// pass the guarded FriendConnectionService and a pointer of Policy as parameters
+// return a FriendConnectionService
+func NewFriendConnectionService(next services.FriendConnectionService, policy *Policy) services.FriendConnectionService {
+	return &friendConnectionService{
+		next:   next,
+		policy: policy,
+	}
+}
+
+// authorize function checks the emails before the policy, so that malformed requests keep failing with a validation error
+func (svc *friendConnectionService) authorize(ctx context.Context, action Action, emails ...string) error {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return err
+	}
+	return svc.policy.Authorize(ctx, action, emails...)
+}
+
+// CreateUser function authorizes the create_user action for the email of the new user
+func (svc *friendConnectionService) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.CreatingUserResponse, error) {
+	if err := svc.authorize(ctx, ActionCreateUser, request.Email); err != nil {
+		return models.CreatingUserResponse{}, err
+	}
+	return svc.next.CreateUser(ctx, request)
+}
+
+// CreateConnection function authorizes the add_friend action for both friends
+func (svc *friendConnectionService) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := svc.authorize(ctx, ActionAddFriend, request.Friends...); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	return svc.next.CreateConnection(ctx, request)
+}
+
+// RemoveConnection function authorizes the remove_friend action for both friends
+func (svc *friendConnectionService) RemoveConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := svc.authorize(ctx, ActionRemoveFriend, request.Friends...); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	return svc.next.RemoveConnection(ctx, request)
+}
+
+// GetFriendConnection function authorizes the read_friends action for the owner of the friend list
+func (svc *friendConnectionService) GetFriendConnection(ctx context.Context, request models.FriendListRequest) (models.FriendListResponse, error) {
+	if err := svc.authorize(ctx, ActionReadFriends, request.Email); err != nil {
+		return models.FriendListResponse{}, err
+	}
+	return svc.next.GetFriendConnection(ctx, request)
+}
+
+// ShowCommonFriendList function authorizes the read_common_friends action for both users
+func (svc *friendConnectionService) ShowCommonFriendList(ctx context.Context, request models.CommonFriendListRequest) (models.CommonFriendListResponse, error) {
+	if err := svc.authorize(ctx, ActionReadCommonFriends, request.Friends...); err != nil {
+		return models.CommonFriendListResponse{}, err
+	}
+	return svc.next.ShowCommonFriendList(ctx, request)
+}
+
+// SubscribeFromEmail function authorizes the subscribe action for the requestor
+func (svc *friendConnectionService) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if err := svc.authorize(ctx, ActionSubscribe, request.Requestor); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+	return svc.next.SubscribeFromEmail(ctx, request)
+}
+
+// UnsubscribeFromEmail function authorizes the unsubscribe action for the requestor
+func (svc *friendConnectionService) UnsubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if err := svc.authorize(ctx, ActionUnsubscribe, request.Requestor); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+	return svc.next.UnsubscribeFromEmail(ctx, request)
+}
+
+// BlockSubscribeByEmail function authorizes the block action for the requestor
+func (svc *friendConnectionService) BlockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+	if err := svc.authorize(ctx, ActionBlock, request.Requestor); err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+	return svc.next.BlockSubscribeByEmail(ctx, request)
+}
+
+// UnblockSubscribeByEmail function authorizes the unblock action for the requestor
+func (svc *friendConnectionService) UnblockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+	if err := svc.authorize(ctx, ActionUnblock, request.Requestor); err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+	return svc.next.UnblockSubscribeByEmail(ctx, request)
+}
+
+// GetSubscribingEmailListByEmail function authorizes the read_recipients action for the sender
+func (svc *friendConnectionService) GetSubscribingEmailListByEmail(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+	if err := svc.authorize(ctx, ActionReadRecipients, request.Sender); err != nil {
+		return models.GetSubscribingEmailListResponse{}, err
+	}
+	return svc.next.GetSubscribingEmailListByEmail(ctx, request)
+}
+
+// StreamRecipients function authorizes the read_recipients action for the sender
+func (svc *friendConnectionService) StreamRecipients(ctx context.Context, request models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	if err := svc.authorize(ctx, ActionReadRecipients, request.Sender); err != nil {
+		return err
+	}
+	return svc.next.StreamRecipients(ctx, request, fn)
+}
+
+// PostUpdate function authorizes the post_update action for the sender
+func (svc *friendConnectionService) PostUpdate(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+	if err := svc.authorize(ctx, ActionPostUpdate, request.Sender); err != nil {
+		return models.GetSubscribingEmailListResponse{}, err
+	}
+	return svc.next.PostUpdate(ctx, request)
+}
+
+// GetUserRelationships function authorizes the read_relationships action for each email separately,
+// the whole batch is rejected when one of them is denied
+func (svc *friendConnectionService) GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error) {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if err := svc.policy.Authorize(ctx, ActionReadRelationships, email); err != nil {
+			return nil, err
+		}
+	}
+	return svc.next.GetUserRelationships(ctx, emails)
+}
+
+// GetFriendListsByEmails function authorizes the read_friend_lists action for each email separately,
+// the denied emails are reported in Errors instead of rejecting the whole batch, and an empty batch is rejected by the service
+func (svc *friendConnectionService) GetFriendListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchFriendListResponse, error) {
+	emails, denied, err := svc.authorizeEach(ctx, ActionReadFriendLists, request.Emails)
+	if err != nil {
+		return models.BatchFriendListResponse{}, err
+	}
+	if len(emails) == 0 && len(denied) > 0 {
+		return models.BatchFriendListResponse{Success: true, Results: map[string]models.BatchFriendList{}, Errors: denied}, nil
+	}
+
+	response, err := svc.next.GetFriendListsByEmails(ctx, models.BatchEmailsRequest{Emails: emails})
+	if err != nil {
+		return models.BatchFriendListResponse{}, err
+	}
+	response.Errors = mergeBatchErrors(response.Errors, denied)
+	return response, nil
+}
+
+// GetSubscriberListsByEmails function authorizes the read_subscribers action for each email separately,
+// the denied emails are reported in Errors instead of rejecting the whole batch, and an empty batch is rejected by the service
+func (svc *friendConnectionService) GetSubscriberListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchSubscriberListResponse, error) {
+	emails, denied, err := svc.authorizeEach(ctx, ActionReadSubscribers, request.Emails)
+	if err != nil {
+		return models.BatchSubscriberListResponse{}, err
+	}
+	if len(emails) == 0 && len(denied) > 0 {
+		return models.BatchSubscriberListResponse{Success: true, Results: map[string]models.BatchSubscriberList{}, Errors: denied}, nil
+	}
+
+	response, err := svc.next.GetSubscriberListsByEmails(ctx, models.BatchEmailsRequest{Emails: emails})
+	if err != nil {
+		return models.BatchSubscriberListResponse{}, err
+	}
+	response.Errors = mergeBatchErrors(response.Errors, denied)
+	return response, nil
+}
+
+// GetPrivacySettings function authorizes the read_privacy_settings action for the owner of the settings
+func (svc *friendConnectionService) GetPrivacySettings(ctx context.Context, email string) (models.PrivacySettings, error) {
+	if err := svc.authorize(ctx, ActionReadPrivacy, email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return svc.next.GetPrivacySettings(ctx, email)
+}
+
+// UpdatePrivacySettings function authorizes the update_privacy_settings action for the owner of the settings
+func (svc *friendConnectionService) UpdatePrivacySettings(ctx context.Context, email string, request models.UpdatingPrivacySettingsRequest) (models.PrivacySettings, error) {
+	if err := svc.authorize(ctx, ActionUpdatePrivacy, email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return svc.next.UpdatePrivacySettings(ctx, email, request)
+}
+
+// authorizeEach function authorizes an action for each email of a batch read,
+// the malformed emails and the batches too large to be served are left to the service layer, which reports them;
+// a missing principal or a failing check rejects the whole batch
+func (svc *friendConnectionService) authorizeEach(ctx context.Context, action Action, emails []string) ([]string, map[string]models.BatchItemError, error) {
+	emails = pkg.RemoveDuplicatedItems(emails)
+	if len(emails) == 0 || len(emails) > services.MaxBatchEmails {
+		return emails, map[string]models.BatchItemError{}, nil
+	}
+
+	var allowed []string
+	denied := make(map[string]models.BatchItemError)
+	for _, email := range emails {
+		if pkg.CheckValidEmail(email) != nil {
+			allowed = append(allowed, email)
+			continue
+		}
+		if err := svc.policy.Authorize(ctx, action, email); err != nil {
+			if apperrors.KindOf(err) != apperrors.KindForbidden {
+				return nil, nil, err
+			}
+			denied[email] = services.NewBatchItemError(err)
+			continue
+		}
+		allowed = append(allowed, email)
+	}
+	return allowed, denied, nil
+}
+
+// mergeBatchErrors function used to add the denials of the policy to the errors reported by the service layer
+func mergeBatchErrors(errs, denied map[string]models.BatchItemError) map[string]models.BatchItemError {
+	if errs == nil {
+		errs = make(map[string]models.BatchItemError, len(denied))
+	}
+	for email, err := range denied {
+		errs[email] = err
+	}
+	return errs
+}
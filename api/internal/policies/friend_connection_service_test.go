@@ -0,0 +1,108 @@
+package policies
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/repositories"
+	"golang_project/api/internal/services"
+)
+
+func TestServiceRejectsActingForSomeoneElse(t *testing.T) {
+	next := &serviceFake{}
+	policy, _ := New(DefaultRules)
+	svc := NewFriendConnectionService(next, policy)
+	user := userContext("thehaohcm@yahoo.com.vn")
+
+	_, err := svc.SubscribeFromEmail(user, models.SubscribeRequest{Requestor: "hao.nguyen@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	_, err = svc.BlockSubscribeByEmail(user, models.BlockSubscribeRequest{Requestor: "hao.nguyen@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	_, err = svc.GetUserRelationships(user, []string{"thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, next.calls)
+
+	result, err := svc.SubscribeFromEmail(user, models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestServiceValidatesBeforeAuthorizing(t *testing.T) {
+	policy, _ := New(DefaultRules)
+	svc := NewFriendConnectionService(&serviceFake{}, policy)
+
+	_, err := svc.CreateConnection(userContext("thehaohcm@yahoo.com.vn"), models.FriendConnectionRequest{})
+	assert.Equal(t, pkg.ErrEmptyEmail, err)
+}
+
+func TestServiceReportsBatchDenialsPerEmail(t *testing.T) {
+	next := &serviceFake{}
+	policy, _ := New(Rules{ActionReadFriendLists: {ConditionSelf}})
+	svc := NewFriendConnectionService(next, policy)
+
+	result, err := svc.GetFriendListsByEmails(userContext("thehaohcm@yahoo.com.vn"), models.BatchEmailsRequest{Emails: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thehaohcm@yahoo.com.vn"}, next.emails)
+	assert.Equal(t, map[string]models.BatchItemError{
+		"hao.nguyen@s3corp.com.vn": {Code: "forbidden", Error: "you are not allowed to perform this action"},
+	}, result.Errors)
+
+	result, err = svc.GetFriendListsByEmails(userContext("thehaohcm@yahoo.com.vn"), models.BatchEmailsRequest{Emails: []string{"hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+	assert.True(t, result.Success)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 1, next.calls)
+
+	_, err = svc.GetSubscriberListsByEmails(context.Background(), models.BatchEmailsRequest{Emails: []string{"hao.nguyen@s3corp.com.vn"}})
+	assert.ErrorIs(t, err, apperrors.ErrUnauthenticated)
+}
+
+func TestServiceLeavesEmptyBatchesToTheService(t *testing.T) {
+	policy, _ := New(DefaultRules)
+	svc := NewFriendConnectionService(services.New(repositories.NewMemoryRepository()), policy)
+	user := userContext("thehaohcm@yahoo.com.vn")
+
+	_, err := svc.GetFriendListsByEmails(user, models.BatchEmailsRequest{Emails: []string{}})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+	_, err = svc.GetSubscriberListsByEmails(user, models.BatchEmailsRequest{Emails: []string{}})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+// serviceFake counts the delegated calls, the methods which are not overridden are not expected to be called
+func TestServiceAuthorizesPostingApartFromReadingRecipients(t *testing.T) {
+	policy, _ := New(Rules{ActionReadRecipients: {ConditionAuthenticated}, ActionPostUpdate: {ConditionSelf}})
+	svc := NewFriendConnectionService(services.New(repositories.NewMemoryRepository()), policy)
+	user := userContext("thehaohcm@yahoo.com.vn")
+
+	_, err := svc.PostUpdate(user, models.GetSubscribingEmailListRequest{Sender: "hao.nguyen@s3corp.com.vn", Text: "hi"})
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	_, err = svc.GetSubscribingEmailListByEmail(user, models.GetSubscribingEmailListRequest{Sender: "hao.nguyen@s3corp.com.vn", Text: "hi"})
+	assert.NotErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+type serviceFake struct {
+	services.FriendConnectionService
+	calls  int
+	emails []string
+}
+
+func (s *serviceFake) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	s.calls++
+	return models.SubscribeResponse{Success: true}, nil
+}
+
+func (s *serviceFake) GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error) {
+	s.calls++
+	return map[string]models.UserRelationships{}, nil
+}
+
+func (s *serviceFake) GetFriendListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchFriendListResponse, error) {
+	s.calls++
+	s.emails = request.Emails
+	return models.BatchFriendListResponse{Success: true, Results: map[string]models.BatchFriendList{}}, nil
+}
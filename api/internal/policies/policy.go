@@ -0,0 +1,245 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+)
+
+// Action names an operation guarded by the policy
+type Action string
+
+// actions of the FriendConnectionService
+const (
+	ActionCreateUser        Action = "create_user"
+	ActionAddFriend         Action = "add_friend"
+	ActionRemoveFriend      Action = "remove_friend"
+	ActionReadFriends       Action = "read_friends"
+	ActionReadCommonFriends Action = "read_common_friends"
+	ActionSubscribe         Action = "subscribe"
+	ActionUnsubscribe       Action = "unsubscribe"
+	ActionBlock             Action = "block"
+	ActionUnblock           Action = "unblock"
+	ActionReadRecipients    Action = "read_recipients"
+	ActionPostUpdate        Action = "post_update"
+	ActionReadRelationships Action = "read_relationships"
+	ActionReadFriendLists   Action = "read_friend_lists"
+	ActionReadSubscribers   Action = "read_subscribers"
+	ActionReadPrivacy       Action = "read_privacy_settings"
+	ActionUpdatePrivacy     Action = "update_privacy_settings"
+)
+
+// conditions which can be listed in Rules, a rule grants the action when any of its conditions holds
+const (
+	// ConditionSelf holds when the email of the principal is one of the emails the action is performed for
+	ConditionSelf = "self"
+	// ConditionVisible holds when the friend lists of every email are visible to the principal
+	ConditionVisible = "visible"
+	// ConditionAuthenticated holds for any authenticated principal
+	ConditionAuthenticated = "authenticated"
+	// ConditionRolePrefix is followed by a role name, e.g. "role:admin", and holds when the principal has this role
+	ConditionRolePrefix = "role:"
+)
+
+// Rules type maps each action to the conditions granting it, an action without rule is always denied
+type Rules map[Action][]string
+
+// DefaultRules are the rules applied when no policy file overrides them:
+// users act only as themselves, friend lists are readable when visible, admins and service accounts may act for anyone;
+// read_relationships and read_friend_lists only need authentication because the service layer hides the friend lists which are not visible
+var DefaultRules = Rules{
+	ActionCreateUser:        {"role:admin", "role:service", ConditionSelf},
+	ActionAddFriend:         {"role:admin", "role:service", ConditionSelf},
+	ActionRemoveFriend:      {"role:admin", "role:service", ConditionSelf},
+	ActionReadFriends:       {"role:admin", "role:service", ConditionSelf, ConditionVisible},
+	ActionReadCommonFriends: {"role:admin", "role:service", ConditionSelf, ConditionVisible},
+	ActionSubscribe:         {"role:admin", "role:service", ConditionSelf},
+	ActionUnsubscribe:       {"role:admin", "role:service", ConditionSelf},
+	ActionBlock:             {"role:admin", "role:service", ConditionSelf},
+	ActionUnblock:           {"role:admin", "role:service", ConditionSelf},
+	ActionReadRecipients:    {"role:admin", "role:service", ConditionSelf},
+	ActionPostUpdate:        {"role:admin", "role:service", ConditionSelf},
+	ActionReadRelationships: {ConditionAuthenticated},
+	ActionReadFriendLists:   {ConditionAuthenticated},
+	ActionReadSubscribers:   {ConditionAuthenticated},
+	ActionReadPrivacy:       {"role:admin", "role:service", ConditionSelf},
+	ActionUpdatePrivacy:     {"role:admin", "role:service", ConditionSelf},
+}
+
+// Visibility interface is consulted by the "visible" condition to know whether a viewer may read the friend list of an owner
+type Visibility interface {
+	CanViewFriends(ctx context.Context, viewer, owner string) (bool, error)
+}
+
+// Denial struct describes a request rejected by the policy, as written to the audit trail
+type Denial struct {
+	Time      time.Time
+	RequestID string
+	Subject   string
+	Roles     []string
+	Action    Action
+	Emails    []string
+}
+
+// AuditLog interface records the denials of the policy
+type AuditLog interface {
+	RecordDenial(ctx context.Context, denial Denial)
+}
+
+// Policy struct evaluates Rules against the principal of a request
+type Policy struct {
+	rules      Rules
+	visibility Visibility
+	audit      AuditLog
+}
+
+// Option type used to customize a Policy when calling New
+type Option func(*Policy)
+
+// WithVisibility function used to set the Visibility consulted by the "visible" condition,
+// without it every friend list is visible
+// pass a Visibility as parameter
+// return an Option
+func WithVisibility(visibility Visibility) Option {
+	return func(p *Policy) {
+		p.visibility = visibility
+	}
+}
+
+// WithAuditLog function used to set where the denials are recorded, without it they are not recorded
+// pass an AuditLog as parameter
+// return an Option
+func WithAuditLog(audit AuditLog) Option {
+	return func(p *Policy) {
+		p.audit = audit
+	}
+}
+
+// New function used for initializing a Policy
+// pass Rules and optional Options as parameters
+// return a pointer of Policy and an error type when a rule names an unknown action or condition
+func New(rules Rules, opts ...Option) (*Policy, error) {
+	for action, conditions := range rules {
+		if _, ok := DefaultRules[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		for _, condition := range conditions {
+			if err := checkCondition(condition); err != nil {
+				return nil, fmt.Errorf("action %q: %w", action, err)
+			}
+		}
+	}
+
+	policy := &Policy{rules: rules}
+	for _, opt := range opts {
+		opt(policy)
+	}
+	return policy, nil
+}
+
+// LoadRules function used to read rules from a JSON file, e.g. {"read_friends": ["role:admin", "self"]},
+// the actions missing from the file keep their DefaultRules
+// pass the path of the file as parameter
+// return Rules and an error type
+func LoadRules(path string) (Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides Rules
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+
+	rules := make(Rules, len(DefaultRules))
+	for action, conditions := range DefaultRules {
+		rules[action] = conditions
+	}
+	for action, conditions := range overrides {
+		rules[action] = conditions
+	}
+	return rules, nil
+}
+
+// Authorize function used to check whether the principal of the context may perform an action for the given emails
+// pass a context, an Action and the emails the action is performed for as parameters
+// return an error type, of KindUnauthenticated without principal and of KindForbidden when denied
+func (p *Policy) Authorize(ctx context.Context, action Action, emails ...string) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return apperrors.Unauthenticated("missing_credentials", "missing credentials")
+	}
+
+	for _, condition := range p.rules[action] {
+		granted, err := p.holds(ctx, condition, principal, emails)
+		if err != nil {
+			return err
+		}
+		if granted {
+			return nil
+		}
+	}
+
+	if p.audit != nil {
+		p.audit.RecordDenial(ctx, Denial{
+			Time:      time.Now().UTC(),
+			RequestID: logger.RequestIDFromContext(ctx),
+			Subject:   principal.Subject,
+			Roles:     principal.Roles,
+			Action:    action,
+			Emails:    emails,
+		})
+	}
+	return apperrors.Forbidden("forbidden", "you are not allowed to perform this action").WithDetail("action", string(action))
+}
+
+func (p *Policy) holds(ctx context.Context, condition string, principal auth.Principal, emails []string) (bool, error) {
+	switch {
+	case condition == ConditionAuthenticated:
+		return true, nil
+	case strings.HasPrefix(condition, ConditionRolePrefix):
+		return principal.HasRole(strings.TrimPrefix(condition, ConditionRolePrefix)), nil
+	case condition == ConditionSelf:
+		if principal.Email == "" {
+			return false, nil
+		}
+		for _, email := range emails {
+			if strings.EqualFold(email, principal.Email) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case condition == ConditionVisible:
+		if len(emails) == 0 {
+			return false, nil
+		}
+		if p.visibility == nil {
+			return true, nil
+		}
+		for _, email := range emails {
+			visible, err := p.visibility.CanViewFriends(ctx, principal.Email, email)
+			if err != nil || !visible {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func checkCondition(condition string) error {
+	switch {
+	case condition == ConditionSelf, condition == ConditionVisible, condition == ConditionAuthenticated:
+		return nil
+	case strings.HasPrefix(condition, ConditionRolePrefix) && auth.IsValidRole(strings.TrimPrefix(condition, ConditionRolePrefix)):
+		return nil
+	}
+	return fmt.Errorf("unknown condition %q", condition)
+}
@@ -0,0 +1,92 @@
+package policies
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+)
+
+func TestAuthorizeWithDefaultRules(t *testing.T) {
+	audit := &auditLogFake{}
+	policy, err := New(DefaultRules, WithAuditLog(audit))
+	assert.Nil(t, err)
+
+	user := userContext("thehaohcm@yahoo.com.vn")
+	assert.Nil(t, policy.Authorize(user, ActionSubscribe, "THEHAOHCM@yahoo.com.vn"))
+	assert.Nil(t, policy.Authorize(user, ActionAddFriend, "hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn"))
+	assert.Nil(t, policy.Authorize(user, ActionReadFriends, "hao.nguyen@s3corp.com.vn"))
+
+	err = policy.Authorize(logger.WithRequestID(user, "req-1"), ActionSubscribe, "hao.nguyen@s3corp.com.vn")
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	assert.Equal(t, "subscribe", apperrors.From(err).Details["action"])
+	if assert.Len(t, audit.denials, 1) {
+		denial := audit.denials[0]
+		assert.Equal(t, Denial{Time: denial.Time, RequestID: "req-1", Subject: "thehaohcm@yahoo.com.vn", Roles: []string{auth.RoleUser}, Action: ActionSubscribe, Emails: []string{"hao.nguyen@s3corp.com.vn"}}, denial)
+	}
+
+	for _, role := range []string{auth.RoleAdmin, auth.RoleService} {
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "key-1", Roles: []string{role}, Method: auth.MethodAPIKey})
+		assert.Nil(t, policy.Authorize(ctx, ActionBlock, "hao.nguyen@s3corp.com.vn"), role)
+	}
+
+	assert.ErrorIs(t, policy.Authorize(context.Background(), ActionReadFriends, "hao.nguyen@s3corp.com.vn"), apperrors.ErrUnauthenticated)
+}
+
+func TestAuthorizeFollowsVisibility(t *testing.T) {
+	policy, err := New(DefaultRules, WithVisibility(visibilityFake{"hao.nguyen@s3corp.com.vn": true}))
+	assert.Nil(t, err)
+	user := userContext("thehaohcm@yahoo.com.vn")
+
+	assert.Nil(t, policy.Authorize(user, ActionReadFriends, "hao.nguyen@s3corp.com.vn"))
+	assert.ErrorIs(t, policy.Authorize(user, ActionReadFriends, "chinh.nguyen@s3corp.com.vn"), apperrors.ErrForbidden)
+	assert.Nil(t, policy.Authorize(user, ActionReadFriends, "thehaohcm@yahoo.com.vn"))
+	assert.ErrorIs(t, policy.Authorize(user, ActionReadCommonFriends, "hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"), apperrors.ErrForbidden)
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"read_friends": ["role:admin", "self"]}`), 0o600))
+
+	rules, err := LoadRules(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"role:admin", "self"}, rules[ActionReadFriends])
+	assert.Equal(t, DefaultRules[ActionSubscribe], rules[ActionSubscribe])
+
+	policy, err := New(rules)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, policy.Authorize(userContext("thehaohcm@yahoo.com.vn"), ActionReadFriends, "hao.nguyen@s3corp.com.vn"), apperrors.ErrForbidden)
+}
+
+func TestNewRejectsUnknownRules(t *testing.T) {
+	_, err := New(Rules{"delete_everything": {ConditionSelf}})
+	assert.NotNil(t, err)
+	_, err = New(Rules{ActionSubscribe: {"role:root"}})
+	assert.NotNil(t, err)
+	_, err = New(Rules{ActionSubscribe: {"friends"}})
+	assert.NotNil(t, err)
+}
+
+func userContext(email string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: email, Email: email, Roles: []string{auth.RoleUser}, Method: auth.MethodJWT})
+}
+
+type auditLogFake struct {
+	denials []Denial
+}
+
+func (a *auditLogFake) RecordDenial(ctx context.Context, denial Denial) {
+	a.denials = append(a.denials, denial)
+}
+
+// visibilityFake makes the friend lists of the listed owners visible to everyone
+type visibilityFake map[string]bool
+
+func (v visibilityFake) CanViewFriends(ctx context.Context, viewer, owner string) (bool, error) {
+	return v[owner], nil
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"context"
+
+	"golang_project/api/internal/resilience"
+)
+
+type breakerStore struct {
+	next    Store
+	breaker *resilience.Breaker
+}
+
+// NewBreakerStore function used for initializing a Store whose calls go through the circuit breaker of its database,
+// while it is open they fail fast with resilience.ErrOpen, so the requests are accepted without waiting for their timeout
+// pass the wrapped Store and a resilience.Breaker as parameters
+// return a Store
+func NewBreakerStore(store Store, breaker *resilience.Breaker) Store {
+	return &breakerStore{
+		next:    store,
+		breaker: breaker,
+	}
+}
+
+// Take function used to take a token from the bucket of a key through the circuit breaker
+func (s *breakerStore) Take(ctx context.Context, key string, limit Limit) (result Result, err error) {
+	err = s.breaker.Do(func() error {
+		result, err = s.next.Take(ctx, key, limit)
+		return err
+	})
+	return result, err
+}
+
+// Give function used to give a token back to the bucket of a key through the circuit breaker
+func (s *breakerStore) Give(ctx context.Context, key string, limit Limit) error {
+	return s.breaker.Do(func() error {
+		return s.next.Give(ctx, key, limit)
+	})
+}
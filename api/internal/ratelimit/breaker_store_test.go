@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/resilience"
+)
+
+func TestBreakerStoreFailsFastWhileTheBreakerIsOpen(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+	breaker := resilience.NewBreaker("test", 1, time.Minute, func(error) bool { return true })
+	store := NewBreakerStore(NewPostgresStore(mockDB), breaker)
+	limit := Limit{Requests: 10, Per: 10 * time.Second}
+
+	sqlMock.ExpectQuery("INSERT INTO public.rate_limit_bucket").WillReturnError(errors.New("connection refused"))
+	_, err = store.Take(context.Background(), "http:createConnection:ip:127.0.0.1", limit)
+	assert.NotNil(t, err)
+
+	// the breaker is open, the database is not called anymore
+	_, err = store.Take(context.Background(), "http:createConnection:ip:127.0.0.1", limit)
+	assert.ErrorIs(t, err, resilience.ErrOpen)
+	assert.ErrorIs(t, store.Give(context.Background(), "http:createConnection:ip:127.0.0.1", limit), resilience.ErrOpen)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit struct describes a token bucket: Requests tokens are refilled every Per, and the bucket holds at most Requests tokens
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit function used to read a limit written as "<requests>/<duration>", e.g. "10/1m" or "100/24h"
+// pass a string as parameter
+// return a Limit and an error type
+func ParseLimit(value string) (Limit, error) {
+	requests, per, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <requests>/<duration>", value)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, the number of requests must be positive", value)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, the duration must be positive", value)
+	}
+	return Limit{Requests: count, Per: duration}, nil
+}
+
+// IsZero function used to know whether the limit is unset, an unset limit never rejects
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// String function returns the limit in the format read by ParseLimit
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Per.String()
+}
+
+// ratePerSecond function returns the number of tokens refilled per second
+func (l Limit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result struct describes the state of a bucket after a token was requested
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token, zero when the request was allowed
+	RetryAfter time.Duration
+}
+
+// Store interface keeps the token buckets, Take and Give must be atomic for a given key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	Give(ctx context.Context, key string, limit Limit) error
+}
+
+// newResult function used to build the Result of a bucket holding the given number of tokens after the request
+func newResult(allowed bool, limit Limit, tokens float64) Result {
+	rate := limit.ratePerSecond()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+func seconds(value float64) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the idle buckets are removed from a MemoryStore
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	per       time.Duration
+}
+
+// MemoryStore struct keeps the token buckets in the memory of the process, so each replica enforces its own limits
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore function used for initializing a MemoryStore
+// return a pointer of MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Take function used to take a token from the bucket of a key, the bucket starts full
+// pass a context, the key of the bucket and its Limit as parameters
+// return a Result and an error type, always nil
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*limit.ratePerSecond())
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return newResult(false, limit, b.tokens), nil
+	}
+	b.tokens--
+	return newResult(true, limit, b.tokens), nil
+}
+
+// Give function used to give back a token taken from the bucket of a key, the bucket never holds more than its Limit
+// pass a context, the key of the bucket and its Limit as parameters
+// return an error type, always nil
+func (s *MemoryStore) Give(ctx context.Context, key string, limit Limit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a missing bucket is full
+	if b, ok := s.buckets[key]; ok {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+1)
+	}
+	return nil
+}
+
+// sweep function removes the buckets which had time to refill completely, they are equivalent to missing buckets
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) > b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreAllowsBurstThenRejects(t *testing.T) {
+	store, _ := newMemoryStoreForTesting()
+	limit := Limit{Requests: 3, Per: time.Minute}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "key", limit)
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(context.Background(), "key", limit)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	other, _ := store.Take(context.Background(), "other", limit)
+	assert.True(t, other.Allowed)
+}
+
+func TestMemoryStoreRefillsOverTime(t *testing.T) {
+	store, clock := newMemoryStoreForTesting()
+	limit := Limit{Requests: 2, Per: time.Minute}
+
+	store.Take(context.Background(), "key", limit)
+	store.Take(context.Background(), "key", limit)
+	result, _ := store.Take(context.Background(), "key", limit)
+	assert.False(t, result.Allowed)
+
+	*clock = clock.Add(30 * time.Second)
+	result, _ = store.Take(context.Background(), "key", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	*clock = clock.Add(time.Hour)
+	result, _ = store.Take(context.Background(), "key", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestMemoryStoreSweepsIdleBuckets(t *testing.T) {
+	store, clock := newMemoryStoreForTesting()
+
+	store.Take(context.Background(), "idle", Limit{Requests: 1, Per: time.Second})
+	*clock = clock.Add(2 * sweepInterval)
+	store.Take(context.Background(), "active", Limit{Requests: 1, Per: time.Second})
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "active")
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit(" 100/24h ")
+	assert.Nil(t, err)
+	assert.Equal(t, Limit{Requests: 100, Per: 24 * time.Hour}, limit)
+	assert.Equal(t, "100/24h0m0s", limit.String())
+
+	for _, value := range []string{"", "10", "ten/1m", "0/1m", "10/soon", "10/-1m"} {
+		_, err := ParseLimit(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func newMemoryStoreForTesting() (*MemoryStore, *time.Time) {
+	clock := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return clock }
+	return store, &clock
+}
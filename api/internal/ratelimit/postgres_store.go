@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"golang_project/api/internal/logger"
+)
+
+// PostgresStore struct keeps the token buckets in the rate_limit_bucket table, so every replica shares the same limits
+type PostgresStore struct {
+	db        *sql.DB
+	lastSweep atomic.Int64
+}
+
+// NewPostgresStore function used for initializing a PostgresStore
+// pass a pointer sql.DB as parameter
+// return a pointer of PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// Take function used to take a token from the bucket of a key, in a single statement so concurrent replicas cannot overspend it
+// the refill is computed with the clock of the database, the bucket starts full
+// pass a context, the key of the bucket and its Limit as parameters
+// return a Result and an error type
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var tokens float64
+	var allowed bool
+	err := s.db.QueryRowContext(ctx, `INSERT INTO public.rate_limit_bucket(bucket_key, tokens, allowed, updated_at, expires_at)
+	VALUES($1, $2::float8 - 1, true, now(), now() + make_interval(secs => $4::float8))
+	ON CONFLICT (bucket_key) DO UPDATE SET
+	tokens = CASE WHEN LEAST($2::float8, rate_limit_bucket.tokens + EXTRACT(EPOCH FROM now() - rate_limit_bucket.updated_at) * $3::float8) >= 1
+		THEN LEAST($2::float8, rate_limit_bucket.tokens + EXTRACT(EPOCH FROM now() - rate_limit_bucket.updated_at) * $3::float8) - 1
+		ELSE LEAST($2::float8, rate_limit_bucket.tokens + EXTRACT(EPOCH FROM now() - rate_limit_bucket.updated_at) * $3::float8) END,
+	allowed = LEAST($2::float8, rate_limit_bucket.tokens + EXTRACT(EPOCH FROM now() - rate_limit_bucket.updated_at) * $3::float8) >= 1,
+	updated_at = now(),
+	expires_at = now() + make_interval(secs => $4::float8)
+	RETURNING tokens, allowed`, key, float64(limit.Requests), limit.ratePerSecond(), limit.Per.Seconds()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if now := time.Now(); now.Sub(time.Unix(0, s.lastSweep.Load())) >= sweepInterval {
+		s.lastSweep.Store(now.UnixNano())
+		if _, err := s.DeleteExpired(ctx); err != nil {
+			logger.FromContext(ctx).Warn("cannot delete the expired rate limit buckets", slog.Any("error", err))
+		}
+	}
+	return newResult(allowed, limit, tokens), nil
+}
+
+// Give function used to give back a token taken from the bucket of a key, the bucket never holds more than its Limit
+// pass a context, the key of the bucket and its Limit as parameters
+// return an error type
+func (s *PostgresStore) Give(ctx context.Context, key string, limit Limit) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE public.rate_limit_bucket SET tokens = LEAST($2::float8, tokens + 1) WHERE bucket_key = $1`,
+		key, float64(limit.Requests))
+	return err
+}
+
+// DeleteExpired function used to remove the buckets which had time to refill completely, Take calls it about once a minute
+// pass a context as parameter
+// return the number of removed buckets and an error type
+func (s *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM public.rate_limit_bucket WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStoreTake(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	limit := Limit{Requests: 10, Per: 10 * time.Second}
+	sqlMock.ExpectQuery("INSERT INTO public.rate_limit_bucket").
+		WithArgs("http:createConnection:ip:127.0.0.1", float64(10), float64(1), float64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(9.0, true))
+	sqlMock.ExpectExec("DELETE FROM public.rate_limit_bucket").WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectQuery("INSERT INTO public.rate_limit_bucket").
+		WithArgs("http:createConnection:ip:127.0.0.1", float64(10), float64(1), float64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.5, false))
+
+	store := NewPostgresStore(mockDB)
+	result, err := store.Take(context.Background(), "http:createConnection:ip:127.0.0.1", limit)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: limit, Remaining: 9, Reset: time.Second}, result)
+
+	// the expired buckets are only deleted once a minute
+	result, err = store.Take(context.Background(), "http:createConnection:ip:127.0.0.1", limit)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestPostgresStoreGive(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectExec("UPDATE public.rate_limit_bucket SET tokens = LEAST\\(\\$2::float8, tokens \\+ 1\\) WHERE bucket_key = \\$1").
+		WithArgs("quota:subscriptions:abc@def.com", float64(30)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewPostgresStore(mockDB).Give(context.Background(), "quota:subscriptions:abc@def.com", Limit{Requests: 30, Per: time.Hour})
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
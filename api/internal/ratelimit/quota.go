@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/services"
+)
+
+// Quotas struct holds the business limits applied to each user, a zero Limit disables the quota
+type Quotas struct {
+	// FriendRequests limits the friend connections created by a user, e.g. 100 per day
+	FriendRequests Limit
+	// Subscriptions limits the subscriptions created by a user, e.g. 30 per hour
+	Subscriptions Limit
+}
+
+type quotaService struct {
+	services.FriendConnectionService
+	store  Store
+	quotas Quotas
+}
+
+// NewFriendConnectionService function used for initializing a FriendConnectionService which enforces the Quotas
+// before delegating the calls creating relationships, the other calls are delegated as they are;
+// a call rejected by the wrapped service, e.g. for an unregistered user, gives its token back, so only the relationships created count
+// admins and service accounts are not subject to the quotas
+// pass the wrapped FriendConnectionService, a Store and Quotas as parameters
+// return a FriendConnectionService
+func NewFriendConnectionService(next services.FriendConnectionService, store Store, quotas Quotas) services.FriendConnectionService {
+	return &quotaService{
+		FriendConnectionService: next,
+		store:                   store,
+		quotas:                  quotas,
+	}
+}
+
+// CreateConnection function enforces the friend request quota of the user creating the connection
+func (svc *quotaService) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	key, err := svc.consume(ctx, "friend_requests", svc.quotas.FriendRequests, request.Friends...)
+	if err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	response, err := svc.FriendConnectionService.CreateConnection(ctx, request)
+	if err != nil {
+		svc.giveBack(ctx, key, svc.quotas.FriendRequests)
+	}
+	return response, err
+}
+
+// SubscribeFromEmail function enforces the subscription quota of the requestor
+func (svc *quotaService) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	key, err := svc.consume(ctx, "subscriptions", svc.quotas.Subscriptions, request.Requestor)
+	if err != nil {
+		return models.SubscribeResponse{}, err
+	}
+	response, err := svc.FriendConnectionService.SubscribeFromEmail(ctx, request)
+	if err != nil {
+		svc.giveBack(ctx, key, svc.quotas.Subscriptions)
+	}
+	return response, err
+}
+
+// consume function takes a token from the quota of the acting user: the authenticated user when it is one of the emails,
+// the first email otherwise; invalid requests are left to the wrapped service and a failing store never rejects a request
+// return the key of the bucket the token was taken from, empty when no token was taken, and an error type
+func (svc *quotaService) consume(ctx context.Context, quota string, limit Limit, emails ...string) (string, error) {
+	if limit.IsZero() || pkg.CheckValidEmails(emails) != nil {
+		return "", nil
+	}
+	actor := emails[0]
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		if principal.HasRole(auth.RoleAdmin, auth.RoleService) {
+			return "", nil
+		}
+		for _, email := range emails {
+			if strings.EqualFold(email, principal.Email) {
+				actor = email
+			}
+		}
+	}
+
+	key := "quota:" + quota + ":" + strings.ToLower(actor)
+	result, err := svc.store.Take(ctx, key, limit)
+	if err != nil {
+		logger.FromContext(ctx).Warn("cannot check the quota, the request is accepted", slog.String("quota", quota), slog.Any("error", err))
+		return "", nil
+	}
+	if result.Allowed {
+		return key, nil
+	}
+	return "", apperrors.RateLimited("quota_exceeded", "the "+strings.ReplaceAll(quota, "_", " ")+" quota is exceeded, retry later").
+		WithDetail("quota", quota).
+		WithDetail("limit", limit.String()).
+		WithDetail("retry_after", RetryAfterSeconds(result))
+}
+
+// giveBack function gives back the token taken by consume for a call which failed, the failure to give it back is only logged
+func (svc *quotaService) giveBack(ctx context.Context, key string, limit Limit) {
+	if key == "" {
+		return
+	}
+	if err := svc.store.Give(ctx, key, limit); err != nil {
+		logger.FromContext(ctx).Warn("cannot give back the token of a rejected request", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// RetryAfterSeconds function used to get the value of the Retry-After header of a rejected request, at least one second
+// pass a Result as parameter
+// return a number of seconds
+func RetryAfterSeconds(result Result) int {
+	return int(math.Max(1, math.Ceil(result.RetryAfter.Seconds())))
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/services"
+)
+
+func TestQuotaRejectsFriendRequestsOverTheLimit(t *testing.T) {
+	next := &serviceFake{}
+	service := NewFriendConnectionService(next, NewMemoryStore(), Quotas{FriendRequests: Limit{Requests: 2, Per: 24 * time.Hour}})
+	ctx := userContext("thehaohcm@yahoo.com.vn")
+
+	for _, friend := range []string{"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"} {
+		_, err := service.CreateConnection(ctx, models.FriendConnectionRequest{Friends: []string{friend, "thehaohcm@yahoo.com.vn"}})
+		assert.Nil(t, err)
+	}
+	_, err := service.CreateConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "other@example.com"}})
+
+	var appErr *apperrors.Error
+	if assert.True(t, errors.As(err, &appErr)) {
+		assert.Equal(t, apperrors.KindRateLimited, appErr.Kind)
+		assert.Equal(t, "quota_exceeded", appErr.Code)
+		assert.Equal(t, "friend_requests", appErr.Details["quota"])
+		assert.Equal(t, 43200, appErr.Details["retry_after"])
+	}
+	assert.Equal(t, 2, next.connections)
+
+	// the quota is per user
+	_, err = service.CreateConnection(userContext("other@example.com"), models.FriendConnectionRequest{Friends: []string{"other@example.com", "thehaohcm@yahoo.com.vn"}})
+	assert.Nil(t, err)
+}
+
+func TestQuotaRejectsSubscriptionsOverTheLimit(t *testing.T) {
+	next := &serviceFake{}
+	service := NewFriendConnectionService(next, NewMemoryStore(), Quotas{Subscriptions: Limit{Requests: 1, Per: time.Hour}})
+	request := models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"}
+
+	_, err := service.SubscribeFromEmail(userContext("thehaohcm@yahoo.com.vn"), request)
+	assert.Nil(t, err)
+	_, err = service.SubscribeFromEmail(userContext("thehaohcm@yahoo.com.vn"), request)
+	assert.True(t, errors.Is(err, apperrors.ErrRateLimited))
+	assert.Equal(t, 1, next.subscriptions)
+
+	// friend connections are not limited by a zero quota
+	_, err = service.CreateConnection(userContext("thehaohcm@yahoo.com.vn"), models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+}
+
+func TestQuotaExemptsAdminsAndFailsOpen(t *testing.T) {
+	quotas := Quotas{Subscriptions: Limit{Requests: 1, Per: time.Hour}}
+	request := models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"}
+
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "key-1", Roles: []string{auth.RoleAdmin}})
+	service := NewFriendConnectionService(&serviceFake{}, NewMemoryStore(), quotas)
+	for i := 0; i < 3; i++ {
+		_, err := service.SubscribeFromEmail(admin, request)
+		assert.Nil(t, err)
+	}
+
+	service = NewFriendConnectionService(&serviceFake{}, failingStore{}, quotas)
+	for i := 0; i < 3; i++ {
+		_, err := service.SubscribeFromEmail(userContext("thehaohcm@yahoo.com.vn"), request)
+		assert.Nil(t, err)
+	}
+}
+
+func TestQuotaGivesBackTheTokenOfARejectedRequest(t *testing.T) {
+	next := &serviceFake{err: apperrors.NotFound("user_not_found", "the user is not registered")}
+	service := NewFriendConnectionService(next, NewMemoryStore(), Quotas{Subscriptions: Limit{Requests: 1, Per: time.Hour}})
+	ctx := userContext("thehaohcm@yahoo.com.vn")
+	request := models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "unknown@example.com"}
+
+	for i := 0; i < 3; i++ {
+		_, err := service.SubscribeFromEmail(ctx, request)
+		assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+	}
+	next.err = nil
+	_, err := service.SubscribeFromEmail(ctx, request)
+	assert.Nil(t, err)
+	_, err = service.SubscribeFromEmail(ctx, request)
+	assert.True(t, errors.Is(err, apperrors.ErrRateLimited))
+	assert.Equal(t, 4, next.subscriptions)
+}
+
+func userContext(email string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: email, Email: email, Roles: []string{auth.RoleUser}, Method: auth.MethodJWT})
+}
+
+// serviceFake counts the calls reaching the wrapped service, the other methods are not expected to be called
+type serviceFake struct {
+	services.FriendConnectionService
+	connections   int
+	subscriptions int
+	err           error
+}
+
+func (s *serviceFake) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	s.connections++
+	if s.err != nil {
+		return models.FriendConnectionResponse{}, s.err
+	}
+	return models.FriendConnectionResponse{Success: true}, nil
+}
+
+func (s *serviceFake) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	s.subscriptions++
+	if s.err != nil {
+		return models.SubscribeResponse{}, s.err
+	}
+	return models.SubscribeResponse{Success: true}, nil
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func (failingStore) Give(ctx context.Context, key string, limit Limit) error {
+	return errors.New("connection refused")
+}
@@ -0,0 +1,185 @@
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sweepInterval is how often the expired writes are removed from a Set
+const sweepInterval = time.Minute
+
+type member struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Set struct routes the reads of a primary database to its read replicas:
+// the reads go to the healthy replicas in turn, or to the primary when none is healthy,
+// and the reads concerning a user go to the primary for a short window after a write of this user, so the user reads its writes
+// the window only covers the writes made by this process
+type Set struct {
+	primary  *sql.DB
+	replicas []*member
+	next     atomic.Uint64
+	window   time.Duration
+
+	mu        sync.Mutex
+	writes    map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New function used for initializing a Set, every replica is healthy until a health check or a read fails on it
+// pass the primary database, the replicas keyed by a name used in the logs, and the read-your-writes window as parameters
+// return a pointer of Set
+func New(primary *sql.DB, replicas map[string]*sql.DB, window time.Duration) *Set {
+	s := &Set{
+		primary: primary,
+		window:  window,
+		writes:  map[string]time.Time{},
+		now:     time.Now,
+	}
+	names := make([]string, 0, len(replicas))
+	for name := range replicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := &member{name: name, db: replicas[name]}
+		m.healthy.Store(true)
+		s.replicas = append(s.replicas, m)
+	}
+	return s
+}
+
+// Primary function used to get the primary database, the one of the writes
+// return a pointer of sql.DB
+func (s *Set) Primary() *sql.DB {
+	return s.primary
+}
+
+// Reader function used to get the database of a read concerning users:
+// the primary when one of the users wrote within the window or when no replica is healthy, otherwise the next healthy replica
+// pass the emails of the users as parameters
+// return a pointer of sql.DB
+func (s *Set) Reader(emails ...string) *sql.DB {
+	if len(s.replicas) == 0 || s.wroteRecently(emails) {
+		return s.primary
+	}
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		m := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if m.healthy.Load() {
+			return m.db
+		}
+	}
+	return s.primary
+}
+
+// Wrote function used to remember that users wrote, their reads go to the primary for the window of the Set
+// pass the emails of the users as parameters
+func (s *Set) Wrote(emails ...string) {
+	if len(s.replicas) == 0 || s.window <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	for _, email := range emails {
+		s.writes[email] = now
+	}
+}
+
+// Failed function used to report that a read failed on a database because it is unreachable,
+// a replica is then left aside until a health check succeeds on it
+// pass the database and the error as parameters
+func (s *Set) Failed(db *sql.DB, err error) {
+	for _, m := range s.replicas {
+		if m.db == db && m.healthy.Swap(false) {
+			slog.Warn("read replica is unhealthy, its reads go to the other replicas or the primary", slog.String("replica", m.name), slog.Any("error", err))
+		}
+	}
+}
+
+// Check function used to ping every replica, they are healthy when the ping succeeds within the timeout
+// pass a context and the timeout of a ping as parameters
+func (s *Set) Check(ctx context.Context, timeout time.Duration) {
+	for _, m := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := m.db.PingContext(pingCtx)
+		cancel()
+		if err != nil {
+			s.Failed(m.db, err)
+			continue
+		}
+		if !m.healthy.Swap(true) {
+			slog.Info("read replica is healthy again", slog.String("replica", m.name))
+		}
+	}
+}
+
+// Watch function used to check the replicas every interval until the context is done, see Check
+// pass a context and the interval of the health checks as parameters
+func (s *Set) Watch(ctx context.Context, interval time.Duration) {
+	if len(s.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx, interval)
+		}
+	}
+}
+
+// Healthy function used to get the number of healthy replicas
+// return an integer
+func (s *Set) Healthy() int {
+	healthy := 0
+	for _, m := range s.replicas {
+		if m.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+func (s *Set) wroteRecently(emails []string) bool {
+	if s.window <= 0 || len(emails) == 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, email := range emails {
+		if at, ok := s.writes[email]; ok && now.Sub(at) < s.window {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Set) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for email, at := range s.writes {
+		if now.Sub(at) >= s.window {
+			delete(s.writes, email)
+		}
+	}
+}
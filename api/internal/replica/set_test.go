@@ -0,0 +1,90 @@
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDBForTesting(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func TestReaderSpreadsTheReadsOverTheReplicas(t *testing.T) {
+	primary, _ := newDBForTesting(t)
+	first, _ := newDBForTesting(t)
+	second, _ := newDBForTesting(t)
+	set := New(primary, map[string]*sql.DB{"first": first, "second": second}, time.Second)
+
+	read := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		read[set.Reader("a@example.com")]++
+	}
+	assert.Equal(t, map[*sql.DB]int{first: 2, second: 2}, read)
+	assert.Equal(t, primary, set.Primary())
+}
+
+func TestReaderUsesThePrimaryWithoutReplicas(t *testing.T) {
+	primary, _ := newDBForTesting(t)
+	set := New(primary, nil, time.Second)
+
+	set.Wrote("a@example.com")
+	assert.Equal(t, primary, set.Reader("b@example.com"))
+	assert.Empty(t, set.writes)
+}
+
+func TestReaderUsesThePrimaryAfterAWrite(t *testing.T) {
+	primary, _ := newDBForTesting(t)
+	replica, _ := newDBForTesting(t)
+	set := New(primary, map[string]*sql.DB{"replica": replica}, 5*time.Second)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	set.now = func() time.Time { return clock }
+
+	set.Wrote("a@example.com", "b@example.com")
+	assert.Equal(t, primary, set.Reader("a@example.com"))
+	assert.Equal(t, primary, set.Reader("c@example.com", "b@example.com"))
+	assert.Equal(t, replica, set.Reader("c@example.com"))
+
+	clock = clock.Add(5 * time.Second)
+	assert.Equal(t, replica, set.Reader("a@example.com"))
+
+	// the expired writes are swept
+	clock = clock.Add(sweepInterval)
+	set.Wrote("c@example.com")
+	assert.Equal(t, 1, len(set.writes))
+}
+
+func TestReaderSkipsTheUnhealthyReplicas(t *testing.T) {
+	primary, _ := newDBForTesting(t)
+	first, firstMock := newDBForTesting(t)
+	second, secondMock := newDBForTesting(t)
+	set := New(primary, map[string]*sql.DB{"first": first, "second": second}, time.Second)
+
+	set.Failed(first, errors.New("connection refused"))
+	assert.Equal(t, 1, set.Healthy())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, second, set.Reader("a@example.com"))
+	}
+
+	// the health check brings the first replica back, and finds that the second one is down
+	firstMock.ExpectPing()
+	secondMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	set.Check(context.Background(), time.Second)
+	assert.Nil(t, firstMock.ExpectationsWereMet())
+	assert.Nil(t, secondMock.ExpectationsWereMet())
+	assert.Equal(t, first, set.Reader("a@example.com"))
+
+	set.Failed(first, errors.New("connection refused"))
+	assert.Equal(t, 0, set.Healthy())
+	assert.Equal(t, primary, set.Reader("a@example.com"))
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+// APIKeyRepository interface declares all functions used to store the API keys of service accounts
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	FindAPIKeyByID(ctx context.Context, id string) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository function used for initializing an APIKeyRepository
+// pass a pointer sql.DB as parameter
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+// CreateAPIKey function used to insert a new API key into api_key table
+// pass a context and an APIKey model as parameters
+// return the stored APIKey model and an error type
+func (repo *apiKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	err := repo.db.QueryRowContext(ctx, `INSERT INTO public.api_key(key_id, name, key_hash, roles) VALUES($1,$2,$3,$4)
+	RETURNING created_at`, key.ID, key.Name, key.KeyHash, pq.Array(key.Roles)).Scan(&key.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, dbError(ctx, "CreateAPIKey", err)
+	}
+	return key, nil
+}
+
+// FindAPIKeyByID function used to query an API key from api_key table, revoked keys are returned too
+// pass a context and the id of the key as parameters
+// return an APIKey model and an error type, of KindNotFound when the key does not exist
+func (repo *apiKeyRepository) FindAPIKeyByID(ctx context.Context, id string) (models.APIKey, error) {
+	key, err := scanAPIKey(repo.db.QueryRowContext(ctx, `SELECT key_id, name, key_hash, roles, created_at, revoked_at
+	FROM public.api_key WHERE key_id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIKey{}, apperrors.NotFound("api_key_not_found", "api key not found")
+	}
+	if err != nil {
+		return models.APIKey{}, dbError(ctx, "FindAPIKeyByID", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys function used to query every API key from api_key table, newest first
+// pass a context as parameter
+// return an array of APIKey model and an error type
+func (repo *apiKeyRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := repo.db.QueryContext(ctx, `SELECT key_id, name, key_hash, roles, created_at, revoked_at
+	FROM public.api_key ORDER BY created_at DESC, key_id`)
+	if err != nil {
+		return []models.APIKey{}, dbError(ctx, "ListAPIKeys", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return []models.APIKey{}, dbError(ctx, "ListAPIKeys", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.APIKey{}, dbError(ctx, "ListAPIKeys", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey function used to mark an API key as revoked, revoking a key twice keeps the first revocation date
+// pass a context and the id of the key as parameters
+// return an error type, of KindNotFound when the key does not exist
+func (repo *apiKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	result, err := repo.db.ExecContext(ctx, `UPDATE public.api_key SET revoked_at=COALESCE(revoked_at, now()) WHERE key_id=$1`, id)
+	if err != nil {
+		return dbError(ctx, "RevokeAPIKey", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return dbError(ctx, "RevokeAPIKey", err)
+	}
+	if affected == 0 {
+		return apperrors.NotFound("api_key_not_found", "api key not found")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.KeyHash, pq.Array(&key.Roles), &key.CreatedAt, &revokedAt); err != nil {
+		return models.APIKey{}, err
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sqlMock.ExpectQuery("INSERT INTO public.api_key").
+		WithArgs("0123", "reporting", "hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+
+	result, err := NewAPIKeyRepository(mockDB).CreateAPIKey(context.Background(), models.APIKey{ID: "0123", Name: "reporting", KeyHash: "hash", Roles: []string{"service"}})
+	assert.Nil(t, err)
+	assert.Equal(t, models.APIKey{ID: "0123", Name: "reporting", KeyHash: "hash", Roles: []string{"service"}, CreatedAt: createdAt}, result)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindAPIKeyByID(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sqlMock.ExpectQuery("SELECT key_id, name, key_hash, roles, created_at, revoked_at").WithArgs("0123").
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "name", "key_hash", "roles", "created_at", "revoked_at"}).
+			AddRow("0123", "reporting", "hash", "{service,admin}", createdAt, createdAt))
+	sqlMock.ExpectQuery("SELECT key_id, name, key_hash, roles, created_at, revoked_at").WithArgs("4567").
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewAPIKeyRepository(mockDB)
+	result, err := repo.FindAPIKeyByID(context.Background(), "0123")
+	assert.Nil(t, err)
+	assert.Equal(t, models.APIKey{ID: "0123", Name: "reporting", KeyHash: "hash", Roles: []string{"service", "admin"}, CreatedAt: createdAt, RevokedAt: &createdAt}, result)
+
+	_, err = repo.FindAPIKeyByID(context.Background(), "4567")
+	assert.Equal(t, apperrors.KindNotFound, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRevokeUnknownAPIKey(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectExec("UPDATE public.api_key SET revoked_at").WithArgs("4567").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewAPIKeyRepository(mockDB).RevokeAPIKey(context.Background(), "4567")
+	assert.Equal(t, apperrors.KindNotFound, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
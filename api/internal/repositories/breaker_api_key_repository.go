@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/resilience"
+)
+
+type breakerAPIKeyRepository struct {
+	next    APIKeyRepository
+	breaker *resilience.Breaker
+}
+
+// NewBreakerAPIKeyRepository function used for initializing an APIKeyRepository whose calls go through a circuit breaker,
+// the one of the friend connections since they share the database, so that during an outage the requests authenticated
+// with an API key fail fast with a database_unavailable error (503) instead of waiting for their timeout
+// pass the wrapped APIKeyRepository and a resilience.Breaker, counting the failures told by OutageError, as parameters
+// return an APIKeyRepository
+func NewBreakerAPIKeyRepository(repo APIKeyRepository, breaker *resilience.Breaker) APIKeyRepository {
+	return &breakerAPIKeyRepository{
+		next:    repo,
+		breaker: breaker,
+	}
+}
+
+func (repo *breakerAPIKeyRepository) do(operation func() error) error {
+	return breakerError(repo.breaker, repo.breaker.Do(operation))
+}
+
+// CreateAPIKey function used to insert an API key through the circuit breaker
+func (repo *breakerAPIKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (created models.APIKey, err error) {
+	err = repo.do(func() error {
+		created, err = repo.next.CreateAPIKey(ctx, key)
+		return err
+	})
+	return created, err
+}
+
+// FindAPIKeyByID function used to find an API key through the circuit breaker
+func (repo *breakerAPIKeyRepository) FindAPIKeyByID(ctx context.Context, id string) (key models.APIKey, err error) {
+	err = repo.do(func() error {
+		key, err = repo.next.FindAPIKeyByID(ctx, id)
+		return err
+	})
+	return key, err
+}
+
+// ListAPIKeys function used to list the API keys through the circuit breaker
+func (repo *breakerAPIKeyRepository) ListAPIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	err = repo.do(func() error {
+		keys, err = repo.next.ListAPIKeys(ctx)
+		return err
+	})
+	return keys, err
+}
+
+// RevokeAPIKey function used to revoke an API key through the circuit breaker
+func (repo *breakerAPIKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	return repo.do(func() error {
+		return repo.next.RevokeAPIKey(ctx, id)
+	})
+}
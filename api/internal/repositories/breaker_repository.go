@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/resilience"
+)
+
+type breakerRepository struct {
+	next    FriendConnectionRepository
+	breaker *resilience.Breaker
+}
+
+// NewBreakerRepository function used for initializing a FriendConnectionRepository whose calls go through a circuit breaker,
+// during a database outage they fail fast with a database_unavailable error (503) instead of waiting for their timeout
+// pass the wrapped FriendConnectionRepository and a resilience.Breaker, counting the failures told by OutageError, as parameters
+// return a FriendConnectionRepository
+func NewBreakerRepository(repo FriendConnectionRepository, breaker *resilience.Breaker) FriendConnectionRepository {
+	return &breakerRepository{
+		next:    repo,
+		breaker: breaker,
+	}
+}
+
+// OutageError function used to know whether an error of a repository means that the database is down or overloaded,
+// i.e. it cannot be reached or refuses the connections; the errors of the request itself, e.g. a validation error,
+// a canceled request or a request reaching its own deadline, are not outages, so slow routes do not open the breaker of the others
+// pass an error as parameter
+// return a boolean
+func OutageError(err error) bool {
+	return apperrors.KindOf(translateError(err)) == apperrors.KindUnavailable
+}
+
+func (repo *breakerRepository) do(operation func() error) error {
+	return repo.openError(repo.breaker.Do(operation))
+}
+
+func (repo *breakerRepository) openError(err error) error {
+	return breakerError(repo.breaker, err)
+}
+
+// breakerError function used to turn the ErrOpen of a breaker into a database_unavailable error telling when to retry
+func breakerError(breaker *resilience.Breaker, err error) error {
+	if errors.Is(err, resilience.ErrOpen) {
+		retryAfter := int(math.Ceil(breaker.RetryAfter().Seconds()))
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is unavailable, retry later").
+			WithDetail("retry_after", retryAfter)
+	}
+	return err
+}
+
+// CreateUser function used to create a user through the circuit breaker
+func (repo *breakerRepository) CreateUser(ctx context.Context, request models.CreatingUserRequest) (user models.User, err error) {
+	err = repo.do(func() error {
+		user, err = repo.next.CreateUser(ctx, request)
+		return err
+	})
+	return user, err
+}
+
+// CreateUsersIfNotExist function used to create users through the circuit breaker
+func (repo *breakerRepository) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	return repo.do(func() error {
+		return repo.next.CreateUsersIfNotExist(ctx, emails)
+	})
+}
+
+// FindUnregisteredEmails function used to find the unregistered emails through the circuit breaker
+func (repo *breakerRepository) FindUnregisteredEmails(ctx context.Context, emails []string) (unregistered []string, err error) {
+	err = repo.do(func() error {
+		unregistered, err = repo.next.FindUnregisteredEmails(ctx, emails)
+		return err
+	})
+	return unregistered, err
+}
+
+// FindFriendsByEmail function used to find a friend list through the circuit breaker
+func (repo *breakerRepository) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.FindFriendsByEmail(ctx, request)
+		return err
+	})
+	return relationships, err
+}
+
+// FindCommonFriendsByEmails function used to find common friends through the circuit breaker
+func (repo *breakerRepository) FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.FindCommonFriendsByEmails(ctx, request)
+		return err
+	})
+	return relationships, err
+}
+
+// CreateFriendConnection function used to create a friendship through the circuit breaker
+func (repo *breakerRepository) CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.CreateFriendConnection(ctx, friendConnectionRequest)
+		return err
+	})
+	return relationship, err
+}
+
+// RemoveFriendConnection function used to remove a friendship through the circuit breaker
+func (repo *breakerRepository) RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.RemoveFriendConnection(ctx, friendConnectionRequest)
+		return err
+	})
+	return relationship, err
+}
+
+// SubscribeFromEmail function used to create a subscription through the circuit breaker
+func (repo *breakerRepository) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.SubscribeFromEmail(ctx, req)
+		return err
+	})
+	return relationship, err
+}
+
+// UnsubscribeFromEmail function used to remove a subscription through the circuit breaker
+func (repo *breakerRepository) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.UnsubscribeFromEmail(ctx, req)
+		return err
+	})
+	return relationship, err
+}
+
+// BlockSubscribeByEmail function used to block a user through the circuit breaker
+func (repo *breakerRepository) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.BlockSubscribeByEmail(ctx, req)
+		return err
+	})
+	return relationship, err
+}
+
+// UnblockSubscribeByEmail function used to unblock a user through the circuit breaker
+func (repo *breakerRepository) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (relationship models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationship, err = repo.next.UnblockSubscribeByEmail(ctx, req)
+		return err
+	})
+	return relationship, err
+}
+
+// GetSubscribingEmailListByEmail function used to get the recipients of an update through the circuit breaker
+func (repo *breakerRepository) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.GetSubscribingEmailListByEmail(ctx, req)
+		return err
+	})
+	return relationships, err
+}
+
+// StreamRecipients function used to stream the recipients of an update through the circuit breaker,
+// only opening the cursor and reading the first recipient go through it: a long stream to a slow client must not hold
+// the trial of a half-open breaker, and the errors of the callback are not outages of the database
+func (repo *breakerRepository) StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	record, err := repo.breaker.Start()
+	if err != nil {
+		return repo.openError(err)
+	}
+	started := false
+	start := func(err error) {
+		if !started {
+			started = true
+			record(err)
+		}
+	}
+	// a panic before the first recipient must not leave the trial running forever
+	defer start(resilience.ErrAborted)
+
+	err = repo.next.StreamRecipients(ctx, req, func(email string) error {
+		start(nil)
+		return fn(email)
+	})
+	// the stream failed or ended before its first recipient
+	start(err)
+	return err
+}
+
+// FindRelationshipsByEmails function used to find the relationships of users through the circuit breaker
+func (repo *breakerRepository) FindRelationshipsByEmails(ctx context.Context, emails []string) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.FindRelationshipsByEmails(ctx, emails)
+		return err
+	})
+	return relationships, err
+}
+
+// FindFriendsByEmails function used to find the friends of users through the circuit breaker
+func (repo *breakerRepository) FindFriendsByEmails(ctx context.Context, emails []string) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.FindFriendsByEmails(ctx, emails)
+		return err
+	})
+	return relationships, err
+}
+
+// FindSubscribersByEmails function used to find the subscribers of users through the circuit breaker
+func (repo *breakerRepository) FindSubscribersByEmails(ctx context.Context, emails []string) (relationships []models.Relationship, err error) {
+	err = repo.do(func() error {
+		relationships, err = repo.next.FindSubscribersByEmails(ctx, emails)
+		return err
+	})
+	return relationships, err
+}
+
+// FindPrivacySettingsByEmails function used to find the privacy settings of users through the circuit breaker
+func (repo *breakerRepository) FindPrivacySettingsByEmails(ctx context.Context, emails []string) (settings []models.PrivacySettings, err error) {
+	err = repo.do(func() error {
+		settings, err = repo.next.FindPrivacySettingsByEmails(ctx, emails)
+		return err
+	})
+	return settings, err
+}
+
+// SavePrivacySettings function used to save privacy settings through the circuit breaker
+func (repo *breakerRepository) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (saved models.PrivacySettings, err error) {
+	err = repo.do(func() error {
+		saved, err = repo.next.SavePrivacySettings(ctx, settings)
+		return err
+	})
+	return saved, err
+}
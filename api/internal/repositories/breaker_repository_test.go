@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/resilience"
+)
+
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestBreakerRepositoryFailsFastDuringAnOutage(t *testing.T) {
+	mockDB, sqlMock, _ := sqlmock.New()
+	defer mockDB.Close()
+	repo := NewBreakerRepository(New(mockDB), resilience.NewBreaker("test", 2, time.Minute, OutageError))
+	request := models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"}
+
+	// the errors of the request do not count
+	_, err := repo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "invalid"})
+	assert.NotNil(t, err)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = repo.FindFriendsByEmail(canceled, request)
+	assert.Equal(t, apperrors.KindCanceled, apperrors.KindOf(translateError(err)))
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	for i := 0; i < 2; i++ {
+		_, err = repo.FindFriendsByEmail(expired, request)
+		assert.Equal(t, apperrors.KindTimeout, apperrors.KindOf(translateError(err)))
+	}
+
+	sqlMock.ExpectQuery("SELECT requestor FROM public.relationship").WillReturnError(errConnectionRefused)
+	sqlMock.ExpectBegin().WillReturnError(errConnectionRefused)
+	_, err = repo.FindFriendsByEmail(context.Background(), request)
+	assert.Equal(t, apperrors.KindUnavailable, apperrors.KindOf(err))
+	_, err = repo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"})
+	assert.Equal(t, apperrors.KindUnavailable, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+
+	// the breaker is open, the database is not called anymore
+	_, err = repo.FindFriendsByEmail(context.Background(), request)
+	appErr := apperrors.From(err)
+	assert.Equal(t, apperrors.KindUnavailable, appErr.Kind)
+	assert.Equal(t, "database_unavailable", appErr.Code)
+	assert.Equal(t, 60, appErr.Details["retry_after"])
+	assert.True(t, errors.Is(err, resilience.ErrOpen))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBreakerRepositoryOnlyGuardsTheStartOfAStream(t *testing.T) {
+	memory := NewMemoryRepository()
+	ctx := context.Background()
+	assert.Nil(t, memory.CreateUsersIfNotExist(ctx, []string{"thehaohcm@yahoo.com.vn", "son.le@s3corp.com.vn", "kate@example.com"}))
+	_, err := memory.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "son.le@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	_, err = memory.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+
+	// the breaker is half-open, the stream is its trial
+	breaker := resilience.NewBreaker("test", 1, 0, OutageError)
+	breaker.Do(func() error { return errConnectionRefused })
+	repo := NewBreakerRepository(memory, breaker)
+
+	request := models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hello"}
+	err = repo.StreamRecipients(ctx, request, func(email string) error {
+		// the other calls go on while the stream is written
+		_, err := repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+		assert.Nil(t, err)
+		// the client went away, it is not an outage of the database
+		return errConnectionRefused
+	})
+	assert.ErrorIs(t, err, errConnectionRefused)
+
+	err = repo.StreamRecipients(ctx, request, func(email string) error { return errConnectionRefused })
+	assert.ErrorIs(t, err, errConnectionRefused)
+	_, err = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+}
+
+func TestFindFriendsByEmailRetriesTransientErrors(t *testing.T) {
+	mockDB, sqlMock, _ := sqlmock.New()
+	defer mockDB.Close()
+	repo := New(mockDB, WithReadRetries(resilience.Backoff{Attempts: 3, Base: time.Millisecond}))
+
+	sqlMock.ExpectQuery("SELECT requestor FROM public.relationship").WillReturnError(errConnectionRefused)
+	sqlMock.ExpectQuery("SELECT requestor FROM public.relationship").WillReturnRows(sqlmock.NewRows([]string{"requestor"}).AddRow("son.le@s3corp.com.vn"))
+	result, err := repo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"}}, result)
+
+	// the other errors are not retried
+	sqlMock.ExpectQuery("SELECT requestor FROM public.relationship").WillReturnError(errors.New("syntax error"))
+	_, err = repo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, apperrors.KindInternal, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSubscribeFromEmailIsNotRetried(t *testing.T) {
+	mockDB, sqlMock, _ := sqlmock.New()
+	defer mockDB.Close()
+	repo := New(mockDB, WithReadRetries(resilience.Backoff{Attempts: 3, Base: time.Millisecond}))
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnError(errConnectionRefused)
+	sqlMock.ExpectRollback()
+	_, err := repo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"})
+	assert.Equal(t, apperrors.KindUnavailable, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
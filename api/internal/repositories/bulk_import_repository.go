@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"golang_project/api/internal/models"
+)
+
+// the relationships of a batch, requestors[i] being related to targets[i], in one direction or in both
+const (
+	importedPairs       = `(requestor, target) IN (SELECT * FROM unnest($1::varchar[], $2::varchar[]))`
+	importedFriendPairs = `(requestor, target) IN (SELECT * FROM unnest($1::varchar[] || $2::varchar[], $2::varchar[] || $1::varchar[]))`
+)
+
+// BulkImportRepository interface declares the functions used to import users and relationships in bulk,
+// the writes of an import happen in transactions opened by Begin
+type BulkImportRepository interface {
+	Begin(ctx context.Context) (BulkImportTx, error)
+}
+
+// BulkImportTx interface declares the batched writes of a bulk import, they are visible to the other calls of the same transaction
+// relationships are given as two arrays of the same length, requestors[i] being related to targets[i]
+type BulkImportTx interface {
+	CreateUsersIfNotExist(ctx context.Context, emails []string) error
+	FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error)
+	UpsertFriendships(ctx context.Context, requestors, targets []string) error
+	UpsertSubscriptions(ctx context.Context, requestors, targets []string) error
+	UpsertBlocks(ctx context.Context, requestors, targets []string) error
+	Commit() error
+	Rollback() error
+}
+
+type bulkImportRepository struct {
+	db     *sql.DB
+	outbox bool
+}
+
+type bulkImportTx struct {
+	tx     *sql.Tx
+	outbox bool
+}
+
+// BulkImportOption function customizes a BulkImportRepository built by NewBulkImportRepository
+type BulkImportOption func(*bulkImportRepository)
+
+// WithBulkImportOutbox function used to write the domain events of the imported users and relationships to outbox table,
+// in the transaction of the import, like the writes of the FriendConnectionRepository built with WithOutbox
+// return a BulkImportOption
+func WithBulkImportOutbox() BulkImportOption {
+	return func(repo *bulkImportRepository) {
+		repo.outbox = true
+	}
+}
+
+// NewBulkImportRepository function used for initializing a BulkImportRepository
+// pass a pointer sql.DB and the options as parameters
+func NewBulkImportRepository(db *sql.DB, opts ...BulkImportOption) BulkImportRepository {
+	repo := &bulkImportRepository{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	return repo
+}
+
+// Begin function used to open the transaction of an import, or of a batch of an import
+// pass a context as parameter
+// return a BulkImportTx and an error type
+func (repo *bulkImportRepository) Begin(ctx context.Context) (BulkImportTx, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(ctx, "BeginBulkImport", err)
+	}
+	return &bulkImportTx{tx: tx, outbox: repo.outbox}, nil
+}
+
+// CreateUsersIfNotExist function used to insert a batch of users into user table, skipping the ones already registered
+// pass a context and an array of emails as parameters
+// return an error type
+func (t *bulkImportTx) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if err := insertUsers(ctx, t.tx, emails, t.outbox); err != nil {
+		return dbError(ctx, "ImportUsers", err)
+	}
+	return nil
+}
+
+// FindUnregisteredEmails function used to get the emails of a batch which are not registered, users imported earlier in the transaction included
+// pass a context and an array of emails as parameters
+// return an array of unregistered emails and an error type
+func (t *bulkImportTx) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	rows, err := t.tx.QueryContext(ctx, `SELECT DISTINCT e.email FROM unnest($1::varchar[]) AS e(email) 
+	WHERE NOT EXISTS (SELECT 1 FROM public.user_account ua WHERE ua.user_email = e.email)`, pq.Array(emails))
+	if err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredImportEmails", err)
+	}
+	defer rows.Close()
+
+	unregistered := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return []string{}, dbError(ctx, "FindUnregisteredImportEmails", err)
+		}
+		unregistered = append(unregistered, email)
+	}
+	if err := rows.Err(); err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredImportEmails", err)
+	}
+	return unregistered, nil
+}
+
+// UpsertFriendships function used to insert a batch of friend connections into relationship table, in both directions,
+// the changed relationships are audited like the friendships created one at a time
+// pass a context and two arrays of emails as parameters
+// return an error type
+func (t *bulkImportTx) UpsertFriendships(ctx context.Context, requestors, targets []string) error {
+	err := auditedWrite(ctx, t.tx, models.AuditActionFriend, importedFriendPairs, `INSERT INTO public.relationship(requestor, target, is_friend) 
+	SELECT DISTINCT r.requestor, r.target, true FROM unnest($1::varchar[] || $2::varchar[], $2::varchar[] || $1::varchar[]) AS r(requestor, target) 
+	ON CONFLICT (requestor,target) DO UPDATE SET is_friend = EXCLUDED.is_friend`, pq.Array(requestors), pq.Array(targets))
+	if err == nil && t.outbox {
+		err = appendRelationshipEvents(ctx, t.tx, models.EventFriendConnected, requestors, targets)
+	}
+	if err != nil {
+		return dbError(ctx, "ImportFriendships", err)
+	}
+	return nil
+}
+
+// UpsertSubscriptions function used to insert a batch of subscriptions into relationship table, the changed relationships are audited
+// pass a context and two arrays of emails as parameters
+// return an error type
+func (t *bulkImportTx) UpsertSubscriptions(ctx context.Context, requestors, targets []string) error {
+	err := auditedWrite(ctx, t.tx, models.AuditActionSubscribe, importedPairs, `INSERT INTO public.relationship(requestor, target, subscribed) 
+	SELECT DISTINCT r.requestor, r.target, true FROM unnest($1::varchar[], $2::varchar[]) AS r(requestor, target) 
+	ON CONFLICT (requestor,target) DO UPDATE SET subscribed = EXCLUDED.subscribed`, pq.Array(requestors), pq.Array(targets))
+	if err == nil && t.outbox {
+		err = appendRelationshipEvents(ctx, t.tx, models.EventSubscribed, requestors, targets)
+	}
+	if err != nil {
+		return dbError(ctx, "ImportSubscriptions", err)
+	}
+	return nil
+}
+
+// UpsertBlocks function used to insert a batch of blocks into relationship table, the changed relationships are audited
+// pass a context and two arrays of emails as parameters
+// return an error type
+func (t *bulkImportTx) UpsertBlocks(ctx context.Context, requestors, targets []string) error {
+	err := auditedWrite(ctx, t.tx, models.AuditActionBlock, importedPairs, `INSERT INTO public.relationship(requestor, target, subscribe_blocked) 
+	SELECT DISTINCT r.requestor, r.target, true FROM unnest($1::varchar[], $2::varchar[]) AS r(requestor, target) 
+	ON CONFLICT (requestor,target) DO UPDATE SET subscribe_blocked = EXCLUDED.subscribe_blocked`, pq.Array(requestors), pq.Array(targets))
+	if err == nil && t.outbox {
+		err = appendRelationshipEvents(ctx, t.tx, models.EventSubscriptionBlocked, requestors, targets)
+	}
+	if err != nil {
+		return dbError(ctx, "ImportBlocks", err)
+	}
+	return nil
+}
+
+// Commit function used to commit the transaction of the import
+// return an error type
+func (t *bulkImportTx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// Rollback function used to discard the writes of the import
+// return an error type
+func (t *bulkImportTx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return translateError(err)
+	}
+	return nil
+}
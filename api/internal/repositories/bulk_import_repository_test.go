@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/models"
+)
+
+func TestBulkImportFriendships(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	requestors := []string{"thehaohcm@yahoo.com.vn"}
+	targets := []string{"son.le@s3corp.com.vn"}
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("SELECT DISTINCT e.email FROM unnest").
+		WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn", "son.le@s3corp.com.vn"})).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE \\(requestor, target\\) IN (.+) FOR UPDATE").
+		WithArgs(pq.Array(requestors), pq.Array(targets)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("WITH previous AS (.+) INSERT INTO public.relationship\\(requestor, target, is_friend\\) (.+) INSERT INTO public.relationship_audit").
+		WithArgs(pq.Array(requestors), pq.Array(targets), models.AuditActionFriend, "admin@example.com", "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectCommit()
+
+	// the admin running the import is the actor of the audit
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "admin@example.com", Roles: []string{auth.RoleAdmin}})
+	tx, err := NewBulkImportRepository(mockDB).Begin(ctx)
+	assert.Nil(t, err)
+	unregistered, err := tx.FindUnregisteredEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn", "son.le@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Empty(t, unregistered)
+	assert.Nil(t, tx.UpsertFriendships(ctx, requestors, targets))
+	assert.Nil(t, tx.Commit())
+	assert.Nil(t, tx.Rollback())
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBulkImportUsersRollback(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO public.user_account").
+		WithArgs(pq.Array([]string{"a@example.com"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectRollback()
+
+	tx, err := NewBulkImportRepository(mockDB).Begin(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, tx.CreateUsersIfNotExist(context.Background(), []string{"a@example.com"}))
+	assert.Nil(t, tx.Rollback())
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBulkImportSubscriptionsWritesTheirEvents(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	requestors := []string{"thehaohcm@yahoo.com.vn", "kate@example.com"}
+	targets := []string{"son.le@s3corp.com.vn", "son.le@s3corp.com.vn"}
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("WITH created AS \\(INSERT INTO public.user_account(.+) INSERT INTO public.outbox").
+		WithArgs(pq.Array([]string{"kate@example.com"}), models.EventUserCreated, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").
+		WithArgs(pq.Array(requestors), pq.Array(targets)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("INSERT INTO public.relationship\\(requestor, target, subscribed\\)").
+		WithArgs(pq.Array(requestors), pq.Array(targets), models.AuditActionSubscribe, "", "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec("INSERT INTO public.outbox(.+) jsonb_build_object\\('requestor', r.requestor, 'target', r.target\\)(.+) WITH ORDINALITY").
+		WithArgs(pq.Array(requestors), pq.Array(targets), models.EventSubscribed, "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectCommit()
+
+	tx, err := NewBulkImportRepository(mockDB, WithBulkImportOutbox()).Begin(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, tx.CreateUsersIfNotExist(context.Background(), []string{"kate@example.com"}))
+	assert.Nil(t, tx.UpsertSubscriptions(context.Background(), requestors, targets))
+	assert.Nil(t, tx.Commit())
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"golang_project/api/internal/cache"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+// cached reads, they name the keys and the counters of cache.Stats
+const (
+	friendsCache    = "friends"
+	recipientsCache = "recipients"
+)
+
+type cachedRepository struct {
+	FriendConnectionRepository
+	cache cache.Cache
+}
+
+// NewCachedRepository function used for initializing a FriendConnectionRepository which keeps the friend lists and the recipients in a cache,
+// a relationship write invalidates the friend lists and the recipients of both of its users, the other calls are delegated as they are
+// the cache is only an optimization: when it fails, the reads go to the wrapped repository and the error is logged
+// pass the wrapped FriendConnectionRepository and a Cache as parameters
+// return a FriendConnectionRepository
+func NewCachedRepository(repo FriendConnectionRepository, c cache.Cache) FriendConnectionRepository {
+	return &cachedRepository{
+		FriendConnectionRepository: repo,
+		cache:                      c,
+	}
+}
+
+// FindFriendsByEmail function used to get the friend list of a user from the cache, or else from the wrapped repository
+func (repo *cachedRepository) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error) {
+	return readThrough(ctx, repo.cache, friendsCache, request.Email, "", func() ([]models.Relationship, error) {
+		return repo.FriendConnectionRepository.FindFriendsByEmail(ctx, request)
+	})
+}
+
+// GetSubscribingEmailListByEmail function used to get the recipients of an update from the cache, or else from the wrapped repository
+// the recipients are cached per sender and per list of mentioned users, the rest of the text does not change them
+func (repo *cachedRepository) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
+	mentions := strings.Join(mentionedEmails(req.Text), " ")
+	return readThrough(ctx, repo.cache, recipientsCache, req.Sender, mentions, func() ([]models.Relationship, error) {
+		return repo.FriendConnectionRepository.GetSubscribingEmailListByEmail(ctx, req)
+	})
+}
+
+// CreateFriendConnection function used to create a friendship, then to invalidate the cached reads of both friends
+func (repo *cachedRepository) CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, friendConnectionRequest.Friends...)
+	return repo.FriendConnectionRepository.CreateFriendConnection(ctx, friendConnectionRequest)
+}
+
+// RemoveFriendConnection function used to remove a friendship, then to invalidate the cached reads of both friends
+func (repo *cachedRepository) RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, friendConnectionRequest.Friends...)
+	return repo.FriendConnectionRepository.RemoveFriendConnection(ctx, friendConnectionRequest)
+}
+
+// SubscribeFromEmail function used to create a subscription, then to invalidate the cached reads of the requestor and the target
+func (repo *cachedRepository) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, req.Requestor, req.Target)
+	return repo.FriendConnectionRepository.SubscribeFromEmail(ctx, req)
+}
+
+// UnsubscribeFromEmail function used to remove a subscription, then to invalidate the cached reads of the requestor and the target
+func (repo *cachedRepository) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, req.Requestor, req.Target)
+	return repo.FriendConnectionRepository.UnsubscribeFromEmail(ctx, req)
+}
+
+// BlockSubscribeByEmail function used to block a user, then to invalidate the cached reads of the requestor and the target
+func (repo *cachedRepository) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, req.Requestor, req.Target)
+	return repo.FriendConnectionRepository.BlockSubscribeByEmail(ctx, req)
+}
+
+// UnblockSubscribeByEmail function used to unblock a user, then to invalidate the cached reads of the requestor and the target
+func (repo *cachedRepository) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	defer repo.invalidate(ctx, req.Requestor, req.Target)
+	return repo.FriendConnectionRepository.UnblockSubscribeByEmail(ctx, req)
+}
+
+// invalidate function used to drop the cached reads of the users of a relationship write,
+// the write is invalidated even when it failed, a timeout can hide a committed write
+func (repo *cachedRepository) invalidate(ctx context.Context, emails ...string) {
+	invalidateRelationships(ctx, repo.cache, emails...)
+}
+
+type cachedBulkImportRepository struct {
+	BulkImportRepository
+	cache cache.Cache
+}
+
+type cachedBulkImportTx struct {
+	BulkImportTx
+	cache  cache.Cache
+	emails map[string]bool
+}
+
+// NewCachedBulkImportRepository function used for initializing a BulkImportRepository which invalidates the cached reads
+// of every user related by an import, once its transaction is committed, see NewCachedRepository
+// pass the wrapped BulkImportRepository and the Cache of the FriendConnectionRepository as parameters
+// return a BulkImportRepository
+func NewCachedBulkImportRepository(repo BulkImportRepository, c cache.Cache) BulkImportRepository {
+	return &cachedBulkImportRepository{
+		BulkImportRepository: repo,
+		cache:                c,
+	}
+}
+
+// Begin function used to open the transaction of an import, it remembers the users of the relationships written in it
+func (repo *cachedBulkImportRepository) Begin(ctx context.Context) (BulkImportTx, error) {
+	tx, err := repo.BulkImportRepository.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedBulkImportTx{BulkImportTx: tx, cache: repo.cache, emails: map[string]bool{}}, nil
+}
+
+// UpsertFriendships function used to write friendships and to remember their users
+func (t *cachedBulkImportTx) UpsertFriendships(ctx context.Context, requestors, targets []string) error {
+	t.remember(requestors, targets)
+	return t.BulkImportTx.UpsertFriendships(ctx, requestors, targets)
+}
+
+// UpsertSubscriptions function used to write subscriptions and to remember their users
+func (t *cachedBulkImportTx) UpsertSubscriptions(ctx context.Context, requestors, targets []string) error {
+	t.remember(requestors, targets)
+	return t.BulkImportTx.UpsertSubscriptions(ctx, requestors, targets)
+}
+
+// UpsertBlocks function used to write blocks and to remember their users
+func (t *cachedBulkImportTx) UpsertBlocks(ctx context.Context, requestors, targets []string) error {
+	t.remember(requestors, targets)
+	return t.BulkImportTx.UpsertBlocks(ctx, requestors, targets)
+}
+
+// Commit function used to commit the transaction, then to invalidate the cached reads of the users it related
+func (t *cachedBulkImportTx) Commit() error {
+	err := t.BulkImportTx.Commit()
+	emails := make([]string, 0, len(t.emails))
+	for email := range t.emails {
+		emails = append(emails, email)
+	}
+	invalidateRelationships(context.Background(), t.cache, emails...)
+	return err
+}
+
+func (t *cachedBulkImportTx) remember(requestors, targets []string) {
+	for _, email := range requestors {
+		t.emails[email] = true
+	}
+	for _, email := range targets {
+		t.emails[email] = true
+	}
+}
+
+// readThrough function used to get a cached read, or else to run it and to cache its result
+// the errors are not cached, and a context marked by cache.WithBypass skips the cached result but refreshes it
+func readThrough(ctx context.Context, c cache.Cache, name, email, field string, read func() ([]models.Relationship, error)) ([]models.Relationship, error) {
+	key := name + ":" + email
+	value, found, version, err := c.Get(ctx, key, field)
+	if err != nil {
+		cache.Stats.Add(name+"_errors", 1)
+		logger.FromContext(ctx).Warn("cannot read the cache, the repository is read instead", slog.String("cache", name), slog.Any("error", err))
+		return read()
+	}
+
+	if cache.Bypassed(ctx) {
+		cache.Stats.Add(name+"_bypasses", 1)
+	} else if found {
+		var relationships []models.Relationship
+		if err := json.Unmarshal(value, &relationships); err == nil {
+			cache.Stats.Add(name+"_hits", 1)
+			return relationships, nil
+		}
+		cache.Stats.Add(name+"_errors", 1)
+	} else {
+		cache.Stats.Add(name+"_misses", 1)
+	}
+
+	relationships, err := read()
+	if err != nil {
+		return relationships, err
+	}
+	if value, err = json.Marshal(relationships); err == nil {
+		err = c.Set(ctx, key, field, value, version)
+	}
+	if err != nil {
+		cache.Stats.Add(name+"_errors", 1)
+		logger.FromContext(ctx).Warn("cannot write the cache", slog.String("cache", name), slog.Any("error", err))
+	}
+	return relationships, nil
+}
+
+// invalidateRelationships function used to drop the cached friend lists and recipients of users,
+// a relationship between two users changes the friend lists and the recipients of both
+// the invalidation is not canceled with the request, it must happen once the write is done
+func invalidateRelationships(ctx context.Context, c cache.Cache, emails ...string) {
+	if len(emails) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	keys := make([]string, 0, 2*len(emails))
+	for _, email := range emails {
+		keys = append(keys, friendsCache+":"+email, recipientsCache+":"+email)
+	}
+	cache.Stats.Add("invalidations", int64(len(keys)))
+	if err := c.Invalidate(ctx, keys...); err != nil {
+		cache.Stats.Add("invalidation_errors", 1)
+		logger.FromContext(ctx).Error("cannot invalidate the cache, the cached reads can be stale until they expire", slog.Any("keys", keys), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/cache"
+	"golang_project/api/internal/models"
+)
+
+func TestCachedRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) FriendConnectionRepository {
+		return NewCachedRepository(NewMemoryRepository(), cache.NewLRU(100, time.Minute))
+	})
+}
+
+func TestRedisCachedRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) FriendConnectionRepository {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return NewCachedRepository(NewMemoryRepository(), cache.NewRedis(client, "test:", time.Minute))
+	})
+}
+
+func TestCachedRepositoryReadsThroughAndInvalidates(t *testing.T) {
+	memory := NewMemoryRepository()
+	repo := NewCachedRepository(memory, cache.NewLRU(100, time.Minute))
+	ctx := context.Background()
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{contractAlice, contractBob, contractCarol}))
+
+	hits, misses := cacheCounter("friends_hits"), cacheCounter("friends_misses")
+	friends, err := repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.Nil(t, err)
+	assert.Empty(t, friends)
+	friends, _ = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.Empty(t, friends)
+	assert.Equal(t, hits+1, cacheCounter("friends_hits"))
+	assert.Equal(t, misses+1, cacheCounter("friends_misses"))
+
+	// a write made through the wrapped repository is not seen until the cached list expires
+	_, err = memory.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{contractAlice, contractBob}})
+	assert.Nil(t, err)
+	friends, _ = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.Empty(t, friends)
+
+	// but the Cache-Control bypass reads it, and refreshes the cache
+	friends, _ = repo.FindFriendsByEmail(cache.WithBypass(ctx), models.FriendListRequest{Email: contractAlice})
+	assert.Equal(t, []string{contractBob}, friendTargets(friends))
+	friends, _ = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.Equal(t, []string{contractBob}, friendTargets(friends))
+
+	// a write made through the cached repository invalidates the reads of both users
+	recipients, _ := repo.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: contractCarol, Text: "hello"})
+	assert.Empty(t, recipients)
+	_, err = repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{contractCarol, contractAlice}})
+	assert.Nil(t, err)
+	friends, _ = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.ElementsMatch(t, []string{contractBob, contractCarol}, friendTargets(friends))
+	recipients, _ = repo.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: contractCarol, Text: "hello"})
+	assert.Equal(t, []string{contractAlice}, friendTargets(recipients))
+
+	// the recipients are cached per list of mentioned users
+	recipients, _ = repo.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: contractCarol, Text: "hello " + contractBob})
+	assert.Equal(t, []string{contractBob, contractAlice}, friendTargets(recipients))
+}
+
+func TestCachedRepositoryReadsTheRepositoryWhenTheCacheFails(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	memory := NewMemoryRepository()
+	repo := NewCachedRepository(memory, cache.NewRedis(client, "test:", time.Minute))
+	ctx := context.Background()
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{contractAlice, contractBob}))
+	server.Close()
+
+	errs := cacheCounter("friends_errors")
+	_, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{contractAlice, contractBob}})
+	assert.Nil(t, err)
+	friends, err := repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: contractAlice})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{contractBob}, friendTargets(friends))
+	assert.Equal(t, errs+1, cacheCounter("friends_errors"))
+}
+
+func TestCachedRepositoryDoesNotCacheErrors(t *testing.T) {
+	c := cache.NewLRU(100, time.Minute)
+	repo := NewCachedRepository(NewMemoryRepository(), c)
+
+	_, err := repo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "invalid"})
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCachedBulkImportRepositoryInvalidatesOnCommit(t *testing.T) {
+	c := cache.NewLRU(100, time.Minute)
+	ctx := context.Background()
+	for _, key := range []string{"friends:" + contractAlice, "recipients:" + contractBob, "friends:" + contractCarol} {
+		_, _, version, _ := c.Get(ctx, key, "")
+		assert.Nil(t, c.Set(ctx, key, "", []byte("null"), version))
+	}
+
+	repo := NewCachedBulkImportRepository(bulkImportRepoStub{}, c)
+	tx, err := repo.Begin(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.UpsertFriendships(ctx, []string{contractAlice}, []string{contractBob}))
+	_, found, _, _ := c.Get(ctx, "friends:"+contractAlice, "")
+	assert.True(t, found)
+
+	assert.Nil(t, tx.Commit())
+	_, found, _, _ = c.Get(ctx, "friends:"+contractAlice, "")
+	assert.False(t, found)
+	_, found, _, _ = c.Get(ctx, "recipients:"+contractBob, "")
+	assert.False(t, found)
+	_, found, _, _ = c.Get(ctx, "friends:"+contractCarol, "")
+	assert.True(t, found)
+}
+
+type bulkImportRepoStub struct{}
+
+func (bulkImportRepoStub) Begin(ctx context.Context) (BulkImportTx, error) {
+	return bulkImportTxStub{}, nil
+}
+
+type bulkImportTxStub struct{}
+
+func (bulkImportTxStub) CreateUsersIfNotExist(ctx context.Context, emails []string) error { return nil }
+func (bulkImportTxStub) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	return nil, nil
+}
+func (bulkImportTxStub) UpsertFriendships(ctx context.Context, requestors, targets []string) error {
+	return nil
+}
+func (bulkImportTxStub) UpsertSubscriptions(ctx context.Context, requestors, targets []string) error {
+	return nil
+}
+func (bulkImportTxStub) UpsertBlocks(ctx context.Context, requestors, targets []string) error {
+	return nil
+}
+func (bulkImportTxStub) Commit() error   { return nil }
+func (bulkImportTxStub) Rollback() error { return errors.New("not committed") }
+
+func cacheCounter(name string) int64 {
+	if counter, ok := cache.Stats.Get(name).(interface{ Value() int64 }); ok {
+		return counter.Value()
+	}
+	return 0
+}
+
+func friendTargets(relationships []models.Relationship) []string {
+	targets := []string{}
+	for _, relationship := range relationships {
+		targets = append(targets, relationship.Target)
+	}
+	return targets
+}
@@ -3,107 +3,328 @@ package repositories
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/logger"
 	"golang_project/api/internal/models"
 	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/replica"
+	"golang_project/api/internal/resilience"
 )
 
 // FriendConnectionRepository interface declares all functions used in Repository layer
 // and also decouple when invoking these function from Service layer to Repository layer
 // this interface is also useful when we create all mock Repository functions for testing
 type FriendConnectionRepository interface {
-	CreateUser(models.CreatingUserRequest) (models.User, error)
-	FindFriendsByEmail(models.FriendListRequest) ([]models.Relationship, error)
-	FindCommonFriendsByEmails(models.CommonFriendListRequest) ([]models.Relationship, error)
-	CreateFriendConnection(friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error)
-	SubscribeFromEmail(req models.SubscribeRequest) (models.Relationship, error)
-	BlockSubscribeByEmail(req models.BlockSubscribeRequest) (models.Relationship, error)
-	GetSubscribingEmailListByEmail(req models.GetSubscribingEmailListRequest) ([]models.Relationship, error)
+	CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.User, error)
+	CreateUsersIfNotExist(ctx context.Context, emails []string) error
+	FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error)
+	FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error)
+	FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) ([]models.Relationship, error)
+	CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error)
+	RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error)
+	SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error)
+	UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error)
+	BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error)
+	UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error)
+	GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error)
+	StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error
+	FindRelationshipsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error)
+	FindFriendsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error)
+	FindSubscribersByEmails(ctx context.Context, emails []string) ([]models.Relationship, error)
+	FindPrivacySettingsByEmails(ctx context.Context, emails []string) ([]models.PrivacySettings, error)
+	SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error)
 }
 
 type repository struct {
-	db  *sql.DB
-	ctx context.Context
+	db       *sql.DB
+	replicas *replica.Set
+	retries  resilience.Backoff
+	outbox   bool
+}
+
+// Option function customizes a FriendConnectionRepository built by New
+type Option func(*repository)
+
+// WithReplicas function used to send the friend lists, the common friends and the recipients to the read replicas of a Set,
+// the other calls and the writes still use the primary database
+// pass a pointer of replica.Set, whose primary is the database given to New, as parameter
+// return an Option
+func WithReplicas(set *replica.Set) Option {
+	return func(repo *repository) {
+		repo.replicas = set
+	}
+}
+
+// WithReadRetries function used to retry the reads failing with a transient error, e.g. a lost connection or a serialization failure,
+// the writes are never retried since they may have been committed
+// pass a resilience.Backoff as parameter
+// return an Option
+func WithReadRetries(backoff resilience.Backoff) Option {
+	return func(repo *repository) {
+		repo.retries = backoff
+	}
+}
+
+// WithOutbox function used to write the domain events of the writes (UserCreated, FriendConnected, Subscribed, SubscriptionBlocked)
+// to outbox table, in the transaction of the write, see OutboxRepository
+// return an Option
+func WithOutbox() Option {
+	return func(repo *repository) {
+		repo.outbox = true
+	}
 }
 
 // New function used for initializing a FriendConnectionRepository
-// pass a pointer sql.DB as parameter
-func New(db *sql.DB) FriendConnectionRepository {
-	return &repository{
-		db:  db,
-		ctx: context.Background(),
+// pass a pointer sql.DB and the options as parameters
+func New(db *sql.DB, opts ...Option) FriendConnectionRepository {
+	repo := &repository{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(repo)
 	}
+	return repo
+}
+
+// queryReplica function used to run a read concerning users on the database chosen by the replica Set,
+// the read runs again on the primary when the replica is unreachable, and it is retried on transient errors, see WithReadRetries
+// pass a context, the emails of the users, the query and its arguments as parameters
+// return a pointer of sql.Rows and an error type
+func (repo *repository) queryReplica(ctx context.Context, emails []string, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := repo.retries.Retry(ctx, transientError, func() (err error) {
+		db := repo.reader(emails...)
+		rows, err = db.QueryContext(ctx, query, args...)
+		if repo.replicaFailed(ctx, db, err) {
+			rows, err = repo.db.QueryContext(ctx, query, args...)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// query function used to run a read on the primary database, retried on transient errors, see WithReadRetries
+// pass a context, the query and its arguments as parameters
+// return a pointer of sql.Rows and an error type
+func (repo *repository) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := repo.retries.Retry(ctx, transientError, func() (err error) {
+		rows, err = repo.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// reader function used to get the database of a read concerning users, the primary when there is no replica
+func (repo *repository) reader(emails ...string) *sql.DB {
+	if repo.replicas == nil {
+		return repo.db
+	}
+	return repo.replicas.Reader(emails...)
+}
+
+// replicaFailed function used to know whether a read failed because its replica is unreachable, the replica is then reported to the Set
+// pass a context, the database of the read and its error as parameters
+// return true when the read should run again on the primary
+func (repo *repository) replicaFailed(ctx context.Context, db *sql.DB, err error) bool {
+	if err == nil || db == repo.db || ctx.Err() != nil || apperrors.KindOf(translateError(err)) != apperrors.KindUnavailable {
+		return false
+	}
+	repo.replicas.Failed(db, err)
+	logger.FromContext(ctx).Warn("read replica failed, reading the primary", slog.Any("error", err))
+	return true
+}
+
+// wrote function used to send the reads of the users of a relationship write to the primary for a while, see replica.Set
+// the write is remembered even when it failed, a timeout can hide a committed write
+func (repo *repository) wrote(emails ...string) {
+	if repo.replicas != nil {
+		repo.replicas.Wrote(emails...)
+	}
+}
+
+// appendEvent function used to write a domain event in the transaction of a write, when the outbox is enabled, see WithOutbox
+func (repo *repository) appendEvent(ctx context.Context, tx *sql.Tx, eventType, aggregateID string, payload interface{}) error {
+	if !repo.outbox {
+		return nil
+	}
+	return appendEvent(ctx, tx, eventType, aggregateID, payload)
 }
 
 // CreateUser function used to insert data of a new user into user table
-// pass a CreatingUserRequest model as parameter
+// pass a context and a CreatingUserRequest model as parameters
 // return a User model and an error type
-func (repo *repository) CreateUser(request models.CreatingUserRequest) (models.User, error) {
+func (repo *repository) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.User, error) {
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
 		return models.User{}, err
 	}
 
-	tx, err := repo.db.BeginTx(repo.ctx, nil)
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, dbError(ctx, "CreateUser", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO public.user_account(user_email) VALUES($1)`, request.Email)
+	if err == nil {
+		err = repo.appendEvent(ctx, tx, models.EventUserCreated, request.Email, models.UserCreatedPayload{Email: request.Email})
 	}
-	_, err = tx.Exec(`INSERT INTO public.user_account(user_email) VALUES($1)`, request.Email)
 
 	if err != nil {
 		tx.Rollback()
-		return models.User{}, err
+		return models.User{}, dbError(ctx, "CreateUser", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.User{}, dbError(ctx, "CreateUser", err)
 	}
-	tx.Commit()
 
 	return models.User{Email: request.Email}, nil
 }
 
+// CreateUsersIfNotExist function used to insert a list of users into user table, skipping the ones already registered
+// pass a context and an array of emails as parameters
+// return an error type
+func (repo *repository) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return err
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+	err = insertUsers(ctx, tx, emails, repo.outbox)
+
+	if err != nil {
+		tx.Rollback()
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+
+	return nil
+}
+
+// FindUnregisteredEmails function used to query user table to get the emails which are not registered yet
+// pass a context and an array of emails as parameters
+// return an array of unregistered emails, in the order they were given, and an error type
+func (repo *repository) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return []string{}, nil
+	}
+
+	rows, err := repo.query(ctx, `SELECT e.email FROM unnest($1::varchar[]) WITH ORDINALITY AS e(email, idx) 
+	WHERE NOT EXISTS (SELECT 1 FROM public.user_account ua WHERE ua.user_email = e.email) ORDER BY e.idx`, pq.Array(emails))
+	if err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+	}
+	defer rows.Close()
+
+	unregistered := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+		}
+		unregistered = append(unregistered, email)
+	}
+	if err := rows.Err(); err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+	}
+
+	return pkg.RemoveDuplicatedItems(unregistered), nil
+}
+
+// rows of relationship table written by a relationship write, $1 being the requestor and $2 the target, see auditedWrite
+const (
+	oneDirection   = `requestor=$1 AND target=$2`
+	bothDirections = `((requestor=$1 AND target=$2) OR (requestor=$2 AND target=$1))`
+)
+
 // CreateFriendConnection function used to insert data of a new friend connection into relationship table
-// pass a FriendConnectionRequest model as parameter
+// pass a context and a FriendConnectionRequest model as parameters
 // return a Relationship model and an error type
-func (repo *repository) CreateFriendConnection(friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+func (repo *repository) CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
 	//check empty or invalid email format
 	if len(friendConnectionRequest.Friends) != 2 {
-		return models.Relationship{}, errors.New("invalid request")
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
 	}
 	if err := pkg.CheckValidEmails([]string{friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1]}); err != nil {
 		return models.Relationship{}, err
 	}
+	defer repo.wrote(friendConnectionRequest.Friends...)
 
-	tx, err := repo.db.BeginTx(repo.ctx, nil)
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Relationship{}, err
+		return models.Relationship{}, dbError(ctx, "CreateFriendConnection", err)
 	}
-	_, err = tx.Exec(`INSERT INTO public.relationship(requestor, target, is_friend) 
+	err = auditedWrite(ctx, tx, models.AuditActionFriend, bothDirections, `INSERT INTO public.relationship(requestor, target, is_friend) 
 	VALUES($1,$2,true),($2,$1,true) ON CONFLICT (requestor,target) 
 	DO UPDATE SET is_friend = EXCLUDED.is_friend`, friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1])
+	if err == nil {
+		err = repo.appendEvent(ctx, tx, models.EventFriendConnected, friendConnectionRequest.Friends[0], models.FriendConnectedPayload{Friends: friendConnectionRequest.Friends})
+	}
 
 	if err != nil {
 		tx.Rollback()
-		return models.Relationship{}, err
+		return models.Relationship{}, dbError(ctx, "CreateFriendConnection", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "CreateFriendConnection", err)
 	}
-	tx.Commit()
 
 	return models.Relationship{Requestor: friendConnectionRequest.Friends[0], Target: friendConnectionRequest.Friends[1], IsFriend: true}, nil
 }
 
+// RemoveFriendConnection function used to update data in relationship table to remove a friend connection in both directions
+// removing a connection which does not exist is not an error
+// pass a context and a FriendConnectionRequest model as parameters
+// return a Relationship model and an error type
+func (repo *repository) RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	if len(friendConnectionRequest.Friends) != 2 {
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if err := pkg.CheckValidEmails(friendConnectionRequest.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+	defer repo.wrote(friendConnectionRequest.Friends...)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Relationship{}, dbError(ctx, "RemoveFriendConnection", err)
+	}
+	err = auditedWrite(ctx, tx, models.AuditActionUnfriend, bothDirections, `UPDATE public.relationship SET is_friend=false 
+	WHERE `+bothDirections, friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1])
+
+	if err != nil {
+		tx.Rollback()
+		return models.Relationship{}, dbError(ctx, "RemoveFriendConnection", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "RemoveFriendConnection", err)
+	}
+
+	return models.Relationship{Requestor: friendConnectionRequest.Friends[0], Target: friendConnectionRequest.Friends[1]}, nil
+}
+
 // FindFriendsByEmail function used to query data from relationship table to get a list of friend emails by an email address
-// pass a FriendListRequest model as parameter
+// pass a context and a FriendListRequest model as parameters
 // return an array of Relationship model and an error type
-func (repo *repository) FindFriendsByEmail(request models.FriendListRequest) ([]models.Relationship, error) {
+func (repo *repository) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
 		return []models.Relationship{}, err
 	}
 
-	rows, err := repo.db.Query(`SELECT requestor FROM public.relationship WHERE target=$1 and is_friend=true AND friend_blocked=false 
+	rows, err := repo.queryReplica(ctx, []string{request.Email}, `SELECT requestor FROM public.relationship WHERE target=$1 and is_friend=true AND friend_blocked=false 
 	UNION SELECT target FROM public.relationship WHERE requestor=$1 and is_friend=true AND friend_blocked=false`, request.Email)
 
 	if err != nil {
-		return []models.Relationship{}, err
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
 	}
 	defer rows.Close()
 
@@ -111,18 +332,21 @@ func (repo *repository) FindFriendsByEmail(request models.FriendListRequest) ([]
 	for rows.Next() {
 		relationshipTmp := models.Relationship{Requestor: request.Email}
 		if err := rows.Scan(&relationshipTmp.Target); err != nil {
-			return []models.Relationship{}, err
+			return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
 		}
 		relationships = append(relationships, relationshipTmp)
 	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
+	}
 
 	return relationships, nil
 }
 
 // FindCommonFriendsByEmails function used to query data from relationship table to get a list of common friend emails between 2 email addresses
-// pass a CommonFriendListRequest model as parameter
+// pass a context and a CommonFriendListRequest model as parameters
 // return an array of Relationship model and an error type
-func (repo *repository) FindCommonFriendsByEmails(request models.CommonFriendListRequest) ([]models.Relationship, error) {
+func (repo *repository) FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return []models.Relationship{}, err
 	}
@@ -140,9 +364,9 @@ func (repo *repository) FindCommonFriendsByEmails(request models.CommonFriendLis
 		`) group by target having count(*)>1 union SELECT requestor ,count(*) FROM public.relationship where target in (` +
 		dollarSignParams + `) and requestor not in(` + dollarSignParams + `) group by requestor having count(*)>1`
 
-	rows, err := repo.db.Query(sqlStatement, arg...)
+	rows, err := repo.queryReplica(ctx, request.Friends, sqlStatement, arg...)
 	if err != nil {
-		return []models.Relationship{}, err
+		return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
 	}
 	defer rows.Close()
 
@@ -151,142 +375,431 @@ func (repo *repository) FindCommonFriendsByEmails(request models.CommonFriendLis
 		var relationship models.Relationship
 		var count int
 		if err := rows.Scan(&relationship.Target, &count); err != nil {
-			return []models.Relationship{}, err
+			return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
 		}
 		relationships = append(relationships, relationship)
 	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
+	}
 
 	return relationships, nil
 }
 
 // SubscribeFromEmail function used to insert a new subscribe connection into relationship table
-// pass a SubscribeRequest model as parameter
+// pass a context and a SubscribeRequest model as parameters
 // return a Relationship model and an error type
-func (repo *repository) SubscribeFromEmail(req models.SubscribeRequest) (models.Relationship, error) {
+func (repo *repository) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
 	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
 		return models.Relationship{}, err
 	}
-	tx, err := repo.db.BeginTx(repo.ctx, nil)
+	defer repo.wrote(req.Requestor, req.Target)
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Relationship{}, err
+		return models.Relationship{}, dbError(ctx, "SubscribeFromEmail", err)
 	}
 
-	_, err = tx.Exec(`INSERT INTO public.relationship(requestor, target, subscribed) VALUES ($1,$2,true) 
+	err = auditedWrite(ctx, tx, models.AuditActionSubscribe, oneDirection, `INSERT INTO public.relationship(requestor, target, subscribed) VALUES ($1,$2,true) 
 	ON CONFLICT (requestor,target) DO UPDATE SET subscribed = EXCLUDED.subscribed`, req.Requestor, req.Target)
+	if err == nil {
+		err = repo.appendEvent(ctx, tx, models.EventSubscribed, req.Requestor, models.SubscriptionPayload{Requestor: req.Requestor, Target: req.Target})
+	}
 
 	if err != nil {
 		tx.Rollback()
-		return models.Relationship{}, err
+		return models.Relationship{}, dbError(ctx, "SubscribeFromEmail", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "SubscribeFromEmail", err)
 	}
-	tx.Commit()
 
 	return models.Relationship{Requestor: req.Requestor, Target: req.Target, Subscribed: true}, nil
 }
 
-// BlockSubscribeByEmail function used to update data in relationship table to block a subscribe connection
-// pass a BlockSubscribeRequest model as parameter
+// UnsubscribeFromEmail function used to update data in relationship table to remove a subscribe connection
+// removing a subscription which does not exist is not an error
+// pass a context and a SubscribeRequest model as parameters
 // return a Relationship model and an error type
-func (repo *repository) BlockSubscribeByEmail(req models.BlockSubscribeRequest) (models.Relationship, error) {
+func (repo *repository) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
 	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
 		return models.Relationship{}, err
 	}
-	tx, err := repo.db.BeginTx(repo.ctx, nil)
+	defer repo.wrote(req.Requestor, req.Target)
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Relationship{}, dbError(ctx, "UnsubscribeFromEmail", err)
+	}
+
+	err = auditedWrite(ctx, tx, models.AuditActionUnsubscribe, oneDirection, `UPDATE public.relationship SET subscribed=false WHERE `+oneDirection, req.Requestor, req.Target)
+
 	if err != nil {
+		tx.Rollback()
+		return models.Relationship{}, dbError(ctx, "UnsubscribeFromEmail", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "UnsubscribeFromEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// BlockSubscribeByEmail function used to update data in relationship table to block a subscribe connection
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *repository) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
 		return models.Relationship{}, err
 	}
+	defer repo.wrote(req.Requestor, req.Target)
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Relationship{}, dbError(ctx, "BlockSubscribeByEmail", err)
+	}
 
 	// suppose A block B:
 	// if A and B are friend, A no longer receive notify from B
-	_, err = tx.Exec(`INSERT INTO public.relationship(requestor,target,subscribe_blocked) VALUES ($1,$2,true) 
+	err = auditedWrite(ctx, tx, models.AuditActionBlock, oneDirection, `INSERT INTO public.relationship(requestor,target,subscribe_blocked) VALUES ($1,$2,true) 
 	ON CONFLICT (requestor,target) DO UPDATE SET subscribe_blocked = EXCLUDED.subscribe_blocked`, req.Requestor, req.Target)
+	if err == nil {
+		err = repo.appendEvent(ctx, tx, models.EventSubscriptionBlocked, req.Requestor, models.SubscriptionPayload{Requestor: req.Requestor, Target: req.Target})
+	}
 
 	if err != nil {
 		tx.Rollback()
-		return models.Relationship{}, err
+		return models.Relationship{}, dbError(ctx, "BlockSubscribeByEmail", err)
 	}
 
-	tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "BlockSubscribeByEmail", err)
+	}
 
 	return models.Relationship{Requestor: req.Requestor, Target: req.Target, FriendBlocked: true}, nil
 }
 
-// GetSubscribingEmailListByEmail function used to update data in relationship table to block a subscribe connection
-// pass a GetSubscribingEmailListRequest model as parameter
-// return an array of Relationship model and an error type
-func (repo *repository) GetSubscribingEmailListByEmail(req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
+// UnblockSubscribeByEmail function used to update data in relationship table to lift a block on a subscribe connection
+// lifting a block which does not exist is not an error
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *repository) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+	defer repo.wrote(req.Requestor, req.Target)
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Relationship{}, dbError(ctx, "UnblockSubscribeByEmail", err)
+	}
+
+	err = auditedWrite(ctx, tx, models.AuditActionUnblock, oneDirection, `UPDATE public.relationship SET subscribe_blocked=false WHERE `+oneDirection, req.Requestor, req.Target)
+
+	if err != nil {
+		tx.Rollback()
+		return models.Relationship{}, dbError(ctx, "UnblockSubscribeByEmail", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Relationship{}, dbError(ctx, "UnblockSubscribeByEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// recipientsQuery resolves the recipients of an update in a single statement:
+// the mentioned emails first, in the order of the text, then the friends and subscribers of the sender ordered by email,
+// every email once, without the sender and without the mentioned users who blocked the sender.
+// The friend rule reads the rows of the sender through the primary key, the subscriber rule and the block check of the mentions
+// use the partial indexes of create_relationship_indexes.up.sql
+const recipientsQuery = `WITH mentioned AS (
+	SELECT m.email, min(m.idx) AS position FROM unnest($2::varchar[]) WITH ORDINALITY AS m(email, idx)
+	WHERE m.email <> $1 AND NOT EXISTS (SELECT 1 FROM public.relationship rs WHERE rs.requestor = m.email AND rs.target = $1 AND rs.subscribe_blocked = true)
+	GROUP BY m.email
+), connected AS (
+	SELECT rs.target AS email FROM public.relationship rs
+	WHERE rs.requestor = $1 AND rs.is_friend = true AND rs.friend_blocked = false AND rs.subscribe_blocked = false
+	UNION
+	SELECT rs.requestor FROM public.relationship rs
+	WHERE rs.target = $1 AND rs.subscribed = true AND rs.subscribe_blocked = false
+)
+SELECT email FROM (
+	SELECT email, 0 AS source, position FROM mentioned
+	UNION ALL
+	SELECT c.email, 1, 0 FROM connected c WHERE c.email <> $1 AND NOT EXISTS (SELECT 1 FROM mentioned m WHERE m.email = c.email)
+) recipients ORDER BY source, position, email`
+
+// GetSubscribingEmailListByEmail function used to get the emails which receive an update of a sender, with a single query:
+// the friends of the sender, except the blocked friendships, the subscribers of the sender, except the blocked subscriptions,
+// and the valid emails mentioned in the text, which are listed first
+// pass a context and a GetSubscribingEmailListRequest model as parameters
+// return an array of Relationship model with the recipients as Target and an error type
+func (repo *repository) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmail(req.Sender); err != nil {
 		return []models.Relationship{}, err
 	}
 
+	rows, err := repo.queryReplica(ctx, []string{req.Sender}, recipientsQuery, req.Sender, pq.Array(mentionedEmails(req.Text)))
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+	}
+	defer rows.Close()
+
 	var relationships []models.Relationship
+	for rows.Next() {
+		var relationship models.Relationship
+		if err := rows.Scan(&relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+	}
 
-	// has a friend connection
-	rows, err := repo.db.Query(`SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked 
-	FROM public.relationship rs WHERE rs.requestor=$1 AND is_friend=true AND friend_blocked=false AND subscribe_blocked=false`, req.Sender)
+	return relationships, nil
+}
+
+// recipientsFetchSize is the number of recipients read from the cursor of StreamRecipients at once
+const recipientsFetchSize = 1000
+
+// StreamRecipients function used to get the same recipients as GetSubscribingEmailListByEmail, in the same order,
+// from a server-side cursor read recipientsFetchSize rows at a time, so the memory used does not grow with the audience
+// the callback stops the stream by returning an error
+// pass a context, a GetSubscribingEmailListRequest model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (repo *repository) StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	if err := pkg.CheckValidEmail(req.Sender); err != nil {
+		return err
+	}
+
+	// a cursor lives in a transaction, which only reads and is rolled back once the cursor is consumed
+	db := repo.reader(req.Sender)
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if repo.replicaFailed(ctx, db, err) {
+		tx, err = repo.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	}
 	if err != nil {
-		return []models.Relationship{}, err
+		return dbError(ctx, "StreamRecipients", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DECLARE recipients NO SCROLL CURSOR FOR `+recipientsQuery, req.Sender, pq.Array(mentionedEmails(req.Text))); err != nil {
+		return dbError(ctx, "StreamRecipients", err)
 	}
+	for {
+		count, err := fetchRecipients(ctx, tx, fn)
+		if err != nil {
+			return err
+		}
+		if count < recipientsFetchSize {
+			return nil
+		}
+	}
+}
 
+// fetchRecipients function used to pass the next rows of the cursor of StreamRecipients to its callback
+// return the number of rows read and an error type
+func fetchRecipients(ctx context.Context, tx *sql.Tx, fn func(email string) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, `FETCH FORWARD `+strconv.Itoa(recipientsFetchSize)+` FROM recipients`)
+	if err != nil {
+		return 0, dbError(ctx, "StreamRecipients", err)
+	}
 	defer rows.Close()
-	var friends []string
+
+	count := 0
 	for rows.Next() {
-		var relationshipTmp models.Relationship
-		if err := rows.Scan(&relationshipTmp.Requestor, &relationshipTmp.Target, &relationshipTmp.IsFriend, &relationshipTmp.FriendBlocked, &relationshipTmp.Subscribed, &relationshipTmp.SubscribeBlock); err != nil {
-			return []models.Relationship{}, err
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return count, dbError(ctx, "StreamRecipients", err)
+		}
+		count++
+		if err := fn(email); err != nil {
+			return count, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, dbError(ctx, "StreamRecipients", err)
+	}
+	return count, nil
+}
+
+// mentionedEmails function used to get the valid emails mentioned in the text of an update, the words are separated by spaces
+// pass the text as parameter
+// return an array of emails, in the order of the text
+func mentionedEmails(text string) []string {
+	mentions := []string{}
+	for _, word := range strings.Split(text, " ") {
+		if pkg.CheckValidEmail(word) == nil {
+			mentions = append(mentions, word)
 		}
-		friends = append(friends, relationshipTmp.Requestor, relationshipTmp.Target)
 	}
+	return mentions
+}
 
-	// if has a friend connection, but blocked in subscribers tables
-	rows, err = repo.db.Query(`SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked FROM public.relationship rs 
-	WHERE (rs.requestor=$1 OR rs.target=$1) AND is_friend=true AND subscribed=true AND friend_blocked=false AND subscribe_blocked=false`, req.Sender)
+// FindRelationshipsByEmails function used to query every row of relationship table involving one of the given email addresses, in a single query
+// it lets callers resolving many users at once (e.g. the GraphQL loader) avoid one query per user
+// pass a context and an array of emails as parameters
+// return an array of Relationship model and an error type
+func (repo *repository) FindRelationshipsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
 
+	rows, err := repo.query(ctx, `SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked 
+	FROM public.relationship WHERE requestor = ANY($1::varchar[]) OR target = ANY($1::varchar[])`, pq.Array(emails))
 	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+	}
+	defer rows.Close()
+
+	relationships := []models.Relationship{}
+	for rows.Next() {
+		var relationship models.Relationship
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target, &relationship.IsFriend, &relationship.FriendBlocked, &relationship.Subscribed, &relationship.SubscribeBlock); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// FindFriendsByEmails function used to query the friends of many email addresses at once, with a single query
+// each returned relationship has one of the emails as Requestor and one of its friends as Target
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Requestor and Target, and an error type
+func (repo *repository) FindFriendsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
 		return []models.Relationship{}, err
 	}
 
+	rows, err := repo.query(ctx, `SELECT target, requestor FROM public.relationship WHERE target = ANY($1::varchar[]) AND is_friend=true AND friend_blocked=false 
+	UNION SELECT requestor, target FROM public.relationship WHERE requestor = ANY($1::varchar[]) AND is_friend=true AND friend_blocked=false 
+	ORDER BY 1, 2`, pq.Array(emails))
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
+	}
 	defer rows.Close()
+
+	relationships := []models.Relationship{}
 	for rows.Next() {
-		var relationshipTmp models.Relationship
-		if err := rows.Scan(&relationshipTmp.Requestor, &relationshipTmp.Target, &relationshipTmp.IsFriend, &relationshipTmp.FriendBlocked, &relationshipTmp.Subscribed, &relationshipTmp.SubscribeBlock); err != nil {
-			return []models.Relationship{}, err
+		relationship := models.Relationship{IsFriend: true}
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
 		}
-		friends = append(friends, relationshipTmp.Requestor, relationshipTmp.Target)
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
 	}
 
-	// if subscribed to updates
-	rows, err = repo.db.Query(`SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked FROM public.relationship rs 
-	WHERE rs.target=$1 AND subscribed=true AND subscribe_blocked=false`, req.Sender)
+	return relationships, nil
+}
 
-	if err != nil {
+// FindSubscribersByEmails function used to query the subscribers of many email addresses at once, with a single query
+// each returned relationship has a subscriber as Requestor and one of the emails as Target, blocked subscriptions are left out
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Target and Requestor, and an error type
+func (repo *repository) FindSubscribersByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
 		return []models.Relationship{}, err
 	}
 
+	rows, err := repo.query(ctx, `SELECT requestor, target FROM public.relationship 
+	WHERE target = ANY($1::varchar[]) AND subscribed=true AND subscribe_blocked=false ORDER BY target, requestor`, pq.Array(emails))
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
+	}
 	defer rows.Close()
+
+	relationships := []models.Relationship{}
 	for rows.Next() {
-		var relationshipTmp models.Relationship
-		if err := rows.Scan(&relationshipTmp.Requestor, &relationshipTmp.Target, &relationshipTmp.IsFriend, &relationshipTmp.FriendBlocked, &relationshipTmp.Subscribed, &relationshipTmp.SubscribeBlock); err != nil {
-			return []models.Relationship{}, err
+		relationship := models.Relationship{Subscribed: true}
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
 		}
-		friends = append(friends, relationshipTmp.Requestor)
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// FindPrivacySettingsByEmails function used to query the privacy settings of many email addresses from privacy_setting table, in a single query
+// the users who never changed their settings have no row and are missing from the result
+// pass a context and an array of emails as parameters
+// return an array of PrivacySettings model and an error type
+func (repo *repository) FindPrivacySettingsByEmails(ctx context.Context, emails []string) ([]models.PrivacySettings, error) {
+	if len(emails) == 0 {
+		return []models.PrivacySettings{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.PrivacySettings{}, err
 	}
 
-	// if being mentioned in the update
-	textArr := strings.Split(req.Text, " ")
-	for _, text := range textArr {
-		if pkg.CheckValidEmail(text) == nil {
-			relationships = append(relationships, models.Relationship{Target: text})
+	rows, err := repo.query(ctx, `SELECT user_email, friend_list_visibility, subscription_policy, updated_at 
+	FROM public.privacy_setting WHERE user_email = ANY($1::varchar[])`, pq.Array(emails))
+	if err != nil {
+		return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
+	}
+	defer rows.Close()
+
+	settings := []models.PrivacySettings{}
+	for rows.Next() {
+		var setting models.PrivacySettings
+		var updatedAt time.Time
+		if err := rows.Scan(&setting.Email, &setting.FriendListVisibility, &setting.SubscriptionPolicy, &updatedAt); err != nil {
+			return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
 		}
+		setting.UpdatedAt = &updatedAt
+		settings = append(settings, setting)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
 	}
 
-	//remove duplicated items
-	friends = pkg.RemoveDuplicatedItems(friends)
-	//remove requestor itself
-	friends = pkg.RemoveItemInArray(friends, req.Sender)
+	return settings, nil
+}
 
-	for _, item := range friends {
-		relationships = append(relationships, models.Relationship{Target: item})
+// SavePrivacySettings function used to insert or replace the privacy settings of a user into privacy_setting table
+// pass a context and a PrivacySettings model as parameters
+// return the stored PrivacySettings model and an error type, of KindNotFound when the user is not registered
+func (repo *repository) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmail(settings.Email); err != nil {
+		return models.PrivacySettings{}, err
 	}
 
-	return relationships, nil
+	var updatedAt time.Time
+	err := repo.db.QueryRowContext(ctx, `INSERT INTO public.privacy_setting(user_email, friend_list_visibility, subscription_policy) VALUES($1,$2,$3)
+	ON CONFLICT (user_email) DO UPDATE SET friend_list_visibility=EXCLUDED.friend_list_visibility, subscription_policy=EXCLUDED.subscription_policy, updated_at=now()
+	RETURNING updated_at`, settings.Email, settings.FriendListVisibility, settings.SubscriptionPolicy).Scan(&updatedAt)
+	if err != nil {
+		return models.PrivacySettings{}, dbError(ctx, "SavePrivacySettings", err)
+	}
+	settings.UpdatedAt = &updatedAt
+	return settings, nil
+}
+
+// dbError function used to translate a database error into a domain error and log it with the request's logger
+// unexpected failures are logged as errors, failures caused by the request itself (duplicates, unknown users...) as warnings
+func dbError(ctx context.Context, operation string, err error) error {
+	translated := translateError(err)
+	level := slog.LevelWarn
+	switch apperrors.KindOf(translated) {
+	case apperrors.KindInternal, apperrors.KindUnavailable:
+		level = slog.LevelError
+	}
+	logger.FromContext(ctx).Log(ctx, level, "database operation failed", slog.String("operation", operation), slog.Any("error", err))
+	return translated
 }
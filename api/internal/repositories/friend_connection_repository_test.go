@@ -1,13 +1,21 @@
 package repositories
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
 	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/replica"
 )
 
 func TestCreateUserWithSuccessfulCase(t *testing.T) {
@@ -23,7 +31,7 @@ func TestCreateUserWithSuccessfulCase(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.user_account").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateUser(models.CreatingUserRequest{Email: "abc@def.com"})
+	result, err := mockRepo.CreateUser(context.Background(), models.CreatingUserRequest{Email: "abc@def.com"})
 	expectedResult := models.User{Email: "abc@def.com"}
 	assert.Equal(t, expectedResult, result)
 	assert.Equal(t, nil, err)
@@ -42,9 +50,9 @@ func TestCreateUserWithInvalidEmail(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.user_account").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateUser(models.CreatingUserRequest{Email: "abc"})
+	result, err := mockRepo.CreateUser(context.Background(), models.CreatingUserRequest{Email: "abc"})
 	expectedResult := models.User{}
-	errExpected := errors.New("invalid email address")
+	errExpected := pkg.ErrInvalidEmail
 	assert.Equal(t, expectedResult, result)
 	assert.Equal(t, errExpected, err)
 }
@@ -62,9 +70,9 @@ func TestCreateUserWithEmptyBody(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.user_account").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateUser(models.CreatingUserRequest{Email: ""})
+	result, err := mockRepo.CreateUser(context.Background(), models.CreatingUserRequest{Email: ""})
 	expectedResult := models.User{}
-	errExpected := errors.New("invalid email address")
+	errExpected := pkg.ErrInvalidEmail
 	assert.Equal(t, expectedResult, result)
 	assert.Equal(t, errExpected, err)
 }
@@ -82,9 +90,49 @@ func TestCreateUserWithErrorAndRollback(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.user_account").WillReturnError(fmt.Errorf("error"))
 	sqlMock.ExpectRollback()
 
-	result, err := mockRepo.CreateUser(models.CreatingUserRequest{Email: "thehaohcm@yahoo.com.vn"})
+	result, err := mockRepo.CreateUser(context.Background(), models.CreatingUserRequest{Email: "thehaohcm@yahoo.com.vn"})
 	assert.Equal(t, models.User{}, result)
-	assert.Equal(t, errors.New("error"), err)
+	assert.ErrorIs(t, err, apperrors.ErrInternal)
+	assert.Equal(t, "internal server error", err.Error())
+}
+
+func TestCreateUserWithDuplicatedEmail(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO public.user_account").WillReturnError(&pq.Error{Code: "23505", Constraint: "user_account_pkey"})
+	sqlMock.ExpectRollback()
+
+	result, err := mockRepo.CreateUser(context.Background(), models.CreatingUserRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, models.User{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	assert.Equal(t, "user already exists", err.Error())
+}
+
+func TestCreateFriendConnectionWithUnknownUser(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnError(&pq.Error{Code: "23503", Constraint: "fk_target_user_account"})
+	sqlMock.ExpectRollback()
+
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
+	assert.Equal(t, models.Relationship{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Equal(t, "user not found", err.Error())
 }
 
 func TestCreateFriendConnectionWithSuccessfulCase(t *testing.T) {
@@ -97,10 +145,11 @@ func TestCreateFriendConnectionWithSuccessfulCase(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
 	expectedResult := models.Relationship(models.Relationship{Requestor: "abc@def.com", Target: "abc1@def.com", IsFriend: true, FriendBlocked: false, Subscribed: false, SubscribeBlock: false})
 	assert.Equal(t, expectedResult, result)
 	assert.Equal(t, nil, err)
@@ -116,10 +165,11 @@ func TestCreateFriendConnectionWithNilRequest(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{})
 	assert.Equal(t, models.Relationship{}, result)
 	assert.Error(t, err, errors.New("invalid request"))
 }
@@ -134,13 +184,14 @@ func TestCreateFriendConnectionWithEmptyRequest(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{})
 
 	assert.Equal(t, models.Relationship{}, result)
-	assert.Error(t, err, errors.New("email address is empty"))
+	assert.Error(t, err, pkg.ErrEmptyEmail)
 }
 
 func TestCreateFriendConnectionWithInvalidEmail(t *testing.T) {
@@ -153,12 +204,13 @@ func TestCreateFriendConnectionWithInvalidEmail(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{Friends: []string{"test"}})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"test"}})
 	assert.Equal(t, models.Relationship{}, result)
-	assert.Error(t, errors.New("invalid email address"), err)
+	assert.Error(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestCreateFriendConnectionWithExceesEmails(t *testing.T) {
@@ -171,10 +223,11 @@ func TestCreateFriendConnectionWithExceesEmails(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn", "thehaohcm@gmail.com"}})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn", "thehaohcm@gmail.com"}})
 	assert.Equal(t, models.Relationship{}, result)
 	assert.Error(t, err, errors.New("invalid request"))
 }
@@ -189,12 +242,14 @@ func TestCreateFriendConnectionWithErrorAndRollback(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnError(fmt.Errorf("error"))
 	sqlMock.ExpectRollback()
 
-	result, err := mockRepo.CreateFriendConnection(models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn"}})
+	result, err := mockRepo.CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn"}})
 	assert.Equal(t, models.Relationship{}, result)
-	assert.Equal(t, errors.New("error"), err)
+	assert.ErrorIs(t, err, apperrors.ErrInternal)
+	assert.Equal(t, "internal server error", err.Error())
 }
 
 func TestFindFriendsByEmailWithSuccessfulCase(t *testing.T) {
@@ -210,7 +265,7 @@ func TestFindFriendsByEmailWithSuccessfulCase(t *testing.T) {
 		sqlmock.NewRows([]string{"requstor"}).AddRow("chinh.nguyen@s3corp.com.vn").AddRow("son.le@s3corp.com.vn").AddRow("hao.nguyen@s3corp.com.vn"),
 	)
 
-	result, err := mockRepo.FindFriendsByEmail(models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	result, err := mockRepo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
 	expectedResult := []models.Relationship([]models.Relationship{
 		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 		{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
@@ -233,7 +288,7 @@ func TestFindFriendsByEmailWithNoResult(t *testing.T) {
 		sqlmock.NewRows([]string{"requstor"}),
 	)
 
-	result, err := mockRepo.FindFriendsByEmail(models.FriendListRequest{Email: "test@test.com"})
+	result, err := mockRepo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "test@test.com"})
 	expectedResult := []models.Relationship([]models.Relationship(nil))
 	assert.Equal(t, expectedResult, result)
 	assert.IsType(t, nil, err)
@@ -248,10 +303,10 @@ func TestFindFriendsByEmailWithEmptyRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.FindFriendsByEmail(models.FriendListRequest{Email: ""})
+	result, err := mockRepo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: ""})
 	expectedResult := []models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Error(t, err, errors.New("email address is empty"))
+	assert.Error(t, err, pkg.ErrEmptyEmail)
 }
 
 func TestFindFriendsByEmailWithInvalidEmailRequest(t *testing.T) {
@@ -263,10 +318,10 @@ func TestFindFriendsByEmailWithInvalidEmailRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.FindFriendsByEmail(models.FriendListRequest{Email: "abc"})
+	result, err := mockRepo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "abc"})
 	expectedResult := []models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Error(t, err, errors.New("email address is empty"))
+	assert.Error(t, err, pkg.ErrEmptyEmail)
 }
 
 func TestFindCommonFriendsByEmailsWithSuccessfulCase(t *testing.T) {
@@ -283,7 +338,7 @@ func TestFindCommonFriendsByEmailsWithSuccessfulCase(t *testing.T) {
 		sqlmock.NewRows([]string{"target", "count"}).AddRow("chinh.nguyen@s3corp.com.vn", 2),
 	)
 
-	result, err := mockRepo.FindCommonFriendsByEmails(models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
 	expectedRs := []models.Relationship([]models.Relationship{
 		{Requestor: "", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 	})
@@ -304,7 +359,7 @@ func TestFindCommonFriendsByEmailsWithEmptyResponse(t *testing.T) {
 		sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}),
 	)
 
-	result, err := mockRepo.FindCommonFriendsByEmails(models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hung.tong@s3corp.com.vn"}})
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hung.tong@s3corp.com.vn"}})
 	expectedRs := []models.Relationship(nil)
 	assert.Equal(t, expectedRs, result)
 	assert.IsType(t, nil, err)
@@ -319,9 +374,9 @@ func TestFindCommonFriendsByEmailsWithNilRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.FindCommonFriendsByEmails(models.CommonFriendListRequest{})
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{})
 	assert.Equal(t, []models.Relationship{}, result)
-	assert.Error(t, err, errors.New("email address is empty"))
+	assert.Error(t, err, pkg.ErrEmptyEmail)
 }
 
 func TestFindCommonFriendsByEmailsWithEmptyEmailRequest(t *testing.T) {
@@ -333,9 +388,9 @@ func TestFindCommonFriendsByEmailsWithEmptyEmailRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.FindCommonFriendsByEmails(models.CommonFriendListRequest{})
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{})
 	assert.Equal(t, []models.Relationship{}, result)
-	assert.Error(t, err, errors.New("email address is empty"))
+	assert.Error(t, err, pkg.ErrEmptyEmail)
 }
 
 func TestFindCommonFriendsByEmailsWithInvalidEmailRequest(t *testing.T) {
@@ -347,9 +402,9 @@ func TestFindCommonFriendsByEmailsWithInvalidEmailRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.FindCommonFriendsByEmails(models.CommonFriendListRequest{Friends: []string{"test"}})
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{Friends: []string{"test"}})
 	assert.Equal(t, []models.Relationship{}, result)
-	assert.Error(t, errors.New("invalid email address"), err)
+	assert.Error(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestSubscribeFromEmailWithSuccessfulCase(t *testing.T) {
@@ -362,10 +417,11 @@ func TestSubscribeFromEmailWithSuccessfulCase(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	result, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
 	expectedResult := models.Relationship(models.Relationship{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: true, SubscribeBlock: false})
 	assert.Equal(t, expectedResult, result)
 	assert.Equal(t, nil, err)
@@ -380,10 +436,10 @@ func TestSubscribeFromEmailWithInvalidEmail(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen@s3corp.com.vn"})
+	result, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen@s3corp.com.vn"})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestSubscribeFromEmailWithInvalidEmails(t *testing.T) {
@@ -399,10 +455,10 @@ func TestSubscribeFromEmailWithInvalidEmails(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen"})
+	result, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen"})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestSubscribeFromEmailWithNilReq(t *testing.T) {
@@ -418,10 +474,10 @@ func TestSubscribeFromEmailWithNilReq(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.SubscribeFromEmail(models.SubscribeRequest{})
+	result, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestSubscribeFromEmailWithFailureAndRollback(t *testing.T) {
@@ -437,9 +493,9 @@ func TestSubscribeFromEmailWithFailureAndRollback(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnError(fmt.Errorf("error"))
 	sqlMock.ExpectRollback()
 
-	result, err := mockRepo.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	result, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
 	assert.Equal(t, models.Relationship{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestBlockSubscribeByEmailWithSuccessfulCaseAndHaveNoFriend(t *testing.T) {
@@ -452,10 +508,11 @@ func TestBlockSubscribeByEmailWithSuccessfulCaseAndHaveNoFriend(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, _ := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "thehaohcm@gmail.com"})
+	result, _ := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "thehaohcm@gmail.com"})
 	expectedResult := models.Relationship(models.Relationship{Requestor: "thehaohcm@yahoo.com.vn", Target: "thehaohcm@gmail.com", IsFriend: false, FriendBlocked: true, Subscribed: false, SubscribeBlock: false})
 	assert.Equal(t, expectedResult, result)
 }
@@ -470,10 +527,11 @@ func TestBlockSubscribeByEmailWithSuccessfulCaseAndHaveFriend(t *testing.T) {
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
 	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 	sqlMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, _ := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	result, _ := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
 	expectedResult := models.Relationship(models.Relationship{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: true, Subscribed: false, SubscribeBlock: false})
 	assert.Equal(t, expectedResult, result)
 }
@@ -491,10 +549,10 @@ func TestBlockSubscribeByEmailInvalidEmails(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen"})
+	result, err := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm", Target: "chinh.nguyen"})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestBlockSubscribeByEmailWithNilRequest(t *testing.T) {
@@ -506,10 +564,10 @@ func TestBlockSubscribeByEmailWithNilRequest(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{})
+	result, err := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestBlockSubscribeByEmailWithNilRequestor(t *testing.T) {
@@ -525,10 +583,10 @@ func TestBlockSubscribeByEmailWithNilRequestor(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Target: "chinh.nguyen"})
+	result, err := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Target: "chinh.nguyen"})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestBlockSubscribeByEmailWithNilTarget(t *testing.T) {
@@ -544,10 +602,10 @@ func TestBlockSubscribeByEmailWithNilTarget(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectCommit()
 
-	result, err := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm"})
+	result, err := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm"})
 	expectedResult := models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestBlockSubscribeByEmailWithErrorAndRollback(t *testing.T) {
@@ -563,9 +621,9 @@ func TestBlockSubscribeByEmailWithErrorAndRollback(t *testing.T) {
 	sqlMock.ExpectExec("INSERT INTO public.subscribers").WillReturnError(fmt.Errorf("error"))
 	sqlMock.ExpectRollback()
 
-	result, err := mockRepo.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	result, err := mockRepo.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
 	assert.Equal(t, models.Relationship{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestGetSubscribingEmailListByEmailWithSuccessfulCaseAndEmailInText(t *testing.T) {
@@ -577,28 +635,22 @@ func TestGetSubscribingEmailListByEmailWithSuccessfulCaseAndEmailInText(t *testi
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND friend_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
-			AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, false).
-			AddRow("thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn", true, false, false, false),
+	sqlMock.ExpectQuery("WITH mentioned AS (.+) FROM unnest(.+) connected AS (.+) ORDER BY source, position, email").
+		WithArgs("thehaohcm@yahoo.com.vn", pq.Array([]string{"kate@example.com"})).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).
+			AddRow("kate@example.com").
+			AddRow("hao.nguyen@s3corp.com.vn").
+			AddRow("chinh.nguyen@s3corp.com.vn"),
 		)
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND subscribed=true AND friend_blocked=false AND subscribe_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
-			AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, false).
-			AddRow("thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn", true, false, false, false),
-		)
-
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND subscribed=true AND subscribe_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}))
-
-	result, _ := mockRepo.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hello world, kate@example.com"})
+	result, _ := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hello world, kate@example.com"})
 	expectedRs := []models.Relationship([]models.Relationship{
 		{Requestor: "", Target: "kate@example.com", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 		{Requestor: "", Target: "hao.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 		{Requestor: "", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 	})
 	assert.Equal(t, expectedRs, result)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestGetSubscribingEmailListByEmailWithSuccessfulCaseNotEmailInText(t *testing.T) {
@@ -610,27 +662,20 @@ func TestGetSubscribingEmailListByEmailWithSuccessfulCaseNotEmailInText(t *testi
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND friend_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
-			AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, false).
-			AddRow("thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn", true, false, false, false),
+	sqlMock.ExpectQuery("WITH mentioned AS (.+) ORDER BY source, position, email").
+		WithArgs("thehaohcm@yahoo.com.vn", pq.Array([]string{})).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).
+			AddRow("hao.nguyen@s3corp.com.vn").
+			AddRow("chinh.nguyen@s3corp.com.vn"),
 		)
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND subscribed=true AND friend_blocked=false AND subscribe_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
-			AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, false).
-			AddRow("thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn", true, false, false, false),
-		)
-
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND subscribed=true AND subscribe_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}))
-
-	result, _ := mockRepo.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hello world"})
+	result, _ := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hello world"})
 	expectedRs := []models.Relationship([]models.Relationship{
 		{Requestor: "", Target: "hao.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 		{Requestor: "", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: false, FriendBlocked: false, Subscribed: false, SubscribeBlock: false},
 	})
 	assert.Equal(t, expectedRs, result)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestGetSubscribingEmailListByEmailWithSuccessfulAndEmptyResponse(t *testing.T) {
@@ -642,18 +687,89 @@ func TestGetSubscribingEmailListByEmailWithSuccessfulAndEmptyResponse(t *testing
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND friend_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}))
+	sqlMock.ExpectQuery("WITH mentioned AS (.+) ORDER BY source, position, email").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND is_friend=true AND subscribed=true AND friend_blocked=false AND subscribe_blocked=true").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}))
+	result, err := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "hung.tong@s3corp.com.vn", Text: "hello world"})
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+}
 
-	sqlMock.ExpectQuery("SELECT (.+) FROM public.relationship rs WHERE (.+) AND subscribed=true AND subscribe_blocked=false").
-		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}))
+func TestGetSubscribingEmailListByEmailWithDatabaseError(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
 
-	result, _ := mockRepo.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "hung.tong@s3corp.com.vn", Text: "hello world"})
-	expectedRs := []models.Relationship{}
-	assert.Equal(t, expectedRs, result)
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectQuery("WITH mentioned AS (.+)").WillReturnError(&pq.Error{Code: "57014"})
+
+	result, err := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "hung.tong@s3corp.com.vn"})
+	assert.Equal(t, []models.Relationship{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrTimeout)
+}
+
+func TestStreamRecipientsReadsTheCursorInBatches(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	batch := sqlmock.NewRows([]string{"email"})
+	for i := 0; i < recipientsFetchSize; i++ {
+		batch.AddRow(fmt.Sprintf("subscriber%d@example.com", i))
+	}
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("DECLARE recipients NO SCROLL CURSOR FOR WITH mentioned AS (.+)").
+		WithArgs("thehaohcm@yahoo.com.vn", pq.Array([]string{"kate@example.com"})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("FETCH FORWARD 1000 FROM recipients").WillReturnRows(batch)
+	sqlMock.ExpectQuery("FETCH FORWARD 1000 FROM recipients").WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("last@example.com"))
+	sqlMock.ExpectRollback()
+
+	count := 0
+	var last string
+	err = mockRepo.StreamRecipients(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hi kate@example.com"}, func(email string) error {
+		count++
+		last = email
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, recipientsFetchSize+1, count)
+	assert.Equal(t, "last@example.com", last)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestStreamRecipientsStopsOnCallbackError(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("DECLARE recipients").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("FETCH FORWARD").WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("a@example.com").AddRow("b@example.com"))
+	sqlMock.ExpectRollback()
+
+	stop := errors.New("client went away")
+	err = mockRepo.StreamRecipients(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn"}, func(email string) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestMentionedEmails(t *testing.T) {
+	assert.Equal(t, []string{"kate@example.com", "kate@example.com"}, mentionedEmails("hi kate@example.com kate@example.com, @example.com kate@example.com"))
+	assert.Equal(t, []string{}, mentionedEmails(""))
 }
 
 func TestGetSubscribingEmailListByEmailWithNilSender(t *testing.T) {
@@ -665,10 +781,10 @@ func TestGetSubscribingEmailListByEmailWithNilSender(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Text: "hello world"})
+	result, err := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Text: "hello world"})
 	expectedResult := []models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestGetSubscribingEmailListByEmailWithInvalidEmail(t *testing.T) {
@@ -680,8 +796,342 @@ func TestGetSubscribingEmailListByEmailWithInvalidEmail(t *testing.T) {
 
 	var mockRepo FriendConnectionRepository = New(mockDB)
 
-	result, err := mockRepo.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "thehaohcm", Text: "hello world"})
+	result, err := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm", Text: "hello world"})
 	expectedResult := []models.Relationship{}
 	assert.Equal(t, expectedResult, result)
-	assert.Equal(t, errors.New("invalid email address"), err)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
+}
+
+func TestFindFriendsByEmailWithCancelledContext(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectQuery("SELECT requestor FROM public.relationship").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"requestor"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := mockRepo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, []models.Relationship{}, result)
+	assert.Error(t, err)
+}
+
+func TestFindUnregisteredEmailsWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	rows := sqlmock.NewRows([]string{"email"}).AddRow("kate@example.com")
+	sqlMock.ExpectQuery("SELECT e.email FROM unnest").WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn", "kate@example.com"})).WillReturnRows(rows)
+
+	result, err := mockRepo.FindUnregisteredEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn", "kate@example.com"})
+	assert.Equal(t, []string{"kate@example.com"}, result)
+	assert.Nil(t, err)
+}
+
+func TestCreateUsersIfNotExistWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO public.user_account").WithArgs(pq.Array([]string{"kate@example.com"})).WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	err = mockRepo.CreateUsersIfNotExist(context.Background(), []string{"kate@example.com"})
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRemoveFriendConnectionWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("UPDATE public.relationship SET is_friend=false").WithArgs("abc@def.com", "abc1@def.com", models.AuditActionUnfriend, "", "").WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectCommit()
+
+	result, err := mockRepo.RemoveFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
+	assert.Equal(t, models.Relationship{Requestor: "abc@def.com", Target: "abc1@def.com"}, result)
+	assert.Nil(t, err)
+}
+
+func TestUnsubscribeFromEmailWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("UPDATE public.relationship SET subscribed=false").WithArgs("abc@def.com", "abc1@def.com", models.AuditActionUnsubscribe, "", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	result, err := mockRepo.UnsubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "abc@def.com", Target: "abc1@def.com"})
+	assert.Equal(t, models.Relationship{Requestor: "abc@def.com", Target: "abc1@def.com"}, result)
+	assert.Nil(t, err)
+}
+
+func TestUnblockSubscribeByEmailWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("UPDATE public.relationship SET subscribe_blocked=false").WithArgs("abc@def.com", "abc1@def.com", models.AuditActionUnblock, "", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	result, err := mockRepo.UnblockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "abc@def.com", Target: "abc1@def.com"})
+	assert.Equal(t, models.Relationship{Requestor: "abc@def.com", Target: "abc1@def.com"}, result)
+	assert.Nil(t, err)
+}
+
+func TestFindRelationshipsByEmailsWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	rows := sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
+		AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, false).
+		AddRow("kate@example.com", "thehaohcm@yahoo.com.vn", false, false, true, false)
+	sqlMock.ExpectQuery("SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked").
+		WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn", "kate@example.com"})).WillReturnRows(rows)
+
+	result, err := mockRepo.FindRelationshipsByEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn", "kate@example.com"})
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true},
+		{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn", Subscribed: true},
+	}, result)
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindRelationshipsByEmailsWithInvalidEmail(t *testing.T) {
+	var mockDB, _, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	_, err = mockRepo.FindRelationshipsByEmails(context.Background(), []string{"thehaohcm"})
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
+}
+
+func TestFindFriendsByEmailsWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	rows := sqlmock.NewRows([]string{"target", "requestor"}).
+		AddRow("kate@example.com", "thehaohcm@yahoo.com.vn").
+		AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn")
+	sqlMock.ExpectQuery("SELECT target, requestor FROM public.relationship WHERE target = ANY").
+		WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn", "kate@example.com"})).WillReturnRows(rows)
+
+	result, err := mockRepo.FindFriendsByEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn", "kate@example.com"})
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn", IsFriend: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true},
+	}, result)
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindFriendsByEmailsWithEmptyList(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	result, err := mockRepo.FindFriendsByEmails(context.Background(), nil)
+	assert.Equal(t, []models.Relationship{}, result)
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindSubscribersByEmailsWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	rows := sqlmock.NewRows([]string{"requestor", "target"}).
+		AddRow("kate@example.com", "thehaohcm@yahoo.com.vn")
+	sqlMock.ExpectQuery("SELECT requestor, target FROM public.relationship").
+		WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn"})).WillReturnRows(rows)
+
+	result, err := mockRepo.FindSubscribersByEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn", Subscribed: true},
+	}, result)
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindSubscribersByEmailsWithInvalidEmail(t *testing.T) {
+	var mockDB, _, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	_, err = mockRepo.FindSubscribersByEmails(context.Background(), []string{"thehaohcm"})
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
+}
+
+func TestFindPrivacySettingsByEmailsWithSuccessfulCase(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"user_email", "friend_list_visibility", "subscription_policy", "updated_at"}).
+		AddRow("thehaohcm@yahoo.com.vn", "private", "nobody", updatedAt)
+	sqlMock.ExpectQuery("SELECT user_email, friend_list_visibility, subscription_policy, updated_at").
+		WithArgs(pq.Array([]string{"thehaohcm@yahoo.com.vn", "kate@example.com"})).WillReturnRows(rows)
+
+	result, err := mockRepo.FindPrivacySettingsByEmails(context.Background(), []string{"thehaohcm@yahoo.com.vn", "kate@example.com"})
+	assert.Equal(t, []models.PrivacySettings{
+		{Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: "private", SubscriptionPolicy: "nobody", UpdatedAt: &updatedAt},
+	}, result)
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSavePrivacySettingsWithUnknownUser(t *testing.T) {
+	var mockDB, sqlMock, err = sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	var mockRepo FriendConnectionRepository = New(mockDB)
+
+	sqlMock.ExpectQuery("INSERT INTO public.privacy_setting").
+		WithArgs("kate@example.com", "friends", "everyone").
+		WillReturnError(&pq.Error{Code: "23503", Constraint: "fk_privacy_setting_user_account"})
+
+	_, err = mockRepo.SavePrivacySettings(context.Background(), models.PrivacySettings{Email: "kate@example.com", FriendListVisibility: "friends", SubscriptionPolicy: "everyone"})
+	assert.Equal(t, apperrors.KindNotFound, apperrors.KindOf(err))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindFriendsByEmailReadsTheReplica(t *testing.T) {
+	primaryDB, primaryMock, _ := sqlmock.New()
+	defer primaryDB.Close()
+	replicaDB, replicaMock, _ := sqlmock.New()
+	defer replicaDB.Close()
+
+	mockRepo := New(primaryDB, WithReplicas(replica.New(primaryDB, map[string]*sql.DB{"replica": replicaDB}, time.Minute)))
+
+	replicaMock.ExpectQuery("SELECT requestor FROM public.relationship WHERE ").WillReturnRows(sqlmock.NewRows([]string{"requestor"}).AddRow("son.le@s3corp.com.vn"))
+	result, err := mockRepo.FindFriendsByEmail(context.Background(), models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"}}, result)
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+	assert.Nil(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestGetSubscribingEmailListByEmailReadsThePrimaryAfterAWrite(t *testing.T) {
+	primaryDB, primaryMock, _ := sqlmock.New()
+	defer primaryDB.Close()
+	replicaDB, replicaMock, _ := sqlmock.New()
+	defer replicaDB.Close()
+
+	mockRepo := New(primaryDB, WithReplicas(replica.New(primaryDB, map[string]*sql.DB{"replica": replicaDB}, time.Minute)))
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	primaryMock.ExpectExec("INSERT INTO public.relationship").WillReturnResult(sqlmock.NewResult(1, 1))
+	primaryMock.ExpectCommit()
+	primaryMock.ExpectQuery("WITH mentioned AS (.+)").WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("thehaohcm@yahoo.com.vn"))
+	replicaMock.ExpectQuery("WITH mentioned AS (.+)").WillReturnRows(sqlmock.NewRows([]string{"email"}))
+
+	_, err := mockRepo.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	// the target of the subscription reads its new subscriber from the primary, the other senders still read the replica
+	result, err := mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "chinh.nguyen@s3corp.com.vn", Text: "hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Target: "thehaohcm@yahoo.com.vn"}}, result)
+	result, err = mockRepo.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "son.le@s3corp.com.vn", Text: "hello"})
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+	assert.Nil(t, primaryMock.ExpectationsWereMet())
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestFindCommonFriendsByEmailsFallsBackToThePrimaryWhenTheReplicaIsDown(t *testing.T) {
+	primaryDB, primaryMock, _ := sqlmock.New()
+	defer primaryDB.Close()
+	replicaDB, replicaMock, _ := sqlmock.New()
+	defer replicaDB.Close()
+
+	set := replica.New(primaryDB, map[string]*sql.DB{"replica": replicaDB}, time.Minute)
+	mockRepo := New(primaryDB, WithReplicas(set))
+
+	replicaMock.ExpectQuery("SELECT target,count").WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	primaryMock.ExpectQuery("SELECT target,count").WillReturnRows(sqlmock.NewRows([]string{"target", "count"}).AddRow("son.le@s3corp.com.vn", 2))
+
+	result, err := mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Target: "son.le@s3corp.com.vn"}}, result)
+	assert.Equal(t, 0, set.Healthy())
+	assert.Nil(t, primaryMock.ExpectationsWereMet())
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
+
+	// the errors of the query itself are not retried
+	replicaMock.ExpectQuery("SELECT target,count").WillReturnError(&pq.Error{Code: "57014"})
+	set.Check(context.Background(), time.Second)
+	_, err = mockRepo.FindCommonFriendsByEmails(context.Background(), models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"}})
+	assert.True(t, errors.Is(err, apperrors.ErrTimeout))
+	assert.Nil(t, replicaMock.ExpectationsWereMet())
 }
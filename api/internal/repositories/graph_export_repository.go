@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"golang_project/api/internal/models"
+)
+
+// GraphExportRepository interface declares the functions used to export the social graph,
+// the rows are streamed to a callback instead of being loaded in memory, and the callback stops the export by returning an error
+type GraphExportRepository interface {
+	StreamUsers(ctx context.Context, filter models.GraphExportFilter, fn func(email string) error) error
+	StreamRelationships(ctx context.Context, filter models.GraphExportFilter, fn func(relationship models.Relationship) error) error
+}
+
+type graphExportRepository struct {
+	db *sql.DB
+}
+
+// NewGraphExportRepository function used for initializing a GraphExportRepository
+// pass a pointer sql.DB as parameter
+func NewGraphExportRepository(db *sql.DB) GraphExportRepository {
+	return &graphExportRepository{
+		db: db,
+	}
+}
+
+// StreamUsers function used to query the users of user_account table kept by the filter, ordered by email
+// pass a context, a GraphExportFilter model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (repo *graphExportRepository) StreamUsers(ctx context.Context, filter models.GraphExportFilter, fn func(email string) error) error {
+	query, args := graphNodesQuery(filter)
+	rows, err := repo.db.QueryContext(ctx, query+` SELECT user_email FROM nodes ORDER BY user_email`, args...)
+	if err != nil {
+		return dbError(ctx, "StreamUsers", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return dbError(ctx, "StreamUsers", err)
+		}
+		if err := fn(email); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbError(ctx, "StreamUsers", err)
+	}
+	return nil
+}
+
+// StreamRelationships function used to query the rows of relationship table between two users kept by the filter,
+// the rows without any flag set (e.g. removed friendships) are skipped
+// pass a context, a GraphExportFilter model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (repo *graphExportRepository) StreamRelationships(ctx context.Context, filter models.GraphExportFilter, fn func(relationship models.Relationship) error) error {
+	query, args := graphNodesQuery(filter)
+	rows, err := repo.db.QueryContext(ctx, query+` SELECT r.requestor, r.target, r.is_friend, r.friend_blocked, r.subscribed, r.subscribe_blocked 
+	FROM public.relationship r JOIN nodes a ON a.user_email = r.requestor JOIN nodes b ON b.user_email = r.target 
+	WHERE r.is_friend OR r.friend_blocked OR r.subscribed OR r.subscribe_blocked ORDER BY r.requestor, r.target`, args...)
+	if err != nil {
+		return dbError(ctx, "StreamRelationships", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relationship models.Relationship
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target, &relationship.IsFriend, &relationship.FriendBlocked, &relationship.Subscribed, &relationship.SubscribeBlock); err != nil {
+			return dbError(ctx, "StreamRelationships", err)
+		}
+		if err := fn(relationship); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbError(ctx, "StreamRelationships", err)
+	}
+	return nil
+}
+
+// graphNodesQuery function builds the WITH clause of the users kept by a filter, named nodes,
+// the ego network walks every relationship with a flag set, in both directions, at most Hops times
+func graphNodesQuery(filter models.GraphExportFilter) (string, []interface{}) {
+	var args []interface{}
+	query := `WITH RECURSIVE `
+	where := ` WHERE true`
+	if filter.Ego != "" {
+		args = append(args, filter.Ego, filter.Hops)
+		query += `ego(email, depth) AS (SELECT $1::varchar, 0 
+	UNION SELECT CASE WHEN r.requestor = e.email THEN r.target ELSE r.requestor END, e.depth + 1 
+	FROM ego e JOIN public.relationship r ON r.requestor = e.email OR r.target = e.email 
+	WHERE e.depth < $2 AND (r.is_friend OR r.friend_blocked OR r.subscribed OR r.subscribe_blocked)), `
+		where += ` AND ua.user_email IN (SELECT email FROM ego)`
+	}
+	if filter.Domain != "" {
+		args = append(args, filter.Domain)
+		where += ` AND lower(split_part(ua.user_email, '@', 2)) = lower($` + strconv.Itoa(len(args)) + `)`
+	}
+	return query + `nodes AS (SELECT ua.user_email FROM public.user_account ua` + where + `)`, args
+}
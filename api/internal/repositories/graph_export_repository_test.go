@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/models"
+)
+
+func TestStreamUsersOfEgoNetworkInDomain(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectQuery("WITH RECURSIVE ego\\(email, depth\\) AS .* lower\\(\\$3\\)\\) SELECT user_email FROM nodes ORDER BY user_email").
+		WithArgs("thehaohcm@yahoo.com.vn", 2, "s3corp.com.vn").
+		WillReturnRows(sqlmock.NewRows([]string{"user_email"}).AddRow("chinh.nguyen@s3corp.com.vn").AddRow("hao.nguyen@s3corp.com.vn"))
+
+	var emails []string
+	err = NewGraphExportRepository(mockDB).StreamUsers(context.Background(),
+		models.GraphExportFilter{Ego: "thehaohcm@yahoo.com.vn", Hops: 2, Domain: "s3corp.com.vn"},
+		func(email string) error {
+			emails = append(emails, email)
+			return nil
+		})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"chinh.nguyen@s3corp.com.vn", "hao.nguyen@s3corp.com.vn"}, emails)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestStreamRelationshipsOfWholeGraph(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectQuery("WITH RECURSIVE nodes AS \\(SELECT ua.user_email FROM public.user_account ua WHERE true\\) SELECT r.requestor").
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"requestor", "target", "is_friend", "friend_blocked", "subscribed", "subscribe_blocked"}).
+			AddRow("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", true, false, false, true))
+
+	var relationships []models.Relationship
+	err = NewGraphExportRepository(mockDB).StreamRelationships(context.Background(), models.GraphExportFilter{}, func(relationship models.Relationship) error {
+		relationships = append(relationships, relationship)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true, SubscribeBlock: true}}, relationships)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
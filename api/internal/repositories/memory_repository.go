@@ -0,0 +1,551 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+)
+
+var (
+	// errMemoryUserNotFound mirrors the foreign key violation of the Postgres repository when a relationship or a setting targets an unregistered user
+	errMemoryUserNotFound = apperrors.NotFound("user_not_found", "user not found")
+	// errMemoryInvalidValue mirrors the check violation of the Postgres repository on the privacy settings
+	errMemoryInvalidValue = apperrors.Validation("invalid_value", "invalid value")
+)
+
+type relationshipKey struct {
+	requestor string
+	target    string
+}
+
+// MemoryRepository struct is a FriendConnectionRepository keeping the users, relationships and privacy settings in memory,
+// with the same semantics as the Postgres repository: relationships are upserted, relationships and settings require registered users,
+// and the block and recipient rules are the ones of the SQL queries
+// it is safe for concurrent use, backs the --storage=memory mode and serves as the reference implementation in tests
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	users         map[string]bool
+	relationships map[relationshipKey]models.Relationship
+	privacy       map[string]models.PrivacySettings
+	now           func() time.Time
+}
+
+// NewMemoryRepository function used for initializing an empty MemoryRepository
+// return a pointer of MemoryRepository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:         map[string]bool{},
+		relationships: map[relationshipKey]models.Relationship{},
+		privacy:       map[string]models.PrivacySettings{},
+		now:           time.Now,
+	}
+}
+
+// CreateUser function used to register a new user
+// pass a context and a CreatingUserRequest model as parameters
+// return a User model and an error type, of KindAlreadyExists when the user is registered already
+func (repo *MemoryRepository) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.User, error) {
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		return models.User{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.users[request.Email] {
+		return models.User{}, apperrors.AlreadyExists("user_already_exists", "user already exists")
+	}
+	repo.users[request.Email] = true
+	return models.User{Email: request.Email}, nil
+}
+
+// CreateUsersIfNotExist function used to register a list of users, skipping the ones already registered
+// pass a context and an array of emails as parameters
+// return an error type
+func (repo *MemoryRepository) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, email := range emails {
+		repo.users[email] = true
+	}
+	return nil
+}
+
+// FindUnregisteredEmails function used to get the emails which are not registered yet
+// pass a context and an array of emails as parameters
+// return an array of unregistered emails, in the order they were given, and an error type
+func (repo *MemoryRepository) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	unregistered := []string{}
+	for _, email := range emails {
+		if !repo.users[email] {
+			unregistered = append(unregistered, email)
+		}
+	}
+	return pkg.RemoveDuplicatedItems(unregistered), nil
+}
+
+// FindFriendsByEmail function used to get the friends of an email address, in both directions and without the blocked friendships
+// pass a context and a FriendListRequest model as parameters
+// return an array of Relationship model with the email as Requestor, nil when the user has no friend, and an error type
+func (repo *MemoryRepository) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var friends []string
+	for _, relationship := range repo.sortedRelationships() {
+		if !relationship.IsFriend || relationship.FriendBlocked {
+			continue
+		}
+		if relationship.Target == request.Email {
+			friends = append(friends, relationship.Requestor)
+		}
+		if relationship.Requestor == request.Email {
+			friends = append(friends, relationship.Target)
+		}
+	}
+
+	var relationships []models.Relationship
+	for _, friend := range pkg.RemoveDuplicatedItems(friends) {
+		relationships = append(relationships, models.Relationship{Requestor: request.Email, Target: friend})
+	}
+	return relationships, nil
+}
+
+// FindCommonFriendsByEmails function used to get the users related to at least two of the given email addresses
+// like the SQL query, every relationship row is counted whatever its flags, once from the requestor side and once from the target side
+// pass a context and a CommonFriendListRequest model as parameters
+// return an array of Relationship model with the common users as Target, nil when there is none, and an error type
+func (repo *MemoryRepository) FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	users := make(map[string]bool, len(request.Friends))
+	for _, friend := range request.Friends {
+		users[friend] = true
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	targets := map[string]int{}
+	requestors := map[string]int{}
+	for _, relationship := range repo.relationships {
+		if users[relationship.Requestor] && !users[relationship.Target] {
+			targets[relationship.Target]++
+		}
+		if users[relationship.Target] && !users[relationship.Requestor] {
+			requestors[relationship.Requestor]++
+		}
+	}
+
+	var common []string
+	for email, count := range targets {
+		if count > 1 {
+			common = append(common, email)
+		}
+	}
+	for email, count := range requestors {
+		if count > 1 {
+			common = append(common, email)
+		}
+	}
+	common = pkg.RemoveDuplicatedItems(common)
+	sort.Strings(common)
+
+	var relationships []models.Relationship
+	for _, email := range common {
+		relationships = append(relationships, models.Relationship{Target: email})
+	}
+	return relationships, nil
+}
+
+// CreateFriendConnection function used to upsert a friend connection in both directions
+// pass a context and a FriendConnectionRequest model as parameters
+// return a Relationship model and an error type, of KindNotFound when one of the users is not registered
+func (repo *MemoryRepository) CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	if len(friendConnectionRequest.Friends) != 2 {
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if err := pkg.CheckValidEmails(friendConnectionRequest.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+	first, second := friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1]
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.checkRegistered(first, second); err != nil {
+		return models.Relationship{}, err
+	}
+	repo.upsert(first, second, func(relationship *models.Relationship) { relationship.IsFriend = true })
+	repo.upsert(second, first, func(relationship *models.Relationship) { relationship.IsFriend = true })
+
+	return models.Relationship{Requestor: first, Target: second, IsFriend: true}, nil
+}
+
+// RemoveFriendConnection function used to remove a friend connection in both directions
+// removing a connection which does not exist is not an error
+// pass a context and a FriendConnectionRequest model as parameters
+// return a Relationship model and an error type
+func (repo *MemoryRepository) RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	if len(friendConnectionRequest.Friends) != 2 {
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if err := pkg.CheckValidEmails(friendConnectionRequest.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+	first, second := friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1]
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.update(first, second, func(relationship *models.Relationship) { relationship.IsFriend = false })
+	repo.update(second, first, func(relationship *models.Relationship) { relationship.IsFriend = false })
+
+	return models.Relationship{Requestor: first, Target: second}, nil
+}
+
+// SubscribeFromEmail function used to upsert a subscription from the requestor to the target
+// pass a context and a SubscribeRequest model as parameters
+// return a Relationship model and an error type, of KindNotFound when one of the users is not registered
+func (repo *MemoryRepository) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.checkRegistered(req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, err
+	}
+	repo.upsert(req.Requestor, req.Target, func(relationship *models.Relationship) { relationship.Subscribed = true })
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target, Subscribed: true}, nil
+}
+
+// UnsubscribeFromEmail function used to remove a subscription from the requestor to the target
+// removing a subscription which does not exist is not an error
+// pass a context and a SubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *MemoryRepository) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.update(req.Requestor, req.Target, func(relationship *models.Relationship) { relationship.Subscribed = false })
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// BlockSubscribeByEmail function used to upsert a block from the requestor to the target
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type, of KindNotFound when one of the users is not registered
+func (repo *MemoryRepository) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.checkRegistered(req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, err
+	}
+	repo.upsert(req.Requestor, req.Target, func(relationship *models.Relationship) { relationship.SubscribeBlock = true })
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target, FriendBlocked: true}, nil
+}
+
+// UnblockSubscribeByEmail function used to lift a block from the requestor to the target
+// lifting a block which does not exist is not an error
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *MemoryRepository) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.update(req.Requestor, req.Target, func(relationship *models.Relationship) { relationship.SubscribeBlock = false })
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// GetSubscribingEmailListByEmail function used to get the recipients of an update sent by an email address:
+// the friends of the sender, except the blocked friendships, the subscribers of the sender, except the blocked subscriptions,
+// and the valid emails mentioned in the text, except the users who blocked the sender, which are listed first
+// every recipient is listed once and the sender is never one of them
+// pass a context and a GetSubscribingEmailListRequest model as parameters
+// return an array of Relationship model with the recipients as Target and an error type
+func (repo *MemoryRepository) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmail(req.Sender); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	seen := map[string]bool{req.Sender: true}
+	var relationships []models.Relationship
+	for _, mention := range mentionedEmails(req.Text) {
+		if seen[mention] || repo.relationships[relationshipKey{mention, req.Sender}].SubscribeBlock {
+			continue
+		}
+		seen[mention] = true
+		relationships = append(relationships, models.Relationship{Target: mention})
+	}
+
+	var recipients []string
+	for _, relationship := range repo.relationships {
+		switch {
+		case relationship.Requestor == req.Sender && relationship.IsFriend && !relationship.FriendBlocked && !relationship.SubscribeBlock:
+			recipients = append(recipients, relationship.Target)
+		case relationship.Target == req.Sender && relationship.Subscribed && !relationship.SubscribeBlock:
+			recipients = append(recipients, relationship.Requestor)
+		}
+	}
+	sort.Strings(recipients)
+	for _, recipient := range recipients {
+		if !seen[recipient] {
+			seen[recipient] = true
+			relationships = append(relationships, models.Relationship{Target: recipient})
+		}
+	}
+	return relationships, nil
+}
+
+// StreamRecipients function used to pass the recipients of GetSubscribingEmailListByEmail to a callback, one at a time
+// the callback stops the stream by returning an error
+// pass a context, a GetSubscribingEmailListRequest model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (repo *MemoryRepository) StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	recipients, err := repo.GetSubscribingEmailListByEmail(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := fn(recipient.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindRelationshipsByEmails function used to get every relationship involving one of the given email addresses
+// pass a context and an array of emails as parameters
+// return an array of Relationship model ordered by Requestor and Target, and an error type
+func (repo *MemoryRepository) FindRelationshipsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+	users := toSet(emails)
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	relationships := []models.Relationship{}
+	for _, relationship := range repo.sortedRelationships() {
+		if users[relationship.Requestor] || users[relationship.Target] {
+			relationships = append(relationships, relationship)
+		}
+	}
+	return relationships, nil
+}
+
+// FindFriendsByEmails function used to get the friends of many email addresses at once
+// each returned relationship has one of the emails as Requestor and one of its friends as Target
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Requestor and Target, and an error type
+func (repo *MemoryRepository) FindFriendsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+	users := toSet(emails)
+
+	repo.mu.RLock()
+	found := map[relationshipKey]bool{}
+	for _, relationship := range repo.relationships {
+		if !relationship.IsFriend || relationship.FriendBlocked {
+			continue
+		}
+		if users[relationship.Target] {
+			found[relationshipKey{requestor: relationship.Target, target: relationship.Requestor}] = true
+		}
+		if users[relationship.Requestor] {
+			found[relationshipKey{requestor: relationship.Requestor, target: relationship.Target}] = true
+		}
+	}
+	repo.mu.RUnlock()
+
+	relationships := []models.Relationship{}
+	for key := range found {
+		relationships = append(relationships, models.Relationship{Requestor: key.requestor, Target: key.target, IsFriend: true})
+	}
+	sortRelationships(relationships)
+	return relationships, nil
+}
+
+// FindSubscribersByEmails function used to get the subscribers of many email addresses at once, blocked subscriptions are left out
+// each returned relationship has a subscriber as Requestor and one of the emails as Target
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Target and Requestor, and an error type
+func (repo *MemoryRepository) FindSubscribersByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+	users := toSet(emails)
+
+	repo.mu.RLock()
+	relationships := []models.Relationship{}
+	for _, relationship := range repo.relationships {
+		if users[relationship.Target] && relationship.Subscribed && !relationship.SubscribeBlock {
+			relationships = append(relationships, models.Relationship{Requestor: relationship.Requestor, Target: relationship.Target, Subscribed: true})
+		}
+	}
+	repo.mu.RUnlock()
+
+	sort.Slice(relationships, func(i, j int) bool {
+		if relationships[i].Target != relationships[j].Target {
+			return relationships[i].Target < relationships[j].Target
+		}
+		return relationships[i].Requestor < relationships[j].Requestor
+	})
+	return relationships, nil
+}
+
+// FindPrivacySettingsByEmails function used to get the stored privacy settings of many email addresses,
+// the users who never changed their settings are left out
+// pass a context and an array of emails as parameters
+// return an array of PrivacySettings model and an error type
+func (repo *MemoryRepository) FindPrivacySettingsByEmails(ctx context.Context, emails []string) ([]models.PrivacySettings, error) {
+	if len(emails) == 0 {
+		return []models.PrivacySettings{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.PrivacySettings{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	settings := []models.PrivacySettings{}
+	for _, email := range pkg.RemoveDuplicatedItems(emails) {
+		if setting, ok := repo.privacy[email]; ok {
+			updatedAt := *setting.UpdatedAt
+			setting.UpdatedAt = &updatedAt
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+// SavePrivacySettings function used to insert or replace the privacy settings of a user
+// pass a context and a PrivacySettings model as parameters
+// return the stored PrivacySettings model and an error type, of KindNotFound when the user is not registered
+func (repo *MemoryRepository) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmail(settings.Email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	switch settings.FriendListVisibility {
+	case models.FriendListPublic, models.FriendListFriends, models.FriendListPrivate:
+	default:
+		return models.PrivacySettings{}, errMemoryInvalidValue
+	}
+	switch settings.SubscriptionPolicy {
+	case models.SubscriptionEveryone, models.SubscriptionFriendsOfFriends, models.SubscriptionNobody:
+	default:
+		return models.PrivacySettings{}, errMemoryInvalidValue
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.checkRegistered(settings.Email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	updatedAt := repo.now()
+	settings.UpdatedAt = &updatedAt
+	repo.privacy[settings.Email] = settings
+	return settings, nil
+}
+
+// checkRegistered function mirrors the foreign keys to the user table, the caller must hold the lock
+func (repo *MemoryRepository) checkRegistered(emails ...string) error {
+	for _, email := range emails {
+		if !repo.users[email] {
+			return errMemoryUserNotFound
+		}
+	}
+	return nil
+}
+
+// upsert function used to change a relationship, creating it with every flag unset when it does not exist, the caller must hold the lock
+func (repo *MemoryRepository) upsert(requestor, target string, change func(*models.Relationship)) {
+	key := relationshipKey{requestor: requestor, target: target}
+	relationship, ok := repo.relationships[key]
+	if !ok {
+		relationship = models.Relationship{Requestor: requestor, Target: target}
+	}
+	change(&relationship)
+	repo.relationships[key] = relationship
+}
+
+// update function used to change a relationship only when it exists, the caller must hold the lock
+func (repo *MemoryRepository) update(requestor, target string, change func(*models.Relationship)) {
+	key := relationshipKey{requestor: requestor, target: target}
+	if relationship, ok := repo.relationships[key]; ok {
+		change(&relationship)
+		repo.relationships[key] = relationship
+	}
+}
+
+// sortedRelationships function returns a copy of the relationships ordered by Requestor and Target, the caller must hold the lock
+func (repo *MemoryRepository) sortedRelationships() []models.Relationship {
+	relationships := make([]models.Relationship, 0, len(repo.relationships))
+	for _, relationship := range repo.relationships {
+		relationships = append(relationships, relationship)
+	}
+	sortRelationships(relationships)
+	return relationships
+}
+
+func sortRelationships(relationships []models.Relationship) {
+	sort.Slice(relationships, func(i, j int) bool {
+		if relationships[i].Requestor != relationships[j].Requestor {
+			return relationships[i].Requestor < relationships[j].Requestor
+		}
+		return relationships[i].Target < relationships[j].Target
+	})
+}
+
+func toSet(emails []string) map[string]bool {
+	set := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		set[email] = true
+	}
+	return set
+}
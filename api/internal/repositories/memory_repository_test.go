@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+)
+
+func TestMemoryRepositoryCreateUser(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	_, err := repo.CreateUser(ctx, models.CreatingUserRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, "user_already_exists", apperrors.From(err).Code)
+	_, err = repo.CreateUser(ctx, models.CreatingUserRequest{Email: "thehaohcm"})
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
+
+	assert.Nil(t, repo.CreateUsersIfNotExist(ctx, []string{"thehaohcm@yahoo.com.vn", "kate@example.com"}))
+	unregistered, err := repo.FindUnregisteredEmails(ctx, []string{"bob@example.com", "kate@example.com", "alice@example.com", "bob@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bob@example.com", "alice@example.com"}, unregistered)
+}
+
+func TestMemoryRepositoryFriendConnections(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	_, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "unregistered@example.com"}})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+
+	friends, err := repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"},
+	}, friends)
+
+	common, err := repo.FindCommonFriendsByEmails(ctx, models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Target: "chinh.nguyen@s3corp.com.vn"}}, common)
+
+	_, err = repo.RemoveFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn"}})
+	assert.Nil(t, err)
+	_, err = repo.RemoveFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"son.le@s3corp.com.vn", "thehaohcm@yahoo.com.vn"}})
+	assert.Nil(t, err)
+	friends, err = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"}}, friends)
+
+	friends, err = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "son.le@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Nil(t, friends)
+}
+
+func TestMemoryRepositoryUpsertsKeepOtherFlags(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	_, err := repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"})
+	assert.Nil(t, err)
+
+	relationships, err := repo.FindRelationshipsByEmails(ctx, []string{"thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn", IsFriend: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: true, Subscribed: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn", SubscribeBlock: true},
+	}, relationships)
+
+	_, err = repo.UnsubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.UnblockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"})
+	assert.Nil(t, err)
+	relationships, err = repo.FindRelationshipsByEmails(ctx, []string{"son.le@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn"}}, relationships)
+}
+
+func TestMemoryRepositoryRecipients(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	_, err := repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "son.le@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "hung.tong@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "hung.tong@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+
+	recipients, err := repo.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "Hello kate@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{
+		{Target: "kate@example.com"},
+		{Target: "chinh.nguyen@s3corp.com.vn"},
+		{Target: "son.le@s3corp.com.vn"},
+	}, recipients)
+
+	_, err = repo.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	recipients, err = repo.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Target: "son.le@s3corp.com.vn"}}, recipients)
+}
+
+func TestMemoryRepositoryBatchReads(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	_, err := repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "son.le@s3corp.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+
+	friends, err := repo.FindFriendsByEmails(ctx, []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{
+		{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true},
+		{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn", IsFriend: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: true},
+	}, friends)
+
+	subscribers, err := repo.FindSubscribersByEmails(ctx, []string{"chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Relationship{{Requestor: "son.le@s3corp.com.vn", Target: "chinh.nguyen@s3corp.com.vn", Subscribed: true}}, subscribers)
+}
+
+func TestMemoryRepositoryPrivacySettings(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	_, err := repo.SavePrivacySettings(ctx, models.PrivacySettings{Email: "unregistered@example.com", FriendListVisibility: models.FriendListPublic, SubscriptionPolicy: models.SubscriptionEveryone})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = repo.SavePrivacySettings(ctx, models.PrivacySettings{Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: "secret", SubscriptionPolicy: models.SubscriptionEveryone})
+	assert.Equal(t, "invalid_value", apperrors.From(err).Code)
+
+	saved, err := repo.SavePrivacySettings(ctx, models.PrivacySettings{Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: models.FriendListPrivate, SubscriptionPolicy: models.SubscriptionNobody})
+	assert.Nil(t, err)
+	assert.Equal(t, &now, saved.UpdatedAt)
+
+	settings, err := repo.FindPrivacySettingsByEmails(ctx, []string{"thehaohcm@yahoo.com.vn", "kate@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.PrivacySettings{saved}, settings)
+}
+
+func TestMemoryRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	repo := newMemoryRepositoryForTesting(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := "user" + strconv.Itoa(i) + "@example.com"
+			_ = repo.CreateUsersIfNotExist(ctx, []string{email})
+			_, _ = repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{email, "thehaohcm@yahoo.com.vn"}})
+			_, _ = repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+		}(i)
+	}
+	wg.Wait()
+
+	friends, err := repo.FindFriendsByEmail(ctx, models.FriendListRequest{Email: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.Len(t, friends, 21)
+}
+
+// newMemoryRepositoryForTesting function used to get a MemoryRepository holding a few users,
+// thehaohcm@yahoo.com.vn being friend with chinh.nguyen@s3corp.com.vn
+func newMemoryRepositoryForTesting(t *testing.T) *MemoryRepository {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	if err := repo.CreateUsersIfNotExist(ctx, []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn", "son.le@s3corp.com.vn", "hung.tong@s3corp.com.vn"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateFriendConnection(ctx, models.FriendConnectionRequest{Friends: []string{"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn"}}); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+// outboxLockKey is the key of the advisory lock taken by a relay while it claims a batch, "outbox" in ASCII
+const outboxLockKey = 0x6f7574626f78
+
+// OutboxRepository interface declares the functions used to write the domain events to outbox table and to relay them,
+// the events of the relationship writes are written by the FriendConnectionRepository, in the transaction of the write, see WithOutbox
+type OutboxRepository interface {
+	Append(ctx context.Context, eventType, aggregateID string, payload interface{}) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error)
+	Complete(ctx context.Context, published []int64, failures []OutboxFailure, released []int64) error
+}
+
+// OutboxFailure struct used to record a failed publication of an event, see Complete
+type OutboxFailure struct {
+	Sequence   int64
+	Reason     string
+	RetryAfter time.Duration
+}
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository function used for initializing an OutboxRepository
+// pass a pointer sql.DB as parameter
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// Append function used to write a standalone domain event, which is the only write of its operation, e.g. UpdatePosted
+// pass a context, the type of the event, the email of its user and its payload as parameters
+// return an error type
+func (repo *outboxRepository) Append(ctx context.Context, eventType, aggregateID string, payload interface{}) error {
+	if err := appendEvent(ctx, repo.db, eventType, aggregateID, payload); err != nil {
+		return dbError(ctx, "AppendEvent", err)
+	}
+	return nil
+}
+
+// Claim function used to lease the oldest unpublished events of outbox table to a relay, in the order they were written,
+// in a short transaction: the claimed events are not claimed again, by this relay or another one, until the lease ends,
+// and neither are the next events of their users, so the events of a user are published in order;
+// the events of a user waiting for a retry after a failure are skipped too, with the events written after them
+// the claims are serialized by an advisory lock, nothing is claimed while another relay is claiming
+// pass a context, the maximum number of events and the duration of the lease as parameters
+// return an array of Event model, in the order they were written, and an error type
+func (repo *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+	}
+	if !locked {
+		return []models.Event{}, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `WITH claimed AS (SELECT o.outbox_id FROM public.outbox o 
+	WHERE o.published_at IS NULL AND NOT EXISTS (SELECT 1 FROM public.outbox w 
+	WHERE w.aggregate_id = o.aggregate_id AND w.published_at IS NULL AND w.outbox_id <= o.outbox_id AND w.available_at > now()) 
+	ORDER BY o.outbox_id LIMIT $1 FOR UPDATE SKIP LOCKED) 
+	UPDATE public.outbox o SET available_at=now() + $2::bigint * interval '1 millisecond' FROM claimed WHERE o.outbox_id = claimed.outbox_id 
+	RETURNING o.outbox_id, o.event_id::text, o.event_type, o.aggregate_id, o.payload, COALESCE(o.request_id, ''), o.occurred_at, o.attempts`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		var event models.Event
+		var payload []byte
+		if err := rows.Scan(&event.Sequence, &event.ID, &event.Type, &event.AggregateID, &payload, &event.RequestID, &event.OccurredAt, &event.Attempts); err != nil {
+			return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return []models.Event{}, dbError(ctx, "ClaimEvents", err)
+	}
+	// the rows returned by an UPDATE are not ordered
+	sort.Slice(events, func(i, j int) bool { return events[i].Sequence < events[j].Sequence })
+	return events, nil
+}
+
+// Complete function used to record the outcome of the publication of a claimed batch in a short transaction:
+// the published events are not relayed anymore, the failed ones are relayed again after their delay, with the next events of their users,
+// and the lease of the events left out, e.g. the next events of a failed one or the events not reached before the end of the lease, is released
+// pass a context, the sequences of the published events, the failed publications and the sequences of the events left out as parameters
+// return an error type
+func (repo *outboxRepository) Complete(ctx context.Context, published []int64, failures []OutboxFailure, released []int64) error {
+	if len(published) == 0 && len(failures) == 0 && len(released) == 0 {
+		return nil
+	}
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "CompleteEvents", err)
+	}
+	defer tx.Rollback()
+
+	for _, failure := range failures {
+		_, err := tx.ExecContext(ctx, `UPDATE public.outbox SET attempts=attempts+1, last_error=$2, available_at=now() + $3::bigint * interval '1 millisecond' 
+	WHERE outbox_id=$1`, failure.Sequence, failure.Reason, failure.RetryAfter.Milliseconds())
+		if err != nil {
+			return dbError(ctx, "CompleteEvents", err)
+		}
+	}
+	if len(published) > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE public.outbox SET published_at=now() WHERE outbox_id = ANY($1::bigint[])`, pq.Int64Array(published)); err != nil {
+			return dbError(ctx, "CompleteEvents", err)
+		}
+	}
+	if len(released) > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE public.outbox SET available_at=now() WHERE outbox_id = ANY($1::bigint[])`, pq.Int64Array(released)); err != nil {
+			return dbError(ctx, "CompleteEvents", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "CompleteEvents", err)
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// appendEvent function used to write a domain event to outbox table, with the request id of the context
+// pass a context, the transaction or the database, the type of the event, the email of its user and its payload as parameters
+// return an error type
+func appendEvent(ctx context.Context, db execer, eventType, aggregateID string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO public.outbox(event_type, aggregate_id, payload, request_id) VALUES($1,$2,$3,NULLIF($4, ''))`,
+		eventType, aggregateID, string(data), logger.RequestIDFromContext(ctx))
+	return err
+}
+
+// insertUsers function used to insert users into user table, skipping the ones already registered,
+// with a UserCreated event for each user actually created when events is true
+// pass a context, the transaction, an array of emails and whether to write the events as parameters
+// return an error type
+func insertUsers(ctx context.Context, db execer, emails []string, events bool) error {
+	if !events {
+		_, err := db.ExecContext(ctx, `INSERT INTO public.user_account(user_email) SELECT unnest($1::varchar[]) 
+	ON CONFLICT (user_email) DO NOTHING`, pq.Array(emails))
+		return err
+	}
+	_, err := db.ExecContext(ctx, `WITH created AS (INSERT INTO public.user_account(user_email) SELECT unnest($1::varchar[]) 
+	ON CONFLICT (user_email) DO NOTHING RETURNING user_email) 
+	INSERT INTO public.outbox(event_type, aggregate_id, payload, request_id) 
+	SELECT $2, user_email, jsonb_build_object('email', user_email), NULLIF($3, '') FROM created`,
+		pq.Array(emails), models.EventUserCreated, logger.RequestIDFromContext(ctx))
+	return err
+}
+
+// appendRelationshipEvents function used to write the events of a batch of relationships to outbox table, in the order of the batch,
+// a FriendConnected event (aggregate the requestor, payload the two friends) or a Subscribed or SubscriptionBlocked event
+// (aggregate the requestor, payload the requestor and the target) per relationship, like the writes of one relationship
+// pass a context, the transaction, the type of the events and two arrays of emails, requestors[i] being related to targets[i], as parameters
+// return an error type
+func appendRelationshipEvents(ctx context.Context, db execer, eventType string, requestors, targets []string) error {
+	payload := `jsonb_build_object('requestor', r.requestor, 'target', r.target)`
+	if eventType == models.EventFriendConnected {
+		payload = `jsonb_build_object('friends', jsonb_build_array(r.requestor, r.target))`
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO public.outbox(event_type, aggregate_id, payload, request_id) 
+	SELECT $3, r.requestor, `+payload+`, NULLIF($4, '') 
+	FROM unnest($1::varchar[], $2::varchar[]) WITH ORDINALITY AS r(requestor, target, position) ORDER BY r.position`,
+		pq.Array(requestors), pq.Array(targets), eventType, logger.RequestIDFromContext(ctx))
+	return err
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+func TestSubscribeFromEmailWritesItsEventInItsTransaction(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("INSERT INTO public.relationship\\(requestor, target, subscribed\\)").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectExec("INSERT INTO public.outbox\\(event_type, aggregate_id, payload, request_id\\)").
+		WithArgs(models.EventSubscribed, "abc@def.com", `{"requestor":"abc@def.com","target":"abc1@def.com"}`, "req-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	_, err = New(mockDB, WithOutbox()).SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "abc@def.com", Target: "abc1@def.com"})
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCreateFriendConnectionIsRolledBackWhenItsEventFails(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE (.+) FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("INSERT INTO public.relationship\\(requestor, target, is_friend\\)").WillReturnResult(sqlmock.NewResult(1, 2))
+	sqlMock.ExpectExec("INSERT INTO public.outbox").
+		WithArgs(models.EventFriendConnected, "abc@def.com", `{"friends":["abc@def.com","abc1@def.com"]}`, "").
+		WillReturnError(&pq.Error{Code: "42P01"})
+	sqlMock.ExpectRollback()
+
+	_, err = New(mockDB, WithOutbox()).CreateFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
+	assert.NotNil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCreateUsersIfNotExistWritesAnEventPerCreatedUser(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("WITH created AS \\(INSERT INTO public.user_account(.+) RETURNING user_email\\)(.+)INSERT INTO public.outbox").
+		WithArgs(pq.Array([]string{"abc@def.com"}), models.EventUserCreated, "").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	assert.Nil(t, New(mockDB, WithOutbox()).CreateUsersIfNotExist(context.Background(), []string{"abc@def.com"}))
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClaimLeasesTheOldestEventsOfTheUsersNotWaiting(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	occurredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WithArgs(outboxLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	sqlMock.ExpectQuery("WITH claimed AS \\(SELECT o.outbox_id FROM public.outbox o\\s+WHERE o.published_at IS NULL AND NOT EXISTS (.+) w.available_at > now\\(\\)\\)\\s+"+
+		"ORDER BY o.outbox_id LIMIT \\$1 FOR UPDATE SKIP LOCKED\\)\\s+UPDATE public.outbox o SET available_at=now\\(\\) \\+ \\$2(.+) RETURNING").
+		WithArgs(100, int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"outbox_id", "event_id", "event_type", "aggregate_id", "payload", "request_id", "occurred_at", "attempts"}).
+			AddRow(4, "5d1e0f3a-7c2b-4e8d-a1f6-9b3c2d4e5f60", models.EventSubscribed, "abc1@def.com", []byte(`{"requestor":"abc1@def.com","target":"abc@def.com"}`), "", occurredAt, 0).
+			AddRow(3, "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", models.EventUserCreated, "abc@def.com", []byte(`{"email":"abc@def.com"}`), "req-1", occurredAt, 2))
+	sqlMock.ExpectCommit()
+
+	events, err := NewOutboxRepository(mockDB).Claim(context.Background(), 100, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Event{
+		{ID: "0b6c7a1e-2f4e-4a53-9d8e-3f1c2b9a7d10", Sequence: 3, Type: models.EventUserCreated, AggregateID: "abc@def.com",
+			Payload: []byte(`{"email":"abc@def.com"}`), RequestID: "req-1", OccurredAt: occurredAt, Attempts: 2},
+		{ID: "5d1e0f3a-7c2b-4e8d-a1f6-9b3c2d4e5f60", Sequence: 4, Type: models.EventSubscribed, AggregateID: "abc1@def.com",
+			Payload: []byte(`{"requestor":"abc1@def.com","target":"abc@def.com"}`), OccurredAt: occurredAt},
+	}, events)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClaimReturnsNothingWhileAnotherRelayClaims(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WithArgs(outboxLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	sqlMock.ExpectRollback()
+
+	events, err := NewOutboxRepository(mockDB).Claim(context.Background(), 100, time.Minute)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCompleteRecordsTheOutcomeOfABatch(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("UPDATE public.outbox SET attempts=attempts\\+1").WithArgs(int64(3), "webhook answered 503", int64(2000)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("UPDATE public.outbox SET published_at=now\\(\\) WHERE outbox_id = ANY").WithArgs(pq.Int64Array{4, 5}).WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec("UPDATE public.outbox SET available_at=now\\(\\) WHERE outbox_id = ANY").WithArgs(pq.Int64Array{6}).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	err = NewOutboxRepository(mockDB).Complete(context.Background(), []int64{4, 5},
+		[]OutboxFailure{{Sequence: 3, Reason: "webhook answered 503", RetryAfter: 2 * time.Second}}, []int64{6})
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
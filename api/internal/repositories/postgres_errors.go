@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+	"golang_project/api/internal/apperrors"
+	"modernc.org/sqlite"
+)
+
+// translateError function used to convert an error returned by database/sql, lib/pq or the SQLite driver into a domain error
+// the original error is kept as the cause so it can still be logged, but its SQL details are never sent to clients
+// pass an error as parameter
+// return an error, nil when err is nil
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return translatePqError(pqErr)
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return translateSQLiteError(sqliteErr)
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return apperrors.From(err)
+	case errors.Is(err, sql.ErrNoRows):
+		return apperrors.Wrap(err, apperrors.KindNotFound, "not_found", "resource not found")
+	case errors.Is(err, sql.ErrConnDone), errors.Is(err, driver.ErrBadConn):
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+
+	// the server cannot be reached, e.g. connection refused
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+
+	return apperrors.Internal(err)
+}
+
+func translatePqError(err *pq.Error) error {
+	switch err.Code.Name() {
+	case "unique_violation":
+		if strings.Contains(err.Constraint, "user_account") {
+			return apperrors.Wrap(err, apperrors.KindAlreadyExists, "user_already_exists", "user already exists")
+		}
+		return apperrors.Wrap(err, apperrors.KindAlreadyExists, "already_exists", "resource already exists")
+	case "foreign_key_violation":
+		return apperrors.Wrap(err, apperrors.KindNotFound, "user_not_found", "user not found")
+	case "not_null_violation", "check_violation", "string_data_right_truncation", "invalid_text_representation":
+		return apperrors.Wrap(err, apperrors.KindValidation, "invalid_value", "invalid value")
+	case "serialization_failure", "deadlock_detected", "lock_not_available":
+		return apperrors.Wrap(err, apperrors.KindConflict, "concurrent_update", "the resource was modified concurrently, please retry")
+	case "query_canceled":
+		return apperrors.Wrap(err, apperrors.KindTimeout, "timeout", "the request took too long to complete")
+	case "too_many_connections", "admin_shutdown", "crash_shutdown", "cannot_connect_now":
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+
+	if err.Code.Class() == "08" {
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+
+	return apperrors.Internal(err)
+}
+
+// transientError function used to know whether a failed read may succeed when run again:
+// the database could not be reached, or the read conflicted with a concurrent write
+// pass an error as parameter
+// return a boolean
+func transientError(err error) bool {
+	appErr := apperrors.From(translateError(err))
+	return appErr.Kind == apperrors.KindUnavailable || appErr.Code == "concurrent_update"
+}
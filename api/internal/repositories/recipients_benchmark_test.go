@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"golang_project/api/internal/models"
+)
+
+// benchmarkSender has the subscribers seeded by the recipients benchmarks, one in ten of them blocked the sender,
+// and 100 friends who also receive the updates
+const benchmarkSender = "sender@example.com"
+
+var benchmarkSubscribers = []int{1000, 10000, 100000}
+
+// BenchmarkSQLiteRecipients measures GetSubscribingEmailListByEmail and StreamRecipients for senders with up to 100k subscribers
+// go test ./api/internal/repositories/ -run '^$' -bench Recipients -benchmem
+func BenchmarkSQLiteRecipients(b *testing.B) {
+	for _, subscribers := range benchmarkSubscribers {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+			if err != nil {
+				b.Fatal(err)
+			}
+			db.SetMaxOpenConns(1)
+			defer db.Close()
+			if err := MigrateSQLite(context.Background(), db); err != nil {
+				b.Fatal(err)
+			}
+			seedRecipientsForBenchmark(b, db, subscribers, func(n int) (string, string) {
+				return fmt.Sprintf("WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i+1 FROM seq WHERE i < %d) ", n), "seq"
+			})
+			benchmarkRecipients(b, NewSQLiteRepository(db), subscribers)
+		})
+	}
+}
+
+// BenchmarkPostgresRecipients is BenchmarkSQLiteRecipients against the database of TEST_DATABASE_URL, which is emptied first
+func BenchmarkPostgresRecipients(b *testing.B) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, subscribers := range benchmarkSubscribers {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			if _, err := db.Exec(`TRUNCATE public.relationship, public.privacy_setting, public.user_account CASCADE`); err != nil {
+				b.Fatal(err)
+			}
+			seedRecipientsForBenchmark(b, db, subscribers, func(n int) (string, string) {
+				return "", fmt.Sprintf("generate_series(1, %d) AS seq(i)", n)
+			})
+			if _, err := db.Exec(`ANALYZE public.user_account; ANALYZE public.relationship`); err != nil {
+				b.Fatal(err)
+			}
+			benchmarkRecipients(b, New(db), subscribers)
+		})
+	}
+}
+
+func benchmarkRecipients(b *testing.B, repo FriendConnectionRepository, subscribers int) {
+	request := models.GetSubscribingEmailListRequest{Sender: benchmarkSender, Text: "hello friend1@example.com subscriber1@example.com"}
+	recipients, err := repo.GetSubscribingEmailListByEmail(context.Background(), request)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// subscriber10, subscriber20... blocked the sender, the mentioned users are already recipients
+	if expected := subscribers - subscribers/10 + 100; len(recipients) != expected {
+		b.Fatalf("got %d recipients, expected %d", len(recipients), expected)
+	}
+
+	b.Run("list", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetSubscribingEmailListByEmail(context.Background(), request); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// the stream allocates less and keeps nothing: each recipient can be collected once the callback returns
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := repo.StreamRecipients(context.Background(), request, func(email string) error { return nil }); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// seedRecipientsForBenchmark function used to insert the sender, its subscribers and 100 friends with set-based statements
+// pass a function returning the WITH clause and the FROM source of the numbers 1 to n as parameter, they differ between SQLite and Postgres
+func seedRecipientsForBenchmark(b *testing.B, db *sql.DB, subscribers int, series func(n int) (string, string)) {
+	withSubscribers, fromSubscribers := series(subscribers)
+	withFriends, fromFriends := series(100)
+	statements := []string{
+		`INSERT INTO user_account(user_email) VALUES ('` + benchmarkSender + `')`,
+		withSubscribers + `INSERT INTO user_account(user_email) SELECT 'subscriber' || i || '@example.com' FROM ` + fromSubscribers,
+		withFriends + `INSERT INTO user_account(user_email) SELECT 'friend' || i || '@example.com' FROM ` + fromFriends,
+		withSubscribers + `INSERT INTO relationship(requestor, target, subscribed, subscribe_blocked)
+		SELECT 'subscriber' || i || '@example.com', '` + benchmarkSender + `', true, i % 10 = 0 FROM ` + fromSubscribers,
+		withFriends + `INSERT INTO relationship(requestor, target, is_friend)
+		SELECT '` + benchmarkSender + `', 'friend' || i || '@example.com', true FROM ` + fromFriends + `
+		UNION ALL SELECT 'friend' || i || '@example.com', '` + benchmarkSender + `', true FROM ` + fromFriends,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
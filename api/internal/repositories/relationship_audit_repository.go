@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+// RelationshipAuditRepository interface declares the functions used to query the audit of the relationship writes
+type RelationshipAuditRepository interface {
+	FindRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) ([]models.RelationshipAuditEntry, error)
+}
+
+type relationshipAuditRepository struct {
+	db *sql.DB
+}
+
+// NewRelationshipAuditRepository function used for initializing a RelationshipAuditRepository
+// pass a pointer sql.DB as parameter
+func NewRelationshipAuditRepository(db *sql.DB) RelationshipAuditRepository {
+	return &relationshipAuditRepository{
+		db: db,
+	}
+}
+
+// FindRelationshipAudit function used to query the rows of relationship_audit table kept by a filter, newest first
+// pass a context and a RelationshipAuditFilter model as parameters
+// return an array of RelationshipAuditEntry model and an error type
+func (repo *relationshipAuditRepository) FindRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) ([]models.RelationshipAuditEntry, error) {
+	var args []interface{}
+	where := ` WHERE true`
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		where += ` AND (requestor = $` + strconv.Itoa(len(args)) + ` OR target = $` + strconv.Itoa(len(args)) + `)`
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		where += ` AND action = $` + strconv.Itoa(len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where += ` AND changed_at >= $` + strconv.Itoa(len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where += ` AND changed_at < $` + strconv.Itoa(len(args))
+	}
+	if filter.Before != 0 {
+		args = append(args, filter.Before)
+		where += ` AND audit_id < $` + strconv.Itoa(len(args))
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := repo.db.QueryContext(ctx, `SELECT audit_id, requestor, target, action, COALESCE(actor, ''), COALESCE(request_id, ''),
+	before_is_friend, before_friend_blocked, before_subscribed, before_subscribe_blocked,
+	after_is_friend, after_friend_blocked, after_subscribed, after_subscribe_blocked, changed_at
+	FROM public.relationship_audit`+where+` ORDER BY audit_id DESC LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return []models.RelationshipAuditEntry{}, dbError(ctx, "FindRelationshipAudit", err)
+	}
+	defer rows.Close()
+
+	entries := []models.RelationshipAuditEntry{}
+	for rows.Next() {
+		var entry models.RelationshipAuditEntry
+		var isFriend, friendBlocked, subscribed, subscribeBlocked sql.NullBool
+		if err := rows.Scan(&entry.ID, &entry.Requestor, &entry.Target, &entry.Action, &entry.Actor, &entry.RequestID,
+			&isFriend, &friendBlocked, &subscribed, &subscribeBlocked,
+			&entry.After.IsFriend, &entry.After.FriendBlocked, &entry.After.Subscribed, &entry.After.SubscribeBlocked, &entry.ChangedAt); err != nil {
+			return []models.RelationshipAuditEntry{}, dbError(ctx, "FindRelationshipAudit", err)
+		}
+		// the flags before the write are all null when the write created the relationship
+		if isFriend.Valid {
+			entry.Before = &models.RelationshipFlags{
+				IsFriend:         isFriend.Bool,
+				FriendBlocked:    friendBlocked.Bool,
+				Subscribed:       subscribed.Bool,
+				SubscribeBlocked: subscribeBlocked.Bool,
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.RelationshipAuditEntry{}, dbError(ctx, "FindRelationshipAudit", err)
+	}
+	return entries, nil
+}
+
+// auditedWrite function used to run a write of relationship table in its transaction and to append to relationship_audit table,
+// in the same transaction, a row per relationship the write changed, with its flags before and after the write,
+// the subject of the authenticated caller as actor and the request id of the context
+// the existing rows of the write are locked first, so no other transaction changes them between the audit and the write
+// pass a context, the transaction, the audit action, the condition selecting the rows of the write,
+// the write (an INSERT or an UPDATE of relationship table) and its two parameters, the requestor and the target
+// (two emails, or two arrays of emails for the imports), as parameters
+// return an error type
+func auditedWrite(ctx context.Context, tx *sql.Tx, action, rows, write string, requestor, target interface{}) error {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM public.relationship WHERE `+rows+` FOR UPDATE`, requestor, target); err != nil {
+		return err
+	}
+
+	var actor string
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		actor = principal.Subject
+	}
+	_, err := tx.ExecContext(ctx, `WITH previous AS (
+	SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked FROM public.relationship WHERE `+rows+`
+), changed AS (
+	`+write+`
+	RETURNING requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked
+)
+INSERT INTO public.relationship_audit(requestor, target, action, actor, request_id,
+	before_is_friend, before_friend_blocked, before_subscribed, before_subscribe_blocked,
+	after_is_friend, after_friend_blocked, after_subscribed, after_subscribe_blocked)
+SELECT c.requestor, c.target, $3, NULLIF($4, ''), NULLIF($5, ''), p.is_friend, p.friend_blocked, p.subscribed, p.subscribe_blocked,
+	c.is_friend, c.friend_blocked, c.subscribed, c.subscribe_blocked
+FROM changed c LEFT JOIN previous p ON p.requestor = c.requestor AND p.target = c.target`,
+		requestor, target, action, actor, logger.RequestIDFromContext(ctx))
+	return err
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/logger"
+	"golang_project/api/internal/models"
+)
+
+func TestBlockSubscribeByEmailIsAuditedInItsTransaction(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "abc@def.com", Email: "abc@def.com", Roles: []string{auth.RoleUser}})
+	ctx = logger.WithRequestID(ctx, "req-1")
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE requestor=\\$1 AND target=\\$2 FOR UPDATE").
+		WithArgs("abc@def.com", "abc1@def.com").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("WITH previous AS .* INSERT INTO public.relationship\\(requestor,target,subscribe_blocked\\) .* INSERT INTO public.relationship_audit").
+		WithArgs("abc@def.com", "abc1@def.com", models.AuditActionBlock, "abc@def.com", "req-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	_, err = New(mockDB).BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "abc@def.com", Target: "abc1@def.com"})
+	assert.Nil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRemoveFriendConnectionIsNotCommittedWhenTheAuditFails(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("SELECT 1 FROM public.relationship WHERE \\(\\(requestor=\\$1 AND target=\\$2\\) OR \\(requestor=\\$2 AND target=\\$1\\)\\) FOR UPDATE").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec("UPDATE public.relationship SET is_friend=false").
+		WithArgs("abc@def.com", "abc1@def.com", models.AuditActionUnfriend, "", "").WillReturnError(errConnectionRefused)
+	sqlMock.ExpectRollback()
+
+	_, err = New(mockDB).RemoveFriendConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"abc@def.com", "abc1@def.com"}})
+	assert.NotNil(t, err)
+	assert.Nil(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFindRelationshipAuditWithFilters(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer mockDB.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	changedAt := from.Add(time.Hour)
+	columns := []string{"audit_id", "requestor", "target", "action", "actor", "request_id",
+		"before_is_friend", "before_friend_blocked", "before_subscribed", "before_subscribe_blocked",
+		"after_is_friend", "after_friend_blocked", "after_subscribed", "after_subscribe_blocked", "changed_at"}
+	sqlMock.ExpectQuery("FROM public.relationship_audit WHERE true AND \\(requestor = \\$1 OR target = \\$1\\) AND action = \\$2 AND changed_at >= \\$3 AND changed_at < \\$4 AND audit_id < \\$5 ORDER BY audit_id DESC LIMIT \\$6").
+		WithArgs("abc@def.com", models.AuditActionBlock, from, to, int64(42), 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(41, "abc@def.com", "abc1@def.com", "block", "admin@def.com", "req-2", true, false, true, false, true, false, true, true, changedAt).
+			AddRow(40, "abc1@def.com", "abc@def.com", "block", "", "", nil, nil, nil, nil, false, false, false, true, changedAt))
+
+	entries, err := NewRelationshipAuditRepository(mockDB).FindRelationshipAudit(context.Background(), models.RelationshipAuditFilter{
+		Email: "abc@def.com", Action: models.AuditActionBlock, From: &from, To: &to, Before: 42, Limit: 2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.RelationshipAuditEntry{
+		{ID: 41, Requestor: "abc@def.com", Target: "abc1@def.com", Action: "block", Actor: "admin@def.com", RequestID: "req-2",
+			Before: &models.RelationshipFlags{IsFriend: true, Subscribed: true}, After: models.RelationshipFlags{IsFriend: true, Subscribed: true, SubscribeBlocked: true}, ChangedAt: changedAt},
+		{ID: 40, Requestor: "abc1@def.com", Target: "abc@def.com", Action: "block",
+			After: models.RelationshipFlags{SubscribeBlocked: true}, ChangedAt: changedAt},
+	}, entries)
+}
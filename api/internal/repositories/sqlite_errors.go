@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// translateSQLiteError function used to convert an error of the SQLite driver into the same domain errors as translatePqError
+// pass a sqlite.Error as parameter
+// return an error
+func translateSQLiteError(err *sqlite.Error) error {
+	switch err.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		if strings.Contains(err.Error(), "user_account.") {
+			return apperrors.Wrap(err, apperrors.KindAlreadyExists, "user_already_exists", "user already exists")
+		}
+		return apperrors.Wrap(err, apperrors.KindAlreadyExists, "already_exists", "resource already exists")
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return apperrors.Wrap(err, apperrors.KindNotFound, "user_not_found", "user not found")
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_TOOBIG, sqlite3.SQLITE_MISMATCH:
+		return apperrors.Wrap(err, apperrors.KindValidation, "invalid_value", "invalid value")
+	case sqlite3.SQLITE_INTERRUPT:
+		return apperrors.Wrap(err, apperrors.KindTimeout, "timeout", "the request took too long to complete")
+	}
+
+	switch err.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return apperrors.Wrap(err, apperrors.KindConflict, "concurrent_update", "the resource was modified concurrently, please retry")
+	case sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL, sqlite3.SQLITE_READONLY:
+		return apperrors.Wrap(err, apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+	}
+
+	return apperrors.Internal(err)
+}
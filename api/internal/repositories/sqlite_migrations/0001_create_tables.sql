@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_account(user_email varchar primary key);
+
+CREATE TABLE IF NOT EXISTS relationship(requestor varchar not null, target varchar not null, is_friend boolean not null default false, friend_blocked boolean not null default false, subscribed boolean not null default false, subscribe_blocked boolean not null default false,
+constraint pk_mail_app_recipients primary key (requestor, target),
+CONSTRAINT fk_requestor_user_account FOREIGN KEY(requestor) REFERENCES user_account(user_email),
+CONSTRAINT fk_target_user_account FOREIGN KEY(target) REFERENCES user_account(user_email));
+
+CREATE INDEX IF NOT EXISTS relationship_target ON relationship(target);
+
+INSERT INTO user_account(user_email) values('thehaohcm@yahoo.com.vn');
+INSERT INTO user_account(user_email) values('hao.nguyen@s3corp.com.vn');
+INSERT INTO user_account(user_email) values('thehaohcm@gmail.com');
+INSERT INTO user_account(user_email) values('chinh.nguyen@s3corp.com.vn');
+INSERT INTO user_account(user_email) values('son.le@s3corp.com.vn');
+INSERT INTO user_account(user_email) values('hung.tong@s3corp.com.vn');
+
+INSERT INTO relationship(requestor,target, is_friend) values('thehaohcm@yahoo.com.vn','hao.nguyen@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('thehaohcm@gmail.com','hao.nguyen@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('hao.nguyen@s3corp.com.vn','chinh.nguyen@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('chinh.nguyen@s3corp.com.vn','hao.nguyen@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('thehaohcm@yahoo.com.vn','chinh.nguyen@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('chinh.nguyen@s3corp.com.vn','hung.tong@s3corp.com.vn',true);
+INSERT INTO relationship(requestor,target, is_friend) values('hung.tong@s3corp.com.vn','chinh.nguyen@s3corp.com.vn',true);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS privacy_setting(user_email varchar primary key,
+friend_list_visibility varchar not null default 'public', subscription_policy varchar not null default 'everyone',
+updated_at datetime not null default CURRENT_TIMESTAMP,
+CONSTRAINT fk_privacy_setting_user_account FOREIGN KEY(user_email) REFERENCES user_account(user_email),
+CONSTRAINT privacy_setting_friend_list_visibility CHECK (friend_list_visibility IN ('public', 'friends', 'private')),
+CONSTRAINT privacy_setting_subscription_policy CHECK (subscription_policy IN ('everyone', 'friends_of_friends', 'nobody')));
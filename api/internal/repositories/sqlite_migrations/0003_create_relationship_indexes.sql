@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS relationship_subscribers ON relationship(target, requestor) WHERE subscribed = true AND subscribe_blocked = false;
+CREATE INDEX IF NOT EXISTS relationship_friends ON relationship(requestor, target) WHERE is_friend = true AND friend_blocked = false AND subscribe_blocked = false;
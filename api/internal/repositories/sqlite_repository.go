@@ -0,0 +1,575 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+)
+
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrations embed.FS
+
+type sqliteRepository struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLiteRepository function used for initializing a FriendConnectionRepository which keeps its data in a SQLite database
+// the schema must have been created with MigrateSQLite
+// pass a pointer sql.DB opened with the sqlite driver as parameter
+func NewSQLiteRepository(db *sql.DB) FriendConnectionRepository {
+	return &sqliteRepository{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// MigrateSQLite function used to create or upgrade the schema of a SQLite database with the migrations of sqlite_migrations
+// the version of the schema is kept in PRAGMA user_version, so the migrations already applied are skipped
+// pass a context and a pointer sql.DB as parameters
+// return an error type
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(sqliteMigrations, "sqlite_migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("reading sqlite schema version: %w", err)
+	}
+	for i := version; i < len(names); i++ {
+		migration, err := sqliteMigrations.ReadFile(names[i])
+		if err != nil {
+			return err
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying %s: %w", names[i], err)
+		}
+		// PRAGMA does not accept parameters, i+1 is an integer we computed ourselves
+		if _, err := tx.ExecContext(ctx, `PRAGMA user_version = `+strconv.Itoa(i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying %s: %w", names[i], err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("applying %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// CreateUser function used to insert data of a new user into user table
+// pass a context and a CreatingUserRequest model as parameters
+// return a User model and an error type
+func (repo *sqliteRepository) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.User, error) {
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		return models.User{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `INSERT INTO user_account(user_email) VALUES(?)`, request.Email); err != nil {
+		return models.User{}, dbError(ctx, "CreateUser", err)
+	}
+
+	return models.User{Email: request.Email}, nil
+}
+
+// CreateUsersIfNotExist function used to insert a list of users into user table, skipping the ones already registered
+// pass a context and an array of emails as parameters
+// return an error type
+func (repo *sqliteRepository) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return err
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO user_account(user_email) VALUES(?) ON CONFLICT (user_email) DO NOTHING`)
+	if err != nil {
+		tx.Rollback()
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+	defer stmt.Close()
+	for _, email := range emails {
+		if _, err := stmt.ExecContext(ctx, email); err != nil {
+			tx.Rollback()
+			return dbError(ctx, "CreateUsersIfNotExist", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "CreateUsersIfNotExist", err)
+	}
+
+	return nil
+}
+
+// FindUnregisteredEmails function used to query user table to get the emails which are not registered yet
+// pass a context and an array of emails as parameters
+// return an array of unregistered emails, in the order they were given, and an error type
+func (repo *sqliteRepository) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return []string{}, nil
+	}
+
+	placeholders, args := sqliteIn(emails, false)
+	rows, err := repo.db.QueryContext(ctx, `SELECT user_email FROM user_account WHERE user_email IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+	}
+	defer rows.Close()
+
+	registered := map[string]bool{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+		}
+		registered[email] = true
+	}
+	if err := rows.Err(); err != nil {
+		return []string{}, dbError(ctx, "FindUnregisteredEmails", err)
+	}
+
+	unregistered := []string{}
+	for _, email := range emails {
+		if !registered[email] {
+			unregistered = append(unregistered, email)
+		}
+	}
+	return pkg.RemoveDuplicatedItems(unregistered), nil
+}
+
+// CreateFriendConnection function used to insert data of a new friend connection into relationship table, in both directions
+// pass a context and a FriendConnectionRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) CreateFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	if len(friendConnectionRequest.Friends) != 2 {
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if err := pkg.CheckValidEmails(friendConnectionRequest.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+
+	// SQLite takes the upsert clause of Postgres, but its parameters are numbered with "?"
+	if _, err := repo.db.ExecContext(ctx, `INSERT INTO relationship(requestor, target, is_friend)
+	VALUES(?1,?2,true),(?2,?1,true) ON CONFLICT (requestor,target)
+	DO UPDATE SET is_friend = excluded.is_friend`, friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1]); err != nil {
+		return models.Relationship{}, dbError(ctx, "CreateFriendConnection", err)
+	}
+
+	return models.Relationship{Requestor: friendConnectionRequest.Friends[0], Target: friendConnectionRequest.Friends[1], IsFriend: true}, nil
+}
+
+// RemoveFriendConnection function used to update data in relationship table to remove a friend connection in both directions
+// removing a connection which does not exist is not an error
+// pass a context and a FriendConnectionRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) RemoveFriendConnection(ctx context.Context, friendConnectionRequest models.FriendConnectionRequest) (models.Relationship, error) {
+	if len(friendConnectionRequest.Friends) != 2 {
+		return models.Relationship{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if err := pkg.CheckValidEmails(friendConnectionRequest.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `UPDATE relationship SET is_friend=false
+	WHERE (requestor=?1 AND target=?2) OR (requestor=?2 AND target=?1)`, friendConnectionRequest.Friends[0], friendConnectionRequest.Friends[1]); err != nil {
+		return models.Relationship{}, dbError(ctx, "RemoveFriendConnection", err)
+	}
+
+	return models.Relationship{Requestor: friendConnectionRequest.Friends[0], Target: friendConnectionRequest.Friends[1]}, nil
+}
+
+// FindFriendsByEmail function used to query data from relationship table to get a list of friend emails by an email address
+// pass a context and a FriendListRequest model as parameters
+// return an array of Relationship model and an error type
+func (repo *sqliteRepository) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmail(request.Email); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	rows, err := repo.db.QueryContext(ctx, `SELECT requestor FROM relationship WHERE target=?1 and is_friend=true AND friend_blocked=false
+	UNION SELECT target FROM relationship WHERE requestor=?1 and is_friend=true AND friend_blocked=false`, request.Email)
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
+	}
+	defer rows.Close()
+
+	var relationships []models.Relationship
+	for rows.Next() {
+		relationshipTmp := models.Relationship{Requestor: request.Email}
+		if err := rows.Scan(&relationshipTmp.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
+		}
+		relationships = append(relationships, relationshipTmp)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmail", err)
+	}
+
+	return relationships, nil
+}
+
+// FindCommonFriendsByEmails function used to query data from relationship table to get a list of common friend emails between 2 email addresses
+// pass a context and a CommonFriendListRequest model as parameters
+// return an array of Relationship model and an error type
+func (repo *sqliteRepository) FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	params, args := sqliteIn(request.Friends, true)
+	sqlStatement := `SELECT target,count(*) FROM relationship where requestor in (` +
+		params + `) and target not in (` + params +
+		`) group by target having count(*)>1 union SELECT requestor ,count(*) FROM relationship where target in (` +
+		params + `) and requestor not in(` + params + `) group by requestor having count(*)>1`
+
+	rows, err := repo.db.QueryContext(ctx, sqlStatement, args...)
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
+	}
+	defer rows.Close()
+
+	var relationships []models.Relationship
+	for rows.Next() {
+		var relationship models.Relationship
+		var count int
+		if err := rows.Scan(&relationship.Target, &count); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindCommonFriendsByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// SubscribeFromEmail function used to insert a new subscribe connection into relationship table
+// pass a context and a SubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `INSERT INTO relationship(requestor, target, subscribed) VALUES (?,?,true)
+	ON CONFLICT (requestor,target) DO UPDATE SET subscribed = excluded.subscribed`, req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, dbError(ctx, "SubscribeFromEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target, Subscribed: true}, nil
+}
+
+// UnsubscribeFromEmail function used to update data in relationship table to remove a subscribe connection
+// removing a subscription which does not exist is not an error
+// pass a context and a SubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `UPDATE relationship SET subscribed=false WHERE requestor=? AND target=?`, req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, dbError(ctx, "UnsubscribeFromEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// BlockSubscribeByEmail function used to update data in relationship table to block a subscribe connection
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `INSERT INTO relationship(requestor,target,subscribe_blocked) VALUES (?,?,true)
+	ON CONFLICT (requestor,target) DO UPDATE SET subscribe_blocked = excluded.subscribe_blocked`, req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, dbError(ctx, "BlockSubscribeByEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target, FriendBlocked: true}, nil
+}
+
+// UnblockSubscribeByEmail function used to update data in relationship table to lift a block on a subscribe connection
+// lifting a block which does not exist is not an error
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a Relationship model and an error type
+func (repo *sqliteRepository) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
+		return models.Relationship{}, err
+	}
+
+	if _, err := repo.db.ExecContext(ctx, `UPDATE relationship SET subscribe_blocked=false WHERE requestor=? AND target=?`, req.Requestor, req.Target); err != nil {
+		return models.Relationship{}, dbError(ctx, "UnblockSubscribeByEmail", err)
+	}
+
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+// sqliteRecipientsQuery is recipientsQuery for SQLite, the mentions are passed as a JSON array since SQLite has no array parameters
+const sqliteRecipientsQuery = `WITH mentioned AS (
+	SELECT m.value AS email, min(m.key) AS position FROM json_each(?2) m
+	WHERE m.value <> ?1 AND NOT EXISTS (SELECT 1 FROM relationship rs WHERE rs.requestor = m.value AND rs.target = ?1 AND rs.subscribe_blocked = true)
+	GROUP BY m.value
+), connected AS (
+	SELECT rs.target AS email FROM relationship rs
+	WHERE rs.requestor = ?1 AND rs.is_friend = true AND rs.friend_blocked = false AND rs.subscribe_blocked = false
+	UNION
+	SELECT rs.requestor FROM relationship rs
+	WHERE rs.target = ?1 AND rs.subscribed = true AND rs.subscribe_blocked = false
+)
+SELECT email FROM (
+	SELECT email, 0 AS source, position FROM mentioned
+	UNION ALL
+	SELECT c.email, 1, 0 FROM connected c WHERE c.email <> ?1 AND NOT EXISTS (SELECT 1 FROM mentioned m WHERE m.email = c.email)
+) recipients ORDER BY source, position, email`
+
+// GetSubscribingEmailListByEmail function used to get the emails which receive an update of a sender, with a single query and the same rules as the Postgres repository
+// pass a context and a GetSubscribingEmailListRequest model as parameters
+// return an array of Relationship model with the recipients as Target and an error type
+func (repo *sqliteRepository) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmail(req.Sender); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	mentions, err := json.Marshal(mentionedEmails(req.Text))
+	if err != nil {
+		return []models.Relationship{}, err
+	}
+	rows, err := repo.db.QueryContext(ctx, sqliteRecipientsQuery, req.Sender, string(mentions))
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+	}
+	defer rows.Close()
+
+	var relationships []models.Relationship
+	for rows.Next() {
+		var relationship models.Relationship
+		if err := rows.Scan(&relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "GetSubscribingEmailListByEmail", err)
+	}
+
+	return relationships, nil
+}
+
+// StreamRecipients function used to get the same recipients as GetSubscribingEmailListByEmail, in the same order, one at a time
+// the recipients are read completely before the callback is called: the server has a single connection to SQLite,
+// which would be held by a slow reader of the stream and block every other request until the stream ends
+// pass a context, a GetSubscribingEmailListRequest model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (repo *sqliteRepository) StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	recipients, err := repo.GetSubscribingEmailListByEmail(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := fn(recipient.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindRelationshipsByEmails function used to query every row of relationship table involving one of the given email addresses, in a single query
+// pass a context and an array of emails as parameters
+// return an array of Relationship model and an error type
+func (repo *sqliteRepository) FindRelationshipsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	params, args := sqliteIn(emails, true)
+	rows, err := repo.db.QueryContext(ctx, `SELECT requestor, target, is_friend, friend_blocked, subscribed, subscribe_blocked
+	FROM relationship WHERE requestor IN (`+params+`) OR target IN (`+params+`)`, args...)
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+	}
+	defer rows.Close()
+
+	relationships := []models.Relationship{}
+	for rows.Next() {
+		var relationship models.Relationship
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target, &relationship.IsFriend, &relationship.FriendBlocked, &relationship.Subscribed, &relationship.SubscribeBlock); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindRelationshipsByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// FindFriendsByEmails function used to query the friends of many email addresses at once, with a single query
+// each returned relationship has one of the emails as Requestor and one of its friends as Target
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Requestor and Target, and an error type
+func (repo *sqliteRepository) FindFriendsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	params, args := sqliteIn(emails, true)
+	rows, err := repo.db.QueryContext(ctx, `SELECT target, requestor FROM relationship WHERE target IN (`+params+`) AND is_friend=true AND friend_blocked=false
+	UNION SELECT requestor, target FROM relationship WHERE requestor IN (`+params+`) AND is_friend=true AND friend_blocked=false
+	ORDER BY 1, 2`, args...)
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
+	}
+	defer rows.Close()
+
+	relationships := []models.Relationship{}
+	for rows.Next() {
+		relationship := models.Relationship{IsFriend: true}
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindFriendsByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// FindSubscribersByEmails function used to query the subscribers of many email addresses at once, with a single query
+// each returned relationship has a subscriber as Requestor and one of the emails as Target, blocked subscriptions are left out
+// pass a context and an array of emails as parameters
+// return an array of Relationship models ordered by Target and Requestor, and an error type
+func (repo *sqliteRepository) FindSubscribersByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if len(emails) == 0 {
+		return []models.Relationship{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+
+	params, args := sqliteIn(emails, false)
+	rows, err := repo.db.QueryContext(ctx, `SELECT requestor, target FROM relationship
+	WHERE target IN (`+params+`) AND subscribed=true AND subscribe_blocked=false ORDER BY target, requestor`, args...)
+	if err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
+	}
+	defer rows.Close()
+
+	relationships := []models.Relationship{}
+	for rows.Next() {
+		relationship := models.Relationship{Subscribed: true}
+		if err := rows.Scan(&relationship.Requestor, &relationship.Target); err != nil {
+			return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
+		}
+		relationships = append(relationships, relationship)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.Relationship{}, dbError(ctx, "FindSubscribersByEmails", err)
+	}
+
+	return relationships, nil
+}
+
+// FindPrivacySettingsByEmails function used to query the privacy settings of many email addresses from privacy_setting table, in a single query
+// the users who never changed their settings have no row and are missing from the result
+// pass a context and an array of emails as parameters
+// return an array of PrivacySettings model and an error type
+func (repo *sqliteRepository) FindPrivacySettingsByEmails(ctx context.Context, emails []string) ([]models.PrivacySettings, error) {
+	if len(emails) == 0 {
+		return []models.PrivacySettings{}, nil
+	}
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.PrivacySettings{}, err
+	}
+
+	params, args := sqliteIn(emails, false)
+	rows, err := repo.db.QueryContext(ctx, `SELECT user_email, friend_list_visibility, subscription_policy, updated_at
+	FROM privacy_setting WHERE user_email IN (`+params+`)`, args...)
+	if err != nil {
+		return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
+	}
+	defer rows.Close()
+
+	settings := []models.PrivacySettings{}
+	for rows.Next() {
+		var setting models.PrivacySettings
+		var updatedAt time.Time
+		if err := rows.Scan(&setting.Email, &setting.FriendListVisibility, &setting.SubscriptionPolicy, &updatedAt); err != nil {
+			return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
+		}
+		setting.UpdatedAt = &updatedAt
+		settings = append(settings, setting)
+	}
+	if err := rows.Err(); err != nil {
+		return []models.PrivacySettings{}, dbError(ctx, "FindPrivacySettingsByEmails", err)
+	}
+
+	return settings, nil
+}
+
+// SavePrivacySettings function used to insert or replace the privacy settings of a user into privacy_setting table
+// pass a context and a PrivacySettings model as parameters
+// return the stored PrivacySettings model and an error type, of KindNotFound when the user is not registered
+func (repo *sqliteRepository) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmail(settings.Email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+
+	updatedAt := repo.now().UTC()
+	if _, err := repo.db.ExecContext(ctx, `INSERT INTO privacy_setting(user_email, friend_list_visibility, subscription_policy, updated_at) VALUES(?,?,?,?)
+	ON CONFLICT (user_email) DO UPDATE SET friend_list_visibility=excluded.friend_list_visibility, subscription_policy=excluded.subscription_policy, updated_at=excluded.updated_at`,
+		settings.Email, settings.FriendListVisibility, settings.SubscriptionPolicy, updatedAt); err != nil {
+		return models.PrivacySettings{}, dbError(ctx, "SavePrivacySettings", err)
+	}
+	settings.UpdatedAt = &updatedAt
+	return settings, nil
+}
+
+// sqliteIn function used to build the placeholders of an IN list, SQLite has no array parameters
+// numbered placeholders (?1, ?2...) let a statement repeat the list without repeating its arguments
+// pass an array of values and whether the placeholders are numbered as parameters
+// return the placeholders and the arguments of the list
+func sqliteIn(values []string, numbered bool) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		if numbered {
+			placeholders[i] += strconv.Itoa(i + 1)
+		}
+		args[i] = value
+	}
+	return strings.Join(placeholders, ","), args
+}
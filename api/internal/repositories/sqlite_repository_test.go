@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) FriendConnectionRepository {
+		db := newSQLiteForTesting(t)
+		// the contract starts from an empty database, without the users of the migrations
+		if _, err := db.Exec(`DELETE FROM privacy_setting; DELETE FROM relationship; DELETE FROM user_account`); err != nil {
+			t.Fatal(err)
+		}
+		return NewSQLiteRepository(db)
+	})
+}
+
+func TestMigrateSQLiteSkipsAppliedMigrations(t *testing.T) {
+	db := newSQLiteForTesting(t)
+
+	assert.Nil(t, MigrateSQLite(context.Background(), db))
+	var version, users int
+	assert.Nil(t, db.QueryRow(`PRAGMA user_version`).Scan(&version))
+	assert.Nil(t, db.QueryRow(`SELECT count(*) FROM user_account`).Scan(&users))
+	assert.Equal(t, 3, version)
+	assert.Equal(t, 6, users)
+}
+
+func TestSQLiteRepositoryUpserts(t *testing.T) {
+	repo := NewSQLiteRepository(newSQLiteForTesting(t))
+	ctx := context.Background()
+
+	_, err := repo.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = repo.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+
+	relationships, err := repo.FindRelationshipsByEmails(ctx, []string{"thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []models.Relationship{
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true, Subscribed: true, SubscribeBlock: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: true},
+	}, relationships)
+}
+
+func TestSQLiteRepositoryStreamReleasesTheConnection(t *testing.T) {
+	repo := NewSQLiteRepository(newSQLiteForTesting(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the callback can query the database through the only connection, like a concurrent request served during a slow stream
+	var recipients []string
+	err := repo.StreamRecipients(ctx, models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "hi"}, func(email string) error {
+		recipients = append(recipients, email)
+		_, err := repo.FindRelationshipsByEmails(ctx, []string{email})
+		return err
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, recipients)
+}
+
+func TestSQLiteRepositoryPrivacySettingsTime(t *testing.T) {
+	repo := &sqliteRepository{db: newSQLiteForTesting(t), now: func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }}
+	ctx := context.Background()
+
+	_, err := repo.SavePrivacySettings(ctx, models.PrivacySettings{Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: models.FriendListFriends, SubscriptionPolicy: models.SubscriptionNobody})
+	assert.Nil(t, err)
+
+	settings, err := repo.FindPrivacySettingsByEmails(ctx, []string{"thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	if assert.Len(t, settings, 1) {
+		assert.True(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).Equal(*settings[0].UpdatedAt))
+	}
+}
+
+func TestTranslateSQLiteErrors(t *testing.T) {
+	db := newSQLiteForTesting(t)
+
+	_, err := db.Exec(`INSERT INTO user_account(user_email) VALUES('thehaohcm@yahoo.com.vn')`)
+	assert.Equal(t, "user_already_exists", apperrors.From(translateError(err)).Code)
+	_, err = db.Exec(`INSERT INTO relationship(requestor, target) VALUES('thehaohcm@yahoo.com.vn', 'nobody@example.com')`)
+	assert.Equal(t, "user_not_found", apperrors.From(translateError(err)).Code)
+	_, err = db.Exec(`INSERT INTO privacy_setting(user_email, friend_list_visibility) VALUES('thehaohcm@yahoo.com.vn', 'secret')`)
+	assert.Equal(t, "invalid_value", apperrors.From(translateError(err)).Code)
+}
+
+// newSQLiteForTesting function used to open a migrated SQLite database in memory, closed when the test ends
+func newSQLiteForTesting(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to ":memory:" has its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	if err := MigrateSQLite(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,53 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff struct describes how an operation is retried: up to Attempts times in total,
+// waiting a random delay between 0 and Base doubled at each retry, capped by Max ("full jitter"),
+// so the clients retrying after the same outage do not retry all at once
+type Backoff struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+// Delay function used to get a random delay before a retry
+// pass the number of the failed attempt, starting at 1, as parameter
+// return a time.Duration
+func (b Backoff) Delay(attempt int) time.Duration {
+	ceiling := b.Base
+	for i := 1; i < attempt && ceiling < b.Max; i++ {
+		ceiling *= 2
+	}
+	if b.Max > 0 && ceiling > b.Max {
+		ceiling = b.Max
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Retry function used to run an operation until it succeeds, fails with an error which is not retryable, or runs out of attempts
+// the waits stop when the context is done
+// pass a context, a function telling whether an error is retryable and the operation as parameters
+// return the error of the last attempt, or the error of the context when it is done while waiting
+func (b Backoff) Retry(ctx context.Context, retryable func(error) bool, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= b.Attempts || !retryable(err) {
+			return err
+		}
+		timer := time.NewTimer(b.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
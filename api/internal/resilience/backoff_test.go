@@ -0,0 +1,73 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestBackoffDelayIsJitteredAndCapped(t *testing.T) {
+	b := Backoff{Attempts: 5, Base: 10 * time.Millisecond, Max: 35 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, b.Delay(1), 10*time.Millisecond)
+		assert.LessOrEqual(t, b.Delay(2), 20*time.Millisecond)
+		assert.LessOrEqual(t, b.Delay(10), 35*time.Millisecond)
+		assert.GreaterOrEqual(t, b.Delay(10), time.Duration(0))
+	}
+	assert.Equal(t, time.Duration(0), Backoff{}.Delay(3))
+}
+
+func TestRetryStopsOnSuccess(t *testing.T) {
+	calls := 0
+	err := Backoff{Attempts: 3, Base: time.Millisecond}.Retry(context.Background(), isTransient, func() error {
+		calls++
+		if calls < 2 {
+			return errTransient
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryGivesUpAfterTheAttempts(t *testing.T) {
+	calls := 0
+	err := Backoff{Attempts: 3, Base: time.Millisecond}.Retry(context.Background(), isTransient, func() error {
+		calls++
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryDoesNotRetryPermanentErrors(t *testing.T) {
+	permanent := errors.New("permanent")
+	calls := 0
+	err := Backoff{Attempts: 3, Base: time.Millisecond}.Retry(context.Background(), isTransient, func() error {
+		calls++
+		return permanent
+	})
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryStopsWaitingWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Backoff{Attempts: 3, Base: time.Hour, Max: time.Hour}.Retry(ctx, isTransient, func() error {
+		calls++
+		cancel()
+		return errTransient
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
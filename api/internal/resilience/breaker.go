@@ -0,0 +1,132 @@
+package resilience
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Breaker.Do without running the operation while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// ErrAborted is recorded as a failure of an operation started with Breaker.Start whatever the failure function, e.g. when it panics
+var ErrAborted = errors.New("operation aborted")
+
+// breaker states
+const (
+	closed = iota
+	open
+	halfOpen
+)
+
+// Breaker struct is a circuit breaker: after Threshold consecutive failures it opens, and the operations fail fast with ErrOpen
+// for the cooldown; then a single operation is let through as a trial, closing the breaker when it succeeds and opening it again when it fails
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	failure   func(error) bool
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// NewBreaker function used for initializing a Breaker
+// pass the name of the breaker used in the logs, the number of consecutive failures opening it, the cooldown,
+// and a function telling whether an error is a failure of the protected resource (e.g. not a validation error) as parameters
+// return a pointer of Breaker
+func NewBreaker(name string, threshold int, cooldown time.Duration, failure func(error) bool) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		failure:   failure,
+		now:       time.Now,
+	}
+}
+
+// Do function used to run an operation through the breaker
+// pass the operation as parameter
+// return ErrOpen when the breaker is open, otherwise the error of the operation
+func (b *Breaker) Do(operation func() error) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+	// a panicking operation is a failure, and it must not leave a trial running forever
+	failed := true
+	defer func() { b.record(failed) }()
+	err := operation()
+	failed = err != nil && b.failure(err)
+	return err
+}
+
+// Start function used to run through the breaker an operation whose outcome is known before it ends, e.g. a stream
+// whose outcome is the one of its first read; the returned function records the outcome and must be called once
+// return the function recording the error of the operation, and ErrOpen when the breaker is open
+func (b *Breaker) Start() (func(err error), error) {
+	if !b.allow() {
+		return nil, ErrOpen
+	}
+	return func(err error) {
+		b.record(errors.Is(err, ErrAborted) || (err != nil && b.failure(err)))
+	}, nil
+}
+
+// RetryAfter function used to get how long the breaker stays open
+// return a time.Duration, 0 when the breaker is not open
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != open {
+		return 0
+	}
+	if wait := b.cooldown - b.now().Sub(b.openedAt); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = halfOpen
+		return true
+	case halfOpen:
+		// the trial operation is still running
+		return false
+	}
+	return true
+}
+
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		if b.state != closed {
+			slog.Info("circuit breaker closed", slog.String("breaker", b.name))
+		}
+		b.state = closed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		if b.state != open {
+			slog.Warn("circuit breaker opened", slog.String("breaker", b.name), slog.Int("failures", b.failures), slog.Duration("cooldown", b.cooldown))
+		}
+		b.state = open
+		b.openedAt = b.now()
+	}
+}
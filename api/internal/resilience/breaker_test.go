@@ -0,0 +1,89 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBreakerForTesting() (*Breaker, *time.Time) {
+	b := NewBreaker("test", 2, 10*time.Second, isTransient)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return clock }
+	return b, &clock
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newBreakerForTesting()
+	fail := func() error { return errTransient }
+
+	assert.ErrorIs(t, b.Do(fail), errTransient)
+	// a success resets the count
+	assert.Nil(t, b.Do(func() error { return nil }))
+	assert.ErrorIs(t, b.Do(fail), errTransient)
+	// the errors which are not failures of the resource do not count either
+	assert.NotNil(t, b.Do(func() error { return errors.New("invalid email") }))
+	assert.Equal(t, time.Duration(0), b.RetryAfter())
+
+	assert.ErrorIs(t, b.Do(fail), errTransient)
+	assert.ErrorIs(t, b.Do(fail), errTransient)
+	called := false
+	assert.ErrorIs(t, b.Do(func() error { called = true; return nil }), ErrOpen)
+	assert.False(t, called)
+	assert.Equal(t, 10*time.Second, b.RetryAfter())
+}
+
+func TestBreakerLetsATrialThroughAfterTheCooldown(t *testing.T) {
+	b, clock := newBreakerForTesting()
+	fail := func() error { return errTransient }
+	b.Do(fail)
+	b.Do(fail)
+
+	*clock = clock.Add(10 * time.Second)
+	// only one trial runs at a time
+	assert.ErrorIs(t, b.Do(func() error {
+		assert.ErrorIs(t, b.Do(func() error { return nil }), ErrOpen)
+		return errTransient
+	}), errTransient)
+	// the trial failed, the breaker is open for another cooldown
+	assert.ErrorIs(t, b.Do(func() error { return nil }), ErrOpen)
+	assert.Equal(t, 10*time.Second, b.RetryAfter())
+
+	*clock = clock.Add(10 * time.Second)
+	assert.Nil(t, b.Do(func() error { return nil }))
+	assert.Nil(t, b.Do(func() error { return nil }))
+	assert.Equal(t, time.Duration(0), b.RetryAfter())
+}
+
+func TestBreakerCountsPanicsAsFailures(t *testing.T) {
+	b, clock := newBreakerForTesting()
+	b.Do(func() error { return errTransient })
+	b.Do(func() error { return errTransient })
+	*clock = clock.Add(10 * time.Second)
+
+	assert.Panics(t, func() { b.Do(func() error { panic("boom") }) })
+	assert.ErrorIs(t, b.Do(func() error { return nil }), ErrOpen)
+}
+
+func TestBreakerStartRecordsTheOutcomeOnce(t *testing.T) {
+	b, clock := newBreakerForTesting()
+	b.Do(func() error { return errTransient })
+	b.Do(func() error { return errTransient })
+	*clock = clock.Add(10 * time.Second)
+
+	// the trial is running until its outcome is recorded
+	record, err := b.Start()
+	assert.Nil(t, err)
+	_, err = b.Start()
+	assert.ErrorIs(t, err, ErrOpen)
+	record(nil)
+	assert.Nil(t, b.Do(func() error { return nil }))
+
+	record, _ = b.Start()
+	record(ErrAborted)
+	record, _ = b.Start()
+	record(ErrAborted)
+	assert.ErrorIs(t, b.Do(func() error { return nil }), ErrOpen)
+}
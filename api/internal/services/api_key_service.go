@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+)
+
+// APIKeyService interface declares the functions used by admins to manage the API keys of service accounts
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, request models.CreatingAPIKeyRequest) (models.CreatingAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context) (models.APIKeyListResponse, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+type apiKeyService struct {
+	repository repositories.APIKeyRepository
+}
+
+// NewAPIKeyService function used for initializing an APIKeyService
+// pass an APIKeyRepository as parameter
+// return an APIKeyService
+func NewAPIKeyService(repo repositories.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		repository: repo,
+	}
+}
+
+// CreateAPIKey function works as a service function for creating a new API key, only the hash of the key is stored
+// the key gets the service role when no role is requested
+// pass a context and a CreatingAPIKeyRequest model as parameters
+// return a CreatingAPIKeyResponse model, holding the only copy of the plain key, and an error type
+func (svc *apiKeyService) CreateAPIKey(ctx context.Context, request models.CreatingAPIKeyRequest) (models.CreatingAPIKeyResponse, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return models.CreatingAPIKeyResponse{}, apperrors.Validation("empty_name", "api key name is empty")
+	}
+	roles := request.Roles
+	if len(roles) == 0 {
+		roles = []string{auth.RoleService}
+	}
+	for _, role := range roles {
+		if !auth.IsValidRole(role) {
+			return models.CreatingAPIKeyResponse{}, apperrors.Validation("invalid_role", "invalid role: "+role).WithDetail("role", role)
+		}
+	}
+
+	id, hash, key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return models.CreatingAPIKeyResponse{}, apperrors.Internal(err)
+	}
+	stored, err := svc.repository.CreateAPIKey(ctx, models.APIKey{ID: id, Name: name, KeyHash: hash, Roles: roles})
+	if err != nil {
+		return models.CreatingAPIKeyResponse{}, err
+	}
+
+	return models.CreatingAPIKeyResponse{ID: stored.ID, Name: stored.Name, Roles: stored.Roles, Key: key, CreatedAt: stored.CreatedAt}, nil
+}
+
+// ListAPIKeys function works as a service function for listing the API keys, without their hash
+// pass a context as parameter
+// return an APIKeyListResponse model and an error type
+func (svc *apiKeyService) ListAPIKeys(ctx context.Context) (models.APIKeyListResponse, error) {
+	keys, err := svc.repository.ListAPIKeys(ctx)
+	if err != nil {
+		return models.APIKeyListResponse{}, err
+	}
+
+	response := models.APIKeyListResponse{Keys: []models.APIKeyResponse{}}
+	for _, key := range keys {
+		response.Keys = append(response.Keys, models.APIKeyResponse{ID: key.ID, Name: key.Name, Roles: key.Roles, CreatedAt: key.CreatedAt, RevokedAt: key.RevokedAt})
+	}
+	response.Count = len(response.Keys)
+	return response, nil
+}
+
+// RevokeAPIKey function works as a service function for revoking an API key, the key is kept for auditing
+// pass a context and the id of the key as parameters
+// return an error type
+func (svc *apiKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return apperrors.InvalidRequest("invalid_request", "invalid request, the api key id is empty")
+	}
+	return svc.repository.RevokeAPIKey(ctx, id)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+)
+
+func TestCreateAPIKeyDefaultsToServiceRole(t *testing.T) {
+	repo := &apiKeyRepoFake{}
+	result, err := NewAPIKeyService(repo).CreateAPIKey(context.Background(), models.CreatingAPIKeyRequest{Name: " reporting "})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "reporting", result.Name)
+	assert.Equal(t, []string{auth.RoleService}, result.Roles)
+	if assert.Len(t, repo.created, 1) {
+		assert.Equal(t, result.ID, repo.created[0].ID)
+		assert.NotContains(t, result.Key, repo.created[0].KeyHash)
+	}
+}
+
+func TestCreateAPIKeyWithInvalidRequest(t *testing.T) {
+	repo := &apiKeyRepoFake{}
+	svc := NewAPIKeyService(repo)
+
+	_, err := svc.CreateAPIKey(context.Background(), models.CreatingAPIKeyRequest{Name: " "})
+	assert.Equal(t, "empty_name", apperrors.From(err).Code)
+
+	_, err = svc.CreateAPIKey(context.Background(), models.CreatingAPIKeyRequest{Name: "reporting", Roles: []string{"root"}})
+	assert.Equal(t, "invalid_role", apperrors.From(err).Code)
+	assert.Empty(t, repo.created)
+}
+
+// apiKeyRepoFake records the created keys, the other methods are not expected to be called
+type apiKeyRepoFake struct {
+	repositories.APIKeyRepository
+	created []models.APIKey
+}
+
+func (r *apiKeyRepoFake) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	r.created = append(r.created, key)
+	return key, nil
+}
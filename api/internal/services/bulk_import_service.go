@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/bulkimport"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/repositories"
+)
+
+const (
+	// importBatchSize is the number of rows written by one statement
+	importBatchSize = 1000
+	// maxImportErrors bounds the row errors listed in a report, the others are only counted
+	maxImportErrors = 1000
+)
+
+// BulkImportService interface declares the functions used to onboard users and relationships in bulk
+type BulkImportService interface {
+	Import(ctx context.Context, source io.Reader, options models.BulkImportOptions) (models.BulkImportReport, error)
+}
+
+type bulkImportService struct {
+	repository repositories.BulkImportRepository
+	batchSize  int
+}
+
+// NewBulkImportService function used for initializing a BulkImportService
+// pass a BulkImportRepository as parameter
+// return a BulkImportService
+func NewBulkImportService(repo repositories.BulkImportRepository) BulkImportService {
+	return &bulkImportService{
+		repository: repo,
+		batchSize:  importBatchSize,
+	}
+}
+
+// importRun holds the state of one import
+type importRun struct {
+	svc     *bulkImportService
+	options models.BulkImportOptions
+	report  models.BulkImportReport
+	// tx is shared by every batch in dry-run and all-or-nothing modes, otherwise each batch has its own transaction
+	tx repositories.BulkImportTx
+}
+
+// Import function works as a service function for importing the rows of a CSV or JSONL file, see bulkimport.NewDecoder,
+// the rows are read as a stream and written by batches; each row is validated and the failing ones are listed in the report
+// relationships between unregistered users fail, unless AutoCreateUsers is set
+// pass a context, the file and BulkImportOptions as parameters
+// return a BulkImportReport and an error type, set when the file cannot be read or the database fails in dry-run and all-or-nothing modes
+func (svc *bulkImportService) Import(ctx context.Context, source io.Reader, options models.BulkImportOptions) (models.BulkImportReport, error) {
+	decoder, err := bulkimport.NewDecoder(source, options.Format, options.Kind)
+	if err != nil {
+		return models.BulkImportReport{}, err
+	}
+
+	run := &importRun{svc: svc, options: options, report: models.BulkImportReport{
+		Kind:         options.Kind,
+		Format:       options.Format,
+		DryRun:       options.DryRun,
+		AllOrNothing: options.AllOrNothing,
+		Errors:       []models.BulkImportRowError{},
+	}}
+	if options.DryRun || options.AllOrNothing {
+		if run.tx, err = svc.repository.Begin(ctx); err != nil {
+			return models.BulkImportReport{}, err
+		}
+		defer run.tx.Rollback()
+	}
+
+	batch := make([]bulkimport.Record, 0, svc.batchSize)
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return models.BulkImportReport{}, apperrors.Wrap(err, apperrors.KindInvalidRequest, "unreadable_import", "cannot read the import file: "+err.Error())
+		}
+		run.report.Total++
+		if err := validateImportRecord(options.Kind, record); err != nil {
+			run.fail(record.Line, err)
+			continue
+		}
+		batch = append(batch, record)
+		if len(batch) == svc.batchSize {
+			if err := run.write(ctx, batch); err != nil {
+				return models.BulkImportReport{}, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := run.write(ctx, batch); err != nil {
+		return models.BulkImportReport{}, err
+	}
+
+	switch {
+	case run.tx == nil:
+		run.report.Committed = run.report.Imported > 0
+	case options.DryRun:
+	case run.report.Failed > 0:
+		run.report.Imported = 0
+	default:
+		if err := run.tx.Commit(); err != nil {
+			return models.BulkImportReport{}, err
+		}
+		run.report.Committed = true
+	}
+	return run.report, nil
+}
+
+// write function writes a batch of valid rows, rows referencing unregistered users are reported as failed
+// in best effort mode a failing batch is reported row by row and the import goes on, otherwise the import stops
+func (run *importRun) write(ctx context.Context, batch []bulkimport.Record) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	tx := run.tx
+	if tx == nil {
+		var err error
+		if tx, err = run.svc.repository.Begin(ctx); err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	written, err := run.writeBatch(ctx, tx, batch)
+	if err == nil && run.tx == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if run.tx != nil || ctx.Err() != nil {
+			return err
+		}
+		// the rows of unregistered users are already reported, only the rows sent to the database failed with the batch
+		for _, record := range written {
+			run.fail(record.Line, err)
+		}
+		return nil
+	}
+	run.report.Imported += len(written)
+	return nil
+}
+
+// writeBatch function writes the rows of a batch whose users are registered, the others are reported as failed
+// return the rows sent to the database and an error type
+func (run *importRun) writeBatch(ctx context.Context, tx repositories.BulkImportTx, batch []bulkimport.Record) ([]bulkimport.Record, error) {
+	if run.options.Kind == models.ImportUsers {
+		emails := make([]string, len(batch))
+		for i, record := range batch {
+			emails[i] = record.Email
+		}
+		return batch, tx.CreateUsersIfNotExist(ctx, emails)
+	}
+
+	emails := make([]string, 0, 2*len(batch))
+	for _, record := range batch {
+		emails = append(emails, record.Email, record.Target)
+	}
+	emails = pkg.RemoveDuplicatedItems(emails)
+	if run.options.AutoCreateUsers {
+		if err := tx.CreateUsersIfNotExist(ctx, emails); err != nil {
+			return batch, err
+		}
+	}
+	unregistered, err := tx.FindUnregisteredEmails(ctx, emails)
+	if err != nil {
+		return batch, err
+	}
+	missing := make(map[string]bool, len(unregistered))
+	for _, email := range unregistered {
+		missing[email] = true
+	}
+
+	sent := make([]bulkimport.Record, 0, len(batch))
+	requestors := make([]string, 0, len(batch))
+	targets := make([]string, 0, len(batch))
+	for _, record := range batch {
+		if missing[record.Email] || missing[record.Target] {
+			email := record.Email
+			if !missing[email] {
+				email = record.Target
+			}
+			run.fail(record.Line, apperrors.NotFound("user_not_found", "unregistered email address: "+email))
+			continue
+		}
+		sent = append(sent, record)
+		requestors = append(requestors, record.Email)
+		targets = append(targets, record.Target)
+	}
+	if len(requestors) == 0 {
+		return sent, nil
+	}
+
+	switch run.options.Kind {
+	case models.ImportFriendships:
+		err = tx.UpsertFriendships(ctx, requestors, targets)
+	case models.ImportSubscriptions:
+		err = tx.UpsertSubscriptions(ctx, requestors, targets)
+	default:
+		err = tx.UpsertBlocks(ctx, requestors, targets)
+	}
+	return sent, err
+}
+
+// fail function adds a row to the errors of the report
+func (run *importRun) fail(line int, err error) {
+	run.report.Failed++
+	if len(run.report.Errors) == maxImportErrors {
+		run.report.ErrorsTruncated = true
+		return
+	}
+	appErr := apperrors.From(err)
+	run.report.Errors = append(run.report.Errors, models.BulkImportRowError{Line: line, Code: appErr.Code, Error: appErr.Message})
+}
+
+// validateImportRecord function checks the emails of a row
+func validateImportRecord(kind string, record bulkimport.Record) error {
+	if record.Err != nil {
+		return record.Err
+	}
+	if err := pkg.CheckValidEmail(record.Email); err != nil {
+		return invalidImportEmail(record.Email)
+	}
+	if kind == models.ImportUsers {
+		return nil
+	}
+	if err := pkg.CheckValidEmail(record.Target); err != nil {
+		return invalidImportEmail(record.Target)
+	}
+	if strings.EqualFold(record.Email, record.Target) {
+		return errSelfRelation(record.Email)
+	}
+	return nil
+}
+
+func invalidImportEmail(email string) error {
+	if strings.TrimSpace(email) == "" {
+		return pkg.ErrEmptyEmail
+	}
+	return apperrors.Validation("invalid_email", "invalid email address: "+email)
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+)
+
+func TestImportUsersInBatches(t *testing.T) {
+	repo := newBulkImportRepoFake()
+	svc := &bulkImportService{repository: repo, batchSize: 2}
+
+	report, err := svc.Import(context.Background(), strings.NewReader("email\na@example.com\nb@example.com\nnot-an-email\nc@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportUsers, Format: models.ImportFormatCSV})
+
+	assert.Nil(t, err)
+	assert.Equal(t, models.BulkImportReport{
+		Kind: models.ImportUsers, Format: models.ImportFormatCSV, Committed: true, Total: 4, Imported: 3, Failed: 1,
+		Errors: []models.BulkImportRowError{{Line: 4, Code: "invalid_email", Error: "invalid email address: not-an-email"}},
+	}, report)
+	assert.True(t, repo.users["c@example.com"])
+	assert.Equal(t, 2, repo.commits)
+}
+
+func TestImportRelationshipsReportsUnregisteredUsers(t *testing.T) {
+	repo := newBulkImportRepoFake("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn")
+	svc := NewBulkImportService(repo)
+
+	report, err := svc.Import(context.Background(), strings.NewReader(`{"requestor":"thehaohcm@yahoo.com.vn","target":"hao.nguyen@s3corp.com.vn"}
+{"requestor":"thehaohcm@yahoo.com.vn","target":"unknown@example.com"}
+{"requestor":"thehaohcm@yahoo.com.vn","target":"thehaohcm@yahoo.com.vn"}`), models.BulkImportOptions{Kind: models.ImportBlocks, Format: models.ImportFormatJSONL})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, []models.BulkImportRowError{
+		{Line: 3, Code: "self_relation", Error: "a user cannot create a relationship with themselves"},
+		{Line: 2, Code: "user_not_found", Error: "unregistered email address: unknown@example.com"},
+	}, report.Errors)
+	assert.Equal(t, []string{"blocks:thehaohcm@yahoo.com.vn>hao.nguyen@s3corp.com.vn"}, repo.relationships)
+}
+
+func TestImportAutoCreatesUsers(t *testing.T) {
+	repo := newBulkImportRepoFake()
+
+	report, err := NewBulkImportService(repo).Import(context.Background(), strings.NewReader("a@example.com,b@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportFriendships, Format: models.ImportFormatCSV, AutoCreateUsers: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.True(t, repo.users["a@example.com"] && repo.users["b@example.com"])
+	assert.Equal(t, []string{"friendships:a@example.com>b@example.com"}, repo.relationships)
+}
+
+func TestImportAllOrNothingRollsBackOnFailure(t *testing.T) {
+	repo := newBulkImportRepoFake("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn")
+	svc := &bulkImportService{repository: repo, batchSize: 1}
+
+	report, err := svc.Import(context.Background(), strings.NewReader("thehaohcm@yahoo.com.vn,hao.nguyen@s3corp.com.vn\nthehaohcm@yahoo.com.vn,unknown@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportSubscriptions, Format: models.ImportFormatCSV, AllOrNothing: true})
+
+	assert.Nil(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, repo.begins)
+	assert.Equal(t, 0, repo.commits)
+	assert.Equal(t, 1, repo.rollbacks)
+
+	report, err = svc.Import(context.Background(), strings.NewReader("thehaohcm@yahoo.com.vn,hao.nguyen@s3corp.com.vn\n"),
+		models.BulkImportOptions{Kind: models.ImportSubscriptions, Format: models.ImportFormatCSV, AllOrNothing: true})
+	assert.Nil(t, err)
+	assert.True(t, report.Committed)
+	assert.Equal(t, 1, repo.commits)
+}
+
+func TestImportDryRunNeverCommits(t *testing.T) {
+	repo := newBulkImportRepoFake()
+
+	report, err := NewBulkImportService(repo).Import(context.Background(), strings.NewReader("a@example.com\nb@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportUsers, Format: models.ImportFormatCSV, DryRun: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Imported)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 0, repo.commits)
+	assert.Equal(t, 1, repo.rollbacks)
+}
+
+func TestImportReportsFailingBatches(t *testing.T) {
+	repo := newBulkImportRepoFake()
+	repo.failure = apperrors.New(apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+
+	report, err := NewBulkImportService(repo).Import(context.Background(), strings.NewReader("a@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportUsers, Format: models.ImportFormatCSV})
+	assert.Nil(t, err)
+	assert.Equal(t, []models.BulkImportRowError{{Line: 1, Code: "database_unavailable", Error: "the database is temporarily unavailable"}}, report.Errors)
+
+	_, err = NewBulkImportService(repo).Import(context.Background(), strings.NewReader("a@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportUsers, Format: models.ImportFormatCSV, AllOrNothing: true})
+	assert.Equal(t, "database_unavailable", apperrors.From(err).Code)
+}
+
+func TestImportReportsTheRowsOfAFailingBatchOnce(t *testing.T) {
+	repo := newBulkImportRepoFake("thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn")
+	repo.upsertFailure = apperrors.New(apperrors.KindUnavailable, "database_unavailable", "the database is temporarily unavailable")
+
+	report, err := NewBulkImportService(repo).Import(context.Background(), strings.NewReader("thehaohcm@yahoo.com.vn,hao.nguyen@s3corp.com.vn\nthehaohcm@yahoo.com.vn,unknown@example.com\n"),
+		models.BulkImportOptions{Kind: models.ImportFriendships, Format: models.ImportFormatCSV})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, []models.BulkImportRowError{
+		{Line: 2, Code: "user_not_found", Error: "unregistered email address: unknown@example.com"},
+		{Line: 1, Code: "database_unavailable", Error: "the database is temporarily unavailable"},
+	}, report.Errors)
+}
+
+// bulkImportRepoFake keeps the registered users in memory and records the imported relationships
+type bulkImportRepoFake struct {
+	users         map[string]bool
+	relationships []string
+	failure       error
+	upsertFailure error
+	begins        int
+	commits       int
+	rollbacks     int
+}
+
+func newBulkImportRepoFake(users ...string) *bulkImportRepoFake {
+	repo := &bulkImportRepoFake{users: map[string]bool{}}
+	for _, user := range users {
+		repo.users[user] = true
+	}
+	return repo
+}
+
+func (r *bulkImportRepoFake) Begin(ctx context.Context) (repositories.BulkImportTx, error) {
+	r.begins++
+	return &bulkImportTxFake{repo: r}, nil
+}
+
+type bulkImportTxFake struct {
+	repo *bulkImportRepoFake
+	done bool
+}
+
+func (t *bulkImportTxFake) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if t.repo.failure != nil {
+		return t.repo.failure
+	}
+	for _, email := range emails {
+		t.repo.users[email] = true
+	}
+	return nil
+}
+
+func (t *bulkImportTxFake) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	unregistered := []string{}
+	for _, email := range emails {
+		if !t.repo.users[email] {
+			unregistered = append(unregistered, email)
+		}
+	}
+	return unregistered, t.repo.failure
+}
+
+func (t *bulkImportTxFake) UpsertFriendships(ctx context.Context, requestors, targets []string) error {
+	return t.upsert(models.ImportFriendships, requestors, targets)
+}
+
+func (t *bulkImportTxFake) UpsertSubscriptions(ctx context.Context, requestors, targets []string) error {
+	return t.upsert(models.ImportSubscriptions, requestors, targets)
+}
+
+func (t *bulkImportTxFake) UpsertBlocks(ctx context.Context, requestors, targets []string) error {
+	return t.upsert(models.ImportBlocks, requestors, targets)
+}
+
+func (t *bulkImportTxFake) upsert(kind string, requestors, targets []string) error {
+	for i := range requestors {
+		t.repo.relationships = append(t.repo.relationships, kind+":"+requestors[i]+">"+targets[i])
+	}
+	if t.repo.upsertFailure != nil {
+		return t.repo.upsertFailure
+	}
+	return t.repo.failure
+}
+
+func (t *bulkImportTxFake) Commit() error {
+	t.done = true
+	t.repo.commits++
+	return nil
+}
+
+func (t *bulkImportTxFake) Rollback() error {
+	if !t.done {
+		t.done = true
+		t.repo.rollbacks++
+	}
+	return nil
+}
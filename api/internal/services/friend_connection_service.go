@@ -1,8 +1,12 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
 
+	"golang_project/api/internal/apperrors"
 	"golang_project/api/internal/models"
 	"golang_project/api/internal/pkg"
 	"golang_project/api/internal/repositories"
@@ -12,33 +16,77 @@ import (
 // and also decouple when invoking these function from Controller layer to Service layer
 // this interface is also useful when we create all mock Service functions for testing
 type FriendConnectionService interface {
-	CreateUser(models.CreatingUserRequest) (models.CreatingUserResponse, error)
-	CreateConnection(models.FriendConnectionRequest) (models.FriendConnectionResponse, error)
-	GetFriendConnection(request models.FriendListRequest) (models.FriendListResponse, error)
-	ShowCommonFriendList(request models.CommonFriendListRequest) (models.CommonFriendListResponse, error)
-	SubscribeFromEmail(request models.SubscribeRequest) (models.SubscribeResponse, error)
-	BlockSubscribeByEmail(request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error)
-	GetSubscribingEmailListByEmail(request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error)
+	CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.CreatingUserResponse, error)
+	CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error)
+	RemoveConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error)
+	GetFriendConnection(ctx context.Context, request models.FriendListRequest) (models.FriendListResponse, error)
+	ShowCommonFriendList(ctx context.Context, request models.CommonFriendListRequest) (models.CommonFriendListResponse, error)
+	SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error)
+	UnsubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error)
+	BlockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error)
+	UnblockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error)
+	GetSubscribingEmailListByEmail(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error)
+	StreamRecipients(ctx context.Context, request models.GetSubscribingEmailListRequest, fn func(email string) error) error
+	PostUpdate(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error)
+	GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error)
+	GetFriendListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchFriendListResponse, error)
+	GetSubscriberListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchSubscriberListResponse, error)
+	GetPrivacySettings(ctx context.Context, email string) (models.PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, email string, request models.UpdatingPrivacySettingsRequest) (models.PrivacySettings, error)
 }
 
+// MaxBatchEmails is the largest number of distinct emails accepted by the batch reads
+const MaxBatchEmails = 500
+
 type service struct {
-	repository repositories.FriendConnectionRepository
+	repository      repositories.FriendConnectionRepository
+	privacy         *PrivacyChecker
+	autoCreateUsers bool
+	outbox          repositories.OutboxRepository
+}
+
+// Option type used to customize a FriendConnectionService when calling New
+type Option func(*service)
+
+// WithAutoCreateUsers function used to enable the autoCreateUsers mode,
+// in which relationships targeting unregistered emails register these users on the fly instead of being rejected
+// pass a boolean as parameter
+// return an Option
+func WithAutoCreateUsers(enabled bool) Option {
+	return func(svc *service) {
+		svc.autoCreateUsers = enabled
+	}
+}
+
+// WithOutbox function used to write an UpdatePosted event to the outbox each time an update is posted, see PostUpdate,
+// the other domain events are written by the repository, see repositories.WithOutbox
+// pass an OutboxRepository as parameter
+// return an Option
+func WithOutbox(outbox repositories.OutboxRepository) Option {
+	return func(svc *service) {
+		svc.outbox = outbox
+	}
 }
 
 // New function used for initializing a FriendConnectionService
-// pass a FriendConnectionRepository as parameter
+// pass a FriendConnectionRepository and optional Options as parameters
 // return a FriendConnectionService model
-func New(repo repositories.FriendConnectionRepository) FriendConnectionService {
-	return &service{
+func New(repo repositories.FriendConnectionRepository, opts ...Option) FriendConnectionService {
+	svc := &service{
 		repository: repo,
+		privacy:    NewPrivacyChecker(repo),
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	return svc
 }
 
 // CreateUser function works as a service function for creating an new user
-// pass a CreatingUserRequest model as parameter
+// pass a context and a CreatingUserRequest model as parameters
 // return a CreatingUserResponse model and an error type
-func (svc *service) CreateUser(request models.CreatingUserRequest) (models.CreatingUserResponse, error) {
-	_, err := svc.repository.CreateUser(request)
+func (svc *service) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.CreatingUserResponse, error) {
+	_, err := svc.repository.CreateUser(ctx, request)
 	if err != nil {
 		return models.CreatingUserResponse{}, err
 	}
@@ -47,10 +95,39 @@ func (svc *service) CreateUser(request models.CreatingUserRequest) (models.Creat
 }
 
 // CreateConnection function works as a service function for creating friend connection between 2 user emails
-// pass a FriendConnectionRequest model as parameter
+// pass a context and a FriendConnectionRequest model as parameters
+// return a FriendConnectionResponse model and an error type
+func (svc *service) CreateConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	if len(request.Friends) != 2 {
+		return models.FriendConnectionResponse{}, apperrors.InvalidRequest("invalid_request", "invalid request, the friends list must have exactly 2 items")
+	}
+	if err := svc.checkRelationshipUsers(ctx, request.Friends[0], request.Friends[1]); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+
+	_, err := svc.repository.CreateFriendConnection(ctx, request)
+	if err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+
+	return models.FriendConnectionResponse{Success: true}, nil
+}
+
+// RemoveConnection function works as a service function for removing the friend connection between 2 user emails
+// pass a context and a FriendConnectionRequest model as parameters
 // return a FriendConnectionResponse model and an error type
-func (svc *service) CreateConnection(request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
-	_, err := svc.repository.CreateFriendConnection(request)
+func (svc *service) RemoveConnection(ctx context.Context, request models.FriendConnectionRequest) (models.FriendConnectionResponse, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return models.FriendConnectionResponse{}, err
+	}
+	if len(request.Friends) != 2 {
+		return models.FriendConnectionResponse{}, apperrors.InvalidRequest("invalid_request", "invalid request, the friends list must have exactly 2 items")
+	}
+
+	_, err := svc.repository.RemoveFriendConnection(ctx, request)
 	if err != nil {
 		return models.FriendConnectionResponse{}, err
 	}
@@ -59,10 +136,21 @@ func (svc *service) CreateConnection(request models.FriendConnectionRequest) (mo
 }
 
 // GetFriendConnection function works as a service function for getting a friend list by an email address
-// pass a FriendListRequest model as parameter
-// return a FriendListResponse model and an error type
-func (svc *service) GetFriendConnection(request models.FriendListRequest) (models.FriendListResponse, error) {
-	relationships, err := svc.repository.FindFriendsByEmail(request)
+// the privacy settings of the owner decide whether the caller may read the list
+// pass a context and a FriendListRequest model as parameters
+// return a FriendListResponse model and an error type, ErrFriendListHidden when the list is not visible to the caller
+func (svc *service) GetFriendConnection(ctx context.Context, request models.FriendListRequest) (models.FriendListResponse, error) {
+	if viewer, restricted := restrictedViewer(ctx); restricted {
+		visible, err := svc.privacy.CanViewFriends(ctx, viewer, request.Email)
+		if err != nil {
+			return models.FriendListResponse{}, err
+		}
+		if !visible {
+			return models.FriendListResponse{}, ErrFriendListHidden.WithDetail("email", request.Email)
+		}
+	}
+
+	relationships, err := svc.repository.FindFriendsByEmail(ctx, request)
 	if err != nil {
 		return models.FriendListResponse{}, err
 	}
@@ -78,29 +166,44 @@ func (svc *service) GetFriendConnection(request models.FriendListRequest) (model
 }
 
 // ShowCommonFriendList function works as a service function for getting a list of common friends between two email addresses
-// pass a CommonFriendListRequest model as parameter
+// a common friend is listed only when both of its friendships are visible to the caller,
+// a friendship being visible when the friend list of one of its sides is
+// pass a context and a CommonFriendListRequest model as parameters
 // return a CommonFriendListResponse model and an error type
-func (svc *service) ShowCommonFriendList(request models.CommonFriendListRequest) (models.CommonFriendListResponse, error) {
+func (svc *service) ShowCommonFriendList(ctx context.Context, request models.CommonFriendListRequest) (models.CommonFriendListResponse, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return models.CommonFriendListResponse{}, err
 	}
-	relationships, err := svc.repository.FindCommonFriendsByEmails(request)
+	relationships, err := svc.repository.FindCommonFriendsByEmails(ctx, request)
 	if err != nil {
-		return models.CommonFriendListResponse{}, nil
+		return models.CommonFriendListResponse{}, err
 	}
 	var friends []string
 	for _, relationship := range relationships {
 		friends = append(friends, relationship.Target)
 	}
+	if viewer, restricted := restrictedViewer(ctx); restricted && len(friends) > 0 {
+		if friends, err = svc.visibleCommonFriends(ctx, viewer, request.Friends, friends); err != nil {
+			return models.CommonFriendListResponse{}, err
+		}
+	}
 
 	return models.CommonFriendListResponse{Success: true, Friends: friends, Count: len(friends)}, nil
 }
 
 // SubscribeFromEmail function works as a service function for creating a subscribe from an email address to another one
-// pass SubscribeRequest model as parameter
-// return a SubscribeResponse model and an error type
-func (svc *service) SubscribeFromEmail(request models.SubscribeRequest) (models.SubscribeResponse, error) {
-	relationships, err := svc.repository.SubscribeFromEmail(request)
+// the subscription policy of the target decides whether the requestor may subscribe
+// pass a context and SubscribeRequest model as parameters
+// return a SubscribeResponse model and an error type, ErrSubscriptionNotAllowed when the policy rejects the requestor
+func (svc *service) SubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if err := svc.checkRelationshipUsers(ctx, request.Requestor, request.Target); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+	if err := svc.privacy.checkSubscription(ctx, request.Requestor, request.Target); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+
+	relationships, err := svc.repository.SubscribeFromEmail(ctx, request)
 	if err != nil {
 		return models.SubscribeResponse{}, err
 	}
@@ -108,11 +211,47 @@ func (svc *service) SubscribeFromEmail(request models.SubscribeRequest) (models.
 	return models.SubscribeResponse{Success: relationships != models.Relationship{}}, nil
 }
 
+// UnsubscribeFromEmail function works as a service function for removing a subscribe from an email address to another one
+// pass a context and SubscribeRequest model as parameters
+// return a SubscribeResponse model and an error type
+func (svc *service) UnsubscribeFromEmail(ctx context.Context, request models.SubscribeRequest) (models.SubscribeResponse, error) {
+	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
+		return models.SubscribeResponse{}, err
+	}
+
+	_, err := svc.repository.UnsubscribeFromEmail(ctx, request)
+	if err != nil {
+		return models.SubscribeResponse{}, err
+	}
+
+	return models.SubscribeResponse{Success: true}, nil
+}
+
 // BlockSubscribeByEmail function works as a service function for creating a block subscribe update from an email address to another one
-// pass a BlockSubscribeRequest model as parameter
+// pass a context and a BlockSubscribeRequest model as parameters
 // return a BlockSubscribeResponse model and an error type
-func (svc *service) BlockSubscribeByEmail(request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
-	_, err := svc.repository.BlockSubscribeByEmail(request)
+func (svc *service) BlockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+	if err := svc.checkRelationshipUsers(ctx, request.Requestor, request.Target); err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+
+	_, err := svc.repository.BlockSubscribeByEmail(ctx, request)
+	if err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+
+	return models.BlockSubscribeResponse{Success: true}, nil
+}
+
+// UnblockSubscribeByEmail function works as a service function for lifting a block subscribe update from an email address to another one
+// pass a context and a BlockSubscribeRequest model as parameters
+// return a BlockSubscribeResponse model and an error type
+func (svc *service) UnblockSubscribeByEmail(ctx context.Context, request models.BlockSubscribeRequest) (models.BlockSubscribeResponse, error) {
+	if err := pkg.CheckValidEmails([]string{request.Requestor, request.Target}); err != nil {
+		return models.BlockSubscribeResponse{}, err
+	}
+
+	_, err := svc.repository.UnblockSubscribeByEmail(ctx, request)
 	if err != nil {
 		return models.BlockSubscribeResponse{}, err
 	}
@@ -121,14 +260,14 @@ func (svc *service) BlockSubscribeByEmail(request models.BlockSubscribeRequest)
 }
 
 // GetSubscribingEmailListByEmail function works as a service function for getting a list of subscribe email by an email address
-// pass a GetSubscribingEmailListRequest model as parameter
+// pass a context and a GetSubscribingEmailListRequest model as parameters
 // return a GetSubscribingEmailListResponse model and an error type
-func (svc *service) GetSubscribingEmailListByEmail(request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+func (svc *service) GetSubscribingEmailListByEmail(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
 	response := models.GetSubscribingEmailListResponse{Success: false}
 	if request == (models.GetSubscribingEmailListRequest{}) {
-		return models.GetSubscribingEmailListResponse{}, errors.New("invalid request")
+		return models.GetSubscribingEmailListResponse{}, apperrors.InvalidRequest("invalid_request", "invalid request")
 	}
-	relationship, err := svc.repository.GetSubscribingEmailListByEmail(request)
+	relationship, err := svc.repository.GetSubscribingEmailListByEmail(ctx, request)
 	if err != nil {
 		return models.GetSubscribingEmailListResponse{}, err
 	} else {
@@ -142,3 +281,346 @@ func (svc *service) GetSubscribingEmailListByEmail(request models.GetSubscribing
 
 	return response, nil
 }
+
+// StreamRecipients function works as a service function for passing the recipients of an update to a callback one at a time,
+// in the order of GetSubscribingEmailListByEmail, without loading them in memory, e.g. to stream them or to fan the update out
+// the callback stops the stream by returning an error
+// pass a context, a GetSubscribingEmailListRequest model and a callback as parameters
+// return an error type, the error of the callback when it fails
+func (svc *service) StreamRecipients(ctx context.Context, request models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	if request == (models.GetSubscribingEmailListRequest{}) {
+		return apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	return svc.repository.StreamRecipients(ctx, request, fn)
+}
+
+// PostUpdate function works as a service function for posting an update and getting its recipients,
+// like GetSubscribingEmailListByEmail, which only reads them; when the outbox is enabled, the UpdatePosted event of the update
+// is written first, as a standalone event since posting an update stores nothing else, so the post fails when its event cannot be written
+// pass a context and a GetSubscribingEmailListRequest model as parameters
+// return a GetSubscribingEmailListResponse model and an error type
+func (svc *service) PostUpdate(ctx context.Context, request models.GetSubscribingEmailListRequest) (models.GetSubscribingEmailListResponse, error) {
+	if request == (models.GetSubscribingEmailListRequest{}) {
+		return models.GetSubscribingEmailListResponse{}, apperrors.InvalidRequest("invalid_request", "invalid request")
+	}
+	if svc.outbox != nil {
+		if err := pkg.CheckValidEmail(request.Sender); err != nil {
+			return models.GetSubscribingEmailListResponse{}, err
+		}
+		if err := svc.outbox.Append(ctx, models.EventUpdatePosted, request.Sender, models.UpdatePostedPayload{Sender: request.Sender, Text: request.Text}); err != nil {
+			return models.GetSubscribingEmailListResponse{}, err
+		}
+	}
+	return svc.GetSubscribingEmailListByEmail(ctx, request)
+}
+
+// GetUserRelationships function works as a service function for getting the friends, subscribers, subscriptions and blocked users
+// of many email addresses at once, with a single repository query
+// every requested email has an entry in the result, even when it has no relationship,
+// the friends are left empty when the privacy settings hide them from the caller
+// pass a context and an array of emails as parameters
+// return a map of UserRelationships model keyed by email and an error type
+func (svc *service) GetUserRelationships(ctx context.Context, emails []string) (map[string]models.UserRelationships, error) {
+	emails = pkg.RemoveDuplicatedItems(emails)
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return nil, err
+	}
+
+	relationships, err := svc.repository.FindRelationshipsByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]models.UserRelationships, len(emails))
+	for _, email := range emails {
+		result[email] = models.UserRelationships{Email: email, Friends: []string{}, Subscribers: []string{}, Subscriptions: []string{}, Blocked: []string{}}
+	}
+	for _, relationship := range relationships {
+		if requestor, ok := result[relationship.Requestor]; ok {
+			if relationship.IsFriend && !relationship.FriendBlocked {
+				requestor.Friends = append(requestor.Friends, relationship.Target)
+			}
+			if relationship.Subscribed && !relationship.SubscribeBlock {
+				requestor.Subscriptions = append(requestor.Subscriptions, relationship.Target)
+			}
+			if relationship.SubscribeBlock {
+				requestor.Blocked = append(requestor.Blocked, relationship.Target)
+			}
+			result[relationship.Requestor] = requestor
+		}
+		if target, ok := result[relationship.Target]; ok {
+			if relationship.IsFriend && !relationship.FriendBlocked {
+				target.Friends = append(target.Friends, relationship.Requestor)
+			}
+			if relationship.Subscribed && !relationship.SubscribeBlock {
+				target.Subscribers = append(target.Subscribers, relationship.Requestor)
+			}
+			result[relationship.Target] = target
+		}
+	}
+
+	var visible map[string]bool
+	viewer, restricted := restrictedViewer(ctx)
+	if restricted {
+		if visible, err = svc.privacy.visibleFriendLists(ctx, viewer, emails); err != nil {
+			return nil, err
+		}
+	}
+	for email, user := range result {
+		if restricted && !visible[email] {
+			user.Friends = nil
+		}
+		user.Friends = sortedUnique(user.Friends)
+		user.Subscribers = sortedUnique(user.Subscribers)
+		user.Subscriptions = sortedUnique(user.Subscriptions)
+		user.Blocked = sortedUnique(user.Blocked)
+		result[email] = user
+	}
+	return result, nil
+}
+
+// GetFriendListsByEmails function works as a service function for getting the friend lists of many email addresses at once,
+// with a single repository query instead of one per email
+// the emails which are malformed, unregistered or whose friend list is hidden from the caller are reported in Errors
+// pass a context and a BatchEmailsRequest model as parameters
+// return a BatchFriendListResponse model and an error type when the whole batch is rejected
+func (svc *service) GetFriendListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchFriendListResponse, error) {
+	emails, errs, err := svc.checkBatchEmails(ctx, request.Emails)
+	if err != nil {
+		return models.BatchFriendListResponse{}, err
+	}
+	if viewer, restricted := restrictedViewer(ctx); restricted && len(emails) > 0 {
+		visible, err := svc.privacy.visibleFriendLists(ctx, viewer, emails)
+		if err != nil {
+			return models.BatchFriendListResponse{}, err
+		}
+		var shown []string
+		for _, email := range emails {
+			if !visible[email] {
+				errs[email] = NewBatchItemError(ErrFriendListHidden)
+				continue
+			}
+			shown = append(shown, email)
+		}
+		emails = shown
+	}
+
+	relationships, err := svc.repository.FindFriendsByEmails(ctx, emails)
+	if err != nil {
+		return models.BatchFriendListResponse{}, err
+	}
+
+	friends := make(map[string][]string, len(emails))
+	for _, email := range emails {
+		friends[email] = []string{}
+	}
+	for _, relationship := range relationships {
+		if list, ok := friends[relationship.Requestor]; ok {
+			friends[relationship.Requestor] = append(list, relationship.Target)
+		}
+	}
+	results := make(map[string]models.BatchFriendList, len(friends))
+	for email, list := range friends {
+		list = sortedUnique(list)
+		results[email] = models.BatchFriendList{Friends: list, Count: len(list)}
+	}
+	return models.BatchFriendListResponse{Success: true, Results: results, Errors: errs}, nil
+}
+
+// GetSubscriberListsByEmails function works as a service function for getting the subscriber lists of many email addresses at once,
+// with a single repository query instead of one per email, the blocked subscribers are left out
+// the emails which are malformed or unregistered are reported in Errors
+// pass a context and a BatchEmailsRequest model as parameters
+// return a BatchSubscriberListResponse model and an error type when the whole batch is rejected
+func (svc *service) GetSubscriberListsByEmails(ctx context.Context, request models.BatchEmailsRequest) (models.BatchSubscriberListResponse, error) {
+	emails, errs, err := svc.checkBatchEmails(ctx, request.Emails)
+	if err != nil {
+		return models.BatchSubscriberListResponse{}, err
+	}
+
+	relationships, err := svc.repository.FindSubscribersByEmails(ctx, emails)
+	if err != nil {
+		return models.BatchSubscriberListResponse{}, err
+	}
+
+	subscribers := make(map[string][]string, len(emails))
+	for _, email := range emails {
+		subscribers[email] = []string{}
+	}
+	for _, relationship := range relationships {
+		if list, ok := subscribers[relationship.Target]; ok {
+			subscribers[relationship.Target] = append(list, relationship.Requestor)
+		}
+	}
+	results := make(map[string]models.BatchSubscriberList, len(subscribers))
+	for email, list := range subscribers {
+		list = sortedUnique(list)
+		results[email] = models.BatchSubscriberList{Subscribers: list, Count: len(list)}
+	}
+	return models.BatchSubscriberListResponse{Success: true, Results: results, Errors: errs}, nil
+}
+
+// GetPrivacySettings function works as a service function for getting the privacy settings of a user
+// pass a context and an email as parameters
+// return a PrivacySettings model, the default settings when the user never changed them, and an error type
+func (svc *service) GetPrivacySettings(ctx context.Context, email string) (models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmail(email); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	unregistered, err := svc.repository.FindUnregisteredEmails(ctx, []string{email})
+	if err != nil {
+		return models.PrivacySettings{}, err
+	}
+	if len(unregistered) > 0 {
+		return models.PrivacySettings{}, apperrors.NotFound("user_not_found", "unregistered email address: "+email).WithDetail("emails", unregistered)
+	}
+
+	settings, err := svc.privacy.settings(ctx, []string{email})
+	if err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return settings[email], nil
+}
+
+// UpdatePrivacySettings function works as a service function for changing the privacy settings of a user, the empty fields are left unchanged
+// pass a context, an email and an UpdatingPrivacySettingsRequest model as parameters
+// return the stored PrivacySettings model and an error type
+func (svc *service) UpdatePrivacySettings(ctx context.Context, email string, request models.UpdatingPrivacySettingsRequest) (models.PrivacySettings, error) {
+	if request == (models.UpdatingPrivacySettingsRequest{}) {
+		return models.PrivacySettings{}, apperrors.InvalidRequest("invalid_request", "invalid request, no setting to update")
+	}
+	if request.FriendListVisibility != "" && !isValidFriendListVisibility(request.FriendListVisibility) {
+		return models.PrivacySettings{}, apperrors.Validation("invalid_friend_list_visibility", "friend_list_visibility must be public, friends or private").
+			WithDetail("friend_list_visibility", request.FriendListVisibility)
+	}
+	if request.SubscriptionPolicy != "" && !isValidSubscriptionPolicy(request.SubscriptionPolicy) {
+		return models.PrivacySettings{}, apperrors.Validation("invalid_subscription_policy", "subscription_policy must be everyone, friends_of_friends or nobody").
+			WithDetail("subscription_policy", request.SubscriptionPolicy)
+	}
+
+	settings, err := svc.GetPrivacySettings(ctx, email)
+	if err != nil {
+		return models.PrivacySettings{}, err
+	}
+	if request.FriendListVisibility != "" {
+		settings.FriendListVisibility = request.FriendListVisibility
+	}
+	if request.SubscriptionPolicy != "" {
+		settings.SubscriptionPolicy = request.SubscriptionPolicy
+	}
+	return svc.repository.SavePrivacySettings(ctx, settings)
+}
+
+// visibleCommonFriends function used to hide the common friends of two users whose friendships are not visible to the viewer
+func (svc *service) visibleCommonFriends(ctx context.Context, viewer string, users, commonFriends []string) ([]string, error) {
+	visible, err := svc.privacy.visibleFriendLists(ctx, viewer, pkg.RemoveDuplicatedItems(append(append([]string{}, users...), commonFriends...)))
+	if err != nil {
+		return nil, err
+	}
+
+	var friends []string
+	for _, friend := range commonFriends {
+		shown := true
+		for _, user := range users {
+			if !visible[user] && !visible[friend] {
+				shown = false
+			}
+		}
+		if shown {
+			friends = append(friends, friend)
+		}
+	}
+	return friends, nil
+}
+
+// checkBatchEmails function used to validate the emails of a batch read,
+// the whole batch is rejected when it is empty or larger than MaxBatchEmails,
+// otherwise the malformed and unregistered emails are reported one by one and left out of the returned emails
+func (svc *service) checkBatchEmails(ctx context.Context, emails []string) ([]string, map[string]models.BatchItemError, error) {
+	emails = pkg.RemoveDuplicatedItems(emails)
+	if len(emails) == 0 {
+		return nil, nil, apperrors.InvalidRequest("invalid_request", "invalid request, the emails list must not be empty")
+	}
+	if len(emails) > MaxBatchEmails {
+		return nil, nil, apperrors.Validation("too_many_emails", "a batch accepts at most "+strconv.Itoa(MaxBatchEmails)+" emails").
+			WithDetail("max", MaxBatchEmails)
+	}
+
+	errs := make(map[string]models.BatchItemError)
+	var valid []string
+	for _, email := range emails {
+		if err := pkg.CheckValidEmail(email); err != nil {
+			errs[email] = NewBatchItemError(err)
+			continue
+		}
+		valid = append(valid, email)
+	}
+	if len(valid) == 0 {
+		return nil, errs, nil
+	}
+
+	unregistered, err := svc.repository.FindUnregisteredEmails(ctx, valid)
+	if err != nil {
+		return nil, nil, err
+	}
+	missing := make(map[string]bool, len(unregistered))
+	for _, email := range unregistered {
+		missing[email] = true
+		errs[email] = NewBatchItemError(apperrors.NotFound("user_not_found", "unregistered email address: "+email))
+	}
+	var registered []string
+	for _, email := range valid {
+		if !missing[email] {
+			registered = append(registered, email)
+		}
+	}
+	return registered, errs, nil
+}
+
+// NewBatchItemError function used to report the error of one email of a batch read
+// pass an error as parameter
+// return a BatchItemError model carrying the code and the client-safe message of the error
+func NewBatchItemError(err error) models.BatchItemError {
+	appErr := apperrors.From(err)
+	return models.BatchItemError{Code: appErr.Code, Error: appErr.Message}
+}
+
+func sortedUnique(emails []string) []string {
+	emails = pkg.RemoveDuplicatedItems(emails)
+	sort.Strings(emails)
+	return emails
+}
+
+// errSelfRelation function used to reject a relationship of a user with itself, for the APIs and the imports alike
+// pass the email of the user as parameter
+// return an error type
+func errSelfRelation(email string) error {
+	return apperrors.Validation("self_relation", "a user cannot create a relationship with themselves").WithDetail("emails", []string{email})
+}
+
+// checkRelationshipUsers function used to validate both sides of a relationship before it is written:
+// the emails must be well-formed, must be different users and must be registered
+// in autoCreateUsers mode the unregistered emails are created instead of being reported
+// pass a context, a requestor email and a target email as parameters
+// return an error type
+func (svc *service) checkRelationshipUsers(ctx context.Context, requestor, target string) error {
+	if err := pkg.CheckValidEmails([]string{requestor, target}); err != nil {
+		return err
+	}
+	if strings.EqualFold(strings.TrimSpace(requestor), strings.TrimSpace(target)) {
+		return errSelfRelation(requestor)
+	}
+
+	unregistered, err := svc.repository.FindUnregisteredEmails(ctx, []string{requestor, target})
+	if err != nil {
+		return err
+	}
+	if len(unregistered) == 0 {
+		return nil
+	}
+	if svc.autoCreateUsers {
+		return svc.repository.CreateUsersIfNotExist(ctx, unregistered)
+	}
+
+	return apperrors.NotFound("user_not_found", "unregistered email address: "+strings.Join(unregistered, ", ")).WithDetail("emails", unregistered)
+}
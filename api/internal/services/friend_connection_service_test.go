@@ -1,19 +1,24 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
 	"golang_project/api/internal/models"
 	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/repositories"
 )
 
 func TestCreateUserSuccessfulCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.CreateUser(models.CreatingUserRequest{Email: "hao.nguyen@s3corp.com.vn"})
+	result, err := myService.CreateUser(context.Background(), models.CreatingUserRequest{Email: "hao.nguyen@s3corp.com.vn"})
 	expectedRs := models.CreatingUserResponse{Success: true}
 	assert.Equal(t, expectedRs, result)
 	assert.Equal(t, nil, err)
@@ -22,23 +27,23 @@ func TestCreateUserSuccessfulCase(t *testing.T) {
 func TestCreateUserInvalidEmailCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.CreateUser(models.CreatingUserRequest{Email: "hao.nguyen"})
+	result, err := myService.CreateUser(context.Background(), models.CreatingUserRequest{Email: "hao.nguyen"})
 	assert.Equal(t, models.CreatingUserResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestCreateUserNilCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.CreateUser(models.CreatingUserRequest{})
+	result, err := myService.CreateUser(context.Background(), models.CreatingUserRequest{})
 	assert.Equal(t, models.CreatingUserResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestFriendConnectionSuccessfulCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.CreateConnection(models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	result, err := myService.CreateConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
 	expectedRs := models.FriendConnectionResponse{Success: true}
 	assert.Equal(t, expectedRs, result)
 	assert.Equal(t, nil, err)
@@ -47,10 +52,54 @@ func TestFriendConnectionSuccessfulCase(t *testing.T) {
 func TestFriendConnectionFailCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.CreateConnection(models.FriendConnectionRequest{Friends: []string{}})
+	result, err := myService.CreateConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{}})
 	expectedRs := models.FriendConnectionResponse{Success: false}
 	assert.Equal(t, expectedRs, result)
-	assert.Equal(t, errors.New("email address is empty"), err)
+	assert.Equal(t, pkg.ErrEmptyEmail, err)
+}
+
+func TestFriendConnectionWithSelfRelation(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.CreateConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "TheHaoHcm@yahoo.com.vn"}})
+	assert.Equal(t, models.FriendConnectionResponse{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+	assert.Equal(t, "self_relation", apperrors.From(err).Code)
+}
+
+func TestFriendConnectionWithUnregisteredEmails(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.CreateConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"unregistered1@example.com", "unregistered2@example.com"}})
+	assert.Equal(t, models.FriendConnectionResponse{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Equal(t, []string{"unregistered1@example.com", "unregistered2@example.com"}, apperrors.From(err).Details["emails"])
+	assert.Empty(t, repoMock.createdUsers)
+}
+
+func TestFriendConnectionWithAutoCreateUsers(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock, WithAutoCreateUsers(true))
+	result, err := myService.CreateConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "unregistered@example.com"}})
+	assert.Equal(t, models.FriendConnectionResponse{Success: true}, result)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"unregistered@example.com"}, repoMock.createdUsers)
+}
+
+func TestRemoveConnectionSuccessfulCase(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.RemoveConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Equal(t, models.FriendConnectionResponse{Success: true}, result)
+	assert.Nil(t, err)
+}
+
+func TestRemoveConnectionWithInvalidEmail(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.RemoveConnection(context.Background(), models.FriendConnectionRequest{Friends: []string{"thehaohcm", "hao.nguyen@s3corp.com.vn"}})
+	assert.Equal(t, models.FriendConnectionResponse{}, result)
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
 }
 
 func TestShowFriendsByEmailSuccessfulCase(t *testing.T) {
@@ -61,7 +110,7 @@ func TestShowFriendsByEmailSuccessfulCase(t *testing.T) {
 		Email: "thehaohcm@yahoo.com.vn",
 	}
 
-	response, err := myService.GetFriendConnection(request)
+	response, err := myService.GetFriendConnection(context.Background(), request)
 
 	exp := models.FriendListResponse{
 		Success: true,
@@ -77,9 +126,9 @@ func TestShowFriendsByEmailSuccessfulCase(t *testing.T) {
 func TestShowFriendsByEmailEmptyModel(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.GetFriendConnection(models.FriendListRequest{})
+	result, err := myService.GetFriendConnection(context.Background(), models.FriendListRequest{})
 	assert.Equal(t, models.FriendListResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestShowFriendsByEmailWithEmptyResponse(t *testing.T) {
@@ -90,7 +139,7 @@ func TestShowFriendsByEmailWithEmptyResponse(t *testing.T) {
 		Email: "test@test.com",
 	}
 
-	response, err := myService.GetFriendConnection(request)
+	response, err := myService.GetFriendConnection(context.Background(), request)
 
 	exp := models.FriendListResponse{
 		Success: false,
@@ -109,7 +158,7 @@ func TestShowCommonFriendListSuccessfulCase(t *testing.T) {
 		Friends: []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"},
 	}
 
-	response, err := myService.ShowCommonFriendList(request)
+	response, err := myService.ShowCommonFriendList(context.Background(), request)
 
 	exp := models.CommonFriendListResponse{
 		Success: true,
@@ -125,26 +174,26 @@ func TestShowCommonFriendListSuccessfulCase(t *testing.T) {
 func TestShowCommonFriendListWithInvalidEmail(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.ShowCommonFriendList(models.CommonFriendListRequest{Friends: []string{"hao.nguyen"}})
+	result, err := myService.ShowCommonFriendList(context.Background(), models.CommonFriendListRequest{Friends: []string{"hao.nguyen"}})
 	assert.Equal(t, models.CommonFriendListResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestShowCommonFriendListEmptyModel(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
 
-	response, err := myService.ShowCommonFriendList(models.CommonFriendListRequest{})
+	response, err := myService.ShowCommonFriendList(context.Background(), models.CommonFriendListRequest{})
 
 	exp := models.CommonFriendListResponse{}
 	assert.Equal(t, exp, response)
-	assert.Equal(t, errors.New("email address is empty"), err)
+	assert.Equal(t, pkg.ErrEmptyEmail, err)
 }
 
 func TestSubscribeFromEmailSuccessfulCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
 	expectedRs := models.SubscribeResponse{Success: true}
 	assert.Equal(t, expectedRs, result)
 	assert.Equal(t, nil, err)
@@ -153,34 +202,75 @@ func TestSubscribeFromEmailSuccessfulCase(t *testing.T) {
 func TestSubscribeFromEmailFailCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.SubscribeFromEmail(models.SubscribeRequest{})
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{})
 	expectedRs := models.SubscribeResponse{Success: false}
 	assert.Equal(t, expectedRs, result)
-	assert.Equal(t, nil, err)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
 }
 
 func TestSubscribeFromEmailWithEmptyRequestor(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.SubscribeFromEmail(models.SubscribeRequest{Target: "hao.nguyen@s3corp.com.vn"})
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Target: "hao.nguyen@s3corp.com.vn"})
 	expectedRs := models.SubscribeResponse{Success: false}
 	assert.Equal(t, expectedRs, result)
-	assert.Equal(t, nil, err)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
 }
 
 func TestSubscribeFromEmailWithEmptyTarget(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.SubscribeFromEmail(models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn"})
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn"})
 	expectedRs := models.SubscribeResponse{Success: false}
 	assert.Equal(t, expectedRs, result)
-	assert.Equal(t, nil, err)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+}
+
+func TestSubscribeFromEmailWithSelfRelation(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, models.SubscribeResponse{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+}
+
+func TestSubscribeFromEmailWithUnregisteredTarget(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "unregistered@example.com"})
+	assert.Equal(t, models.SubscribeResponse{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Equal(t, "unregistered email address: unregistered@example.com", err.Error())
+}
+
+func TestBlockSubscribeByEmailWithSelfRelation(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Equal(t, models.BlockSubscribeResponse{}, result)
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+}
+
+func TestUnsubscribeFromEmailSuccessfulCase(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.UnsubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Equal(t, models.SubscribeResponse{Success: true}, result)
+	assert.Nil(t, err)
+}
+
+func TestUnblockSubscribeByEmailSuccessfulCase(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+	result, err := myService.UnblockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Equal(t, models.BlockSubscribeResponse{Success: true}, result)
+	assert.Nil(t, err)
 }
 
 func TestBlockSubscribeByEmailSuccessfulCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	result, err := myService.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
 	expectedRs := models.BlockSubscribeResponse{Success: true}
 	assert.Equal(t, expectedRs, result)
 	assert.Equal(t, nil, err)
@@ -189,25 +279,25 @@ func TestBlockSubscribeByEmailSuccessfulCase(t *testing.T) {
 func TestBlockSubscribeByEmailFailCase(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.BlockSubscribeByEmail(models.BlockSubscribeRequest{})
+	result, err := myService.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{})
 	assert.Equal(t, models.BlockSubscribeResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestBlockSubscribeByEmailWithEmptyTarget(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.BlockSubscribeByEmail(models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn"})
+	result, err := myService.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Requestor: "thehaohcm@yahoo.com.vn"})
 	assert.Equal(t, models.BlockSubscribeResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestBlockSubscribeByEmailWithEmptyRequestor(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.BlockSubscribeByEmail(models.BlockSubscribeRequest{Target: "thehaohcm@yahoo.com.vn"})
+	result, err := myService.BlockSubscribeByEmail(context.Background(), models.BlockSubscribeRequest{Target: "thehaohcm@yahoo.com.vn"})
 	assert.Equal(t, models.BlockSubscribeResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestGetSubscribingEmailListWithEmailSuccessfulCase(t *testing.T) {
@@ -219,7 +309,7 @@ func TestGetSubscribingEmailListWithEmailSuccessfulCase(t *testing.T) {
 		Text:   "helloworld! kate@example.com",
 	}
 
-	response, err := myService.GetSubscribingEmailListByEmail(model)
+	response, err := myService.GetSubscribingEmailListByEmail(context.Background(), model)
 
 	exp := models.GetSubscribingEmailListResponse{
 		Success: true,
@@ -241,7 +331,7 @@ func TestGetSubscribingEmailListWithEmailFailCase(t *testing.T) {
 		Text:   "helloworld!",
 	}
 
-	response, err := myService.GetSubscribingEmailListByEmail(model)
+	response, err := myService.GetSubscribingEmailListByEmail(context.Background(), model)
 
 	exp := models.GetSubscribingEmailListResponse{
 		Success:    true,
@@ -255,62 +345,368 @@ func TestGetSubscribingEmailListWithEmailEmptyModel(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
 
-	response, err := myService.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{})
+	response, err := myService.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{})
 
 	exp := models.GetSubscribingEmailListResponse{}
 	assert.Equal(t, exp, response)
-	assert.Equal(t, errors.New("invalid request"), err)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
 }
 
 func TestGetSubscribingEmailListWithInvalidEmail(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "thehaohcm", Text: "abc"})
+	result, err := myService.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm", Text: "abc"})
 	assert.Equal(t, models.GetSubscribingEmailListResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestGetSubscribingEmailListWithNilSender(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
-	result, err := myService.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Text: "abc"})
+	result, err := myService.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Text: "abc"})
 	assert.Equal(t, models.GetSubscribingEmailListResponse{}, result)
-	assert.IsType(t, errors.New(""), err)
+	assert.IsType(t, &apperrors.Error{}, err)
 }
 
 func TestGetSubscribingEmailListWithEmptyReponse(t *testing.T) {
 	repoMock := &FriendConnectionRepoMock{}
 	myService := New(repoMock)
 
-	response, err := myService.GetSubscribingEmailListByEmail(models.GetSubscribingEmailListRequest{Sender: "hung.tong@s3corp.com.vn", Text: "abc"})
+	response, err := myService.GetSubscribingEmailListByEmail(context.Background(), models.GetSubscribingEmailListRequest{Sender: "hung.tong@s3corp.com.vn", Text: "abc"})
 	expRs := models.GetSubscribingEmailListResponse{Success: true, Recipients: nil}
 	assert.Equal(t, expRs, response)
 	assert.Equal(t, nil, err)
 }
 
+func TestGetUserRelationshipsWithSuccessfulCase(t *testing.T) {
+	repoMock := new(FriendConnectionRepoMock)
+	myService := New(repoMock)
+
+	result, err := myService.GetUserRelationships(context.Background(), []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", "son.le@s3corp.com.vn", "thehaohcm@yahoo.com.vn"})
+
+	assert.Nil(t, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, models.UserRelationships{
+		Email:         "thehaohcm@yahoo.com.vn",
+		Friends:       []string{"hao.nguyen@s3corp.com.vn"},
+		Subscribers:   []string{"kate@example.com"},
+		Subscriptions: []string{"son.le@s3corp.com.vn"},
+		Blocked:       []string{"hung.tong@s3corp.com.vn"},
+	}, result["thehaohcm@yahoo.com.vn"])
+	assert.Equal(t, []string{"thehaohcm@yahoo.com.vn"}, result["hao.nguyen@s3corp.com.vn"].Friends)
+	assert.Equal(t, []string{"thehaohcm@yahoo.com.vn"}, result["son.le@s3corp.com.vn"].Subscribers)
+}
+
+func TestGetUserRelationshipsWithInvalidEmail(t *testing.T) {
+	repoMock := new(FriendConnectionRepoMock)
+	myService := New(repoMock)
+
+	_, err := myService.GetUserRelationships(context.Background(), []string{"thehaohcm"})
+	assert.Equal(t, pkg.ErrInvalidEmail, err)
+}
+
+func TestGetFriendConnectionFollowsPrivacySettings(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{
+		friends: map[string][]string{"hao.nguyen@s3corp.com.vn": {"thehaohcm@yahoo.com.vn"}, "thehaohcm@yahoo.com.vn": {"hao.nguyen@s3corp.com.vn"}},
+		privacySettings: map[string]models.PrivacySettings{
+			"hao.nguyen@s3corp.com.vn":   {Email: "hao.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListFriends, SubscriptionPolicy: models.SubscriptionEveryone},
+			"chinh.nguyen@s3corp.com.vn": {Email: "chinh.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListPrivate, SubscriptionPolicy: models.SubscriptionEveryone},
+		},
+	}
+	myService := New(repoMock)
+
+	friend := userContext("thehaohcm@yahoo.com.vn")
+	stranger := userContext("son.le@s3corp.com.vn")
+	result, err := myService.GetFriendConnection(friend, models.FriendListRequest{Email: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thehaohcm@yahoo.com.vn"}, result.Friends)
+
+	_, err = myService.GetFriendConnection(stranger, models.FriendListRequest{Email: "hao.nguyen@s3corp.com.vn"})
+	assert.ErrorIs(t, err, ErrFriendListHidden)
+	_, err = myService.GetFriendConnection(friend, models.FriendListRequest{Email: "chinh.nguyen@s3corp.com.vn"})
+	assert.ErrorIs(t, err, ErrFriendListHidden)
+
+	_, err = myService.GetFriendConnection(context.Background(), models.FriendListRequest{Email: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "key-1", Roles: []string{auth.RoleAdmin}})
+	_, err = myService.GetFriendConnection(admin, models.FriendListRequest{Email: "chinh.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+}
+
+func TestShowCommonFriendListHidesPrivateFriendships(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{
+		privacySettings: map[string]models.PrivacySettings{
+			"thehaohcm@yahoo.com.vn":   {Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: models.FriendListPrivate},
+			"hao.nguyen@s3corp.com.vn": {Email: "hao.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListPrivate},
+		},
+	}
+	myService := New(repoMock)
+	request := models.CommonFriendListRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"}}
+
+	result, err := myService.ShowCommonFriendList(userContext("son.le@s3corp.com.vn"), request)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Friends)
+	assert.Equal(t, 0, result.Count)
+
+	result, err = myService.ShowCommonFriendList(userContext("thehaohcm@yahoo.com.vn"), request)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn"}, result.Friends)
+}
+
+func TestSubscribeFromEmailFollowsSubscriptionPolicy(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{
+		friends: map[string][]string{
+			"hao.nguyen@s3corp.com.vn":   {"chinh.nguyen@s3corp.com.vn"},
+			"son.le@s3corp.com.vn":       {"chinh.nguyen@s3corp.com.vn"},
+			"hung.tong@s3corp.com.vn":    {},
+			"chinh.nguyen@s3corp.com.vn": {"hao.nguyen@s3corp.com.vn", "son.le@s3corp.com.vn"},
+		},
+		privacySettings: map[string]models.PrivacySettings{
+			"hao.nguyen@s3corp.com.vn":   {Email: "hao.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListPublic, SubscriptionPolicy: models.SubscriptionFriendsOfFriends},
+			"chinh.nguyen@s3corp.com.vn": {Email: "chinh.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListPublic, SubscriptionPolicy: models.SubscriptionNobody},
+		},
+	}
+	myService := New(repoMock)
+
+	_, err := myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "son.le@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "chinh.nguyen@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	_, err = myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "hung.tong@s3corp.com.vn", Target: "hao.nguyen@s3corp.com.vn"})
+	assert.ErrorIs(t, err, ErrSubscriptionNotAllowed)
+	_, err = myService.SubscribeFromEmail(context.Background(), models.SubscribeRequest{Requestor: "hao.nguyen@s3corp.com.vn", Target: "chinh.nguyen@s3corp.com.vn"})
+	assert.ErrorIs(t, err, ErrSubscriptionNotAllowed)
+}
+
+func TestUpdatePrivacySettings(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{}
+	myService := New(repoMock)
+
+	result, err := myService.UpdatePrivacySettings(context.Background(), "thehaohcm@yahoo.com.vn", models.UpdatingPrivacySettingsRequest{SubscriptionPolicy: models.SubscriptionNobody})
+	assert.Nil(t, err)
+	assert.Equal(t, models.PrivacySettings{Email: "thehaohcm@yahoo.com.vn", FriendListVisibility: models.FriendListPublic, SubscriptionPolicy: models.SubscriptionNobody}, result)
+
+	result, err = myService.GetPrivacySettings(context.Background(), "thehaohcm@yahoo.com.vn")
+	assert.Nil(t, err)
+	assert.Equal(t, models.SubscriptionNobody, result.SubscriptionPolicy)
+
+	_, err = myService.UpdatePrivacySettings(context.Background(), "thehaohcm@yahoo.com.vn", models.UpdatingPrivacySettingsRequest{FriendListVisibility: "everyone"})
+	assert.Equal(t, "invalid_friend_list_visibility", apperrors.From(err).Code)
+	_, err = myService.UpdatePrivacySettings(context.Background(), "thehaohcm@yahoo.com.vn", models.UpdatingPrivacySettingsRequest{})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+	_, err = myService.GetPrivacySettings(context.Background(), "unregistered@example.com")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestGetFriendListsByEmailsReportsPartialErrors(t *testing.T) {
+	repoMock := &FriendConnectionRepoMock{
+		friends: map[string][]string{
+			"hao.nguyen@s3corp.com.vn":   {"thehaohcm@yahoo.com.vn", "chinh.nguyen@s3corp.com.vn"},
+			"chinh.nguyen@s3corp.com.vn": {"hao.nguyen@s3corp.com.vn"},
+			"son.le@s3corp.com.vn":       {},
+		},
+		privacySettings: map[string]models.PrivacySettings{
+			"chinh.nguyen@s3corp.com.vn": {Email: "chinh.nguyen@s3corp.com.vn", FriendListVisibility: models.FriendListPrivate},
+		},
+	}
+	myService := New(repoMock)
+
+	result, err := myService.GetFriendListsByEmails(userContext("thehaohcm@yahoo.com.vn"), models.BatchEmailsRequest{Emails: []string{
+		"hao.nguyen@s3corp.com.vn", "chinh.nguyen@s3corp.com.vn", "son.le@s3corp.com.vn", "thehaohcm", "unregistered@example.com", "hao.nguyen@s3corp.com.vn",
+	}})
+
+	assert.Nil(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, map[string]models.BatchFriendList{
+		"hao.nguyen@s3corp.com.vn": {Friends: []string{"chinh.nguyen@s3corp.com.vn", "thehaohcm@yahoo.com.vn"}, Count: 2},
+		"son.le@s3corp.com.vn":     {Friends: []string{}, Count: 0},
+	}, result.Results)
+	assert.Equal(t, map[string]models.BatchItemError{
+		"chinh.nguyen@s3corp.com.vn": {Code: "friend_list_hidden", Error: ErrFriendListHidden.Message},
+		"thehaohcm":                  {Code: "invalid_email", Error: "invalid email address"},
+		"unregistered@example.com":   {Code: "user_not_found", Error: "unregistered email address: unregistered@example.com"},
+	}, result.Errors)
+	assert.Equal(t, [][]string{{"hao.nguyen@s3corp.com.vn", "son.le@s3corp.com.vn"}}, repoMock.batchQueries)
+}
+
+func TestGetFriendListsByEmailsRejectsInvalidBatches(t *testing.T) {
+	repoMock := new(FriendConnectionRepoMock)
+	myService := New(repoMock)
+
+	_, err := myService.GetFriendListsByEmails(context.Background(), models.BatchEmailsRequest{})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+
+	emails := make([]string, MaxBatchEmails+1)
+	for i := range emails {
+		emails[i] = "user" + strconv.Itoa(i) + "@example.com"
+	}
+	_, err = myService.GetFriendListsByEmails(context.Background(), models.BatchEmailsRequest{Emails: emails})
+	assert.Equal(t, "too_many_emails", apperrors.From(err).Code)
+	assert.Empty(t, repoMock.batchQueries)
+}
+
+func TestGetSubscriberListsByEmails(t *testing.T) {
+	repoMock := new(FriendConnectionRepoMock)
+	myService := New(repoMock)
+
+	result, err := myService.GetSubscriberListsByEmails(context.Background(), models.BatchEmailsRequest{Emails: []string{
+		"thehaohcm@yahoo.com.vn", "son.le@s3corp.com.vn", "hung.tong@s3corp.com.vn", "unregistered@example.com",
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]models.BatchSubscriberList{
+		"thehaohcm@yahoo.com.vn":  {Subscribers: []string{"kate@example.com"}, Count: 1},
+		"son.le@s3corp.com.vn":    {Subscribers: []string{"thehaohcm@yahoo.com.vn"}, Count: 1},
+		"hung.tong@s3corp.com.vn": {Subscribers: []string{}, Count: 0},
+	}, result.Results)
+	assert.Equal(t, "user_not_found", result.Errors["unregistered@example.com"].Code)
+	assert.Equal(t, [][]string{{"thehaohcm@yahoo.com.vn", "son.le@s3corp.com.vn", "hung.tong@s3corp.com.vn"}}, repoMock.batchQueries)
+}
+
+func TestStreamRecipients(t *testing.T) {
+	myService := New(&FriendConnectionRepoMock{})
+
+	var recipients []string
+	err := myService.StreamRecipients(context.Background(), models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "helloworld! kate@example.com"}, func(email string) error {
+		recipients = append(recipients, email)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "kate@example.com"}, recipients)
+
+	err = myService.StreamRecipients(context.Background(), models.GetSubscribingEmailListRequest{}, func(email string) error {
+		t.Fatal("no recipient expected")
+		return nil
+	})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestPostUpdateWritesAnUpdatePostedEvent(t *testing.T) {
+	outbox := &outboxRepoFake{}
+	myService := New(&FriendConnectionRepoMock{}, WithOutbox(outbox))
+	request := models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "helloworld! kate@example.com"}
+
+	response, err := myService.PostUpdate(context.Background(), request)
+	assert.Nil(t, err)
+	assert.True(t, response.Success)
+	assert.Equal(t, []models.UpdatePostedPayload{{Sender: request.Sender, Text: request.Text}}, outbox.updates)
+
+	// reading the recipients of an update does not post it
+	_, err = myService.GetSubscribingEmailListByEmail(context.Background(), request)
+	assert.Nil(t, err)
+	err = myService.StreamRecipients(context.Background(), request, func(email string) error { return nil })
+	assert.Nil(t, err)
+	assert.Len(t, outbox.updates, 1)
+
+	// the post fails when its event cannot be written, and an invalid update has no event
+	outbox.err = apperrors.New(apperrors.KindUnavailable, "database_unavailable", "the database is unavailable, retry later")
+	_, err = myService.PostUpdate(context.Background(), request)
+	assert.Equal(t, apperrors.KindUnavailable, apperrors.KindOf(err))
+	_, err = myService.PostUpdate(context.Background(), models.GetSubscribingEmailListRequest{Sender: "invalid"})
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+	assert.Len(t, outbox.updates, 1)
+}
+
+// outboxRepoFake records the UpdatePosted events, or fails with err
+type outboxRepoFake struct {
+	repositories.OutboxRepository
+	updates []models.UpdatePostedPayload
+	err     error
+}
+
+func (o *outboxRepoFake) Append(ctx context.Context, eventType, aggregateID string, payload interface{}) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.updates = append(o.updates, payload.(models.UpdatePostedPayload))
+	return nil
+}
+
+func TestServiceWithMemoryRepository(t *testing.T) {
+	myService := New(repositories.NewMemoryRepository())
+	ctx := context.Background()
+	for _, email := range []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn", "kate@example.com"} {
+		_, err := myService.CreateUser(ctx, models.CreatingUserRequest{Email: email})
+		assert.Nil(t, err)
+	}
+
+	_, err := myService.CreateConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"}})
+	assert.Nil(t, err)
+	_, err = myService.CreateConnection(ctx, models.FriendConnectionRequest{Friends: []string{"thehaohcm@yahoo.com.vn", "unregistered@example.com"}})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = myService.SubscribeFromEmail(ctx, models.SubscribeRequest{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+
+	friends, err := myService.GetFriendConnection(ctx, models.FriendListRequest{Email: "hao.nguyen@s3corp.com.vn"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thehaohcm@yahoo.com.vn"}, friends.Friends)
+	recipients, err := myService.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "Hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn", "kate@example.com"}, recipients.Recipients)
+
+	_, err = myService.BlockSubscribeByEmail(ctx, models.BlockSubscribeRequest{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn"})
+	assert.Nil(t, err)
+	recipients, err = myService.GetSubscribingEmailListByEmail(ctx, models.GetSubscribingEmailListRequest{Sender: "thehaohcm@yahoo.com.vn", Text: "Hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hao.nguyen@s3corp.com.vn"}, recipients.Recipients)
+}
+
+func userContext(email string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: email, Email: email, Roles: []string{auth.RoleUser}, Method: auth.MethodJWT})
+}
+
 type FriendConnectionRepoMock struct {
 	mock.Mock
+	createdUsers    []string
+	friends         map[string][]string
+	privacySettings map[string]models.PrivacySettings
+	batchQueries    [][]string
 }
 
-func (f *FriendConnectionRepoMock) CreateUser(request models.CreatingUserRequest) (models.User, error) {
+func (f *FriendConnectionRepoMock) CreateUser(ctx context.Context, request models.CreatingUserRequest) (models.User, error) {
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
 		return models.User{}, err
 	}
 	return models.User{Email: request.Email}, nil
 }
 
-func (f *FriendConnectionRepoMock) FindFriendsByEmail(request models.FriendListRequest) ([]models.Relationship, error) {
+func (f *FriendConnectionRepoMock) CreateUsersIfNotExist(ctx context.Context, emails []string) error {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return err
+	}
+	f.createdUsers = append(f.createdUsers, emails...)
+	return nil
+}
+
+func (f *FriendConnectionRepoMock) FindUnregisteredEmails(ctx context.Context, emails []string) ([]string, error) {
+	unregistered := []string{}
+	for _, email := range emails {
+		if strings.HasPrefix(email, "unregistered") {
+			unregistered = append(unregistered, email)
+		}
+	}
+	return unregistered, nil
+}
+
+func (f *FriendConnectionRepoMock) FindFriendsByEmail(ctx context.Context, request models.FriendListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmail(request.Email); err != nil {
 		return []models.Relationship{}, err
 	}
 
+	if friends, ok := f.friends[request.Email]; ok {
+		relationships := []models.Relationship{}
+		for _, friend := range friends {
+			relationships = append(relationships, models.Relationship{Requestor: request.Email, Target: friend})
+		}
+		return relationships, nil
+	}
 	if request.Email == "thehaohcm@yahoo.com.vn" {
 		return []models.Relationship{{Target: "hao.nguyen@s3corp.com.vn"}}, nil
 	}
 	return []models.Relationship{}, nil
 }
 
-func (f *FriendConnectionRepoMock) FindCommonFriendsByEmails(request models.CommonFriendListRequest) ([]models.Relationship, error) {
+func (f *FriendConnectionRepoMock) FindCommonFriendsByEmails(ctx context.Context, request models.CommonFriendListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return []models.Relationship{}, err
 	}
@@ -323,7 +719,7 @@ func (f *FriendConnectionRepoMock) FindCommonFriendsByEmails(request models.Comm
 	return []models.Relationship{}, nil
 }
 
-func (f *FriendConnectionRepoMock) CreateFriendConnection(request models.FriendConnectionRequest) (models.Relationship, error) {
+func (f *FriendConnectionRepoMock) CreateFriendConnection(ctx context.Context, request models.FriendConnectionRequest) (models.Relationship, error) {
 	if err := pkg.CheckValidEmails(request.Friends); err != nil {
 		return models.Relationship{}, err
 	}
@@ -333,14 +729,29 @@ func (f *FriendConnectionRepoMock) CreateFriendConnection(request models.FriendC
 	return models.Relationship{}, nil
 }
 
-func (f *FriendConnectionRepoMock) SubscribeFromEmail(req models.SubscribeRequest) (models.Relationship, error) {
+func (f *FriendConnectionRepoMock) RemoveFriendConnection(ctx context.Context, request models.FriendConnectionRequest) (models.Relationship, error) {
+	if err := pkg.CheckValidEmails(request.Friends); err != nil {
+		return models.Relationship{}, err
+	}
+	return models.Relationship{Requestor: request.Friends[0], Target: request.Friends[1]}, nil
+}
+
+func (f *FriendConnectionRepoMock) UnsubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+func (f *FriendConnectionRepoMock) UnblockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
+	return models.Relationship{Requestor: req.Requestor, Target: req.Target}, nil
+}
+
+func (f *FriendConnectionRepoMock) SubscribeFromEmail(ctx context.Context, req models.SubscribeRequest) (models.Relationship, error) {
 	if len(req.Requestor) > 0 && len(req.Target) > 0 {
 		return models.Relationship{Target: "hao.nguyen@s3corp.com.vn"}, nil
 	}
 	return models.Relationship{}, nil
 }
 
-func (f *FriendConnectionRepoMock) BlockSubscribeByEmail(req models.BlockSubscribeRequest) (models.Relationship, error) {
+func (f *FriendConnectionRepoMock) BlockSubscribeByEmail(ctx context.Context, req models.BlockSubscribeRequest) (models.Relationship, error) {
 	if err := pkg.CheckValidEmails([]string{req.Requestor, req.Target}); err != nil {
 		return models.Relationship{}, err
 	}
@@ -350,7 +761,7 @@ func (f *FriendConnectionRepoMock) BlockSubscribeByEmail(req models.BlockSubscri
 	return models.Relationship{}, nil
 }
 
-func (f *FriendConnectionRepoMock) GetSubscribingEmailListByEmail(req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
+func (f *FriendConnectionRepoMock) GetSubscribingEmailListByEmail(ctx context.Context, req models.GetSubscribingEmailListRequest) ([]models.Relationship, error) {
 	if err := pkg.CheckValidEmail(req.Sender); err != nil {
 		return []models.Relationship{}, err
 	}
@@ -359,3 +770,81 @@ func (f *FriendConnectionRepoMock) GetSubscribingEmailListByEmail(req models.Get
 	}
 	return []models.Relationship{}, nil
 }
+
+func (f *FriendConnectionRepoMock) StreamRecipients(ctx context.Context, req models.GetSubscribingEmailListRequest, fn func(email string) error) error {
+	recipients, err := f.GetSubscribingEmailListByEmail(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := fn(recipient.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FriendConnectionRepoMock) FindRelationshipsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.Relationship{}, err
+	}
+	return []models.Relationship{
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true},
+		{Requestor: "hao.nguyen@s3corp.com.vn", Target: "thehaohcm@yahoo.com.vn", IsFriend: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "chinh.nguyen@s3corp.com.vn", IsFriend: true, FriendBlocked: true},
+		{Requestor: "kate@example.com", Target: "thehaohcm@yahoo.com.vn", Subscribed: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "son.le@s3corp.com.vn", Subscribed: true},
+		{Requestor: "thehaohcm@yahoo.com.vn", Target: "hung.tong@s3corp.com.vn", SubscribeBlock: true},
+	}, nil
+}
+
+func (f *FriendConnectionRepoMock) FindFriendsByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	f.batchQueries = append(f.batchQueries, emails)
+	relationships := []models.Relationship{}
+	for _, email := range emails {
+		friends, err := f.FindFriendsByEmail(ctx, models.FriendListRequest{Email: email})
+		if err != nil {
+			return []models.Relationship{}, err
+		}
+		relationships = append(relationships, friends...)
+	}
+	return relationships, nil
+}
+
+func (f *FriendConnectionRepoMock) FindSubscribersByEmails(ctx context.Context, emails []string) ([]models.Relationship, error) {
+	f.batchQueries = append(f.batchQueries, emails)
+	all, err := f.FindRelationshipsByEmails(ctx, emails)
+	if err != nil {
+		return []models.Relationship{}, err
+	}
+	relationships := []models.Relationship{}
+	for _, relationship := range all {
+		for _, email := range emails {
+			if relationship.Target == email && relationship.Subscribed && !relationship.SubscribeBlock {
+				relationships = append(relationships, relationship)
+			}
+		}
+	}
+	return relationships, nil
+}
+
+func (f *FriendConnectionRepoMock) FindPrivacySettingsByEmails(ctx context.Context, emails []string) ([]models.PrivacySettings, error) {
+	if err := pkg.CheckValidEmails(emails); err != nil {
+		return []models.PrivacySettings{}, err
+	}
+	settings := []models.PrivacySettings{}
+	for _, email := range emails {
+		if setting, ok := f.privacySettings[email]; ok {
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+func (f *FriendConnectionRepoMock) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	if f.privacySettings == nil {
+		f.privacySettings = map[string]models.PrivacySettings{}
+	}
+	f.privacySettings[settings.Email] = settings
+	return settings, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/graphexport"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/repositories"
+)
+
+const (
+	// defaultEgoHops is the depth of an ego network when none is requested
+	defaultEgoHops = 1
+	// maxEgoHops bounds the depth of an ego network, further hops usually cover the whole graph anyway
+	maxEgoHops = 5
+)
+
+var domainRegex = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// GraphExportService interface declares the functions used to export the social graph for analysis tools
+type GraphExportService interface {
+	Export(ctx context.Context, w io.Writer, format string, filter models.GraphExportFilter) error
+}
+
+type graphExportService struct {
+	repository repositories.GraphExportRepository
+}
+
+// NewGraphExportService function used for initializing a GraphExportService
+// pass a GraphExportRepository as parameter
+// return a GraphExportService
+func NewGraphExportService(repo repositories.GraphExportRepository) GraphExportService {
+	return &graphExportService{
+		repository: repo,
+	}
+}
+
+// Export function works as a service function for streaming the users and relationships kept by a filter,
+// in GraphML, GEXF, DOT or edge list CSV format; each row of relationship table is a directed edge, so friendships appear in both directions
+// the options are validated before anything is written
+// pass a context, the io.Writer receiving the document, a format and a GraphExportFilter model as parameters
+// return an error type
+func (svc *graphExportService) Export(ctx context.Context, w io.Writer, format string, filter models.GraphExportFilter) error {
+	filter, err := normalizeGraphExportFilter(filter)
+	if err != nil {
+		return err
+	}
+	writer, err := graphexport.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.repository.StreamUsers(ctx, filter, writer.WriteNode); err != nil {
+		return err
+	}
+	if err := svc.repository.StreamRelationships(ctx, filter, writer.WriteEdge); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// normalizeGraphExportFilter function validates a filter and applies the default depth of an ego network
+func normalizeGraphExportFilter(filter models.GraphExportFilter) (models.GraphExportFilter, error) {
+	filter.Ego = strings.TrimSpace(filter.Ego)
+	filter.Domain = strings.TrimPrefix(strings.TrimSpace(filter.Domain), "@")
+	if filter.Ego == "" {
+		if filter.Hops != 0 {
+			return filter, apperrors.Validation("invalid_hops", "hops requires an ego email")
+		}
+	} else {
+		if err := pkg.CheckValidEmail(filter.Ego); err != nil {
+			return filter, err
+		}
+		if filter.Hops == 0 {
+			filter.Hops = defaultEgoHops
+		}
+		if filter.Hops < 0 || filter.Hops > maxEgoHops {
+			return filter, apperrors.Validation("invalid_hops", "hops must be between 1 and 5").WithDetail("hops", filter.Hops)
+		}
+	}
+	if filter.Domain != "" && !domainRegex.MatchString(filter.Domain) {
+		return filter, apperrors.Validation("invalid_domain", "invalid email domain").WithDetail("domain", filter.Domain)
+	}
+	return filter, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestExportGraphAppliesDefaultHops(t *testing.T) {
+	repo := &graphExportRepoFake{users: []string{"thehaohcm@yahoo.com.vn", "hao.nguyen@s3corp.com.vn"},
+		relationships: []models.Relationship{{Requestor: "thehaohcm@yahoo.com.vn", Target: "hao.nguyen@s3corp.com.vn", IsFriend: true}}}
+	var b strings.Builder
+
+	err := NewGraphExportService(repo).Export(context.Background(), &b, models.GraphFormatCSV, models.GraphExportFilter{Ego: " thehaohcm@yahoo.com.vn ", Domain: "@s3corp.com.vn"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, models.GraphExportFilter{Ego: "thehaohcm@yahoo.com.vn", Hops: 1, Domain: "s3corp.com.vn"}, repo.filter)
+	assert.Equal(t, "source,target,is_friend,friend_blocked,subscribed,subscribe_blocked\n"+
+		"thehaohcm@yahoo.com.vn,hao.nguyen@s3corp.com.vn,true,false,false,false\n", b.String())
+}
+
+func TestExportGraphWithInvalidOptions(t *testing.T) {
+	svc := NewGraphExportService(&graphExportRepoFake{})
+	var b strings.Builder
+
+	for code, filter := range map[string]models.GraphExportFilter{
+		"invalid_email":  {Ego: "thehaohcm"},
+		"invalid_hops":   {Ego: "thehaohcm@yahoo.com.vn", Hops: 6},
+		"invalid_domain": {Domain: "s3corp..com"},
+	} {
+		err := svc.Export(context.Background(), &b, models.GraphFormatDOT, filter)
+		assert.Equal(t, code, apperrors.From(err).Code, code)
+	}
+	err := svc.Export(context.Background(), &b, models.GraphFormatDOT, models.GraphExportFilter{Hops: 2})
+	assert.Equal(t, "invalid_hops", apperrors.From(err).Code)
+	err = svc.Export(context.Background(), &b, "png", models.GraphExportFilter{})
+	assert.Equal(t, "invalid_graph_format", apperrors.From(err).Code)
+	assert.Empty(t, b.String())
+}
+
+// graphExportRepoFake streams fixed users and relationships and records the filter it got
+type graphExportRepoFake struct {
+	users         []string
+	relationships []models.Relationship
+	filter        models.GraphExportFilter
+}
+
+func (r *graphExportRepoFake) StreamUsers(ctx context.Context, filter models.GraphExportFilter, fn func(email string) error) error {
+	r.filter = filter
+	for _, user := range r.users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *graphExportRepoFake) StreamRelationships(ctx context.Context, filter models.GraphExportFilter, fn func(relationship models.Relationship) error) error {
+	for _, relationship := range r.relationships {
+		if err := fn(relationship); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/auth"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/repositories"
+)
+
+var (
+	// ErrFriendListHidden is returned when the privacy settings of a user hide its friend list from the caller
+	ErrFriendListHidden = apperrors.Forbidden("friend_list_hidden", "the friend list of this user is not visible to you")
+	// ErrSubscriptionNotAllowed is returned when the privacy settings of the target do not accept the subscription
+	ErrSubscriptionNotAllowed = apperrors.Forbidden("subscription_not_allowed", "this user does not accept subscriptions from you")
+)
+
+// PrivacyChecker struct applies the privacy settings of the users, it is shared by the service layer and the authorization policy
+type PrivacyChecker struct {
+	repository repositories.FriendConnectionRepository
+}
+
+// NewPrivacyChecker function used for initializing a PrivacyChecker
+// pass a FriendConnectionRepository as parameter
+// return a pointer of PrivacyChecker
+func NewPrivacyChecker(repo repositories.FriendConnectionRepository) *PrivacyChecker {
+	return &PrivacyChecker{
+		repository: repo,
+	}
+}
+
+// CanViewFriends function used to know whether a viewer may read the friend list of an owner,
+// owners always see their own list, an empty viewer only sees public lists
+// pass a context, the viewer email and the owner email as parameters
+// return a boolean and an error type
+func (c *PrivacyChecker) CanViewFriends(ctx context.Context, viewer, owner string) (bool, error) {
+	visible, err := c.visibleFriendLists(ctx, viewer, []string{owner})
+	if err != nil {
+		return false, err
+	}
+	return visible[owner], nil
+}
+
+// visibleFriendLists function used to know which friend lists of many owners are visible to a viewer,
+// with one query for the settings and at most one for the friends of the viewer
+func (c *PrivacyChecker) visibleFriendLists(ctx context.Context, viewer string, owners []string) (map[string]bool, error) {
+	settings, err := c.settings(ctx, owners)
+	if err != nil {
+		return nil, err
+	}
+
+	var viewerFriends map[string]bool
+	visible := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		switch {
+		case viewer != "" && strings.EqualFold(owner, viewer):
+			visible[owner] = true
+		case settings[owner].FriendListVisibility == models.FriendListPublic:
+			visible[owner] = true
+		case settings[owner].FriendListVisibility == models.FriendListFriends && viewer != "":
+			if viewerFriends == nil {
+				if viewerFriends, err = c.friends(ctx, viewer); err != nil {
+					return nil, err
+				}
+			}
+			visible[owner] = viewerFriends[owner]
+		default:
+			visible[owner] = false
+		}
+	}
+	return visible, nil
+}
+
+// checkSubscription function used to enforce the subscription policy of the target of a new subscription
+// pass a context, the requestor email and the target email as parameters
+// return an error type, ErrSubscriptionNotAllowed when the policy rejects the requestor
+func (c *PrivacyChecker) checkSubscription(ctx context.Context, requestor, target string) error {
+	settings, err := c.settings(ctx, []string{target})
+	if err != nil {
+		return err
+	}
+
+	switch settings[target].SubscriptionPolicy {
+	case models.SubscriptionNobody:
+		return ErrSubscriptionNotAllowed.WithDetail("subscription_policy", models.SubscriptionNobody)
+	case models.SubscriptionFriendsOfFriends:
+		targetFriends, err := c.friends(ctx, target)
+		if err != nil {
+			return err
+		}
+		if targetFriends[requestor] {
+			return nil
+		}
+		requestorFriends, err := c.friends(ctx, requestor)
+		if err != nil {
+			return err
+		}
+		for friend := range requestorFriends {
+			if targetFriends[friend] {
+				return nil
+			}
+		}
+		return ErrSubscriptionNotAllowed.WithDetail("subscription_policy", models.SubscriptionFriendsOfFriends)
+	}
+	return nil
+}
+
+// settings function used to get the privacy settings of many users, with the default settings for the users without a row
+func (c *PrivacyChecker) settings(ctx context.Context, emails []string) (map[string]models.PrivacySettings, error) {
+	stored, err := c.repository.FindPrivacySettingsByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]models.PrivacySettings, len(emails))
+	for _, email := range emails {
+		settings[email] = models.DefaultPrivacySettings(email)
+	}
+	for _, setting := range stored {
+		settings[setting.Email] = setting
+	}
+	return settings, nil
+}
+
+func (c *PrivacyChecker) friends(ctx context.Context, email string) (map[string]bool, error) {
+	relationships, err := c.repository.FindFriendsByEmail(ctx, models.FriendListRequest{Email: email})
+	if err != nil {
+		return nil, err
+	}
+	friends := make(map[string]bool, len(relationships))
+	for _, relationship := range relationships {
+		friends[relationship.Target] = true
+	}
+	return friends, nil
+}
+
+// restrictedViewer function used to get the email the privacy settings are applied for,
+// restricted is false when the caller may see everything: admins, service accounts,
+// or internal calls without principal such as the requests served when authentication is disabled
+func restrictedViewer(ctx context.Context) (viewer string, restricted bool) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.HasRole(auth.RoleAdmin, auth.RoleService) {
+		return "", false
+	}
+	return principal.Email, true
+}
+
+// isValidFriendListVisibility function used to know whether a value is a known friend list visibility
+func isValidFriendListVisibility(value string) bool {
+	switch value {
+	case models.FriendListPublic, models.FriendListFriends, models.FriendListPrivate:
+		return true
+	}
+	return false
+}
+
+// isValidSubscriptionPolicy function used to know whether a value is a known subscription policy
+func isValidSubscriptionPolicy(value string) bool {
+	switch value {
+	case models.SubscriptionEveryone, models.SubscriptionFriendsOfFriends, models.SubscriptionNobody:
+		return true
+	}
+	return false
+}
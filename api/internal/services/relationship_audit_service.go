@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+	"golang_project/api/internal/pkg"
+	"golang_project/api/internal/repositories"
+)
+
+const (
+	// defaultAuditLimit is the size of a page of the audit when none is requested
+	defaultAuditLimit = 100
+	// maxAuditLimit bounds the size of a page of the audit
+	maxAuditLimit = 1000
+)
+
+// RelationshipAuditService interface declares the functions used by admins to query the audit of the relationship writes
+type RelationshipAuditService interface {
+	ListRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) (models.RelationshipAuditResponse, error)
+}
+
+type relationshipAuditService struct {
+	repository repositories.RelationshipAuditRepository
+}
+
+// NewRelationshipAuditService function used for initializing a RelationshipAuditService
+// pass a RelationshipAuditRepository as parameter
+// return a RelationshipAuditService
+func NewRelationshipAuditService(repo repositories.RelationshipAuditRepository) RelationshipAuditService {
+	return &relationshipAuditService{
+		repository: repo,
+	}
+}
+
+// ListRelationshipAudit function works as a service function for listing a page of the relationship writes kept by a filter, newest first
+// pass a context and a RelationshipAuditFilter model as parameters
+// return a RelationshipAuditResponse model and an error type
+func (svc *relationshipAuditService) ListRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) (models.RelationshipAuditResponse, error) {
+	filter, err := normalizeRelationshipAuditFilter(filter)
+	if err != nil {
+		return models.RelationshipAuditResponse{}, err
+	}
+
+	// one more entry tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := svc.repository.FindRelationshipAudit(ctx, filter)
+	if err != nil {
+		return models.RelationshipAuditResponse{}, err
+	}
+
+	response := models.RelationshipAuditResponse{Entries: entries}
+	if len(entries) > limit {
+		response.Entries = entries[:limit]
+		response.NextBefore = response.Entries[limit-1].ID
+	}
+	response.Count = len(response.Entries)
+	return response, nil
+}
+
+// normalizeRelationshipAuditFilter function validates a filter and applies the default size of a page
+func normalizeRelationshipAuditFilter(filter models.RelationshipAuditFilter) (models.RelationshipAuditFilter, error) {
+	filter.Email = strings.TrimSpace(filter.Email)
+	if filter.Email != "" {
+		if err := pkg.CheckValidEmail(filter.Email); err != nil {
+			return filter, err
+		}
+	}
+	switch filter.Action {
+	case "", models.AuditActionFriend, models.AuditActionUnfriend, models.AuditActionSubscribe,
+		models.AuditActionUnsubscribe, models.AuditActionBlock, models.AuditActionUnblock:
+	default:
+		return filter, apperrors.Validation("invalid_action", "invalid action, expected friend, unfriend, subscribe, unsubscribe, block or unblock").WithDetail("action", filter.Action)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, apperrors.Validation("invalid_time_range", "from must be before to")
+	}
+	if filter.Before < 0 {
+		return filter, apperrors.Validation("invalid_before", "before must be a positive entry id")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit < 0 || filter.Limit > maxAuditLimit {
+		return filter, apperrors.Validation("invalid_limit", "limit must be between 1 and 1000").WithDetail("limit", filter.Limit)
+	}
+	return filter, nil
+}
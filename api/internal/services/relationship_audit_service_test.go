@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang_project/api/internal/apperrors"
+	"golang_project/api/internal/models"
+)
+
+func TestListRelationshipAuditPages(t *testing.T) {
+	repo := &relationshipAuditRepoFake{entries: []models.RelationshipAuditEntry{{ID: 9}, {ID: 8}, {ID: 7}}}
+	svc := NewRelationshipAuditService(repo)
+
+	response, err := svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{Email: " abc@def.com ", Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, models.RelationshipAuditResponse{Entries: []models.RelationshipAuditEntry{{ID: 9}, {ID: 8}}, Count: 2, NextBefore: 8}, response)
+	assert.Equal(t, models.RelationshipAuditFilter{Email: "abc@def.com", Limit: 3}, repo.filter)
+
+	// the last page has no next one
+	response, err = svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, response.Count)
+	assert.Zero(t, response.NextBefore)
+	assert.Equal(t, defaultAuditLimit+1, repo.filter.Limit)
+}
+
+func TestListRelationshipAuditWithInvalidFilter(t *testing.T) {
+	svc := NewRelationshipAuditService(&relationshipAuditRepoFake{})
+	now := time.Now()
+
+	_, err := svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{Email: "abc"})
+	assert.ErrorIs(t, err, apperrors.ErrValidation)
+	_, err = svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{Action: "delete"})
+	assert.Equal(t, "invalid_action", apperrors.From(err).Code)
+	_, err = svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{From: &now, To: &now})
+	assert.Equal(t, "invalid_time_range", apperrors.From(err).Code)
+	_, err = svc.ListRelationshipAudit(context.Background(), models.RelationshipAuditFilter{Limit: 5000})
+	assert.Equal(t, "invalid_limit", apperrors.From(err).Code)
+}
+
+// relationshipAuditRepoFake returns its entries up to the limit of the filter, and records the filter
+type relationshipAuditRepoFake struct {
+	entries []models.RelationshipAuditEntry
+	filter  models.RelationshipAuditFilter
+}
+
+func (r *relationshipAuditRepoFake) FindRelationshipAudit(ctx context.Context, filter models.RelationshipAuditFilter) ([]models.RelationshipAuditEntry, error) {
+	r.filter = filter
+	if len(r.entries) > filter.Limit {
+		return r.entries[:filter.Limit], nil
+	}
+	return r.entries, nil
+}